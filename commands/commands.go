@@ -6,22 +6,35 @@ package commands
 
 import (
 	"context"
+	"expvar"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/casbin/casbin/v2"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
 	"github.com/peterbourgon/ff/v3"
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/go-api-basic/datastore"
 	"github.com/gilcrest/go-api-basic/datastore/pingstore"
+	"github.com/gilcrest/go-api-basic/datastore/policystore"
+	"github.com/gilcrest/go-api-basic/datastore/ratelimitstore"
+	"github.com/gilcrest/go-api-basic/datastore/usagestore"
+	"github.com/gilcrest/go-api-basic/datastore/userstore"
 	"github.com/gilcrest/go-api-basic/domain/auth"
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/logger"
+	"github.com/gilcrest/go-api-basic/domain/org/settingscache"
+	"github.com/gilcrest/go-api-basic/domain/ratelimit"
 	"github.com/gilcrest/go-api-basic/domain/secure"
 	"github.com/gilcrest/go-api-basic/domain/secure/random"
+	"github.com/gilcrest/go-api-basic/domain/usage"
+	"github.com/gilcrest/go-api-basic/domain/user/usercache"
 	"github.com/gilcrest/go-api-basic/gateway/authgateway"
+	"github.com/gilcrest/go-api-basic/gateway/secretgateway"
 	"github.com/gilcrest/go-api-basic/server"
 	"github.com/gilcrest/go-api-basic/service"
 )
@@ -49,6 +62,42 @@ const (
 	dbSearchPath string = "DB_SEARCH_PATH"
 	// encryption key environment variable name
 	encryptKey string = "ENCRYPT_KEY"
+	// API key secret environment variable name
+	apiKeySecretEnv string = "API_KEY_SECRET"
+	// Google OAuth2 client IDs environment variable name
+	googleClientIDsEnv string = "GOOGLE_CLIENT_IDS"
+	// Sign in with Apple client ID environment variable name
+	appleClientIDEnv string = "APPLE_CLIENT_ID"
+	// identity cache size environment variable name
+	identityCacheSizeEnv string = "IDENTITY_CACHE_SIZE"
+	// identity cache TTL environment variable name
+	identityCacheTTLEnv string = "IDENTITY_CACHE_TTL"
+	// org settings cache TTL environment variable name
+	orgSettingsCacheTTLEnv string = "ORG_SETTINGS_CACHE_TTL"
+	// OpenID Connect provider configuration file environment variable name
+	oidcConfigEnv string = "OIDC_CONFIG"
+	// authorization permission detail environment variable name
+	authzPermissionDetailEnv string = "AUTHZ_PERMISSION_DETAIL"
+	// rate limit environment variable name
+	rateLimitEnv string = "RATE_LIMIT"
+	// rate limit per user environment variable name
+	rateLimitPerUserEnv string = "RATE_LIMIT_PER_USER"
+	// rate limit store environment variable name
+	rateLimitStoreEnv string = "RATE_LIMIT_STORE"
+	// usage flush interval environment variable name
+	usageFlushIntervalEnv string = "USAGE_FLUSH_INTERVAL"
+	// monthly quota environment variable name
+	monthlyQuotaEnv string = "MONTHLY_QUOTA"
+	// secret source environment variable name
+	secretSourceEnv string = "SECRET_SOURCE"
+	// secret directory environment variable name
+	secretDirEnv string = "SECRET_DIR"
+	// secret command environment variable name
+	secretCommandEnv string = "SECRET_COMMAND"
+	// secret refresh interval environment variable name
+	secretRefreshIntervalEnv string = "SECRET_REFRESH_INTERVAL"
+	// local KMS key file environment variable name
+	kmsKeyFileEnv string = "KMS_KEY_FILE"
 )
 
 type flags struct {
@@ -90,8 +139,88 @@ type flags struct {
 	// dbsearchpath is the database search path
 	dbsearchpath string
 
-	// encryptkey is the encryption key
+	// encryptkey is the encryption keyring, a comma separated list
+	// of id:hexkey entries, the first being the primary key. A single
+	// hex key without an ID is accepted as well.
 	encryptkey string
+
+	// apiKeySecret is the server secret API keys are hashed with
+	apiKeySecret string
+
+	// googleClientIDs is a comma separated list of Google OAuth2
+	// client IDs. Google ID tokens issued to one of them are
+	// verified locally instead of calling the Userinfo API
+	googleClientIDs string
+
+	// appleClientID is the Sign in with Apple Services ID (or
+	// App bundle ID) used to validate the audience of Apple
+	// identity tokens
+	appleClientID string
+
+	// identityCacheSize is the maximum number of authenticated
+	// identities held in memory. Zero disables the cache.
+	identityCacheSize int
+
+	// identityCacheTTL is the maximum time an authenticated
+	// identity is cached
+	identityCacheTTL time.Duration
+
+	// orgSettingsCacheTTL is the maximum time the settings of an
+	// Org are cached. Zero disables the cache.
+	orgSettingsCacheTTL time.Duration
+
+	// oidcConfig is the path to a JSON file configuring the
+	// OpenID Connect providers (Okta, Auth0, Keycloak, etc.)
+	oidcConfig string
+
+	// authzPermissionDetail flag determines whether the action and
+	// resource a user lacks permission for are sent in the 403
+	// response body
+	authzPermissionDetail bool
+
+	// rateLimit is the default number of requests per minute allowed
+	// for an App, Apps may have their own limit. Zero disables rate
+	// limiting for Apps without their own limit.
+	rateLimit int
+
+	// rateLimitPerUser flag determines whether each User of an App
+	// is rate limited separately
+	rateLimitPerUser bool
+
+	// rateLimitStore is where rate limiting state is kept, either
+	// memory (per instance) or postgres (shared by all instances)
+	rateLimitStore string
+
+	// usageFlushInterval is how often the metered usage of Apps is
+	// flushed to the database
+	usageFlushInterval time.Duration
+
+	// monthlyQuota is the default number of requests per calendar
+	// month allowed for an App, Apps may have their own quota. Zero
+	// means no quota for Apps without their own.
+	monthlyQuota int64
+
+	// secretSource is where the encryption key and database password
+	// are retrieved from: flags (the encrypt-key and db-password
+	// flags or their environment variables), env, file or exec
+	secretSource string
+
+	// secretDir is the directory of the secret files when
+	// secretSource is file, e.g. /run/secrets
+	secretDir string
+
+	// secretCommand is the command run with the secret name as its
+	// last argument when secretSource is exec
+	secretCommand string
+
+	// secretRefreshInterval is how often secrets are retrieved again
+	// to pick up rotated values. Zero disables refreshing.
+	secretRefreshInterval time.Duration
+
+	// kmsKeyFile is the path to a file holding the key-encryption
+	// keyring of the local KMS. If set, each new API key is hashed
+	// with a data key of its own, wrapped by the KMS.
+	kmsKeyFile string
 }
 
 // newFlags parses the command line flags using ff and returns
@@ -102,17 +231,35 @@ func newFlags(args []string) (flags, error) {
 	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
 
 	var (
-		logLvlMin     = flagSet.String("log-level-min", "trace", fmt.Sprintf("sets minimum log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", logLevelMinEnv))
-		loglvl        = flagSet.String("log-level", "info", fmt.Sprintf("sets log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", loglevelEnv))
-		logErrorStack = flagSet.Bool("log-error-stack", true, fmt.Sprintf("if true, log full error stacktrace, else just log error, (also via %s)", logErrorStackEnv))
-		port          = flagSet.Int("port", 8080, fmt.Sprintf("listen port for server (also via %s)", portEnv))
-		dbhost        = flagSet.String("db-host", "", fmt.Sprintf("postgresql database host (also via %s)", dbHostEnv))
-		dbport        = flagSet.Int("db-port", 5432, fmt.Sprintf("postgresql database port (also via %s)", dbPortEnv))
-		dbname        = flagSet.String("db-name", "", fmt.Sprintf("postgresql database name (also via %s)", dbNameEnv))
-		dbuser        = flagSet.String("db-user", "", fmt.Sprintf("postgresql database user (also via %s)", dbUserEnv))
-		dbpassword    = flagSet.String("db-password", "", fmt.Sprintf("postgresql database password (also via %s)", dbPasswordEnv))
-		dbsearchpath  = flagSet.String("db-search-path", "", fmt.Sprintf("postgresql database search path (also via %s)", dbSearchPath))
-		encryptkey    = flagSet.String("encrypt-key", "", fmt.Sprintf("encryption key (also via %s)", encryptKey))
+		logLvlMin             = flagSet.String("log-level-min", "trace", fmt.Sprintf("sets minimum log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", logLevelMinEnv))
+		loglvl                = flagSet.String("log-level", "info", fmt.Sprintf("sets log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", loglevelEnv))
+		logErrorStack         = flagSet.Bool("log-error-stack", true, fmt.Sprintf("if true, log full error stacktrace, else just log error, (also via %s)", logErrorStackEnv))
+		port                  = flagSet.Int("port", 8080, fmt.Sprintf("listen port for server (also via %s)", portEnv))
+		dbhost                = flagSet.String("db-host", "", fmt.Sprintf("postgresql database host (also via %s)", dbHostEnv))
+		dbport                = flagSet.Int("db-port", 5432, fmt.Sprintf("postgresql database port (also via %s)", dbPortEnv))
+		dbname                = flagSet.String("db-name", "", fmt.Sprintf("postgresql database name (also via %s)", dbNameEnv))
+		dbuser                = flagSet.String("db-user", "", fmt.Sprintf("postgresql database user (also via %s)", dbUserEnv))
+		dbpassword            = flagSet.String("db-password", "", fmt.Sprintf("postgresql database password (also via %s)", dbPasswordEnv))
+		dbsearchpath          = flagSet.String("db-search-path", "", fmt.Sprintf("postgresql database search path (also via %s)", dbSearchPath))
+		encryptkey            = flagSet.String("encrypt-key", "", fmt.Sprintf("comma separated encryption keys (id:hexkey), the first is the primary key (also via %s)", encryptKey))
+		apiKeySecret          = flagSet.String("api-key-secret", "", fmt.Sprintf("secret API keys are hashed with (also via %s)", apiKeySecretEnv))
+		googleClientIDs       = flagSet.String("google-client-ids", "", fmt.Sprintf("comma separated Google OAuth2 client IDs for local ID token verification (also via %s)", googleClientIDsEnv))
+		appleClientID         = flagSet.String("apple-client-id", "", fmt.Sprintf("Sign in with Apple client ID (also via %s)", appleClientIDEnv))
+		identityCacheSize     = flagSet.Int("identity-cache-size", 10000, fmt.Sprintf("maximum number of cached user identities, 0 disables the cache (also via %s)", identityCacheSizeEnv))
+		identityCacheTTL      = flagSet.Duration("identity-cache-ttl", 5*time.Minute, fmt.Sprintf("maximum time a user identity is cached (also via %s)", identityCacheTTLEnv))
+		orgSettingsCacheTTL   = flagSet.Duration("org-settings-cache-ttl", time.Minute, fmt.Sprintf("maximum time org settings are cached, 0 disables the cache (also via %s)", orgSettingsCacheTTLEnv))
+		oidcConfig            = flagSet.String("oidc-config", "", fmt.Sprintf("path to OpenID Connect provider configuration file (also via %s)", oidcConfigEnv))
+		authzPermissionDetail = flagSet.Bool("authz-permission-detail", false, fmt.Sprintf("if true, send the required permission in 403 responses (also via %s)", authzPermissionDetailEnv))
+		rateLimit             = flagSet.Int("rate-limit", 600, fmt.Sprintf("default requests per minute allowed for an app, 0 disables the default limit (also via %s)", rateLimitEnv))
+		rateLimitPerUser      = flagSet.Bool("rate-limit-per-user", false, fmt.Sprintf("if true, rate limit each user of an app separately (also via %s)", rateLimitPerUserEnv))
+		rateLimitStore        = flagSet.String("rate-limit-store", "memory", fmt.Sprintf("where rate limit state is kept (memory, postgres) (also via %s)", rateLimitStoreEnv))
+		usageFlushInterval    = flagSet.Duration("usage-flush-interval", 30*time.Second, fmt.Sprintf("how often metered app usage is flushed to the database (also via %s)", usageFlushIntervalEnv))
+		monthlyQuota          = flagSet.Int64("monthly-quota", 0, fmt.Sprintf("default requests per month allowed for an app, 0 for no quota (also via %s)", monthlyQuotaEnv))
+		secretSource          = flagSet.String("secret-source", "flags", fmt.Sprintf("where the encryption key, API key secret and database password are retrieved from (flags, env, file, exec) (also via %s)", secretSourceEnv))
+		secretDir             = flagSet.String("secret-dir", "/run/secrets", fmt.Sprintf("directory of secret files for the file secret source (also via %s)", secretDirEnv))
+		secretCommand         = flagSet.String("secret-command", "", fmt.Sprintf("command run with the secret name as last argument for the exec secret source (also via %s)", secretCommandEnv))
+		secretRefreshInterval = flagSet.Duration("secret-refresh-interval", 5*time.Minute, fmt.Sprintf("how often secrets are retrieved again to pick up rotated values, 0 disables refreshing (also via %s)", secretRefreshIntervalEnv))
+		kmsKeyFile            = flagSet.String("kms-key-file", "", fmt.Sprintf("path to the key-encryption keyring file of the local KMS, enables envelope encryption (also via %s)", kmsKeyFileEnv))
 	)
 
 	// Parse the command line flags from above
@@ -122,17 +269,35 @@ func newFlags(args []string) (flags, error) {
 	}
 
 	return flags{
-		loglvl:        *loglvl,
-		logLvlMin:     *logLvlMin,
-		logErrorStack: *logErrorStack,
-		port:          *port,
-		dbhost:        *dbhost,
-		dbport:        *dbport,
-		dbname:        *dbname,
-		dbuser:        *dbuser,
-		dbpassword:    *dbpassword,
-		dbsearchpath:  *dbsearchpath,
-		encryptkey:    *encryptkey,
+		loglvl:                *loglvl,
+		logLvlMin:             *logLvlMin,
+		logErrorStack:         *logErrorStack,
+		port:                  *port,
+		dbhost:                *dbhost,
+		dbport:                *dbport,
+		dbname:                *dbname,
+		dbuser:                *dbuser,
+		dbpassword:            *dbpassword,
+		dbsearchpath:          *dbsearchpath,
+		encryptkey:            *encryptkey,
+		apiKeySecret:          *apiKeySecret,
+		googleClientIDs:       *googleClientIDs,
+		appleClientID:         *appleClientID,
+		identityCacheSize:     *identityCacheSize,
+		identityCacheTTL:      *identityCacheTTL,
+		orgSettingsCacheTTL:   *orgSettingsCacheTTL,
+		oidcConfig:            *oidcConfig,
+		authzPermissionDetail: *authzPermissionDetail,
+		rateLimit:             *rateLimit,
+		rateLimitPerUser:      *rateLimitPerUser,
+		rateLimitStore:        *rateLimitStore,
+		usageFlushInterval:    *usageFlushInterval,
+		monthlyQuota:          *monthlyQuota,
+		secretSource:          *secretSource,
+		secretDir:             *secretDir,
+		secretCommand:         *secretCommand,
+		secretRefreshInterval: *secretRefreshInterval,
+		kmsKeyFile:            *kmsKeyFile,
 	}, nil
 }
 
@@ -196,53 +361,352 @@ func Run(args []string) error {
 	// set listener address
 	s.Addr = fmt.Sprintf(":%d", flgs.port)
 
-	if flgs.encryptkey == "" {
-		lgr.Fatal().Msg("no encryption key found")
+	// retrieve the encryption key, API key secret and database
+	// password from the secret source
+	secrets, err := newSecretCache(context.Background(), flgs)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newSecretCache error")
+	}
+
+	// decode and retrieve encryption keyring
+	keyring, err := secure.ParseKeyring(secrets.Get(encryptKey))
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("secure.ParseKeyring() error")
+	}
+
+	if secrets.Get(apiKeySecretEnv) == "" {
+		lgr.Fatal().Msg("no API key secret found")
 	}
 
-	// decode and retrieve encryption key
-	ek, err := secure.ParseEncryptionKey(flgs.encryptkey)
+	// decode and retrieve API key secret
+	aks, err := secure.ParseEncryptionKey(secrets.Get(apiKeySecretEnv))
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("secure.ParseEncryptionKey() error")
+		lgr.Fatal().Err(err).Msg("secure.ParseEncryptionKey() error for API key secret")
 	}
 
-	// initialize PostgreSQL database
-	dbpool, cleanup, err := datastore.NewPostgreSQLPool(context.Background(), newPostgreSQLDSN(flgs), lgr)
+	// initialize the KMS for envelope encryption, if configured
+	kms, err := newKMS(flgs)
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("datastore.NewPostgreSQLPool error")
+		lgr.Fatal().Err(err).Msg("newKMS error")
+	}
+
+	// initialize PostgreSQL database, new connections use the latest
+	// database password
+	dbpool, cleanup, err := datastore.NewRotatingPostgreSQLPool(context.Background(), newPostgreSQLDSN(flgs), func() string { return secrets.Get(dbPasswordEnv) }, lgr)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("datastore.NewRotatingPostgreSQLPool error")
 	}
 	defer cleanup()
 
+	// reconnect idle connections when the database password is
+	// rotated and refresh the secrets in the background
+	secrets.OnChange(func(name, _ string) {
+		switch name {
+		case dbPasswordEnv:
+			n := datastore.CloseIdleConns(context.Background(), dbpool)
+			lgr.Info().Msgf("database password changed, %d idle connections closed", n)
+		case encryptKey:
+			lgr.Warn().Msg("encryption key changed, restart the server to use it")
+		case apiKeySecretEnv:
+			lgr.Warn().Msg("API key secret changed, restart the server to use it")
+		}
+	})
+	secretsCtx, stopSecrets := context.WithCancel(context.Background())
+	defer stopSecrets()
+	if flgs.secretSource != "flags" && flgs.secretRefreshInterval > 0 {
+		go secrets.Run(secretsCtx, flgs.secretRefreshInterval, lgr)
+	}
+
 	// initialize Datastore
 	ds := datastore.NewDatastore(dbpool)
 
-	// initialize casbin enforcer (using config files for now, will migrate to db)
-	casbinEnforcer, err := casbin.NewEnforcer("config/rbac_model.conf", "config/rbac_policy.csv")
+	// hash any API keys still stored encrypted
+	migrated, err := service.MigrateAPIKeyService{Datastorer: ds, Keyring: keyring, APIKeySecret: aks, KMS: kms}.Migrate(context.Background())
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("MigrateAPIKeyService.Migrate error")
+	}
+	if migrated > 0 {
+		lgr.Info().Msgf("%d encrypted API keys migrated to hashed keys", migrated)
+	}
+
+	// initialize casbin enforcer with the policy stored in the database
+	casbinEnforcer, err := casbin.NewSyncedEnforcer("config/rbac_model.conf", policystore.NewAdapter(ds))
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("casbin.NewSyncedEnforcer error")
+	}
+
+	// seed an empty database policy from the default policy file,
+	// reloading the policy once locked as another instance may have
+	// seeded it in the meantime
+	err = policystore.WithLock(context.Background(), ds, func() error {
+		if err := casbinEnforcer.LoadPolicy(); err != nil {
+			return err
+		}
+		return seedPolicy(casbinEnforcer, "config/rbac_model.conf", "config/rbac_policy.csv")
+	})
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("seedPolicy error")
+	}
+
+	// reload the policy whenever another instance changes it
+	policyWatcher := policystore.NewWatcher(context.Background(), ds, lgr)
+	defer policyWatcher.Close()
+	err = casbinEnforcer.SetWatcher(policyWatcher)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("casbinEnforcer.SetWatcher error")
+	}
+
+	// initialize OpenID Connect providers, if configured
+	var oidcConfigs []authgateway.OIDCProviderConfig
+	if flgs.oidcConfig != "" {
+		oidcConfigs, err = authgateway.LoadOIDCProviderConfigs(flgs.oidcConfig)
+		if err != nil {
+			lgr.Fatal().Err(err).Msg("authgateway.LoadOIDCProviderConfigs error")
+		}
+	}
+	oidcConverter, err := authgateway.NewOIDCTokenConverter(oidcConfigs, nil, 0)
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("casbin.NewEnforcer error")
+		lgr.Fatal().Err(err).Msg("authgateway.NewOIDCTokenConverter error")
 	}
 
+	// initialize the user identity cache, with hit/miss counts
+	// published through expvar. Users changed on any instance are
+	// removed from the cache of every instance.
+	var identityCache service.IdentityCache
+	if flgs.identityCacheSize > 0 {
+		lru := usercache.NewLRU(flgs.identityCacheSize, flgs.identityCacheTTL)
+		expvar.Publish("identity_cache", expvar.Func(func() interface{} { return lru.Stats() }))
+		userWatcher := userstore.NewWatcher(context.Background(), ds, lgr)
+		defer userWatcher.Close()
+		broadcast := usercache.NewBroadcast(lru, userWatcher, lgr)
+		userWatcher.SetCallback(broadcast.Receive)
+		identityCache = broadcast
+	}
+
+	// initialize the org settings cache, settings are removed from
+	// it when changed through this server
+	var settingsCache service.SettingsCache
+	if flgs.orgSettingsCacheTTL > 0 {
+		settingsCache = settingscache.New(flgs.orgSettingsCacheTTL)
+	}
+
+	// initialize the rate limit store, the postgres store shares
+	// limits across all instances of the server
+	var rateLimitStore service.RateLimitStore
+	switch flgs.rateLimitStore {
+	case "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimitStore = ratelimitstore.NewStore(ds)
+	default:
+		lgr.Fatal().Msgf("invalid rate-limit-store %q, must be memory or postgres", flgs.rateLimitStore)
+	}
+
+	// start the usage meter, which flushes usage to the database in
+	// the background. Pending usage is flushed when the server stops.
+	usageMeter := usage.NewMeter(usagestore.NewStore(ds), flgs.usageFlushInterval, lgr)
+	meterCtx, stopMeter := context.WithCancel(context.Background())
+	meterDone := make(chan struct{})
+	go func() {
+		usageMeter.Run(meterCtx)
+		close(meterDone)
+	}()
+
 	s.Services = server.Services{
+		CreateMovieService: service.CreateMovieService{Datastorer: ds},
+		UpdateMovieService: service.UpdateMovieService{Datastorer: ds},
+		DeleteMovieService: service.DeleteMovieService{Datastorer: ds},
+		FindMovieService:   service.FindMovieService{Datastorer: ds},
 		SeedService: service.SeedService{
 			Datastorer:            ds,
 			CryptoRandomGenerator: random.CryptoGenerator{},
-			EncryptionKey:         ek,
+			APIKeySecret:          aks,
+			KMS:                   kms,
+			PolicyManager:         casbinEnforcer,
 		},
-		PingService:      service.PingService{Pinger: pingstore.Pinger{Datastorer: ds}},
-		LoggerService:    service.LoggerService{Logger: lgr},
-		CreateOrgService: service.CreateOrgService{Datastorer: ds},
-		UpdateOrgService: service.UpdateOrgService{Datastorer: ds},
-		FindOrgService:   service.FindOrgService{Datastorer: ds},
-		CreateAppService: service.CreateAppService{Datastorer: ds, CryptoRandomGenerator: random.CryptoGenerator{}, EncryptionKey: ek},
-		FindAppService:   service.FindAppService{Datastorer: ds},
-		FindUserService:  service.FindUserService{GoogleOauth2TokenConverter: authgateway.GoogleOauth2TokenConverter{}, Datastorer: ds},
-		AuthorizeService: service.AuthorizeService{Authorizer: auth.CasbinAuthorizer{Enforcer: casbinEnforcer}},
-	}
-
-	return s.ListenAndServe()
+		PingService:         service.PingService{Pinger: pingstore.Pinger{Datastorer: ds}},
+		LoggerService:       service.LoggerService{Logger: lgr},
+		CreateOrgService:    service.CreateOrgService{Datastorer: ds, PolicyManager: casbinEnforcer},
+		UpdateOrgService:    service.UpdateOrgService{Datastorer: ds, SettingsCache: settingsCache},
+		DeleteOrgService:    service.DeleteOrgService{Datastorer: ds, PolicyManager: casbinEnforcer, IdentityCache: identityCache, SettingsCache: settingsCache},
+		MoveOrgService:      service.MoveOrgService{Datastorer: ds},
+		OrgSettingsService:  service.OrgSettingsService{Datastorer: ds, SettingsCache: settingsCache},
+		FindOrgService:      service.FindOrgService{Datastorer: ds},
+		CreateAppService:    service.CreateAppService{Datastorer: ds, CryptoRandomGenerator: random.CryptoGenerator{}, APIKeySecret: aks, KMS: kms, SettingsCache: settingsCache},
+		FindOrgAppService:   service.FindOrgAppService{Datastorer: ds},
+		UpdateAppService:    service.UpdateAppService{Datastorer: ds},
+		DeleteAppService:    service.DeleteAppService{Datastorer: ds},
+		FindAppService:      service.FindAppService{Datastorer: ds, APIKeySecret: aks, KMS: kms},
+		APIKeyService:       service.APIKeyService{Datastorer: ds, CryptoRandomGenerator: random.CryptoGenerator{}, APIKeySecret: aks, KMS: kms, SettingsCache: settingsCache},
+		FindAppUsageService: service.FindAppUsageService{Datastorer: ds, DefaultQuota: flgs.monthlyQuota},
+		FindUserService: service.FindUserService{
+			GoogleOauth2TokenConverter: authgateway.NewGoogleOauth2TokenConverter(splitList(flgs.googleClientIDs), nil),
+			AppleTokenConverter:        authgateway.NewAppleTokenConverter(flgs.appleClientID, nil),
+			OIDCTokenConverter:         oidcConverter,
+			IdentityCache:              identityCache,
+			SettingsCache:              settingsCache,
+			Datastorer:                 ds,
+		},
+		RegisterUserService:   service.RegisterUserService{Datastorer: ds, PolicyManager: casbinEnforcer},
+		FindOrgUserService:    service.FindOrgUserService{Datastorer: ds},
+		UpdateUserService:     service.UpdateUserService{Datastorer: ds, IdentityCache: identityCache},
+		DeactivateUserService: service.DeactivateUserService{Datastorer: ds, IdentityCache: identityCache},
+		AuthorizeService:      service.AuthorizeService{Authorizer: auth.CasbinAuthorizer{Enforcer: casbinEnforcer, PermissionDetail: flgs.authzPermissionDetail}},
+		PolicyService:         service.PolicyService{Datastorer: ds, PolicyManager: casbinEnforcer},
+		RateLimitService:      service.RateLimitService{Store: rateLimitStore, DefaultLimit: flgs.rateLimit, PerUser: flgs.rateLimitPerUser},
+		UsageMeter:            usageMeter,
+		QuotaService:          service.NewQuotaService(ds, usageMeter, flgs.monthlyQuota),
+	}
+
+	err = s.ListenAndServe()
+
+	stopMeter()
+	<-meterDone
+
+	return err
+}
+
+// RotateKeys parses the same command line flags as Run and
+// re-encrypts every stored ciphertext with the primary key of the
+// encryption keyring, inside a single transaction. If a KMS is
+// configured, every stored data key is also rewrapped with its primary
+// key-encryption key. After rotating, keys other than the primary key
+// can be removed from the keyring and the KMS key file.
+func RotateKeys(args []string) error {
+	flgs, err := newFlags(args)
+	if err != nil {
+		return err
+	}
+
+	minlvl, err := zerolog.ParseLevel(flgs.logLvlMin)
+	if err != nil {
+		return err
+	}
+	lgr := logger.NewLogger(os.Stdout, minlvl, true)
+
+	secrets, err := newSecretCache(context.Background(), flgs)
+	if err != nil {
+		return err
+	}
+	keyring, err := secure.ParseKeyring(secrets.Get(encryptKey))
+	if err != nil {
+		return err
+	}
+
+	kms, err := newKMS(flgs)
+	if err != nil {
+		return err
+	}
+
+	dbpool, cleanup, err := datastore.NewRotatingPostgreSQLPool(context.Background(), newPostgreSQLDSN(flgs), func() string { return secrets.Get(dbPasswordEnv) }, lgr)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rotated, err := service.RotateEncryptionKeyService{Datastorer: datastore.NewDatastore(dbpool), Keyring: keyring, KMS: kms}.Rotate(context.Background())
+	if err != nil {
+		return err
+	}
+	lgr.Info().Msgf("%d ciphertexts and data keys rotated to encryption key %s and the primary KMS key", rotated, keyring.PrimaryID())
+
+	return nil
+}
+
+// seedPolicy adds the policy rules and role assignments in the
+// policy file to the enforcer, provided the enforcer has no policy
+// yet (i.e. the database policy has never been initialized). The
+// database policy is seeded holding policystore.WithLock.
+func seedPolicy(e *casbin.SyncedEnforcer, modelPath, policyPath string) error {
+	if len(e.GetPolicy()) > 0 || len(e.GetGroupingPolicy()) > 0 {
+		return nil
+	}
+
+	fe, err := casbin.NewEnforcer(modelPath, fileadapter.NewAdapter(policyPath))
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range fe.GetPolicy() {
+		if _, err = e.AddPolicy(rule); err != nil {
+			return err
+		}
+	}
+	for _, rule := range fe.GetGroupingPolicy() {
+		if _, err = e.AddGroupingPolicy(rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated flag value into its trimmed,
+// non-empty elements
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// newSecretCache retrieves the encryption key, API key secret and
+// database password from the secret source set by the flags
+func newSecretCache(ctx context.Context, flgs flags) (*secretgateway.Cache, error) {
+	var p secretgateway.Provider
+	switch flgs.secretSource {
+	case "flags":
+		p = secretgateway.StaticProvider{encryptKey: flgs.encryptkey, apiKeySecretEnv: flgs.apiKeySecret, dbPasswordEnv: flgs.dbpassword}
+	case "env":
+		p = secretgateway.EnvProvider{}
+	case "file":
+		p = secretgateway.FileProvider{Dir: flgs.secretDir}
+	case "exec":
+		ep, err := secretgateway.NewExecProvider(flgs.secretCommand)
+		if err != nil {
+			return nil, err
+		}
+		p = ep
+	default:
+		return nil, errs.E(errs.Internal, fmt.Sprintf("invalid secret-source %q, must be flags, env, file or exec", flgs.secretSource))
+	}
+
+	secrets, err := secretgateway.NewCache(ctx, p, encryptKey, apiKeySecretEnv, dbPasswordEnv)
+	if err != nil {
+		return nil, err
+	}
+	if secrets.Get(encryptKey) == "" {
+		return nil, errs.E(errs.Internal, "no encryption key found")
+	}
+
+	return secrets, nil
+}
+
+// dataKeyCacheTTL is the maximum time a data key unwrapped by the KMS
+// is held in memory for reuse
+const dataKeyCacheTTL = time.Minute
+
+// newKMS initializes the local KMS given the path to its key file, or
+// returns nil if none is set. Unwrapped data keys are cached for
+// dataKeyCacheTTL.
+func newKMS(flgs flags) (secure.KMS, error) {
+	if flgs.kmsKeyFile == "" {
+		return nil, nil
+	}
+	kms, err := secure.NewLocalKMSFromFile(flgs.kmsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return secure.NewCachingKMS(kms, dataKeyCacheTTL), nil
 }
 
-// newPostgreSQLDSN initializes a datastore.PostgreSQLDSN given a Flags struct
+// newPostgreSQLDSN initializes a datastore.PostgreSQLDSN given a Flags
+// struct. The password is left out as it is retrieved from the secret
+// source each time a connection is opened.
 func newPostgreSQLDSN(flgs flags) datastore.PostgreSQLDSN {
 	return datastore.PostgreSQLDSN{
 		Host:       flgs.dbhost,
@@ -250,7 +714,6 @@ func newPostgreSQLDSN(flgs flags) datastore.PostgreSQLDSN {
 		DBName:     flgs.dbname,
 		SearchPath: flgs.dbsearchpath,
 		User:       flgs.dbuser,
-		Password:   flgs.dbpassword,
 	}
 }
 
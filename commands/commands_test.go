@@ -3,7 +3,9 @@ package commands
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/casbin/casbin/v2"
 	"github.com/google/go-cmp/cmp"
 
 	qt "github.com/frankban/quicktest"
@@ -53,6 +55,24 @@ func Test_newFlags(t *testing.T) {
 		c.Setenv(dbPasswordEnv, "yeet")
 		c.Setenv(dbSearchPath, "u2")
 		c.Setenv(encryptKey, "reallyGoodKey")
+		c.Setenv(apiKeySecretEnv, "reallyGoodSecret")
+		c.Setenv(googleClientIDsEnv, "123.apps.googleusercontent.com")
+		c.Setenv(appleClientIDEnv, "dev.gab.service")
+		c.Setenv(identityCacheSizeEnv, "500")
+		c.Setenv(identityCacheTTLEnv, "1m")
+		c.Setenv(orgSettingsCacheTTLEnv, "30s")
+		c.Setenv(oidcConfigEnv, "config/oidc.json")
+		c.Setenv(authzPermissionDetailEnv, "true")
+		c.Setenv(rateLimitEnv, "120")
+		c.Setenv(rateLimitPerUserEnv, "true")
+		c.Setenv(rateLimitStoreEnv, "postgres")
+		c.Setenv(usageFlushIntervalEnv, "1m")
+		c.Setenv(monthlyQuotaEnv, "1000000")
+		c.Setenv(secretSourceEnv, "file")
+		c.Setenv(secretDirEnv, "/var/run/secrets/gab")
+		c.Setenv(secretCommandEnv, "")
+		c.Setenv(secretRefreshIntervalEnv, "1m")
+		c.Setenv(kmsKeyFileEnv, "/var/run/secrets/gab/kms.key")
 		c.Log("Environment setup completed")
 	}
 
@@ -69,52 +89,122 @@ func Test_newFlags(t *testing.T) {
 		c.Setenv(dbPasswordEnv, "")
 		c.Setenv(dbSearchPath, "")
 		c.Setenv(encryptKey, "")
+		c.Setenv(apiKeySecretEnv, "")
+		c.Setenv(googleClientIDsEnv, "")
+		c.Setenv(appleClientIDEnv, "")
+		c.Setenv(identityCacheSizeEnv, "")
+		c.Setenv(identityCacheTTLEnv, "")
+		c.Setenv(orgSettingsCacheTTLEnv, "")
+		c.Setenv(oidcConfigEnv, "")
+		c.Setenv(authzPermissionDetailEnv, "")
+		c.Setenv(rateLimitEnv, "")
+		c.Setenv(rateLimitPerUserEnv, "")
+		c.Setenv(rateLimitStoreEnv, "")
+		c.Setenv(usageFlushIntervalEnv, "")
+		c.Setenv(monthlyQuotaEnv, "")
+		c.Setenv(secretSourceEnv, "")
+		c.Setenv(secretDirEnv, "")
+		c.Setenv(secretCommandEnv, "")
+		c.Setenv(secretRefreshIntervalEnv, "")
+		c.Setenv(kmsKeyFileEnv, "")
 		c.Log("Environment setup completed")
 	}
 
-	a1 := args{args: []string{"server", "-log-level=info", "-log-level-min=debug", "-log-error-stack", "-port=8080", "-db-host=localhost", "-db-port=5432", "-db-name=go_api_basic", "-db-user=postgres", "-db-password=sosecret", "-db-search-path=demo", "-encrypt-key=reallyGoodKey"}}
+	a1 := args{args: []string{"server", "-log-level=info", "-log-level-min=debug", "-log-error-stack", "-port=8080", "-db-host=localhost", "-db-port=5432", "-db-name=go_api_basic", "-db-user=postgres", "-db-password=sosecret", "-db-search-path=demo", "-encrypt-key=reallyGoodKey", "-api-key-secret=reallyGoodSecret", "-google-client-ids=123.apps.googleusercontent.com", "-apple-client-id=dev.gab.service", "-identity-cache-size=2000", "-identity-cache-ttl=10m", "-org-settings-cache-ttl=2m", "-oidc-config=config/oidc.json", "-authz-permission-detail", "-rate-limit=60", "-rate-limit-per-user", "-rate-limit-store=postgres", "-usage-flush-interval=10s", "-monthly-quota=5000", "-secret-source=exec", "-secret-command=get-secret --project gab", "-secret-refresh-interval=30s", "-kms-key-file=config/kms.key"}}
 	f1 := flags{
-		loglvl:        "info",
-		logLvlMin:     "debug",
-		logErrorStack: true,
-		port:          8080,
-		dbhost:        "localhost",
-		dbport:        5432,
-		dbname:        "go_api_basic",
-		dbuser:        "postgres",
-		dbpassword:    "sosecret",
-		dbsearchpath:  "demo",
-		encryptkey:    "reallyGoodKey",
+		loglvl:                "info",
+		logLvlMin:             "debug",
+		logErrorStack:         true,
+		port:                  8080,
+		dbhost:                "localhost",
+		dbport:                5432,
+		dbname:                "go_api_basic",
+		dbuser:                "postgres",
+		dbpassword:            "sosecret",
+		dbsearchpath:          "demo",
+		encryptkey:            "reallyGoodKey",
+		apiKeySecret:          "reallyGoodSecret",
+		googleClientIDs:       "123.apps.googleusercontent.com",
+		appleClientID:         "dev.gab.service",
+		identityCacheSize:     2000,
+		identityCacheTTL:      10 * time.Minute,
+		orgSettingsCacheTTL:   2 * time.Minute,
+		oidcConfig:            "config/oidc.json",
+		authzPermissionDetail: true,
+		rateLimit:             60,
+		rateLimitPerUser:      true,
+		rateLimitStore:        "postgres",
+		usageFlushInterval:    10 * time.Second,
+		monthlyQuota:          5000,
+		secretSource:          "exec",
+		secretDir:             "/run/secrets",
+		secretCommand:         "get-secret --project gab",
+		secretRefreshInterval: 30 * time.Second,
+		kmsKeyFile:            "config/kms.key",
 	}
 
 	a2 := args{args: []string{"server"}}
 	f2 := flags{
-		loglvl:        "warn",
-		logLvlMin:     "debug",
-		logErrorStack: false,
-		port:          8081,
-		dbhost:        "hostwiththemost",
-		dbport:        5150,
-		dbname:        "whatisinaname",
-		dbuser:        "usersarelosers",
-		dbpassword:    "yeet",
-		dbsearchpath:  "u2",
-		encryptkey:    "reallyGoodKey",
+		loglvl:                "warn",
+		logLvlMin:             "debug",
+		logErrorStack:         false,
+		port:                  8081,
+		dbhost:                "hostwiththemost",
+		dbport:                5150,
+		dbname:                "whatisinaname",
+		dbuser:                "usersarelosers",
+		dbpassword:            "yeet",
+		dbsearchpath:          "u2",
+		encryptkey:            "reallyGoodKey",
+		apiKeySecret:          "reallyGoodSecret",
+		googleClientIDs:       "123.apps.googleusercontent.com",
+		appleClientID:         "dev.gab.service",
+		identityCacheSize:     500,
+		identityCacheTTL:      time.Minute,
+		orgSettingsCacheTTL:   30 * time.Second,
+		oidcConfig:            "config/oidc.json",
+		authzPermissionDetail: true,
+		rateLimit:             120,
+		rateLimitPerUser:      true,
+		rateLimitStore:        "postgres",
+		usageFlushInterval:    time.Minute,
+		monthlyQuota:          1000000,
+		secretSource:          "file",
+		secretDir:             "/var/run/secrets/gab",
+		secretRefreshInterval: time.Minute,
+		kmsKeyFile:            "/var/run/secrets/gab/kms.key",
 	}
 
 	a3 := args{args: []string{"server", "-log-level=error"}}
 	f3 := flags{
-		loglvl:        "error",
-		logLvlMin:     "debug",
-		logErrorStack: false,
-		port:          8081,
-		dbhost:        "hostwiththemost",
-		dbport:        5150,
-		dbname:        "whatisinaname",
-		dbuser:        "usersarelosers",
-		dbpassword:    "yeet",
-		dbsearchpath:  "u2",
-		encryptkey:    "reallyGoodKey",
+		loglvl:                "error",
+		logLvlMin:             "debug",
+		logErrorStack:         false,
+		port:                  8081,
+		dbhost:                "hostwiththemost",
+		dbport:                5150,
+		dbname:                "whatisinaname",
+		dbuser:                "usersarelosers",
+		dbpassword:            "yeet",
+		dbsearchpath:          "u2",
+		encryptkey:            "reallyGoodKey",
+		apiKeySecret:          "reallyGoodSecret",
+		googleClientIDs:       "123.apps.googleusercontent.com",
+		appleClientID:         "dev.gab.service",
+		identityCacheSize:     500,
+		identityCacheTTL:      time.Minute,
+		orgSettingsCacheTTL:   30 * time.Second,
+		oidcConfig:            "config/oidc.json",
+		authzPermissionDetail: true,
+		rateLimit:             120,
+		rateLimitPerUser:      true,
+		rateLimitStore:        "postgres",
+		usageFlushInterval:    time.Minute,
+		monthlyQuota:          1000000,
+		secretSource:          "file",
+		secretDir:             "/var/run/secrets/gab",
+		secretRefreshInterval: time.Minute,
+		kmsKeyFile:            "/var/run/secrets/gab/kms.key",
 	}
 
 	a4 := args{args: []string{"server", "-badflag=true"}}
@@ -122,15 +212,24 @@ func Test_newFlags(t *testing.T) {
 
 	a5 := args{args: []string{"server", "-log-level=debug", "-log-level-min=debug", "-log-error-stack", "-port=8080", "-db-host=localhost", "-db-port=5432", "-db-name=go_api_basic", "-db-user=postgres", "-db-password=sosecret"}}
 	f5 := flags{
-		loglvl:        "debug",
-		logLvlMin:     "debug",
-		logErrorStack: true,
-		port:          8080,
-		dbhost:        "localhost",
-		dbport:        5432,
-		dbname:        "go_api_basic",
-		dbuser:        "postgres",
-		dbpassword:    "sosecret",
+		loglvl:                "debug",
+		logLvlMin:             "debug",
+		logErrorStack:         true,
+		port:                  8080,
+		dbhost:                "localhost",
+		dbport:                5432,
+		dbname:                "go_api_basic",
+		dbuser:                "postgres",
+		dbpassword:            "sosecret",
+		identityCacheSize:     10000,
+		identityCacheTTL:      5 * time.Minute,
+		orgSettingsCacheTTL:   time.Minute,
+		rateLimit:             600,
+		rateLimitStore:        "memory",
+		usageFlushInterval:    30 * time.Second,
+		secretSource:          "flags",
+		secretDir:             "/run/secrets",
+		secretRefreshInterval: 5 * time.Minute,
 	}
 
 	tests := []struct {
@@ -157,3 +256,28 @@ func Test_newFlags(t *testing.T) {
 		})
 	}
 }
+
+func Test_seedPolicy(t *testing.T) {
+	c := qt.New(t)
+
+	// enforcer without an adapter stands in for an empty database
+	e, err := casbin.NewSyncedEnforcer("../config/rbac_model.conf")
+	c.Assert(err, qt.IsNil)
+
+	err = seedPolicy(e, "../config/rbac_model.conf", "../config/rbac_policy.csv")
+	c.Assert(err, qt.IsNil)
+	_, err = e.AddGroupingPolicy("otto.maddox711@gmail.com", "admin", "org1")
+	c.Assert(err, qt.IsNil)
+	ok, err := e.Enforce("otto.maddox711@gmail.com", "org1", "/api/v1/policies", "write")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+
+	// an existing policy is left alone
+	_, err = e.RemovePolicy("admin", "*", "/api/v1/policies", "write")
+	c.Assert(err, qt.IsNil)
+	err = seedPolicy(e, "../config/rbac_model.conf", "../config/rbac_policy.csv")
+	c.Assert(err, qt.IsNil)
+	ok, err = e.Enforce("otto.maddox711@gmail.com", "org1", "/api/v1/policies", "write")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+}
@@ -7,7 +7,8 @@ import "os"
 // This is a farce and only to be used when developing locally.
 //
 // You should use Cloud Secrets or something like that and set environment vars
-// dynamically through those at deployment/run time.
+// dynamically through those at deployment/run time, or retrieve the
+// encryption key and database password with the secret-source flag.
 //
 // This file should not be included in your git repository and should
 // be added to .gitignore. I have included it in this repository since
@@ -82,5 +83,11 @@ func OverrideEnv() error {
 		return err
 	}
 
+	// API key secret
+	err = os.Setenv(apiKeySecretEnv, "REPLACE_ME")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -23,13 +23,24 @@ type App struct {
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	// scopes (resource:level) limiting the requests the app can make, regardless of the user
+	Scopes []string
+	// if true, the app may authenticate without a user on routes allowing it, acting as its own service principal
+	ServiceAccount bool
+	// requests per minute allowed for the app, the server default if null
+	RateLimit sql.NullInt32
+	// requests per calendar month (UTC) allowed for the app, the server default if null
+	MonthlyQuota sql.NullInt64
 }
 
 type AppApiKey struct {
-	// app_key is a hash of a key given to a user for an app
-	ApiKey string
+	// legacy encrypted key, emptied once the key is migrated to a prefix and hash
+	ApiKey sql.NullString
+	// API key unique External ID, used to manage the key without sending it
+	ApiKeyExtlID string
 	// foreign key to app table
-	AppID           uuid.UUID
+	AppID uuid.UUID
+	// the time the API key is no longer usable, set to the current time when the key is revoked
 	DeactvDate      time.Time
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
@@ -37,6 +48,12 @@ type AppApiKey struct {
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	// leading characters of the key, used to look the key up
+	ApiKeyPrefix sql.NullString
+	// hex encoded HMAC-SHA256 of the key, keyed with its data key or the server API key secret
+	ApiKeyHash sql.NullString
+	// data key the key is hashed with, wrapped by the KMS, null if the key is hashed with the server API key secret
+	ApiKeyDataKey sql.NullString
 }
 
 type Org struct {
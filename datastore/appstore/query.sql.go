@@ -14,8 +14,9 @@ import (
 
 const createApp = `-- name: CreateApp :execresult
 INSERT INTO app (app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id,
-                 create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+                 create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit,
+                 monthly_quota)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 `
 
 type CreateAppParams struct {
@@ -31,6 +32,10 @@ type CreateAppParams struct {
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	Scopes          []string
+	ServiceAccount  bool
+	RateLimit       sql.NullInt32
+	MonthlyQuota    sql.NullInt64
 }
 
 func (q *Queries) CreateApp(ctx context.Context, arg CreateAppParams) (pgconn.CommandTag, error) {
@@ -47,17 +52,24 @@ func (q *Queries) CreateApp(ctx context.Context, arg CreateAppParams) (pgconn.Co
 		arg.UpdateAppID,
 		arg.UpdateUserID,
 		arg.UpdateTimestamp,
+		arg.Scopes,
+		arg.ServiceAccount,
+		arg.RateLimit,
+		arg.MonthlyQuota,
 	)
 }
 
 const createAppAPIKey = `-- name: CreateAppAPIKey :execresult
-INSERT INTO app_api_key (api_key, app_id, deactv_date, create_app_id, create_user_id,
-                         create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+INSERT INTO app_api_key (api_key_extl_id, api_key_prefix, api_key_hash, app_id, deactv_date, create_app_id,
+                         create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp,
+                         api_key_data_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 `
 
 type CreateAppAPIKeyParams struct {
-	ApiKey          string
+	ApiKeyExtlID    string
+	ApiKeyPrefix    sql.NullString
+	ApiKeyHash      sql.NullString
 	AppID           uuid.UUID
 	DeactvDate      time.Time
 	CreateAppID     uuid.UUID
@@ -66,11 +78,14 @@ type CreateAppAPIKeyParams struct {
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	ApiKeyDataKey   sql.NullString
 }
 
 func (q *Queries) CreateAppAPIKey(ctx context.Context, arg CreateAppAPIKeyParams) (pgconn.CommandTag, error) {
 	return q.db.Exec(ctx, createAppAPIKey,
-		arg.ApiKey,
+		arg.ApiKeyExtlID,
+		arg.ApiKeyPrefix,
+		arg.ApiKeyHash,
 		arg.AppID,
 		arg.DeactvDate,
 		arg.CreateAppID,
@@ -79,7 +94,37 @@ func (q *Queries) CreateAppAPIKey(ctx context.Context, arg CreateAppAPIKeyParams
 		arg.UpdateAppID,
 		arg.UpdateUserID,
 		arg.UpdateTimestamp,
+		arg.ApiKeyDataKey,
+	)
+}
+
+const deactivateAppAPIKeys = `-- name: DeactivateAppAPIKeys :exec
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE app_id = $5
+  AND deactv_date > $1
+`
+
+type DeactivateAppAPIKeysParams struct {
+	DeactvDate      time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	AppID           uuid.UUID
+}
+
+func (q *Queries) DeactivateAppAPIKeys(ctx context.Context, arg DeactivateAppAPIKeysParams) error {
+	_, err := q.db.Exec(ctx, deactivateAppAPIKeys,
+		arg.DeactvDate,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.AppID,
 	)
+	return err
 }
 
 const deleteApp = `-- name: DeleteApp :exec
@@ -92,9 +137,72 @@ func (q *Queries) DeleteApp(ctx context.Context, appID uuid.UUID) error {
 	return err
 }
 
+const findAPIKeyByExtlID = `-- name: FindAPIKeyByExtlID :one
+SELECT api_key, api_key_extl_id, app_id, deactv_date, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, api_key_prefix, api_key_hash, api_key_data_key FROM app_api_key
+WHERE app_id = $1
+  AND api_key_extl_id = $2 LIMIT 1
+`
+
+type FindAPIKeyByExtlIDParams struct {
+	AppID        uuid.UUID
+	ApiKeyExtlID string
+}
+
+func (q *Queries) FindAPIKeyByExtlID(ctx context.Context, arg FindAPIKeyByExtlIDParams) (AppApiKey, error) {
+	row := q.db.QueryRow(ctx, findAPIKeyByExtlID, arg.AppID, arg.ApiKeyExtlID)
+	var i AppApiKey
+	err := row.Scan(
+		&i.ApiKey,
+		&i.ApiKeyExtlID,
+		&i.AppID,
+		&i.DeactvDate,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
+		&i.ApiKeyPrefix,
+		&i.ApiKeyHash,
+		&i.ApiKeyDataKey,
+	)
+	return i, err
+}
+
+const findAPIKeyDataKeys = `-- name: FindAPIKeyDataKeys :many
+SELECT api_key_extl_id, api_key_data_key FROM app_api_key
+WHERE api_key_data_key IS NOT NULL
+`
+
+type FindAPIKeyDataKeysRow struct {
+	ApiKeyExtlID  string
+	ApiKeyDataKey sql.NullString
+}
+
+func (q *Queries) FindAPIKeyDataKeys(ctx context.Context) ([]FindAPIKeyDataKeysRow, error) {
+	rows, err := q.db.Query(ctx, findAPIKeyDataKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAPIKeyDataKeysRow
+	for rows.Next() {
+		var i FindAPIKeyDataKeysRow
+		if err := rows.Scan(&i.ApiKeyExtlID, &i.ApiKeyDataKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findAPIKeysByAppID = `-- name: FindAPIKeysByAppID :many
-SELECT api_key, app_id, deactv_date, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app_api_key
+SELECT api_key, api_key_extl_id, app_id, deactv_date, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, api_key_prefix, api_key_hash, api_key_data_key FROM app_api_key
 WHERE app_id = $1
+ORDER BY create_timestamp
 `
 
 func (q *Queries) FindAPIKeysByAppID(ctx context.Context, appID uuid.UUID) ([]AppApiKey, error) {
@@ -108,6 +216,7 @@ func (q *Queries) FindAPIKeysByAppID(ctx context.Context, appID uuid.UUID) ([]Ap
 		var i AppApiKey
 		if err := rows.Scan(
 			&i.ApiKey,
+			&i.ApiKeyExtlID,
 			&i.AppID,
 			&i.DeactvDate,
 			&i.CreateAppID,
@@ -116,6 +225,9 @@ func (q *Queries) FindAPIKeysByAppID(ctx context.Context, appID uuid.UUID) ([]Ap
 			&i.UpdateAppID,
 			&i.UpdateUserID,
 			&i.UpdateTimestamp,
+			&i.ApiKeyPrefix,
+			&i.ApiKeyHash,
+			&i.ApiKeyDataKey,
 		); err != nil {
 			return nil, err
 		}
@@ -127,55 +239,80 @@ func (q *Queries) FindAPIKeysByAppID(ctx context.Context, appID uuid.UUID) ([]Ap
 	return items, nil
 }
 
-const findAppAPIKeysByAppExtlID = `-- name: FindAppAPIKeysByAppExtlID :many
+const findAppAPIKeysByPrefix = `-- name: FindAppAPIKeysByPrefix :many
 select a.app_id,
        a.app_extl_id,
        a.app_name,
        a.app_description,
+       a.scopes,
+       a.service_account,
+       a.rate_limit,
+       a.monthly_quota,
        o.org_id,
        o.org_extl_id,
        o.org_name,
        o.org_description,
-       aak.api_key,
+       o.parent_org_id,
+       aak.api_key_hash,
+       aak.api_key_data_key,
        aak.deactv_date
 from app a
          inner join org o on o.org_id = a.org_id
          inner join app_api_key aak on a.app_id = aak.app_id
 where a.app_extl_id = $1
+  and a.active
+  and aak.api_key_prefix = $2
 `
 
-type FindAppAPIKeysByAppExtlIDRow struct {
+type FindAppAPIKeysByPrefixParams struct {
+	AppExtlID    string
+	ApiKeyPrefix sql.NullString
+}
+
+type FindAppAPIKeysByPrefixRow struct {
 	AppID          uuid.UUID
 	AppExtlID      string
 	AppName        string
 	AppDescription string
+	Scopes         []string
+	ServiceAccount bool
+	RateLimit      sql.NullInt32
+	MonthlyQuota   sql.NullInt64
 	OrgID          uuid.UUID
 	OrgExtlID      string
 	OrgName        string
 	OrgDescription string
-	ApiKey         string
+	ParentOrgID    uuid.NullUUID
+	ApiKeyHash     sql.NullString
+	ApiKeyDataKey  sql.NullString
 	DeactvDate     time.Time
 }
 
-func (q *Queries) FindAppAPIKeysByAppExtlID(ctx context.Context, appExtlID string) ([]FindAppAPIKeysByAppExtlIDRow, error) {
-	rows, err := q.db.Query(ctx, findAppAPIKeysByAppExtlID, appExtlID)
+func (q *Queries) FindAppAPIKeysByPrefix(ctx context.Context, arg FindAppAPIKeysByPrefixParams) ([]FindAppAPIKeysByPrefixRow, error) {
+	rows, err := q.db.Query(ctx, findAppAPIKeysByPrefix, arg.AppExtlID, arg.ApiKeyPrefix)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []FindAppAPIKeysByAppExtlIDRow
+	var items []FindAppAPIKeysByPrefixRow
 	for rows.Next() {
-		var i FindAppAPIKeysByAppExtlIDRow
+		var i FindAppAPIKeysByPrefixRow
 		if err := rows.Scan(
 			&i.AppID,
 			&i.AppExtlID,
 			&i.AppName,
 			&i.AppDescription,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
 			&i.OrgID,
 			&i.OrgExtlID,
 			&i.OrgName,
 			&i.OrgDescription,
-			&i.ApiKey,
+			&i.ParentOrgID,
+			&i.ApiKeyHash,
+			&i.ApiKeyDataKey,
 			&i.DeactvDate,
 		); err != nil {
 			return nil, err
@@ -189,7 +326,7 @@ func (q *Queries) FindAppAPIKeysByAppExtlID(ctx context.Context, appExtlID strin
 }
 
 const findAppByExternalID = `-- name: FindAppByExternalID :one
-SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
 WHERE app_extl_id = $1 LIMIT 1
 `
 
@@ -209,12 +346,16 @@ func (q *Queries) FindAppByExternalID(ctx context.Context, appExtlID string) (Ap
 		&i.UpdateAppID,
 		&i.UpdateUserID,
 		&i.UpdateTimestamp,
+		&i.Scopes,
+		&i.ServiceAccount,
+		&i.RateLimit,
+		&i.MonthlyQuota,
 	)
 	return i, err
 }
 
 const findAppByID = `-- name: FindAppByID :one
-SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
 WHERE app_id = $1 LIMIT 1
 `
 
@@ -234,12 +375,16 @@ func (q *Queries) FindAppByID(ctx context.Context, appID uuid.UUID) (App, error)
 		&i.UpdateAppID,
 		&i.UpdateUserID,
 		&i.UpdateTimestamp,
+		&i.Scopes,
+		&i.ServiceAccount,
+		&i.RateLimit,
+		&i.MonthlyQuota,
 	)
 	return i, err
 }
 
 const findApps = `-- name: FindApps :many
-SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
 ORDER BY app_name
 `
 
@@ -265,6 +410,10 @@ func (q *Queries) FindApps(ctx context.Context) ([]App, error) {
 			&i.UpdateAppID,
 			&i.UpdateUserID,
 			&i.UpdateTimestamp,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
 		); err != nil {
 			return nil, err
 		}
@@ -275,3 +424,527 @@ func (q *Queries) FindApps(ctx context.Context) ([]App, error) {
 	}
 	return items, nil
 }
+
+const findAppsByIDs = `-- name: FindAppsByIDs :many
+select a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       a.app_description,
+       o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description
+from app a
+         inner join org o on o.org_id = a.org_id
+where a.app_id = ANY ($1::uuid[])
+`
+
+type FindAppsByIDsRow struct {
+	AppID          uuid.UUID
+	AppExtlID      string
+	AppName        string
+	AppDescription string
+	OrgID          uuid.UUID
+	OrgExtlID      string
+	OrgName        string
+	OrgDescription string
+}
+
+func (q *Queries) FindAppsByIDs(ctx context.Context, appIds []uuid.UUID) ([]FindAppsByIDsRow, error) {
+	rows, err := q.db.Query(ctx, findAppsByIDs, appIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAppsByIDsRow
+	for rows.Next() {
+		var i FindAppsByIDsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsByOrg = `-- name: FindAppsByOrg :many
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
+WHERE org_id = $1
+ORDER BY app_name
+`
+
+func (q *Queries) FindAppsByOrg(ctx context.Context, orgID uuid.UUID) ([]App, error) {
+	rows, err := q.db.Query(ctx, findAppsByOrg, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []App
+	for rows.Next() {
+		var i App
+		if err := rows.Scan(
+			&i.AppID,
+			&i.OrgID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsPageByCreateTimestamp = `-- name: FindAppsPageByCreateTimestamp :many
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
+WHERE org_id = $1
+  AND app_name LIKE $2
+  AND ($3::timestamp IS NULL OR create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (create_timestamp, app_extl_id) > ($5::timestamp, $4))
+ORDER BY create_timestamp, app_extl_id
+LIMIT $6
+`
+
+type FindAppsPageByCreateTimestampParams struct {
+	OrgID                 uuid.UUID
+	NamePattern           string
+	CreatedAfter          sql.NullTime
+	CursorExtlID          sql.NullString
+	CursorCreateTimestamp time.Time
+	RowLimit              int32
+}
+
+func (q *Queries) FindAppsPageByCreateTimestamp(ctx context.Context, arg FindAppsPageByCreateTimestampParams) ([]App, error) {
+	rows, err := q.db.Query(ctx, findAppsPageByCreateTimestamp,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorCreateTimestamp,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []App
+	for rows.Next() {
+		var i App
+		if err := rows.Scan(
+			&i.AppID,
+			&i.OrgID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsPageByCreateTimestampDesc = `-- name: FindAppsPageByCreateTimestampDesc :many
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
+WHERE org_id = $1
+  AND app_name LIKE $2
+  AND ($3::timestamp IS NULL OR create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (create_timestamp, app_extl_id) < ($5::timestamp, $4))
+ORDER BY create_timestamp DESC, app_extl_id DESC
+LIMIT $6
+`
+
+type FindAppsPageByCreateTimestampDescParams struct {
+	OrgID                 uuid.UUID
+	NamePattern           string
+	CreatedAfter          sql.NullTime
+	CursorExtlID          sql.NullString
+	CursorCreateTimestamp time.Time
+	RowLimit              int32
+}
+
+func (q *Queries) FindAppsPageByCreateTimestampDesc(ctx context.Context, arg FindAppsPageByCreateTimestampDescParams) ([]App, error) {
+	rows, err := q.db.Query(ctx, findAppsPageByCreateTimestampDesc,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorCreateTimestamp,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []App
+	for rows.Next() {
+		var i App
+		if err := rows.Scan(
+			&i.AppID,
+			&i.OrgID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsPageByName = `-- name: FindAppsPageByName :many
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
+WHERE org_id = $1
+  AND app_name LIKE $2
+  AND ($3::timestamp IS NULL OR create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (app_name, app_extl_id) > ($5::varchar, $4))
+ORDER BY app_name, app_extl_id
+LIMIT $6
+`
+
+type FindAppsPageByNameParams struct {
+	OrgID        uuid.UUID
+	NamePattern  string
+	CreatedAfter sql.NullTime
+	CursorExtlID sql.NullString
+	CursorName   string
+	RowLimit     int32
+}
+
+func (q *Queries) FindAppsPageByName(ctx context.Context, arg FindAppsPageByNameParams) ([]App, error) {
+	rows, err := q.db.Query(ctx, findAppsPageByName,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorName,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []App
+	for rows.Next() {
+		var i App
+		if err := rows.Scan(
+			&i.AppID,
+			&i.OrgID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsPageByNameDesc = `-- name: FindAppsPageByNameDesc :many
+SELECT app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit, monthly_quota FROM app
+WHERE org_id = $1
+  AND app_name LIKE $2
+  AND ($3::timestamp IS NULL OR create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (app_name, app_extl_id) < ($5::varchar, $4))
+ORDER BY app_name DESC, app_extl_id DESC
+LIMIT $6
+`
+
+type FindAppsPageByNameDescParams struct {
+	OrgID        uuid.UUID
+	NamePattern  string
+	CreatedAfter sql.NullTime
+	CursorExtlID sql.NullString
+	CursorName   string
+	RowLimit     int32
+}
+
+func (q *Queries) FindAppsPageByNameDesc(ctx context.Context, arg FindAppsPageByNameDescParams) ([]App, error) {
+	rows, err := q.db.Query(ctx, findAppsPageByNameDesc,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorName,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []App
+	for rows.Next() {
+		var i App
+		if err := rows.Scan(
+			&i.AppID,
+			&i.OrgID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.Scopes,
+			&i.ServiceAccount,
+			&i.RateLimit,
+			&i.MonthlyQuota,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findLegacyAPIKeys = `-- name: FindLegacyAPIKeys :many
+SELECT api_key, api_key_extl_id, app_id, deactv_date, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, api_key_prefix, api_key_hash, api_key_data_key FROM app_api_key
+WHERE api_key IS NOT NULL
+`
+
+func (q *Queries) FindLegacyAPIKeys(ctx context.Context) ([]AppApiKey, error) {
+	rows, err := q.db.Query(ctx, findLegacyAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppApiKey
+	for rows.Next() {
+		var i AppApiKey
+		if err := rows.Scan(
+			&i.ApiKey,
+			&i.ApiKeyExtlID,
+			&i.AppID,
+			&i.DeactvDate,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.ApiKeyPrefix,
+			&i.ApiKeyHash,
+			&i.ApiKeyDataKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateApp = `-- name: UpdateApp :exec
+UPDATE app
+SET app_name         = $1,
+    app_description  = $2,
+    active           = $3,
+    scopes           = $4,
+    service_account  = $5,
+    rate_limit       = $6,
+    monthly_quota    = $7,
+    update_app_id    = $8,
+    update_user_id   = $9,
+    update_timestamp = $10
+WHERE app_id = $11
+`
+
+type UpdateAppParams struct {
+	AppName         string
+	AppDescription  string
+	Active          sql.NullBool
+	Scopes          []string
+	ServiceAccount  bool
+	RateLimit       sql.NullInt32
+	MonthlyQuota    sql.NullInt64
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	AppID           uuid.UUID
+}
+
+func (q *Queries) UpdateApp(ctx context.Context, arg UpdateAppParams) error {
+	_, err := q.db.Exec(ctx, updateApp,
+		arg.AppName,
+		arg.AppDescription,
+		arg.Active,
+		arg.Scopes,
+		arg.ServiceAccount,
+		arg.RateLimit,
+		arg.MonthlyQuota,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.AppID,
+	)
+	return err
+}
+
+const updateAppAPIKeyCiphertext = `-- name: UpdateAppAPIKeyCiphertext :exec
+UPDATE app_api_key
+SET api_key = $1
+WHERE api_key_extl_id = $2
+`
+
+type UpdateAppAPIKeyCiphertextParams struct {
+	ApiKey       sql.NullString
+	ApiKeyExtlID string
+}
+
+func (q *Queries) UpdateAppAPIKeyCiphertext(ctx context.Context, arg UpdateAppAPIKeyCiphertextParams) error {
+	_, err := q.db.Exec(ctx, updateAppAPIKeyCiphertext, arg.ApiKey, arg.ApiKeyExtlID)
+	return err
+}
+
+const updateAppAPIKeyDataKey = `-- name: UpdateAppAPIKeyDataKey :exec
+UPDATE app_api_key
+SET api_key_data_key = $1
+WHERE api_key_extl_id = $2
+`
+
+type UpdateAppAPIKeyDataKeyParams struct {
+	ApiKeyDataKey sql.NullString
+	ApiKeyExtlID  string
+}
+
+func (q *Queries) UpdateAppAPIKeyDataKey(ctx context.Context, arg UpdateAppAPIKeyDataKeyParams) error {
+	_, err := q.db.Exec(ctx, updateAppAPIKeyDataKey, arg.ApiKeyDataKey, arg.ApiKeyExtlID)
+	return err
+}
+
+const updateAppAPIKeyDeactvDate = `-- name: UpdateAppAPIKeyDeactvDate :exec
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE api_key_extl_id = $5
+`
+
+type UpdateAppAPIKeyDeactvDateParams struct {
+	DeactvDate      time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	ApiKeyExtlID    string
+}
+
+func (q *Queries) UpdateAppAPIKeyDeactvDate(ctx context.Context, arg UpdateAppAPIKeyDeactvDateParams) error {
+	_, err := q.db.Exec(ctx, updateAppAPIKeyDeactvDate,
+		arg.DeactvDate,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.ApiKeyExtlID,
+	)
+	return err
+}
+
+const updateAppAPIKeyHash = `-- name: UpdateAppAPIKeyHash :exec
+UPDATE app_api_key
+SET api_key          = NULL,
+    api_key_prefix   = $1,
+    api_key_hash     = $2,
+    api_key_data_key = $3
+WHERE api_key_extl_id = $4
+`
+
+type UpdateAppAPIKeyHashParams struct {
+	ApiKeyPrefix  sql.NullString
+	ApiKeyHash    sql.NullString
+	ApiKeyDataKey sql.NullString
+	ApiKeyExtlID  string
+}
+
+func (q *Queries) UpdateAppAPIKeyHash(ctx context.Context, arg UpdateAppAPIKeyHashParams) error {
+	_, err := q.db.Exec(ctx, updateAppAPIKeyHash,
+		arg.ApiKeyPrefix,
+		arg.ApiKeyHash,
+		arg.ApiKeyDataKey,
+		arg.ApiKeyExtlID,
+	)
+	return err
+}
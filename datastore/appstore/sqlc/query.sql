@@ -10,10 +10,70 @@ WHERE app_extl_id = $1 LIMIT 1;
 SELECT * FROM app
 ORDER BY app_name;
 
+-- name: FindAppsByOrg :many
+SELECT * FROM app
+WHERE org_id = $1
+ORDER BY app_name;
+
+-- name: FindAppsPageByCreateTimestamp :many
+SELECT * FROM app
+WHERE org_id = sqlc.arg(org_id)
+  AND app_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (create_timestamp, app_extl_id) > (sqlc.arg(cursor_create_timestamp)::timestamp, sqlc.narg(cursor_extl_id)))
+ORDER BY create_timestamp, app_extl_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindAppsPageByCreateTimestampDesc :many
+SELECT * FROM app
+WHERE org_id = sqlc.arg(org_id)
+  AND app_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (create_timestamp, app_extl_id) < (sqlc.arg(cursor_create_timestamp)::timestamp, sqlc.narg(cursor_extl_id)))
+ORDER BY create_timestamp DESC, app_extl_id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindAppsPageByName :many
+SELECT * FROM app
+WHERE org_id = sqlc.arg(org_id)
+  AND app_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (app_name, app_extl_id) > (sqlc.arg(cursor_name)::varchar, sqlc.narg(cursor_extl_id)))
+ORDER BY app_name, app_extl_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindAppsPageByNameDesc :many
+SELECT * FROM app
+WHERE org_id = sqlc.arg(org_id)
+  AND app_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (app_name, app_extl_id) < (sqlc.arg(cursor_name)::varchar, sqlc.narg(cursor_extl_id)))
+ORDER BY app_name DESC, app_extl_id DESC
+LIMIT sqlc.arg(row_limit);
+
 -- name: CreateApp :execresult
 INSERT INTO app (app_id, org_id, app_extl_id, app_name, app_description, active, create_app_id, create_user_id,
-                 create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+                 create_timestamp, update_app_id, update_user_id, update_timestamp, scopes, service_account, rate_limit,
+                 monthly_quota)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);
+
+-- name: UpdateApp :exec
+UPDATE app
+SET app_name         = $1,
+    app_description  = $2,
+    active           = $3,
+    scopes           = $4,
+    service_account  = $5,
+    rate_limit       = $6,
+    monthly_quota    = $7,
+    update_app_id    = $8,
+    update_user_id   = $9,
+    update_timestamp = $10
+WHERE app_id = $11;
 
 -- name: DeleteApp :exec
 DELETE FROM app
@@ -21,25 +81,96 @@ WHERE app_id = $1;
 
 -- name: FindAPIKeysByAppID :many
 SELECT * FROM app_api_key
-WHERE app_id = $1;
+WHERE app_id = $1
+ORDER BY create_timestamp;
+
+-- name: FindAPIKeyByExtlID :one
+SELECT * FROM app_api_key
+WHERE app_id = $1
+  AND api_key_extl_id = $2 LIMIT 1;
 
 -- name: CreateAppAPIKey :execresult
-INSERT INTO app_api_key (api_key, app_id, deactv_date, create_app_id, create_user_id,
-                         create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+INSERT INTO app_api_key (api_key_extl_id, api_key_prefix, api_key_hash, app_id, deactv_date, create_app_id,
+                         create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp,
+                         api_key_data_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: UpdateAppAPIKeyDeactvDate :exec
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE api_key_extl_id = $5;
 
--- name: FindAppAPIKeysByAppExtlID :many
+-- name: DeactivateAppAPIKeys :exec
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE app_id = $5
+  AND deactv_date > $1;
+
+-- name: FindLegacyAPIKeys :many
+SELECT * FROM app_api_key
+WHERE api_key IS NOT NULL;
+
+-- name: UpdateAppAPIKeyHash :exec
+UPDATE app_api_key
+SET api_key          = NULL,
+    api_key_prefix   = $1,
+    api_key_hash     = $2,
+    api_key_data_key = $3
+WHERE api_key_extl_id = $4;
+
+-- name: UpdateAppAPIKeyCiphertext :exec
+UPDATE app_api_key
+SET api_key = $1
+WHERE api_key_extl_id = $2;
+
+-- name: FindAPIKeyDataKeys :many
+SELECT api_key_extl_id, api_key_data_key FROM app_api_key
+WHERE api_key_data_key IS NOT NULL;
+
+-- name: UpdateAppAPIKeyDataKey :exec
+UPDATE app_api_key
+SET api_key_data_key = $1
+WHERE api_key_extl_id = $2;
+
+-- name: FindAppAPIKeysByPrefix :many
 select a.app_id,
        a.app_extl_id,
        a.app_name,
        a.app_description,
+       a.scopes,
+       a.service_account,
+       a.rate_limit,
+       a.monthly_quota,
        o.org_id,
        o.org_extl_id,
        o.org_name,
        o.org_description,
-       aak.api_key,
+       o.parent_org_id,
+       aak.api_key_hash,
+       aak.api_key_data_key,
        aak.deactv_date
 from app a
          inner join org o on o.org_id = a.org_id
          inner join app_api_key aak on a.app_id = aak.app_id
-where a.app_extl_id = $1;
\ No newline at end of file
+where a.app_extl_id = $1
+  and a.active
+  and aak.api_key_prefix = $2;
+
+-- name: FindAppsByIDs :many
+select a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       a.app_description,
+       o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description
+from app a
+         inner join org o on o.org_id = a.org_id
+where a.app_id = ANY (sqlc.arg(app_ids)::uuid[]);
@@ -181,6 +181,18 @@ func NewNullInt64(i int64) sql.NullInt64 {
 	}
 }
 
+// NewNullInt32 returns a null if i == 0, otherwise it returns
+// the int32 which was input.
+func NewNullInt32(i int32) sql.NullInt32 {
+	if i == 0 {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{
+		Int32: i,
+		Valid: true,
+	}
+}
+
 // NewNullUUID returns a null if id == uuid.Nil, otherwise it returns
 // the uuid.UUID which was input as an uuid.NullUUID type
 func NewNullUUID(id uuid.UUID) uuid.NullUUID {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/rs/zerolog"
 
@@ -12,12 +13,31 @@ import (
 
 // NewPostgreSQLPool returns an open database handle of 0 or more underlying PostgreSQL connections
 func NewPostgreSQLPool(ctx context.Context, dsn PostgreSQLDSN, logger zerolog.Logger) (*pgxpool.Pool, func(), error) {
+	return NewRotatingPostgreSQLPool(ctx, dsn, nil, logger)
+}
+
+// NewRotatingPostgreSQLPool is NewPostgreSQLPool for a database
+// password which may be rotated. If password is not nil, it is called
+// for the password each time a new connection is made, so once the
+// password changes new connections use the new one (see
+// CloseIdleConns) without the pool being recreated.
+func NewRotatingPostgreSQLPool(ctx context.Context, dsn PostgreSQLDSN, password func() string, logger zerolog.Logger) (*pgxpool.Pool, func(), error) {
 
 	f := func() {}
 
-	// Open the postgres database using the pgxpool driver (pq)
-	// func Open(driverName, dataSourceName string) (*DB, error)
-	pool, err := pgxpool.Connect(ctx, dsn.KeywordValueConnectionString())
+	config, err := pgxpool.ParseConfig(dsn.KeywordValueConnectionString())
+	if err != nil {
+		return nil, f, errs.E(errs.Database, err)
+	}
+	if password != nil {
+		config.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			cc.Password = password()
+			return nil
+		}
+	}
+
+	// Open the postgres database using the pgxpool driver
+	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
 		return nil, f, errs.E(errs.Database, err)
 	}
@@ -32,6 +52,20 @@ func NewPostgreSQLPool(ctx context.Context, dsn PostgreSQLDSN, logger zerolog.Lo
 	return pool, func() { pool.Close() }, nil
 }
 
+// CloseIdleConns closes the idle connections of the pool, which are
+// replaced by new connections as needed, e.g. to reconnect with a
+// rotated password. Connections in use are not affected. The number
+// of connections closed is returned.
+func CloseIdleConns(ctx context.Context, pool *pgxpool.Pool) int {
+	conns := pool.AcquireAllIdle(ctx)
+	for _, c := range conns {
+		// a closed connection is destroyed on release
+		_ = c.Conn().Close(ctx)
+		c.Release()
+	}
+	return len(conns)
+}
+
 // ValidatePostgreSQLPool pings the database and logs the current user and database
 func ValidatePostgreSQLPool(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger) error {
 	err := pool.Ping(ctx)
@@ -0,0 +1,28 @@
+package moviestore
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore/datastoretest"
+)
+
+func TestQueries_FindMovieByExternalID(t *testing.T) {
+	t.Run("no row returned", func(t *testing.T) {
+		c := qt.New(t)
+		ds, cleanup := datastoretest.NewDatastore(t)
+		c.Cleanup(cleanup)
+		ctx := context.Background()
+		tx, err := ds.Pool().Begin(ctx)
+		if err != nil {
+			c.Fatal(err)
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = New(tx).FindMovieByExternalID(ctx, "not there")
+		c.Check(err, qt.ErrorIs, pgx.ErrNoRows)
+	})
+}
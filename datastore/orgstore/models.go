@@ -3,6 +3,8 @@
 package orgstore
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,4 +21,10 @@ type Org struct {
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	// The timestamp the org was archived (soft deleted), null if the org is not archived. The apps and users of an archived org are inactive.
+	ArchiveTimestamp sql.NullTime
+	// The parent organization of a sub-organization, null for a top level organization. Roles granted in an organization apply to its descendants.
+	ParentOrgID uuid.NullUUID
+	// The versioned settings and feature flags document of the organization, e.g. self_registration_allowed, allowed_auth_providers and default_api_key_lifetime. The schema is validated by the application.
+	Settings json.RawMessage
 }
@@ -5,12 +5,89 @@ package orgstore
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 )
 
+const archiveOrg = `-- name: ArchiveOrg :exec
+UPDATE org
+SET archive_timestamp = $1,
+    update_app_id     = $2,
+    update_user_id    = $3,
+    update_timestamp  = $1
+WHERE org_id = $4
+`
+
+type ArchiveOrgParams struct {
+	ArchiveTimestamp sql.NullTime
+	UpdateAppID      uuid.UUID
+	UpdateUserID     uuid.NullUUID
+	OrgID            uuid.UUID
+}
+
+func (q *Queries) ArchiveOrg(ctx context.Context, arg ArchiveOrgParams) error {
+	_, err := q.db.Exec(ctx, archiveOrg,
+		arg.ArchiveTimestamp,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.OrgID,
+	)
+	return err
+}
+
+const countOrgDependents = `-- name: CountOrgDependents :one
+SELECT o.genesis_org,
+       (SELECT count(*) FROM app a WHERE a.org_id = o.org_id)                        AS app_count,
+       (SELECT count(*)
+        FROM app_api_key k
+                 INNER JOIN app a ON a.app_id = k.app_id
+        WHERE a.org_id = o.org_id)                                                  AS api_key_count,
+       (SELECT count(*)
+        FROM app_usage u
+                 INNER JOIN app a ON a.app_id = u.app_id
+        WHERE a.org_id = o.org_id)                                                  AS usage_count,
+       (SELECT count(*) FROM app_user u WHERE u.org_id = o.org_id)                   AS user_count,
+       (SELECT count(*) FROM person p WHERE p.org_id = o.org_id)                     AS person_count,
+       (SELECT count(*)
+        FROM person_profile pp
+                 INNER JOIN person p ON p.person_id = pp.person_id
+        WHERE p.org_id = o.org_id)                                                  AS person_profile_count,
+       (SELECT count(*) FROM org c WHERE c.parent_org_id = o.org_id)                AS child_org_count
+FROM org o
+WHERE o.org_id = $1
+`
+
+type CountOrgDependentsRow struct {
+	GenesisOrg         bool
+	AppCount           int64
+	ApiKeyCount        int64
+	UsageCount         int64
+	UserCount          int64
+	PersonCount        int64
+	PersonProfileCount int64
+	ChildOrgCount      int64
+}
+
+func (q *Queries) CountOrgDependents(ctx context.Context, orgID uuid.UUID) (CountOrgDependentsRow, error) {
+	row := q.db.QueryRow(ctx, countOrgDependents, orgID)
+	var i CountOrgDependentsRow
+	err := row.Scan(
+		&i.GenesisOrg,
+		&i.AppCount,
+		&i.ApiKeyCount,
+		&i.UsageCount,
+		&i.UserCount,
+		&i.PersonCount,
+		&i.PersonProfileCount,
+		&i.ChildOrgCount,
+	)
+	return i, err
+}
+
 const countOrgs = `-- name: CountOrgs :one
 SELECT count(*) as org_count FROM org
 `
@@ -23,9 +100,9 @@ func (q *Queries) CountOrgs(ctx context.Context) (int64, error) {
 }
 
 const createOrg = `-- name: CreateOrg :execresult
-INSERT INTO org (org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id,
-                 create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+INSERT INTO org (org_id, org_extl_id, org_name, org_description, settings, create_app_id,
+                 create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, parent_org_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 `
 
 type CreateOrgParams struct {
@@ -33,12 +110,14 @@ type CreateOrgParams struct {
 	OrgExtlID       string
 	OrgName         string
 	OrgDescription  string
+	Settings        json.RawMessage
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	ParentOrgID     uuid.NullUUID
 }
 
 func (q *Queries) CreateOrg(ctx context.Context, arg CreateOrgParams) (pgconn.CommandTag, error) {
@@ -47,13 +126,96 @@ func (q *Queries) CreateOrg(ctx context.Context, arg CreateOrgParams) (pgconn.Co
 		arg.OrgExtlID,
 		arg.OrgName,
 		arg.OrgDescription,
+		arg.Settings,
 		arg.CreateAppID,
 		arg.CreateUserID,
 		arg.CreateTimestamp,
 		arg.UpdateAppID,
 		arg.UpdateUserID,
 		arg.UpdateTimestamp,
+		arg.ParentOrgID,
+	)
+}
+
+const deactivateOrgAPIKeys = `-- name: DeactivateOrgAPIKeys :exec
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $1
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $4)
+  AND deactv_date > $1
+`
+
+type DeactivateOrgAPIKeysParams struct {
+	DeactvDate   time.Time
+	UpdateAppID  uuid.UUID
+	UpdateUserID uuid.NullUUID
+	OrgID        uuid.UUID
+}
+
+func (q *Queries) DeactivateOrgAPIKeys(ctx context.Context, arg DeactivateOrgAPIKeysParams) error {
+	_, err := q.db.Exec(ctx, deactivateOrgAPIKeys,
+		arg.DeactvDate,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.OrgID,
+	)
+	return err
+}
+
+const deactivateOrgApps = `-- name: DeactivateOrgApps :exec
+UPDATE app
+SET active           = false,
+    update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE org_id = $4
+  AND active
+`
+
+type DeactivateOrgAppsParams struct {
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	OrgID           uuid.UUID
+}
+
+func (q *Queries) DeactivateOrgApps(ctx context.Context, arg DeactivateOrgAppsParams) error {
+	_, err := q.db.Exec(ctx, deactivateOrgApps,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.OrgID,
 	)
+	return err
+}
+
+const deactivateOrgUsers = `-- name: DeactivateOrgUsers :exec
+UPDATE app_user
+SET active           = false,
+    update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE org_id = $4
+  AND active
+`
+
+type DeactivateOrgUsersParams struct {
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	OrgID           uuid.UUID
+}
+
+func (q *Queries) DeactivateOrgUsers(ctx context.Context, arg DeactivateOrgUsersParams) error {
+	_, err := q.db.Exec(ctx, deactivateOrgUsers,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.OrgID,
+	)
+	return err
 }
 
 const deleteOrg = `-- name: DeleteOrg :exec
@@ -66,8 +228,112 @@ func (q *Queries) DeleteOrg(ctx context.Context, orgID uuid.UUID) error {
 	return err
 }
 
+const deleteOrgAPIKeys = `-- name: DeleteOrgAPIKeys :exec
+DELETE FROM app_api_key
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $1)
+`
+
+func (q *Queries) DeleteOrgAPIKeys(ctx context.Context, orgID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOrgAPIKeys, orgID)
+	return err
+}
+
+const deleteOrgAppUsage = `-- name: DeleteOrgAppUsage :exec
+DELETE FROM app_usage
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $1)
+`
+
+func (q *Queries) DeleteOrgAppUsage(ctx context.Context, orgID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOrgAppUsage, orgID)
+	return err
+}
+
+const deleteOrgApps = `-- name: DeleteOrgApps :exec
+DELETE FROM app
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgApps(ctx context.Context, orgID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOrgApps, orgID)
+	return err
+}
+
+const deleteOrgPersonProfiles = `-- name: DeleteOrgPersonProfiles :exec
+DELETE FROM person_profile
+WHERE person_id IN (SELECT person_id FROM person WHERE org_id = $1)
+`
+
+func (q *Queries) DeleteOrgPersonProfiles(ctx context.Context, orgID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOrgPersonProfiles, orgID)
+	return err
+}
+
+const deleteOrgPersons = `-- name: DeleteOrgPersons :exec
+DELETE FROM person
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgPersons(ctx context.Context, orgID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOrgPersons, orgID)
+	return err
+}
+
+const deleteOrgUsers = `-- name: DeleteOrgUsers :exec
+DELETE FROM app_user
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgUsers(ctx context.Context, orgID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteOrgUsers, orgID)
+	return err
+}
+
+const findOrgAncestors = `-- name: FindOrgAncestors :many
+WITH RECURSIVE ancestor AS (
+    SELECT p.org_id, p.org_extl_id, p.parent_org_id, 1 AS depth, ARRAY [c.org_id, p.org_id] AS path
+    FROM org c
+             INNER JOIN org p ON p.org_id = c.parent_org_id
+    WHERE c.org_id = $1
+    UNION ALL
+    SELECT p.org_id, p.org_extl_id, p.parent_org_id, a.depth + 1, a.path || p.org_id
+    FROM ancestor a
+             INNER JOIN org p ON p.org_id = a.parent_org_id
+    WHERE NOT p.org_id = ANY (a.path)
+)
+SELECT org_id, org_extl_id, depth::int AS depth
+FROM ancestor
+ORDER BY depth
+`
+
+type FindOrgAncestorsRow struct {
+	OrgID     uuid.UUID
+	OrgExtlID string
+	Depth     int32
+}
+
+// finds the ancestors of an org, its parent first
+func (q *Queries) FindOrgAncestors(ctx context.Context, orgID uuid.UUID) ([]FindOrgAncestorsRow, error) {
+	rows, err := q.db.Query(ctx, findOrgAncestors, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgAncestorsRow
+	for rows.Next() {
+		var i FindOrgAncestorsRow
+		if err := rows.Scan(&i.OrgID, &i.OrgExtlID, &i.Depth); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findOrgByExtlID = `-- name: FindOrgByExtlID :one
-SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM org
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
 WHERE org_extl_id = $1 LIMIT 1
 `
 
@@ -85,12 +351,15 @@ func (q *Queries) FindOrgByExtlID(ctx context.Context, orgExtlID string) (Org, e
 		&i.UpdateAppID,
 		&i.UpdateUserID,
 		&i.UpdateTimestamp,
+		&i.ArchiveTimestamp,
+		&i.ParentOrgID,
+		&i.Settings,
 	)
 	return i, err
 }
 
 const findOrgByID = `-- name: FindOrgByID :one
-SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM org
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
 WHERE org_id = $1 LIMIT 1
 `
 
@@ -108,12 +377,264 @@ func (q *Queries) FindOrgByID(ctx context.Context, orgID uuid.UUID) (Org, error)
 		&i.UpdateAppID,
 		&i.UpdateUserID,
 		&i.UpdateTimestamp,
+		&i.ArchiveTimestamp,
+		&i.ParentOrgID,
+		&i.Settings,
 	)
 	return i, err
 }
 
+const findOrgExternalReferences = `-- name: FindOrgExternalReferences :many
+WITH org_app AS (SELECT app_id FROM app WHERE app.org_id = $1),
+     org_user AS (SELECT user_id FROM app_user WHERE app_user.org_id = $1),
+     org_person AS (SELECT person_id FROM person WHERE person.org_id = $1)
+SELECT 'org'::varchar AS table_name, count(*) AS reference_count
+FROM org t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'app', count(*)
+FROM app t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'app_api_key', count(*)
+FROM app_api_key t
+WHERE t.app_id NOT IN (SELECT app_id FROM org_app)
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'app_user', count(*)
+FROM app_user t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'person', count(*)
+FROM person t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'person_profile', count(*)
+FROM person_profile t
+WHERE t.person_id NOT IN (SELECT person_id FROM org_person)
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'movie', count(*)
+FROM movie t
+WHERE t.create_app_id IN (SELECT app_id FROM org_app)
+   OR t.update_app_id IN (SELECT app_id FROM org_app)
+   OR t.create_user_id IN (SELECT user_id FROM org_user)
+   OR t.update_user_id IN (SELECT user_id FROM org_user)
+`
+
+type FindOrgExternalReferencesRow struct {
+	TableName      string
+	ReferenceCount int64
+}
+
+// finds the records outside the org whose audit columns reference
+// the apps or users of the org, which prevent the org from being
+// deleted
+func (q *Queries) FindOrgExternalReferences(ctx context.Context, orgID uuid.UUID) ([]FindOrgExternalReferencesRow, error) {
+	rows, err := q.db.Query(ctx, findOrgExternalReferences, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgExternalReferencesRow
+	for rows.Next() {
+		var i FindOrgExternalReferencesRow
+		if err := rows.Scan(&i.TableName, &i.ReferenceCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgExtlIDsByIDs = `-- name: FindOrgExtlIDsByIDs :many
+SELECT org_id, org_extl_id FROM org
+WHERE org_id = ANY ($1::uuid[])
+`
+
+type FindOrgExtlIDsByIDsRow struct {
+	OrgID     uuid.UUID
+	OrgExtlID string
+}
+
+func (q *Queries) FindOrgExtlIDsByIDs(ctx context.Context, orgIds []uuid.UUID) ([]FindOrgExtlIDsByIDsRow, error) {
+	rows, err := q.db.Query(ctx, findOrgExtlIDsByIDs, orgIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgExtlIDsByIDsRow
+	for rows.Next() {
+		var i FindOrgExtlIDsByIDsRow
+		if err := rows.Scan(&i.OrgID, &i.OrgExtlID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgSettings = `-- name: FindOrgSettings :one
+SELECT settings FROM org
+WHERE org_id = $1
+`
+
+func (q *Queries) FindOrgSettings(ctx context.Context, orgID uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRow(ctx, findOrgSettings, orgID)
+	var settings json.RawMessage
+	err := row.Scan(&settings)
+	return settings, err
+}
+
+const findOrgSettingsForUpdate = `-- name: FindOrgSettingsForUpdate :one
+SELECT org_id, settings FROM org
+WHERE org_extl_id = $1
+  AND archive_timestamp IS NULL
+    FOR UPDATE
+`
+
+type FindOrgSettingsForUpdateRow struct {
+	OrgID    uuid.UUID
+	Settings json.RawMessage
+}
+
+// finds the settings of an org, locking the org until the end of the
+// transaction so concurrent patches do not overwrite each other
+func (q *Queries) FindOrgSettingsForUpdate(ctx context.Context, orgExtlID string) (FindOrgSettingsForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, findOrgSettingsForUpdate, orgExtlID)
+	var i FindOrgSettingsForUpdateRow
+	err := row.Scan(&i.OrgID, &i.Settings)
+	return i, err
+}
+
+const findOrgSubtree = `-- name: FindOrgSubtree :many
+WITH RECURSIVE subtree AS (
+    SELECT o.org_id, 0 AS depth, ARRAY [o.org_name]::varchar[] AS name_path, ARRAY [o.org_id] AS path
+    FROM org o
+    WHERE o.org_id = $1
+    UNION ALL
+    SELECT c.org_id, s.depth + 1, s.name_path || c.org_name, s.path || c.org_id
+    FROM subtree s
+             INNER JOIN org c ON c.parent_org_id = s.org_id
+    WHERE NOT c.org_id = ANY (s.path)
+)
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       o.create_app_id,
+       o.create_user_id,
+       o.create_timestamp,
+       o.update_app_id,
+       o.update_user_id,
+       o.update_timestamp,
+       o.archive_timestamp,
+       o.parent_org_id,
+       o.settings,
+       s.depth::int AS depth
+FROM subtree s
+         INNER JOIN org o ON o.org_id = s.org_id
+ORDER BY s.name_path
+`
+
+type FindOrgSubtreeRow struct {
+	OrgID            uuid.UUID
+	OrgExtlID        string
+	OrgName          string
+	OrgDescription   string
+	CreateAppID      uuid.UUID
+	CreateUserID     uuid.NullUUID
+	CreateTimestamp  time.Time
+	UpdateAppID      uuid.UUID
+	UpdateUserID     uuid.NullUUID
+	UpdateTimestamp  time.Time
+	ArchiveTimestamp sql.NullTime
+	ParentOrgID      uuid.NullUUID
+	Settings         json.RawMessage
+	Depth            int32
+}
+
+// finds an org and its descendants, depth first with siblings in
+// name order
+func (q *Queries) FindOrgSubtree(ctx context.Context, orgID uuid.UUID) ([]FindOrgSubtreeRow, error) {
+	rows, err := q.db.Query(ctx, findOrgSubtree, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgSubtreeRow
+	for rows.Next() {
+		var i FindOrgSubtreeRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.ArchiveTimestamp,
+			&i.ParentOrgID,
+			&i.Settings,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgUserIDs = `-- name: FindOrgUserIDs :many
+SELECT user_id FROM app_user
+WHERE org_id = $1
+`
+
+func (q *Queries) FindOrgUserIDs(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, findOrgUserIDs, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findOrgs = `-- name: FindOrgs :many
-SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM org
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
+WHERE archive_timestamp IS NULL
 ORDER BY org_name
 `
 
@@ -137,6 +658,219 @@ func (q *Queries) FindOrgs(ctx context.Context) ([]Org, error) {
 			&i.UpdateAppID,
 			&i.UpdateUserID,
 			&i.UpdateTimestamp,
+			&i.ArchiveTimestamp,
+			&i.ParentOrgID,
+			&i.Settings,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgsPageByCreateTimestamp = `-- name: FindOrgsPageByCreateTimestamp :many
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE $1
+  AND ($2::timestamptz IS NULL OR create_timestamp > $2)
+  AND ($3::varchar IS NULL OR
+       (create_timestamp, org_extl_id) > ($4::timestamptz, $3))
+  AND ($5::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT $5::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY create_timestamp, org_extl_id
+LIMIT $6
+`
+
+type FindOrgsPageByCreateTimestampParams struct {
+	NamePattern           string
+	CreatedAfter          sql.NullTime
+	CursorExtlID          sql.NullString
+	CursorCreateTimestamp time.Time
+	RootOrgID             uuid.NullUUID
+	RowLimit              int32
+}
+
+func (q *Queries) FindOrgsPageByCreateTimestamp(ctx context.Context, arg FindOrgsPageByCreateTimestampParams) ([]Org, error) {
+	rows, err := q.db.Query(ctx, findOrgsPageByCreateTimestamp,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorCreateTimestamp,
+		arg.RootOrgID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Org
+	for rows.Next() {
+		var i Org
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.ArchiveTimestamp,
+			&i.ParentOrgID,
+			&i.Settings,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgsPageByCreateTimestampDesc = `-- name: FindOrgsPageByCreateTimestampDesc :many
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE $1
+  AND ($2::timestamptz IS NULL OR create_timestamp > $2)
+  AND ($3::varchar IS NULL OR
+       (create_timestamp, org_extl_id) < ($4::timestamptz, $3))
+  AND ($5::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT $5::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY create_timestamp DESC, org_extl_id DESC
+LIMIT $6
+`
+
+type FindOrgsPageByCreateTimestampDescParams struct {
+	NamePattern           string
+	CreatedAfter          sql.NullTime
+	CursorExtlID          sql.NullString
+	CursorCreateTimestamp time.Time
+	RootOrgID             uuid.NullUUID
+	RowLimit              int32
+}
+
+func (q *Queries) FindOrgsPageByCreateTimestampDesc(ctx context.Context, arg FindOrgsPageByCreateTimestampDescParams) ([]Org, error) {
+	rows, err := q.db.Query(ctx, findOrgsPageByCreateTimestampDesc,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorCreateTimestamp,
+		arg.RootOrgID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Org
+	for rows.Next() {
+		var i Org
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.ArchiveTimestamp,
+			&i.ParentOrgID,
+			&i.Settings,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgsPageByName = `-- name: FindOrgsPageByName :many
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE $1
+  AND ($2::timestamptz IS NULL OR create_timestamp > $2)
+  AND ($3::varchar IS NULL OR
+       (org_name, org_extl_id) > ($4::varchar, $3))
+  AND ($5::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT $5::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY org_name, org_extl_id
+LIMIT $6
+`
+
+type FindOrgsPageByNameParams struct {
+	NamePattern  string
+	CreatedAfter sql.NullTime
+	CursorExtlID sql.NullString
+	CursorName   string
+	RootOrgID    uuid.NullUUID
+	RowLimit     int32
+}
+
+func (q *Queries) FindOrgsPageByName(ctx context.Context, arg FindOrgsPageByNameParams) ([]Org, error) {
+	rows, err := q.db.Query(ctx, findOrgsPageByName,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorName,
+		arg.RootOrgID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Org
+	for rows.Next() {
+		var i Org
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.ArchiveTimestamp,
+			&i.ParentOrgID,
+			&i.Settings,
 		); err != nil {
 			return nil, err
 		}
@@ -148,6 +882,99 @@ func (q *Queries) FindOrgs(ctx context.Context) ([]Org, error) {
 	return items, nil
 }
 
+const findOrgsPageByNameDesc = `-- name: FindOrgsPageByNameDesc :many
+SELECT org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, archive_timestamp, parent_org_id, settings FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE $1
+  AND ($2::timestamptz IS NULL OR create_timestamp > $2)
+  AND ($3::varchar IS NULL OR
+       (org_name, org_extl_id) < ($4::varchar, $3))
+  AND ($5::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT $5::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY org_name DESC, org_extl_id DESC
+LIMIT $6
+`
+
+type FindOrgsPageByNameDescParams struct {
+	NamePattern  string
+	CreatedAfter sql.NullTime
+	CursorExtlID sql.NullString
+	CursorName   string
+	RootOrgID    uuid.NullUUID
+	RowLimit     int32
+}
+
+func (q *Queries) FindOrgsPageByNameDesc(ctx context.Context, arg FindOrgsPageByNameDescParams) ([]Org, error) {
+	rows, err := q.db.Query(ctx, findOrgsPageByNameDesc,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorName,
+		arg.RootOrgID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Org
+	for rows.Next() {
+		var i Org
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+			&i.ArchiveTimestamp,
+			&i.ParentOrgID,
+			&i.Settings,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isGenesisOrg = `-- name: IsGenesisOrg :one
+SELECT genesis_org FROM org
+WHERE org_id = $1
+`
+
+func (q *Queries) IsGenesisOrg(ctx context.Context, orgID uuid.UUID) (bool, error) {
+	row := q.db.QueryRow(ctx, isGenesisOrg, orgID)
+	var genesis_org bool
+	err := row.Scan(&genesis_org)
+	return genesis_org, err
+}
+
+const lockOrgHierarchy = `-- name: LockOrgHierarchy :exec
+SELECT pg_advisory_xact_lock(hashtext('org_hierarchy'))
+`
+
+// serializes changes to the org hierarchy until the end of the
+// transaction, so concurrent moves cannot form a cycle between them
+func (q *Queries) LockOrgHierarchy(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, lockOrgHierarchy)
+	return err
+}
+
 const updateOrg = `-- name: UpdateOrg :exec
 UPDATE org
 SET org_name         = $1,
@@ -178,3 +1005,59 @@ func (q *Queries) UpdateOrg(ctx context.Context, arg UpdateOrgParams) error {
 	)
 	return err
 }
+
+const updateOrgParent = `-- name: UpdateOrgParent :exec
+UPDATE org
+SET parent_org_id    = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE org_id = $5
+`
+
+type UpdateOrgParentParams struct {
+	ParentOrgID     uuid.NullUUID
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	OrgID           uuid.UUID
+}
+
+func (q *Queries) UpdateOrgParent(ctx context.Context, arg UpdateOrgParentParams) error {
+	_, err := q.db.Exec(ctx, updateOrgParent,
+		arg.ParentOrgID,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.OrgID,
+	)
+	return err
+}
+
+const updateOrgSettings = `-- name: UpdateOrgSettings :exec
+UPDATE org
+SET settings         = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE org_id = $5
+`
+
+type UpdateOrgSettingsParams struct {
+	Settings        json.RawMessage
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	OrgID           uuid.UUID
+}
+
+func (q *Queries) UpdateOrgSettings(ctx context.Context, arg UpdateOrgSettingsParams) error {
+	_, err := q.db.Exec(ctx, updateOrgSettings,
+		arg.Settings,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.OrgID,
+	)
+	return err
+}
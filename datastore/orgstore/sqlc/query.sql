@@ -11,22 +11,322 @@ WHERE org_extl_id = $1 LIMIT 1;
 
 -- name: FindOrgs :many
 SELECT * FROM org
+WHERE archive_timestamp IS NULL
 ORDER BY org_name;
 
+-- name: FindOrgsPageByCreateTimestamp :many
+SELECT * FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (create_timestamp, org_extl_id) > (sqlc.arg(cursor_create_timestamp)::timestamptz, sqlc.narg(cursor_extl_id)))
+  AND (sqlc.narg(root_org_id)::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT sqlc.narg(root_org_id)::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY create_timestamp, org_extl_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindOrgsPageByCreateTimestampDesc :many
+SELECT * FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (create_timestamp, org_extl_id) < (sqlc.arg(cursor_create_timestamp)::timestamptz, sqlc.narg(cursor_extl_id)))
+  AND (sqlc.narg(root_org_id)::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT sqlc.narg(root_org_id)::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY create_timestamp DESC, org_extl_id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindOrgsPageByName :many
+SELECT * FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (org_name, org_extl_id) > (sqlc.arg(cursor_name)::varchar, sqlc.narg(cursor_extl_id)))
+  AND (sqlc.narg(root_org_id)::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT sqlc.narg(root_org_id)::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY org_name, org_extl_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindOrgsPageByNameDesc :many
+SELECT * FROM org
+WHERE archive_timestamp IS NULL
+  AND org_name LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (org_name, org_extl_id) < (sqlc.arg(cursor_name)::varchar, sqlc.narg(cursor_extl_id)))
+  AND (sqlc.narg(root_org_id)::uuid IS NULL OR org_id IN (
+      WITH RECURSIVE subtree AS (
+          SELECT sqlc.narg(root_org_id)::uuid AS org_id
+          UNION
+          SELECT c.org_id
+          FROM subtree s
+                   INNER JOIN org c ON c.parent_org_id = s.org_id
+      )
+      SELECT org_id FROM subtree))
+ORDER BY org_name DESC, org_extl_id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindOrgExtlIDsByIDs :many
+SELECT org_id, org_extl_id FROM org
+WHERE org_id = ANY (sqlc.arg(org_ids)::uuid[]);
+
+-- name: IsGenesisOrg :one
+SELECT genesis_org FROM org
+WHERE org_id = $1;
+
+-- name: FindOrgAncestors :many
+-- finds the ancestors of an org, its parent first
+WITH RECURSIVE ancestor AS (
+    SELECT p.org_id, p.org_extl_id, p.parent_org_id, 1 AS depth, ARRAY [c.org_id, p.org_id] AS path
+    FROM org c
+             INNER JOIN org p ON p.org_id = c.parent_org_id
+    WHERE c.org_id = $1
+    UNION ALL
+    SELECT p.org_id, p.org_extl_id, p.parent_org_id, a.depth + 1, a.path || p.org_id
+    FROM ancestor a
+             INNER JOIN org p ON p.org_id = a.parent_org_id
+    WHERE NOT p.org_id = ANY (a.path)
+)
+SELECT org_id, org_extl_id, depth::int AS depth
+FROM ancestor
+ORDER BY depth;
+
+-- name: FindOrgSubtree :many
+-- finds an org and its descendants, depth first with siblings in
+-- name order
+WITH RECURSIVE subtree AS (
+    SELECT o.org_id, 0 AS depth, ARRAY [o.org_name]::varchar[] AS name_path, ARRAY [o.org_id] AS path
+    FROM org o
+    WHERE o.org_id = $1
+    UNION ALL
+    SELECT c.org_id, s.depth + 1, s.name_path || c.org_name, s.path || c.org_id
+    FROM subtree s
+             INNER JOIN org c ON c.parent_org_id = s.org_id
+    WHERE NOT c.org_id = ANY (s.path)
+)
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       o.create_app_id,
+       o.create_user_id,
+       o.create_timestamp,
+       o.update_app_id,
+       o.update_user_id,
+       o.update_timestamp,
+       o.archive_timestamp,
+       o.parent_org_id,
+       o.settings,
+       s.depth::int AS depth
+FROM subtree s
+         INNER JOIN org o ON o.org_id = s.org_id
+ORDER BY s.name_path;
+
 -- name: CreateOrg :execresult
-INSERT INTO org (org_id, org_extl_id, org_name, org_description, create_app_id, create_user_id,
-                 create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+INSERT INTO org (org_id, org_extl_id, org_name, org_description, settings, create_app_id,
+                 create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp, parent_org_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
 
 -- name: UpdateOrg :exec
 UPDATE org
-SET org_name         = $1,
-    org_description  = $2,
-    update_app_id    = $3,
-    update_user_id   = $4,
-    update_timestamp = $5
-WHERE org_id = $6;
+SET org_name         = sqlc.arg(org_name),
+    org_description  = sqlc.arg(org_description),
+    update_app_id    = sqlc.arg(update_app_id),
+    update_user_id   = sqlc.arg(update_user_id),
+    update_timestamp = sqlc.arg(update_timestamp)
+WHERE org_id = sqlc.arg(org_id);
+
+-- name: UpdateOrgParent :exec
+UPDATE org
+SET parent_org_id    = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE org_id = $5;
+
+-- name: FindOrgSettings :one
+SELECT settings FROM org
+WHERE org_id = $1;
+
+-- name: FindOrgSettingsForUpdate :one
+-- finds the settings of an org, locking the org until the end of the
+-- transaction so concurrent patches do not overwrite each other
+SELECT org_id, settings FROM org
+WHERE org_extl_id = $1
+  AND archive_timestamp IS NULL
+    FOR UPDATE;
+
+-- name: UpdateOrgSettings :exec
+UPDATE org
+SET settings         = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE org_id = $5;
+
+-- name: LockOrgHierarchy :exec
+-- serializes changes to the org hierarchy until the end of the
+-- transaction, so concurrent moves cannot form a cycle between them
+SELECT pg_advisory_xact_lock(hashtext('org_hierarchy'));
 
 -- name: DeleteOrg :exec
 DELETE FROM org
+WHERE org_id = $1;
+
+-- name: ArchiveOrg :exec
+UPDATE org
+SET archive_timestamp = $1,
+    update_app_id     = $2,
+    update_user_id    = $3,
+    update_timestamp  = $1
+WHERE org_id = $4;
+
+-- name: DeactivateOrgApps :exec
+UPDATE app
+SET active           = false,
+    update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE org_id = $4
+  AND active;
+
+-- name: DeactivateOrgAPIKeys :exec
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $1
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $4)
+  AND deactv_date > $1;
+
+-- name: DeactivateOrgUsers :exec
+UPDATE app_user
+SET active           = false,
+    update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE org_id = $4
+  AND active;
+
+-- name: FindOrgUserIDs :many
+SELECT user_id FROM app_user
+WHERE org_id = $1;
+
+-- name: CountOrgDependents :one
+SELECT o.genesis_org,
+       (SELECT count(*) FROM app a WHERE a.org_id = o.org_id)                        AS app_count,
+       (SELECT count(*)
+        FROM app_api_key k
+                 INNER JOIN app a ON a.app_id = k.app_id
+        WHERE a.org_id = o.org_id)                                                  AS api_key_count,
+       (SELECT count(*)
+        FROM app_usage u
+                 INNER JOIN app a ON a.app_id = u.app_id
+        WHERE a.org_id = o.org_id)                                                  AS usage_count,
+       (SELECT count(*) FROM app_user u WHERE u.org_id = o.org_id)                   AS user_count,
+       (SELECT count(*) FROM person p WHERE p.org_id = o.org_id)                     AS person_count,
+       (SELECT count(*)
+        FROM person_profile pp
+                 INNER JOIN person p ON p.person_id = pp.person_id
+        WHERE p.org_id = o.org_id)                                                  AS person_profile_count,
+       (SELECT count(*) FROM org c WHERE c.parent_org_id = o.org_id)                AS child_org_count
+FROM org o
+WHERE o.org_id = $1;
+
+-- name: FindOrgExternalReferences :many
+-- finds the records outside the org whose audit columns reference
+-- the apps or users of the org, which prevent the org from being
+-- deleted
+WITH org_app AS (SELECT app_id FROM app WHERE app.org_id = $1),
+     org_user AS (SELECT user_id FROM app_user WHERE app_user.org_id = $1),
+     org_person AS (SELECT person_id FROM person WHERE person.org_id = $1)
+SELECT 'org'::varchar AS table_name, count(*) AS reference_count
+FROM org t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'app', count(*)
+FROM app t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'app_api_key', count(*)
+FROM app_api_key t
+WHERE t.app_id NOT IN (SELECT app_id FROM org_app)
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'app_user', count(*)
+FROM app_user t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'person', count(*)
+FROM person t
+WHERE t.org_id <> $1
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'person_profile', count(*)
+FROM person_profile t
+WHERE t.person_id NOT IN (SELECT person_id FROM org_person)
+  AND (t.create_app_id IN (SELECT app_id FROM org_app) OR t.update_app_id IN (SELECT app_id FROM org_app) OR
+       t.create_user_id IN (SELECT user_id FROM org_user) OR t.update_user_id IN (SELECT user_id FROM org_user))
+UNION ALL
+SELECT 'movie', count(*)
+FROM movie t
+WHERE t.create_app_id IN (SELECT app_id FROM org_app)
+   OR t.update_app_id IN (SELECT app_id FROM org_app)
+   OR t.create_user_id IN (SELECT user_id FROM org_user)
+   OR t.update_user_id IN (SELECT user_id FROM org_user);
+
+-- name: DeleteOrgAppUsage :exec
+DELETE FROM app_usage
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $1);
+
+-- name: DeleteOrgAPIKeys :exec
+DELETE FROM app_api_key
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $1);
+
+-- name: DeleteOrgUsers :exec
+DELETE FROM app_user
+WHERE org_id = $1;
+
+-- name: DeleteOrgPersonProfiles :exec
+DELETE FROM person_profile
+WHERE person_id IN (SELECT person_id FROM person WHERE org_id = $1);
+
+-- name: DeleteOrgPersons :exec
+DELETE FROM person
+WHERE org_id = $1;
+
+-- name: DeleteOrgApps :exec
+DELETE FROM app
 WHERE org_id = $1;
\ No newline at end of file
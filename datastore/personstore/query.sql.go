@@ -147,3 +147,55 @@ func (q *Queries) FindPersonProfileByID(ctx context.Context, personID uuid.UUID)
 	)
 	return i, err
 }
+
+const updatePersonProfile = `-- name: UpdatePersonProfile :exec
+UPDATE person_profile
+SET name_prefix      = $1,
+    first_name       = $2,
+    middle_name      = $3,
+    last_name        = $4,
+    name_suffix      = $5,
+    nickname         = $6,
+    company_name     = $7,
+    company_dept     = $8,
+    job_title        = $9,
+    update_app_id    = $10,
+    update_user_id   = $11,
+    update_timestamp = $12
+WHERE person_profile_id = $13
+`
+
+type UpdatePersonProfileParams struct {
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	UpdateAppID     uuid.NullUUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	PersonProfileID uuid.UUID
+}
+
+func (q *Queries) UpdatePersonProfile(ctx context.Context, arg UpdatePersonProfileParams) error {
+	_, err := q.db.Exec(ctx, updatePersonProfile,
+		arg.NamePrefix,
+		arg.FirstName,
+		arg.MiddleName,
+		arg.LastName,
+		arg.NameSuffix,
+		arg.Nickname,
+		arg.CompanyName,
+		arg.CompanyDept,
+		arg.JobTitle,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.PersonProfileID,
+	)
+	return err
+}
@@ -10,6 +10,22 @@ INSERT INTO person_profile (person_profile_id, person_id, name_prefix, first_nam
                             update_user_id, update_timestamp)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22);
 
+-- name: UpdatePersonProfile :exec
+UPDATE person_profile
+SET name_prefix      = $1,
+    first_name       = $2,
+    middle_name      = $3,
+    last_name        = $4,
+    name_suffix      = $5,
+    nickname         = $6,
+    company_name     = $7,
+    company_dept     = $8,
+    job_title        = $9,
+    update_app_id    = $10,
+    update_user_id   = $11,
+    update_timestamp = $12
+WHERE person_profile_id = $13;
+
 -- name: DeletePersonProfile :exec
 DELETE FROM person_profile
 WHERE person_id = $1;
@@ -0,0 +1,197 @@
+// Package policystore stores casbin authorization policy in the
+// database. Adapter implements the casbin persist.Adapter interface
+// and Watcher implements the casbin persist.Watcher interface using
+// PostgreSQL LISTEN/NOTIFY, so that policy changes made by one
+// instance are picked up by all others.
+package policystore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// maxRuleValues is the number of value columns (v0-v5) in the
+// casbin_rule table
+const maxRuleValues = 6
+
+// Datastorer is an interface for working with the Database
+type Datastorer interface {
+	// Pool returns *pgxpool.Pool
+	Pool() *pgxpool.Pool
+}
+
+// Adapter is a casbin persist.Adapter backed by the casbin_rule table
+type Adapter struct {
+	Datastorer
+}
+
+// NewAdapter is an initializer for Adapter
+func NewAdapter(ds Datastorer) Adapter {
+	return Adapter{ds}
+}
+
+// LoadPolicy loads all policy rules from the database into the model
+func (a Adapter) LoadPolicy(m model.Model) error {
+	rules, err := New(a.Pool()).FindCasbinRules(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		persist.LoadPolicyArray(ruleLine(r), m)
+	}
+
+	return nil
+}
+
+// SavePolicy replaces all policy rules in the database with those
+// in the model
+func (a Adapter) SavePolicy(m model.Model) error {
+	ctx := context.Background()
+
+	tx, err := a.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := New(tx)
+	err = q.DeleteAllCasbinRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				params, err := newCreateCasbinRuleParams(ptype, rule)
+				if err != nil {
+					return err
+				}
+				err = q.CreateCasbinRule(ctx, params)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// AddPolicy adds a policy rule to the database
+func (a Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	params, err := newCreateCasbinRuleParams(ptype, rule)
+	if err != nil {
+		return err
+	}
+
+	return New(a.Pool()).CreateCasbinRule(context.Background(), params)
+}
+
+// RemovePolicy removes a policy rule from the database
+func (a Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	v, err := ruleValues(rule, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = New(a.Pool()).DeleteCasbinRule(context.Background(), DeleteCasbinRuleParams{
+		Ptype: ptype,
+		V0:    v[0],
+		V1:    v[1],
+		V2:    v[2],
+		V3:    v[3],
+		V4:    v[4],
+		V5:    v[5],
+	})
+
+	return err
+}
+
+// RemoveFilteredPolicy removes the policy rules matching the filter
+// from the database. Empty field values match any value.
+func (a Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	v, err := ruleValues(fieldValues, fieldIndex)
+	if err != nil {
+		return err
+	}
+
+	_, err = New(a.Pool()).DeleteCasbinRulesByFilter(context.Background(), DeleteCasbinRulesByFilterParams{
+		Ptype:   ptype,
+		Column2: v[0],
+		Column3: v[1],
+		Column4: v[2],
+		Column5: v[3],
+		Column6: v[4],
+		Column7: v[5],
+	})
+
+	return err
+}
+
+// WithLock calls fn while holding a database lock on the policy,
+// which is released when fn returns. Only one instance at a time
+// holds the lock, e.g. to seed an empty policy.
+func WithLock(ctx context.Context, ds Datastorer, fn func() error) error {
+	tx, err := ds.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = New(tx).LockCasbinRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// newCreateCasbinRuleParams maps a casbin rule to CreateCasbinRuleParams
+func newCreateCasbinRuleParams(ptype string, rule []string) (CreateCasbinRuleParams, error) {
+	v, err := ruleValues(rule, 0)
+	if err != nil {
+		return CreateCasbinRuleParams{}, err
+	}
+
+	return CreateCasbinRuleParams{
+		Ptype: ptype,
+		V0:    v[0],
+		V1:    v[1],
+		V2:    v[2],
+		V3:    v[3],
+		V4:    v[4],
+		V5:    v[5],
+	}, nil
+}
+
+// ruleValues places the rule values in the value columns, starting
+// at offset
+func ruleValues(rule []string, offset int) ([maxRuleValues]string, error) {
+	var v [maxRuleValues]string
+	if offset < 0 || offset+len(rule) > maxRuleValues {
+		return v, fmt.Errorf("casbin rule has more than %d values", maxRuleValues)
+	}
+	copy(v[offset:], rule)
+	return v, nil
+}
+
+// ruleLine converts a CasbinRule to a casbin policy line (the ptype
+// followed by its values), dropping trailing empty values
+func ruleLine(r CasbinRule) []string {
+	line := []string{r.Ptype, r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
+	for len(line) > 1 && line[len(line)-1] == "" {
+		line = line[:len(line)-1]
+	}
+	return line
+}
@@ -0,0 +1,32 @@
+package policystore
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func Test_ruleValues(t *testing.T) {
+	c := qt.New(t)
+
+	v, err := ruleValues([]string{"admin", "/api/v1/movies", "read"}, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, [maxRuleValues]string{"admin", "/api/v1/movies", "read"})
+
+	v, err = ruleValues([]string{"/api/v1/movies"}, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, [maxRuleValues]string{"", "/api/v1/movies"})
+
+	_, err = ruleValues([]string{"a", "b", "c", "d", "e", "f", "g"}, 0)
+	c.Assert(err, qt.IsNotNil)
+
+	_, err = ruleValues([]string{"a", "b"}, 5)
+	c.Assert(err, qt.IsNotNil)
+}
+
+func Test_ruleLine(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(ruleLine(CasbinRule{Ptype: "p", V0: "admin", V1: "/api/v1/movies", V2: "read"}), qt.DeepEquals, []string{"p", "admin", "/api/v1/movies", "read"})
+	c.Assert(ruleLine(CasbinRule{Ptype: "g", V0: "otto.maddox711@gmail.com", V1: "admin"}), qt.DeepEquals, []string{"g", "otto.maddox711@gmail.com", "admin"})
+}
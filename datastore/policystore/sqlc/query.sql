@@ -0,0 +1,36 @@
+-- name: FindCasbinRules :many
+SELECT * FROM casbin_rule
+ORDER BY ptype, v0, v1, v2, v3, v4, v5;
+
+-- name: CreateCasbinRule :exec
+INSERT INTO casbin_rule (ptype, v0, v1, v2, v3, v4, v5)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteCasbinRule :execrows
+DELETE FROM casbin_rule
+WHERE ptype = $1
+  AND v0 = $2
+  AND v1 = $3
+  AND v2 = $4
+  AND v3 = $5
+  AND v4 = $6
+  AND v5 = $7;
+
+-- name: DeleteCasbinRulesByFilter :execrows
+DELETE FROM casbin_rule
+WHERE ptype = $1
+  AND ($2::varchar = '' OR v0 = $2)
+  AND ($3::varchar = '' OR v1 = $3)
+  AND ($4::varchar = '' OR v2 = $4)
+  AND ($5::varchar = '' OR v3 = $5)
+  AND ($6::varchar = '' OR v4 = $6)
+  AND ($7::varchar = '' OR v5 = $7);
+
+-- name: DeleteAllCasbinRules :exec
+DELETE FROM casbin_rule;
+
+-- name: LockCasbinRules :exec
+-- serializes policy initialization until the end of the transaction,
+-- so instances starting together do not seed the policy twice
+SELECT pg_advisory_xact_lock(hashtext('casbin_rule'));
@@ -0,0 +1,135 @@
+package policystore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+)
+
+// WatcherChannel is the PostgreSQL notification channel used to
+// announce policy changes
+const WatcherChannel = "casbin_policy"
+
+// watcherRetryInterval is the time waited before listening again
+// after the listening connection is lost
+const watcherRetryInterval = 5 * time.Second
+
+// Watcher is a casbin persist.Watcher using PostgreSQL LISTEN/NOTIFY.
+// Update sends a notification on WatcherChannel and every other
+// Watcher listening on the channel calls its update callback
+// (typically the enforcer's LoadPolicy).
+type Watcher struct {
+	ds  Datastorer
+	lgr zerolog.Logger
+	// id identifies notifications sent by this Watcher, which
+	// are ignored when received
+	id string
+
+	mu       sync.Mutex
+	callback func(string)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher initializes a Watcher and starts listening for policy
+// changes on a connection acquired from the pool. Close must be
+// called to release the connection.
+func NewWatcher(ctx context.Context, ds Datastorer, lgr zerolog.Logger) *Watcher {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watcher{
+		ds:     ds,
+		lgr:    lgr,
+		id:     uuid.New().String(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.listen(ctx)
+
+	return w
+}
+
+// SetUpdateCallback sets the function called when another instance
+// changes the policy
+func (w *Watcher) SetUpdateCallback(f func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = f
+	return nil
+}
+
+// Update notifies all other instances that the policy has changed
+func (w *Watcher) Update() error {
+	_, err := w.ds.Pool().Exec(context.Background(), "SELECT pg_notify($1, $2)", WatcherChannel, w.id)
+	return err
+}
+
+// Close stops listening and releases the connection
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// listen waits for notifications until ctx is done, reconnecting
+// whenever the connection is lost
+func (w *Watcher) listen(ctx context.Context) {
+	defer close(w.done)
+
+	for {
+		err := w.listenConn(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		w.lgr.Error().Err(err).Msg("policy watcher connection lost")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watcherRetryInterval):
+		}
+
+		// notifications may have been missed while disconnected
+		w.notify("")
+	}
+}
+
+// listenConn acquires a connection, listens on WatcherChannel and
+// waits for notifications until an error occurs
+func (w *Watcher) listenConn(ctx context.Context) error {
+	conn, err := w.ds.Pool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{WatcherChannel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if n.Payload == w.id {
+			continue
+		}
+		w.notify(n.Payload)
+	}
+}
+
+// notify calls the update callback, if set
+func (w *Watcher) notify(payload string) {
+	w.mu.Lock()
+	f := w.callback
+	w.mu.Unlock()
+
+	if f != nil {
+		f(payload)
+	}
+}
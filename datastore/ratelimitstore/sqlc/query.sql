@@ -0,0 +1,15 @@
+-- name: FindRateLimitBucketForUpdate :one
+SELECT * FROM rate_limit_bucket
+WHERE bucket_key = $1
+FOR UPDATE;
+
+-- name: UpsertRateLimitBucket :exec
+INSERT INTO rate_limit_bucket (bucket_key, tokens, update_timestamp)
+VALUES ($1, $2, $3)
+ON CONFLICT (bucket_key) DO UPDATE
+SET tokens = excluded.tokens,
+    update_timestamp = excluded.update_timestamp;
+
+-- name: DeleteRateLimitBucketsBefore :execrows
+DELETE FROM rate_limit_bucket
+WHERE update_timestamp < $1;
@@ -0,0 +1,84 @@
+// Package ratelimitstore stores rate limiting token buckets in the
+// database, so that all instances of the server share the same
+// limits. Store takes tokens while holding a row lock on the bucket.
+package ratelimitstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/ratelimit"
+)
+
+// Datastorer is an interface for working with the Database
+type Datastorer interface {
+	// Pool returns *pgxpool.Pool
+	Pool() *pgxpool.Pool
+}
+
+// Store is a rate limiting bucket store backed by the
+// rate_limit_bucket table
+type Store struct {
+	Datastorer
+}
+
+// NewStore is an initializer for Store
+func NewStore(ds Datastorer) Store {
+	return Store{ds}
+}
+
+// Take takes a token from the bucket for the key
+func (s Store) Take(ctx context.Context, key string, limit int, now time.Time) (ratelimit.Result, error) {
+	tx, err := s.Pool().Begin(ctx)
+	if err != nil {
+		return ratelimit.Result{}, errs.E(errs.Database, err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := New(tx)
+
+	// a missing row is a new (full) bucket, the upsert below will
+	// create it. Concurrent first requests for the same key may both
+	// start from a full bucket, which only matters for a single token.
+	var b ratelimit.Bucket
+	row, err := q.FindRateLimitBucketForUpdate(ctx, key)
+	switch err {
+	case nil:
+		b = ratelimit.Bucket{Tokens: row.Tokens, Updated: row.UpdateTimestamp}
+	case pgx.ErrNoRows:
+	default:
+		return ratelimit.Result{}, errs.E(errs.Database, err)
+	}
+
+	b, r := b.Take(limit, now)
+
+	err = q.UpsertRateLimitBucket(ctx, UpsertRateLimitBucketParams{
+		BucketKey:       key,
+		Tokens:          b.Tokens,
+		UpdateTimestamp: b.Updated,
+	})
+	if err != nil {
+		return ratelimit.Result{}, errs.E(errs.Database, err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return ratelimit.Result{}, errs.E(errs.Database, err)
+	}
+
+	return r, nil
+}
+
+// Prune deletes the buckets not taken from since before the time
+// given. Deleted buckets are full, the same as a new bucket.
+func (s Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	n, err := New(s.Pool()).DeleteRateLimitBucketsBefore(ctx, before)
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,35 @@
+-- name: UpsertAppUsage :exec
+INSERT INTO app_usage (app_id, usage_hour, username, http_method, route, status_code, request_count, duration_ms)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (app_id, usage_hour, username, http_method, route, status_code) DO UPDATE
+SET request_count = app_usage.request_count + excluded.request_count,
+    duration_ms   = app_usage.duration_ms + excluded.duration_ms;
+
+-- name: FindAppUsageByHour :many
+SELECT usage_hour AS usage_start, username, http_method, route, status_code, request_count, duration_ms
+FROM app_usage
+WHERE app_id = $1
+  AND usage_hour >= $2
+  AND usage_hour < $3
+ORDER BY usage_start, route, http_method, status_code, username;
+
+-- name: FindAppUsageByDay :many
+SELECT (date_trunc('day', usage_hour at time zone 'UTC') at time zone 'UTC')::timestamptz AS usage_start,
+       username,
+       http_method,
+       route,
+       status_code,
+       sum(request_count)::bigint AS request_count,
+       sum(duration_ms)::bigint   AS duration_ms
+FROM app_usage
+WHERE app_id = $1
+  AND usage_hour >= $2
+  AND usage_hour < $3
+GROUP BY usage_start, username, http_method, route, status_code
+ORDER BY usage_start, route, http_method, status_code, username;
+
+-- name: CountAppRequestsSince :one
+SELECT coalesce(sum(request_count), 0)::bigint AS request_count
+FROM app_usage
+WHERE app_id = $1
+  AND usage_hour >= $2;
@@ -0,0 +1,63 @@
+// Package usagestore stores the usage of Apps in the database,
+// aggregated by hour. Store implements usage.Store, saving each
+// batch flushed by usage.Meter in a single transaction.
+package usagestore
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/usage"
+)
+
+// Datastorer is an interface for working with the Database
+type Datastorer interface {
+	// Pool returns *pgxpool.Pool
+	Pool() *pgxpool.Pool
+}
+
+// Store saves usage to the app_usage table
+type Store struct {
+	Datastorer
+}
+
+// NewStore is an initializer for Store
+func NewStore(ds Datastorer) Store {
+	return Store{ds}
+}
+
+// SaveUsage adds the usage to the usage already saved for the same
+// App, hour, user, route and status
+func (s Store) SaveUsage(ctx context.Context, uu []usage.Usage) error {
+	tx, err := s.Pool().Begin(ctx)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := New(tx)
+	for _, u := range uu {
+		err = q.UpsertAppUsage(ctx, UpsertAppUsageParams{
+			AppID:        u.AppID,
+			UsageHour:    u.Hour,
+			Username:     u.Username,
+			HttpMethod:   u.Method,
+			Route:        u.Route,
+			StatusCode:   int32(u.Status),
+			RequestCount: u.Requests,
+			DurationMs:   u.Duration.Milliseconds(),
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	return nil
+}
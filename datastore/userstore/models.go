@@ -4,6 +4,7 @@ package userstore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,9 +12,11 @@ import (
 
 type AppUser struct {
 	UserID          uuid.UUID
+	UserExtlID      string
 	Username        string
 	OrgID           uuid.UUID
 	PersonProfileID uuid.UUID
+	Active          bool
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
@@ -33,6 +36,12 @@ type Org struct {
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	// The timestamp the org was archived (soft deleted), null if the org is not archived. The apps and users of an archived org are inactive.
+	ArchiveTimestamp sql.NullTime
+	// The parent organization of a sub-organization, null for a top level organization. Roles granted in an organization apply to its descendants.
+	ParentOrgID uuid.NullUUID
+	// The versioned settings and feature flags document of the organization, e.g. self_registration_allowed, allowed_auth_providers and default_api_key_lifetime. The schema is validated by the application.
+	Settings json.RawMessage
 }
 
 type Person struct {
@@ -13,16 +13,18 @@ import (
 )
 
 const createUser = `-- name: CreateUser :execresult
-INSERT INTO app_user (user_id, username, org_id, person_profile_id, create_app_id, create_user_id,
-                      create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+INSERT INTO app_user (user_id, user_extl_id, username, org_id, person_profile_id, active, create_app_id,
+                      create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 `
 
 type CreateUserParams struct {
 	UserID          uuid.UUID
+	UserExtlID      string
 	Username        string
 	OrgID           uuid.UUID
 	PersonProfileID uuid.UUID
+	Active          bool
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
@@ -34,9 +36,11 @@ type CreateUserParams struct {
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (pgconn.CommandTag, error) {
 	return q.db.Exec(ctx, createUser,
 		arg.UserID,
+		arg.UserExtlID,
 		arg.Username,
 		arg.OrgID,
 		arg.PersonProfileID,
+		arg.Active,
 		arg.CreateAppID,
 		arg.CreateUserID,
 		arg.CreateTimestamp,
@@ -57,8 +61,125 @@ func (q *Queries) DeleteUser(ctx context.Context, userID uuid.UUID) error {
 	return err
 }
 
+const findUserByExternalID = `-- name: FindUserByExternalID :one
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.user_extl_id = $1
+  AND u.org_id = $2
+LIMIT 1
+`
+
+type FindUserByExternalIDParams struct {
+	UserExtlID string
+	OrgID      uuid.UUID
+}
+
+type FindUserByExternalIDRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUserByExternalID(ctx context.Context, arg FindUserByExternalIDParams) (FindUserByExternalIDRow, error) {
+	row := q.db.QueryRow(ctx, findUserByExternalID, arg.UserExtlID, arg.OrgID)
+	var i FindUserByExternalIDRow
+	err := row.Scan(
+		&i.UserID,
+		&i.UserExtlID,
+		&i.Username,
+		&i.OrgID,
+		&i.OrgExtlID,
+		&i.OrgName,
+		&i.OrgDescription,
+		&i.PersonProfileID,
+		&i.NamePrefix,
+		&i.FirstName,
+		&i.MiddleName,
+		&i.LastName,
+		&i.NameSuffix,
+		&i.Nickname,
+		&i.CompanyName,
+		&i.CompanyDept,
+		&i.JobTitle,
+		&i.BirthDate,
+		&i.BirthYear,
+		&i.BirthMonth,
+		&i.BirthDay,
+		&i.LanguageID,
+		&i.PersonID,
+		&i.Active,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
+	)
+	return i, err
+}
+
 const findUserByID = `-- name: FindUserByID :one
 SELECT u.user_id,
+       u.user_extl_id,
        u.username,
        u.org_id,
        o.org_extl_id,
@@ -79,7 +200,14 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
 FROM app_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -90,6 +218,7 @@ LIMIT 1
 
 type FindUserByIDRow struct {
 	UserID          uuid.UUID
+	UserExtlID      string
 	Username        string
 	OrgID           uuid.UUID
 	OrgExtlID       string
@@ -111,6 +240,13 @@ type FindUserByIDRow struct {
 	BirthDay        sql.NullInt64
 	LanguageID      uuid.NullUUID
 	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
 }
 
 func (q *Queries) FindUserByID(ctx context.Context, userID uuid.UUID) (FindUserByIDRow, error) {
@@ -118,6 +254,7 @@ func (q *Queries) FindUserByID(ctx context.Context, userID uuid.UUID) (FindUserB
 	var i FindUserByIDRow
 	err := row.Scan(
 		&i.UserID,
+		&i.UserExtlID,
 		&i.Username,
 		&i.OrgID,
 		&i.OrgExtlID,
@@ -139,12 +276,20 @@ func (q *Queries) FindUserByID(ctx context.Context, userID uuid.UUID) (FindUserB
 		&i.BirthDay,
 		&i.LanguageID,
 		&i.PersonID,
+		&i.Active,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
 	)
 	return i, err
 }
 
 const findUserByUsername = `-- name: FindUserByUsername :one
 SELECT u.user_id,
+       u.user_extl_id,
        u.username,
        u.org_id,
        o.org_extl_id,
@@ -165,7 +310,14 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
 FROM app_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -182,6 +334,7 @@ type FindUserByUsernameParams struct {
 
 type FindUserByUsernameRow struct {
 	UserID          uuid.UUID
+	UserExtlID      string
 	Username        string
 	OrgID           uuid.UUID
 	OrgExtlID       string
@@ -203,6 +356,13 @@ type FindUserByUsernameRow struct {
 	BirthDay        sql.NullInt64
 	LanguageID      uuid.NullUUID
 	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
 }
 
 func (q *Queries) FindUserByUsername(ctx context.Context, arg FindUserByUsernameParams) (FindUserByUsernameRow, error) {
@@ -210,6 +370,7 @@ func (q *Queries) FindUserByUsername(ctx context.Context, arg FindUserByUsername
 	var i FindUserByUsernameRow
 	err := row.Scan(
 		&i.UserID,
+		&i.UserExtlID,
 		&i.Username,
 		&i.OrgID,
 		&i.OrgExtlID,
@@ -231,6 +392,869 @@ func (q *Queries) FindUserByUsername(ctx context.Context, arg FindUserByUsername
 		&i.BirthDay,
 		&i.LanguageID,
 		&i.PersonID,
+		&i.Active,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
 	)
 	return i, err
 }
+
+const findUsersByIDs = `-- name: FindUsersByIDs :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.user_id = ANY ($1::uuid[])
+`
+
+type FindUsersByIDsRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersByIDs(ctx context.Context, userIds []uuid.UUID) ([]FindUsersByIDsRow, error) {
+	rows, err := q.db.Query(ctx, findUsersByIDs, userIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersByIDsRow
+	for rows.Next() {
+		var i FindUsersByIDsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.PersonProfileID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+			&i.BirthYear,
+			&i.BirthMonth,
+			&i.BirthDay,
+			&i.LanguageID,
+			&i.PersonID,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersByOrg = `-- name: FindUsersByOrg :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+ORDER BY u.username
+LIMIT $2 OFFSET $3
+`
+
+type FindUsersByOrgParams struct {
+	OrgID  uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+type FindUsersByOrgRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersByOrg(ctx context.Context, arg FindUsersByOrgParams) ([]FindUsersByOrgRow, error) {
+	rows, err := q.db.Query(ctx, findUsersByOrg, arg.OrgID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersByOrgRow
+	for rows.Next() {
+		var i FindUsersByOrgRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.PersonProfileID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+			&i.BirthYear,
+			&i.BirthMonth,
+			&i.BirthDay,
+			&i.LanguageID,
+			&i.PersonID,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersPageByCreateTimestamp = `-- name: FindUsersPageByCreateTimestamp :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+  AND u.username LIKE $2
+  AND ($3::timestamp IS NULL OR u.create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (u.create_timestamp, u.user_extl_id) > ($5::timestamp, $4))
+ORDER BY u.create_timestamp, u.user_extl_id
+LIMIT $6
+`
+
+type FindUsersPageByCreateTimestampParams struct {
+	OrgID                 uuid.UUID
+	NamePattern           string
+	CreatedAfter          sql.NullTime
+	CursorExtlID          sql.NullString
+	CursorCreateTimestamp time.Time
+	RowLimit              int32
+}
+
+type FindUsersPageByCreateTimestampRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersPageByCreateTimestamp(ctx context.Context, arg FindUsersPageByCreateTimestampParams) ([]FindUsersPageByCreateTimestampRow, error) {
+	rows, err := q.db.Query(ctx, findUsersPageByCreateTimestamp,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorCreateTimestamp,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersPageByCreateTimestampRow
+	for rows.Next() {
+		var i FindUsersPageByCreateTimestampRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.PersonProfileID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+			&i.BirthYear,
+			&i.BirthMonth,
+			&i.BirthDay,
+			&i.LanguageID,
+			&i.PersonID,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersPageByCreateTimestampDesc = `-- name: FindUsersPageByCreateTimestampDesc :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+  AND u.username LIKE $2
+  AND ($3::timestamp IS NULL OR u.create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (u.create_timestamp, u.user_extl_id) < ($5::timestamp, $4))
+ORDER BY u.create_timestamp DESC, u.user_extl_id DESC
+LIMIT $6
+`
+
+type FindUsersPageByCreateTimestampDescParams struct {
+	OrgID                 uuid.UUID
+	NamePattern           string
+	CreatedAfter          sql.NullTime
+	CursorExtlID          sql.NullString
+	CursorCreateTimestamp time.Time
+	RowLimit              int32
+}
+
+type FindUsersPageByCreateTimestampDescRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersPageByCreateTimestampDesc(ctx context.Context, arg FindUsersPageByCreateTimestampDescParams) ([]FindUsersPageByCreateTimestampDescRow, error) {
+	rows, err := q.db.Query(ctx, findUsersPageByCreateTimestampDesc,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorCreateTimestamp,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersPageByCreateTimestampDescRow
+	for rows.Next() {
+		var i FindUsersPageByCreateTimestampDescRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.PersonProfileID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+			&i.BirthYear,
+			&i.BirthMonth,
+			&i.BirthDay,
+			&i.LanguageID,
+			&i.PersonID,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersPageByUsername = `-- name: FindUsersPageByUsername :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+  AND u.username LIKE $2
+  AND ($3::timestamp IS NULL OR u.create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (u.username, u.user_extl_id) > ($5::varchar, $4))
+ORDER BY u.username, u.user_extl_id
+LIMIT $6
+`
+
+type FindUsersPageByUsernameParams struct {
+	OrgID          uuid.UUID
+	NamePattern    string
+	CreatedAfter   sql.NullTime
+	CursorExtlID   sql.NullString
+	CursorUsername string
+	RowLimit       int32
+}
+
+type FindUsersPageByUsernameRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersPageByUsername(ctx context.Context, arg FindUsersPageByUsernameParams) ([]FindUsersPageByUsernameRow, error) {
+	rows, err := q.db.Query(ctx, findUsersPageByUsername,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorUsername,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersPageByUsernameRow
+	for rows.Next() {
+		var i FindUsersPageByUsernameRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.PersonProfileID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+			&i.BirthYear,
+			&i.BirthMonth,
+			&i.BirthDay,
+			&i.LanguageID,
+			&i.PersonID,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersPageByUsernameDesc = `-- name: FindUsersPageByUsernameDesc :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+  AND u.username LIKE $2
+  AND ($3::timestamp IS NULL OR u.create_timestamp > $3)
+  AND ($4::varchar IS NULL OR
+       (u.username, u.user_extl_id) < ($5::varchar, $4))
+ORDER BY u.username DESC, u.user_extl_id DESC
+LIMIT $6
+`
+
+type FindUsersPageByUsernameDescParams struct {
+	OrgID          uuid.UUID
+	NamePattern    string
+	CreatedAfter   sql.NullTime
+	CursorExtlID   sql.NullString
+	CursorUsername string
+	RowLimit       int32
+}
+
+type FindUsersPageByUsernameDescRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	PersonProfileID uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	PersonID        uuid.UUID
+	Active          bool
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersPageByUsernameDesc(ctx context.Context, arg FindUsersPageByUsernameDescParams) ([]FindUsersPageByUsernameDescRow, error) {
+	rows, err := q.db.Query(ctx, findUsersPageByUsernameDesc,
+		arg.OrgID,
+		arg.NamePattern,
+		arg.CreatedAfter,
+		arg.CursorExtlID,
+		arg.CursorUsername,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersPageByUsernameDescRow
+	for rows.Next() {
+		var i FindUsersPageByUsernameDescRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.PersonProfileID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+			&i.BirthYear,
+			&i.BirthMonth,
+			&i.BirthDay,
+			&i.LanguageID,
+			&i.PersonID,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUser = `-- name: UpdateUser :exec
+UPDATE app_user
+SET active           = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE user_id = $5
+`
+
+type UpdateUserParams struct {
+	Active          bool
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	UserID          uuid.UUID
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
+	_, err := q.db.Exec(ctx, updateUser,
+		arg.Active,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.UserID,
+	)
+	return err
+}
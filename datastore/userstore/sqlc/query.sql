@@ -1,5 +1,6 @@
 -- name: FindUserByID :one
 SELECT u.user_id,
+       u.user_extl_id,
        u.username,
        u.org_id,
        o.org_extl_id,
@@ -20,7 +21,14 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
 FROM app_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -28,8 +36,46 @@ FROM app_user u
 WHERE u.user_id = $1
 LIMIT 1;
 
+-- name: FindUsersByIDs :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.user_id = ANY (sqlc.arg(user_ids)::uuid[]);
+
 -- name: FindUserByUsername :one
 SELECT u.user_id,
+       u.user_extl_id,
        u.username,
        u.org_id,
        o.org_extl_id,
@@ -50,7 +96,14 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
 FROM app_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -59,12 +112,270 @@ WHERE u.username = $1
   AND u.org_id = $2
 LIMIT 1;
 
+-- name: FindUserByExternalID :one
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.user_extl_id = $1
+  AND u.org_id = $2
+LIMIT 1;
+
+-- name: FindUsersByOrg :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+ORDER BY u.username
+LIMIT $2 OFFSET $3;
+
+-- name: FindUsersPageByCreateTimestamp :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.username LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR u.create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (u.create_timestamp, u.user_extl_id) > (sqlc.arg(cursor_create_timestamp)::timestamp, sqlc.narg(cursor_extl_id)))
+ORDER BY u.create_timestamp, u.user_extl_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindUsersPageByCreateTimestampDesc :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.username LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR u.create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (u.create_timestamp, u.user_extl_id) < (sqlc.arg(cursor_create_timestamp)::timestamp, sqlc.narg(cursor_extl_id)))
+ORDER BY u.create_timestamp DESC, u.user_extl_id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindUsersPageByUsername :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.username LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR u.create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (u.username, u.user_extl_id) > (sqlc.arg(cursor_username)::varchar, sqlc.narg(cursor_extl_id)))
+ORDER BY u.username, u.user_extl_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: FindUsersPageByUsernameDesc :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       u.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       pp.birth_year,
+       pp.birth_month,
+       pp.birth_day,
+       pp.language_id,
+       p.person_id,
+       u.active,
+       u.create_app_id,
+       u.create_user_id,
+       u.create_timestamp,
+       u.update_app_id,
+       u.update_user_id,
+       u.update_timestamp
+FROM app_user u
+         inner join org o on o.org_id = u.org_id
+         inner join person_profile pp on pp.person_profile_id = u.person_profile_id
+         inner join person p on p.person_id = pp.person_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.username LIKE sqlc.arg(name_pattern)
+  AND (sqlc.narg(created_after)::timestamp IS NULL OR u.create_timestamp > sqlc.narg(created_after))
+  AND (sqlc.narg(cursor_extl_id)::varchar IS NULL OR
+       (u.username, u.user_extl_id) < (sqlc.arg(cursor_username)::varchar, sqlc.narg(cursor_extl_id)))
+ORDER BY u.username DESC, u.user_extl_id DESC
+LIMIT sqlc.arg(row_limit);
+
 -- name: CreateUser :execresult
-INSERT INTO app_user (user_id, username, org_id, person_profile_id, create_app_id, create_user_id,
-                      create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+INSERT INTO app_user (user_id, user_extl_id, username, org_id, person_profile_id, active, create_app_id,
+                      create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: UpdateUser :exec
+UPDATE app_user
+SET active           = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE user_id = $5;
 
 -- name: DeleteUser :exec
 DELETE
 FROM app_user
-WHERE user_id = $1;
+WHERE user_id = $1;
\ No newline at end of file
@@ -0,0 +1,162 @@
+package userstore
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// WatcherChannel is the PostgreSQL notification channel used to
+// announce changed Users
+const WatcherChannel = "user_changed"
+
+// watcherRetryInterval is the time waited before listening again
+// after the listening connection is lost
+const watcherRetryInterval = 5 * time.Second
+
+// Datastorer is an interface for working with the Database
+type Datastorer interface {
+	// Pool returns *pgxpool.Pool
+	Pool() *pgxpool.Pool
+}
+
+// Watcher uses PostgreSQL LISTEN/NOTIFY to announce changed Users
+// to every instance of the server. Publish sends a notification on
+// WatcherChannel and every other Watcher listening on the channel
+// calls its callback with the ID of the changed User (typically to
+// remove the User from an identity cache).
+type Watcher struct {
+	ds  Datastorer
+	lgr zerolog.Logger
+	// id identifies notifications sent by this Watcher, which
+	// are ignored when received
+	id string
+
+	mu       sync.Mutex
+	callback func(uuid.UUID)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher initializes a Watcher and starts listening for changed
+// Users on a connection acquired from the pool. Close must be called
+// to release the connection.
+func NewWatcher(ctx context.Context, ds Datastorer, lgr zerolog.Logger) *Watcher {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watcher{
+		ds:     ds,
+		lgr:    lgr,
+		id:     uuid.New().String(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.listen(ctx)
+
+	return w
+}
+
+// SetCallback sets the function called when another instance changes
+// a User. It is called with uuid.Nil when notifications may have been
+// missed, in which case any User may have changed.
+func (w *Watcher) SetCallback(f func(uuid.UUID)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = f
+}
+
+// Publish notifies all other instances that the User with the given
+// ID has changed
+func (w *Watcher) Publish(id uuid.UUID) error {
+	_, err := w.ds.Pool().Exec(context.Background(), "SELECT pg_notify($1, $2)", WatcherChannel, w.id+":"+id.String())
+	return err
+}
+
+// Close stops listening and releases the connection
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// listen waits for notifications until ctx is done, reconnecting
+// whenever the connection is lost
+func (w *Watcher) listen(ctx context.Context) {
+	defer close(w.done)
+
+	for {
+		err := w.listenConn(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		w.lgr.Error().Err(err).Msg("user watcher connection lost")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watcherRetryInterval):
+		}
+
+		// notifications may have been missed while disconnected
+		w.notify(uuid.Nil)
+	}
+}
+
+// listenConn acquires a connection, listens on WatcherChannel and
+// waits for notifications until an error occurs
+func (w *Watcher) listenConn(ctx context.Context) error {
+	conn, err := w.ds.Pool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{WatcherChannel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		id, ok := w.parsePayload(n.Payload)
+		if !ok {
+			continue
+		}
+		w.notify(id)
+	}
+}
+
+// parsePayload returns the User ID from a notification payload
+// (sender ID and User ID separated by a colon). Notifications sent by
+// this Watcher or which cannot be parsed are not ok.
+func (w *Watcher) parsePayload(payload string) (uuid.UUID, bool) {
+	i := strings.IndexByte(payload, ':')
+	if i < 0 || payload[:i] == w.id {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(payload[i+1:])
+	if err != nil {
+		w.lgr.Error().Err(err).Str("payload", payload).Msg("invalid user watcher notification")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// notify calls the callback, if set
+func (w *Watcher) notify(id uuid.UUID) {
+	w.mu.Lock()
+	f := w.callback
+	w.mu.Unlock()
+
+	if f != nil {
+		f(id)
+	}
+}
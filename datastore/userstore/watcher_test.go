@@ -0,0 +1,29 @@
+package userstore
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func TestWatcher_parsePayload(t *testing.T) {
+	c := qt.New(t)
+
+	w := &Watcher{id: uuid.New().String(), lgr: zerolog.Nop()}
+	userID := uuid.New()
+
+	id, ok := w.parsePayload(uuid.New().String() + ":" + userID.String())
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(id, qt.Equals, userID)
+
+	// own notifications are ignored
+	_, ok = w.parsePayload(w.id + ":" + userID.String())
+	c.Assert(ok, qt.IsFalse)
+
+	_, ok = w.parsePayload("no separator")
+	c.Assert(ok, qt.IsFalse)
+	_, ok = w.parsePayload(uuid.New().String() + ":not a uuid")
+	c.Assert(ok, qt.IsFalse)
+}
@@ -13,11 +13,22 @@ import (
 
 // App is an application that interacts with the system
 type App struct {
-	ID           uuid.UUID
-	ExternalID   secure.Identifier
-	Org          org.Org
-	Name         string
-	Description  string
+	ID          uuid.UUID
+	ExternalID  secure.Identifier
+	Org         org.Org
+	Name        string
+	Description string
+	Active      bool
+	Scopes      Scopes
+	// ServiceAccount: if true, the App may authenticate without a
+	// User on routes which allow it
+	ServiceAccount bool
+	// RateLimit is the number of requests per minute allowed for
+	// the App, zero if the server default applies
+	RateLimit int
+	// MonthlyQuota is the number of requests per calendar month
+	// allowed for the App, zero if the server default applies
+	MonthlyQuota int64
 	CreateAppID  uuid.UUID
 	CreateUserID uuid.UUID
 	CreateTime   time.Time
@@ -28,9 +39,11 @@ type App struct {
 }
 
 // ValidKey determines if the app has a matching key for the input
-// and if that key is valid
-func (a App) ValidKey(realm, matchKey string) error {
-	key, err := a.matchKey(realm, matchKey)
+// and if that key is valid. The secret is the server secret the
+// keys are hashed with, kms unwraps the data keys of keys hashed
+// with a data key of their own.
+func (a App) ValidKey(ctx context.Context, realm, matchKey string, secret *[32]byte, kms secure.KMS) error {
+	key, err := a.matchKey(ctx, realm, matchKey, secret, kms)
 	if err != nil {
 		return err
 	}
@@ -43,9 +56,13 @@ func (a App) ValidKey(realm, matchKey string) error {
 
 // MatchKey returns the matching Key given the string, if exists
 // An error will be sent if no match is found
-func (a App) matchKey(realm, matchKey string) (APIKey, error) {
+func (a App) matchKey(ctx context.Context, realm, matchKey string, secret *[32]byte, kms secure.KMS) (APIKey, error) {
 	for _, apiKey := range a.APIKeys {
-		if matchKey == apiKey.Key() {
+		ok, err := apiKey.matches(ctx, matchKey, secret, kms)
+		if err != nil {
+			return APIKey{}, err
+		}
+		if ok {
 			return apiKey, nil
 		}
 	}
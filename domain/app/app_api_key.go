@@ -1,69 +1,190 @@
 package app
 
 import (
-	"encoding/base64"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/secure"
 )
 
-// APIKeyStringGenerator creates a random, 128 API key string
+// APIKeyPrefixLen is the number of leading characters of an API key
+// stored in the clear and used to look the key up
+const APIKeyPrefixLen = 8
+
+// APIKeyStringGenerator creates a random API key string
 type APIKeyStringGenerator interface {
 	RandomString(n int) (string, error)
 }
 
-// APIKey is an API key for interacting with the system
+// APIKey is an API key for interacting with the system. Only a
+// prefix and a hash of the key are stored, the key itself is only
+// known when the APIKey is created. When a KMS is configured, each
+// key is hashed with a data key of its own, stored wrapped by the
+// KMS, otherwise keys are hashed with the server secret.
 type APIKey struct {
-	// key: the unencrypted API key string
+	// externalID: the unique identifier used to manage the key
+	// without sending the key itself
+	externalID secure.Identifier
+	// key: the API key string, only set when the key is created
 	key string
-	// ciphertext: the encrypted API key as []byte
-	ciphertext []byte
+	// prefix: the leading characters of the API key
+	prefix string
+	// hash: the hex encoded HMAC-SHA256 of the API key
+	hash string
+	// dataKey: the data key the API key is hashed with, wrapped by
+	// the KMS. Empty if the key is hashed with the server secret.
+	dataKey string
 	// deactivationDate: the date the API key is no longer usable
 	deactivationDate time.Time
 }
 
-// NewAPIKey initializes an APIKey. It generates both a 128-bit (16 byte)
-// random string as an API key and its corresponding ciphertext bytes
-func NewAPIKey(g APIKeyStringGenerator, ek *[32]byte) (APIKey, error) {
-	k, err := g.RandomString(18)
+// NewAPIKey initializes an APIKey. It generates a 192-bit (24 byte)
+// random string as an API key and its prefix and hash. If kms is not
+// nil, the key is hashed with a new data key wrapped by it, otherwise
+// with the server secret.
+func NewAPIKey(ctx context.Context, g APIKeyStringGenerator, secret *[32]byte, kms secure.KMS) (APIKey, error) {
+	k, err := g.RandomString(24)
 	if err != nil {
 		return APIKey{}, err
 	}
 
-	ct, err := secure.Encrypt([]byte(k), ek)
+	key, err := newAPIKey(ctx, k, secret, kms)
 	if err != nil {
 		return APIKey{}, err
 	}
+	key.externalID = secure.NewID()
 
-	return APIKey{key: k, ciphertext: ct}, nil
+	return key, nil
 }
 
-// NewAPIKeyFromCipher initializes an APIKey
-func NewAPIKeyFromCipher(ciphertext string, ek *[32]byte) (APIKey, error) {
-	eak, err := base64.URLEncoding.DecodeString(ciphertext)
+// NewAPIKeyFromCipher initializes an APIKey given the ciphertext of
+// a key encrypted with a key of the keyring (or an envelope
+// ciphertext, decrypted with the KMS), the way keys were stored
+// before they were hashed. It is used to migrate those keys forward.
+func NewAPIKeyFromCipher(ctx context.Context, ciphertext string, kr *secure.Keyring, secret *[32]byte, kms secure.KMS) (APIKey, error) {
+	var (
+		apiKey []byte
+		err    error
+	)
+	if secure.IsEnvelope(ciphertext) {
+		if kms == nil {
+			return APIKey{}, errs.E(errs.Internal, "no KMS configured to decrypt envelope ciphertext")
+		}
+		apiKey, err = secure.EnvelopeDecrypt(ctx, kms, ciphertext)
+	} else {
+		apiKey, err = kr.Decrypt(ciphertext)
+	}
 	if err != nil {
-		return APIKey{}, errs.E(errs.Internal, err)
+		return APIKey{}, err
+	}
+
+	return newAPIKey(ctx, string(apiKey), secret, kms)
+}
+
+// NewAPIKeyFromHash initializes an APIKey given its stored prefix
+// and hash
+func NewAPIKeyFromHash(prefix, hash string) APIKey {
+	return APIKey{prefix: prefix, hash: hash}
+}
+
+// newAPIKey initializes an APIKey given the key string, hashed with
+// a new data key if kms is not nil or the server secret otherwise
+func newAPIKey(ctx context.Context, k string, secret *[32]byte, kms secure.KMS) (APIKey, error) {
+	if kms == nil {
+		return APIKey{key: k, prefix: APIKeyPrefix(k), hash: hashAPIKey(k, secret)}, nil
 	}
 
-	apiKey, err := secure.Decrypt(eak, ek)
+	dk, err := secure.NewDataKey(ctx, kms)
 	if err != nil {
 		return APIKey{}, err
 	}
 
-	return APIKey{key: string(apiKey), ciphertext: eak}, nil
+	return APIKey{key: k, prefix: APIKeyPrefix(k), hash: hashAPIKey(k, dk.Key), dataKey: dk.Wrapped}, nil
 }
 
-// Key returns the key for the API key
+// APIKeyPrefix returns the prefix of the given API key string
+func APIKeyPrefix(k string) string {
+	if len(k) < APIKeyPrefixLen {
+		return k
+	}
+	return k[:APIKeyPrefixLen]
+}
+
+// hashAPIKey returns the hex encoded HMAC-SHA256 of the API key
+// string given the server secret
+func hashAPIKey(k string, secret *[32]byte) string {
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(k))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ExternalID returns the unique identifier for the API key
+func (a APIKey) ExternalID() secure.Identifier {
+	return a.externalID
+}
+
+// SetExternalID sets the unique identifier for the API key
+func (a *APIKey) SetExternalID(id secure.Identifier) {
+	a.externalID = id
+}
+
+// Key returns the key for the API key. It is empty unless the
+// APIKey was just created.
 func (a APIKey) Key() string {
 	return a.key
 }
 
-// Ciphertext returns the hex encoded text of the encrypted cipher bytes for the API key
-func (a APIKey) Ciphertext() string {
-	return hex.EncodeToString(a.ciphertext)
+// Prefix returns the prefix of the API key
+func (a APIKey) Prefix() string {
+	return a.prefix
+}
+
+// Hash returns the hex encoded hash of the API key
+func (a APIKey) Hash() string {
+	return a.hash
+}
+
+// DataKey returns the wrapped data key the API key is hashed with,
+// empty if it is hashed with the server secret
+func (a APIKey) DataKey() string {
+	return a.dataKey
+}
+
+// SetDataKey sets the wrapped data key the API key is hashed with
+func (a *APIKey) SetDataKey(wrapped string) {
+	a.dataKey = wrapped
+}
+
+// Masked returns the prefix of the API key with the rest of the key
+// hidden. The length of the key is not revealed.
+func (a APIKey) Masked() string {
+	return a.prefix + strings.Repeat("*", 8)
+}
+
+// matches reports whether the given API key string hashes to the
+// hash of the API key. The hashes are compared in constant time. The
+// data key of the API key, if any, is unwrapped with the KMS for the
+// comparison only.
+func (a APIKey) matches(ctx context.Context, k string, secret *[32]byte, kms secure.KMS) (bool, error) {
+	if a.dataKey == "" {
+		return hmac.Equal([]byte(hashAPIKey(k, secret)), []byte(a.hash)), nil
+	}
+	if kms == nil {
+		return false, errs.E(errs.Internal, "no KMS configured for API keys hashed with a data key")
+	}
+
+	dk, err := secure.UnwrapDataKey(ctx, kms, a.dataKey)
+	if err != nil {
+		return false, err
+	}
+
+	return hmac.Equal([]byte(hashAPIKey(k, dk)), []byte(a.hash)), nil
 }
 
 // DeactivationDate returns the Deactivation Date for the API key
@@ -77,6 +198,14 @@ func (a *APIKey) SetDeactivationDate(t time.Time) {
 	a.deactivationDate = t
 }
 
+// DeactivateBy moves the deactivation date of the API key to t, if
+// t is earlier. A key is never given a later deactivation date.
+func (a *APIKey) DeactivateBy(t time.Time) {
+	if t.Before(a.deactivationDate) {
+		a.deactivationDate = t
+	}
+}
+
 // SetStringAsDeactivationDate sets the deactivation date value to
 // AppAPIkey given a string in RFC3339 format
 func (a *APIKey) SetStringAsDeactivationDate(s string) error {
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+	"github.com/gilcrest/go-api-basic/domain/secure/random"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	c := qt.New(t)
+
+	secret, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+
+	ctx := context.Background()
+	k, err := NewAPIKey(ctx, random.CryptoGenerator{}, secret, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(k.ExternalID(), qt.Not(qt.HasLen), 0)
+	c.Assert(k.Prefix(), qt.Equals, k.Key()[:APIKeyPrefixLen])
+	c.Assert(strings.Contains(k.Hash(), k.Key()), qt.IsFalse)
+	c.Assert(k.DataKey(), qt.Equals, "")
+
+	// only the prefix and hash are stored
+	stored := NewAPIKeyFromHash(k.Prefix(), k.Hash())
+	c.Assert(stored.Key(), qt.Equals, "")
+	assertMatches(c, stored, k.Key(), secret, nil, true)
+	assertMatches(c, stored, k.Key()+"x", secret, nil, false)
+
+	// a different secret gives a different hash
+	other, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+	assertMatches(c, stored, k.Key(), other, nil, false)
+}
+
+func TestNewAPIKey_dataKey(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	secret, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+	kms := newTestKMS(c)
+
+	k, err := NewAPIKey(ctx, random.CryptoGenerator{}, secret, kms)
+	c.Assert(err, qt.IsNil)
+	c.Assert(k.DataKey(), qt.Not(qt.Equals), "")
+
+	// the key is hashed with its data key, not the server secret
+	c.Assert(k.Hash(), qt.Not(qt.Equals), hashAPIKey(k.Key(), secret))
+	stored := NewAPIKeyFromHash(k.Prefix(), k.Hash())
+	stored.SetDataKey(k.DataKey())
+	assertMatches(c, stored, k.Key(), secret, kms, true)
+	assertMatches(c, stored, k.Key()+"x", secret, kms, false)
+
+	// the data key cannot be unwrapped without the KMS
+	_, err = stored.matches(ctx, k.Key(), secret, nil)
+	c.Assert(err, qt.IsNotNil)
+	_, err = stored.matches(ctx, k.Key(), secret, newTestKMS(c))
+	c.Assert(err, qt.IsNotNil)
+}
+
+// assertMatches asserts whether the API key string matches the APIKey
+func assertMatches(c *qt.C, a APIKey, k string, secret *[32]byte, kms secure.KMS, want bool) {
+	c.Helper()
+	ok, err := a.matches(context.Background(), k, secret, kms)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.Equals, want)
+}
+
+// newTestKMS returns a LocalKMS with a new random key-encryption key
+func newTestKMS(c *qt.C) secure.KMS {
+	c.Helper()
+	kek, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+	kr, err := secure.ParseKeyring("kek:" + hex.EncodeToString(kek[:]))
+	c.Assert(err, qt.IsNil)
+	return secure.NewLocalKMS(kr)
+}
+
+func TestNewAPIKeyFromCipher(t *testing.T) {
+	c := qt.New(t)
+
+	ek, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+	kr, err := secure.ParseKeyring(hex.EncodeToString(ek[:]))
+	c.Assert(err, qt.IsNil)
+	secret, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+
+	// keys were stored as hex encoded ciphertext
+	const legacyKey = "zaIbK9DWCbh6Qq2mRFeNQ2Ac"
+	ct, err := secure.Encrypt([]byte(legacyKey), ek)
+	c.Assert(err, qt.IsNil)
+
+	ctx := context.Background()
+	k, err := NewAPIKeyFromCipher(ctx, hex.EncodeToString(ct), kr, secret, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(k.Prefix(), qt.Equals, "zaIbK9DW")
+	assertMatches(c, k, legacyKey, secret, nil, true)
+
+	// envelope ciphertexts are decrypted with the KMS
+	kms := newTestKMS(c)
+	env, err := secure.EnvelopeEncrypt(ctx, kms, []byte(legacyKey))
+	c.Assert(err, qt.IsNil)
+	k, err = NewAPIKeyFromCipher(ctx, env, kr, secret, kms)
+	c.Assert(err, qt.IsNil)
+	assertMatches(c, k, legacyKey, secret, kms, true)
+}
+
+func TestAPIKey_Masked(t *testing.T) {
+	c := qt.New(t)
+
+	k := NewAPIKeyFromHash("zaIbK9DW", "")
+	c.Assert(k.Masked(), qt.Equals, "zaIbK9DW********")
+}
+
+func TestApp_ValidKey(t *testing.T) {
+	c := qt.New(t)
+
+	secret, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+
+	ctx := context.Background()
+	k, err := NewAPIKey(ctx, random.CryptoGenerator{}, secret, nil)
+	c.Assert(err, qt.IsNil)
+	stored := NewAPIKeyFromHash(k.Prefix(), k.Hash())
+	stored.SetDeactivationDate(time.Now().Add(time.Hour))
+
+	a := App{APIKeys: []APIKey{stored}}
+	c.Assert(a.ValidKey(ctx, "test", k.Key(), secret, nil), qt.IsNil)
+
+	err = a.ValidKey(ctx, "test", k.Prefix()+"guessed", secret, nil)
+	c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+}
+
+func TestAPIKey_DeactivateBy(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Now()
+	k := APIKey{deactivationDate: now.Add(48 * time.Hour)}
+
+	// a later date is ignored
+	k.DeactivateBy(now.Add(72 * time.Hour))
+	c.Assert(k.DeactivationDate(), qt.Equals, now.Add(48*time.Hour))
+
+	k.DeactivateBy(now.Add(time.Hour))
+	c.Assert(k.DeactivationDate(), qt.Equals, now.Add(time.Hour))
+	c.Assert(k.isValid("test"), qt.IsNil)
+
+	// revoked
+	k.DeactivateBy(now)
+	c.Assert(errs.KindIs(errs.Unauthenticated, k.isValid("test")), qt.IsTrue)
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// Scope levels, each level includes the levels before it
+const (
+	// ReadScope allows reading (GET) a resource
+	ReadScope string = "read"
+	// WriteScope allows reading and writing (POST, PUT) a resource
+	WriteScope string = "write"
+	// AdminScope allows reading, writing and deleting a resource
+	AdminScope string = "admin"
+)
+
+// AnyResource is the resource of a scope which applies to every
+// resource, e.g. *:read
+const AnyResource string = "*"
+
+// FullAccess is the scope given to Apps which have no restriction
+const FullAccess Scope = Scope(AnyResource + ":" + AdminScope)
+
+var scopeRegexp = regexp.MustCompile(`^([a-z][a-z0-9_-]*|\*):(read|write|admin)$`)
+
+// Scope limits the requests an App can make, regardless of the
+// permissions of the User. A scope is formatted as resource:level,
+// e.g. orgs:read, apps:write or logger:admin. The resource is the
+// first path segment of the API, e.g. apps for /api/v1/apps/{extlID}.
+type Scope string
+
+// ParseScope parses and validates a Scope
+func ParseScope(s string) (Scope, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !scopeRegexp.MatchString(s) {
+		return "", errs.E(errs.Validation, errs.Parameter("scopes"), fmt.Sprintf("invalid scope %q, scopes are formatted as resource:level where level is one of read, write or admin", s))
+	}
+	return Scope(s), nil
+}
+
+// Resource returns the resource of the Scope
+func (s Scope) Resource() string {
+	return strings.SplitN(string(s), ":", 2)[0]
+}
+
+// Level returns the level of the Scope, empty if the Scope is
+// malformed
+func (s Scope) Level() string {
+	parts := strings.SplitN(string(s), ":", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// includes reports whether the Scope grants everything the other
+// Scope grants
+func (s Scope) includes(other Scope) bool {
+	if s.Resource() != AnyResource && s.Resource() != other.Resource() {
+		return false
+	}
+	return levelRank(s.Level()) >= levelRank(other.Level())
+}
+
+// levelRank orders the scope levels
+func levelRank(level string) int {
+	switch level {
+	case ReadScope:
+		return 1
+	case WriteScope:
+		return 2
+	case AdminScope:
+		return 3
+	}
+	return 0
+}
+
+// Scopes is the set of scopes of an App
+type Scopes []Scope
+
+// ParseScopes parses and validates a list of scopes. Duplicates are
+// removed and the scopes are sorted.
+func ParseScopes(ss []string) (Scopes, error) {
+	seen := make(map[Scope]bool, len(ss))
+	scopes := make(Scopes, 0, len(ss))
+	for _, s := range ss {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, err
+		}
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i] < scopes[j] })
+
+	return scopes, nil
+}
+
+// Allow reports whether the Scopes allow the action (read, write or
+// delete, the same actions used for User authorization) on the
+// resource. Deleting requires the admin level.
+func (ss Scopes) Allow(resource, act string) bool {
+	level := ReadScope
+	switch act {
+	case "write":
+		level = WriteScope
+	case "delete":
+		level = AdminScope
+	}
+	return ss.includes(Scope(resource + ":" + level))
+}
+
+// Includes reports whether the Scopes grant everything the other
+// Scopes grant. An App can only give out scopes it holds itself.
+func (ss Scopes) Includes(other Scopes) bool {
+	for _, o := range other {
+		if !ss.includes(o) {
+			return false
+		}
+	}
+	return true
+}
+
+// includes reports whether any of the Scopes includes the Scope
+func (ss Scopes) includes(other Scope) bool {
+	for _, s := range ss {
+		if s.includes(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings returns the Scopes as a string slice
+func (ss Scopes) Strings() []string {
+	s := make([]string, 0, len(ss))
+	for _, scope := range ss {
+		s = append(s, string(scope))
+	}
+	return s
+}
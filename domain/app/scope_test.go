@@ -0,0 +1,50 @@
+package app
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+func TestParseScopes(t *testing.T) {
+	c := qt.New(t)
+
+	got, err := ParseScopes([]string{"orgs:read", " Apps:Write ", "orgs:read", "*:admin"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, Scopes{"*:admin", "apps:write", "orgs:read"})
+
+	for _, s := range []string{"", "orgs", "orgs:delete", ":read", "orgs:read:write"} {
+		_, err = ParseScopes([]string{s})
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue, qt.Commentf("%q", s))
+	}
+}
+
+func TestScopes_Allow(t *testing.T) {
+	c := qt.New(t)
+
+	ss := Scopes{"orgs:read", "apps:write", "logger:admin"}
+
+	c.Assert(ss.Allow("orgs", "read"), qt.IsTrue)
+	c.Assert(ss.Allow("orgs", "write"), qt.IsFalse)
+	c.Assert(ss.Allow("apps", "write"), qt.IsTrue)
+	c.Assert(ss.Allow("apps", "delete"), qt.IsFalse)
+	c.Assert(ss.Allow("logger", "delete"), qt.IsTrue)
+	c.Assert(ss.Allow("movies", "read"), qt.IsFalse)
+
+	c.Assert(Scopes{FullAccess}.Allow("movies", "delete"), qt.IsTrue)
+	c.Assert(Scopes{"*:read"}.Allow("movies", "write"), qt.IsFalse)
+	c.Assert(Scopes{"malformed"}.Allow("malformed", "read"), qt.IsFalse)
+	c.Assert(Scopes(nil).Allow("movies", "read"), qt.IsFalse)
+}
+
+func TestScopes_Includes(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(Scopes{FullAccess}.Includes(Scopes{"orgs:admin", "apps:read"}), qt.IsTrue)
+	c.Assert(Scopes{"apps:write"}.Includes(Scopes{"apps:read"}), qt.IsTrue)
+	c.Assert(Scopes{"apps:write"}.Includes(Scopes{"apps:admin"}), qt.IsFalse)
+	c.Assert(Scopes{"apps:write"}.Includes(Scopes{"*:read"}), qt.IsFalse)
+	c.Assert(Scopes{"apps:write"}.Includes(nil), qt.IsTrue)
+}
@@ -4,6 +4,7 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/casbin/casbin/v2"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/gilcrest/go-api-basic/domain/audit"
 	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/secure"
 )
 
 // BearerTokenType is used in authorization to access a resource
@@ -50,21 +52,52 @@ func NewProvider(s string) Provider {
 	return Invalid
 }
 
-// CasbinAuthorizer holds the casbin.Enforcer struct
+// AnyDomain is the casbin domain of policy rules which apply to
+// every Org. Roles are always granted within the domain of a
+// single Org (its external ID).
+const AnyDomain string = "*"
+
+// Roles granted to users within an Org. A role granted in an Org
+// applies within each of its descendant Orgs as well.
+const (
+	// AdminRole can read and write
+	AdminRole string = "admin"
+	// UserRole can only read
+	UserRole string = "user"
+)
+
+// CasbinAuthorizer holds the casbin.SyncedEnforcer struct. The
+// SyncedEnforcer is safe to use while its policy is reloaded.
 type CasbinAuthorizer struct {
-	Enforcer *casbin.Enforcer
+	Enforcer *casbin.SyncedEnforcer
+	// PermissionDetail opts in to sending the required action and
+	// resource to the client when a request is not authorized
+	PermissionDetail bool
 }
 
 // Authorize ensures that a subject (user.User) can perform a
-// particular action on an object. e.g. subject otto.maddox711@gmail.com
-// can read (GET) the object (resource) at the /api/v1/movies path.
+// particular action on an object within a domain. e.g. subject
+// otto.maddox711@gmail.com can read (GET) the object (resource) at
+// the /api/v1/movies path within the domain of the App's Org.
 // Casbin is set up to use an RBAC (Role-Based Access Control) model
+// with domains, roles are granted per Org. Roles granted in one of
+// the ancestors of the App's Org are checked against the policy of
+// the App's Org, as if they had been granted within it.
 // Users with the admin role can *write* (GET, PUT, POST, DELETE).
 // Users with the user role can only *read* (GET)
+//
+// The App must also have a scope allowing the action on the
+// resource, so an App with read only scopes cannot change data,
+// even for an admin User.
 func (a CasbinAuthorizer) Authorize(lgr zerolog.Logger, r *http.Request, adt audit.Audit) error {
-	// subject: Username
+	// subject: Username. For a service account App calling without
+	// a User, the username of its service principal (app:<App
+	// external ID>), App level roles are granted to it within the Org
 	sub := adt.User.Username
 
+	// domain: external ID of the App's Org
+	dom := adt.App.Org.ExternalID.String()
+
 	// object: current route path
 	route := mux.CurrentRoute(r)
 
@@ -90,12 +123,18 @@ func (a CasbinAuthorizer) Authorize(lgr zerolog.Logger, r *http.Request, adt aud
 		act = "write"
 	}
 
-	authorized, err := a.Enforcer.Enforce(sub, obj, act)
+	authorized, err := a.Enforcer.Enforce(sub, dom, obj, act)
 	if err != nil {
 		return errs.E(errs.Unauthorized, err)
 	}
 	if !authorized {
-		lgr.Info().Str("sub", sub).Str("obj", obj).Str("act", act).Msgf("Unauthorized (sub: %s, obj: %s, act: %s)", sub, obj, act)
+		authorized, err = a.inheritedRoleAllows(sub, adt.App.Org.Ancestors, dom, obj, act)
+		if err != nil {
+			return errs.E(errs.Unauthorized, err)
+		}
+	}
+	if !authorized {
+		lgr.Info().Str("sub", sub).Str("dom", dom).Str("obj", obj).Str("act", act).Msgf("Unauthorized (sub: %s, dom: %s, obj: %s, act: %s)", sub, dom, obj, act)
 
 		// "In summary, a 401 Unauthorized response should be used for missing or
 		// bad authentication, and a 403 Forbidden response should be used afterwards,
@@ -103,9 +142,57 @@ func (a CasbinAuthorizer) Authorize(lgr zerolog.Logger, r *http.Request, adt aud
 		// requested operation on the given resource."
 		// If the user has gotten here, they have gotten through authentication
 		// but do have the right access, this they are Unauthorized
-		return errs.E(errs.Unauthorized, fmt.Sprintf("user %s does not have %s permission for %s", sub, act, obj))
+		err = errs.E(errs.Unauthorized, fmt.Sprintf("user %s does not have %s permission for %s", sub, act, obj))
+		if a.PermissionDetail {
+			err = errs.E(err, errs.Permission{Action: act, Resource: obj})
+		}
+		return err
 	}
 
-	lgr.Debug().Str("sub", sub).Str("obj", obj).Str("act", act).Msgf("Authorized (sub: %s, obj: %s, act: %s)", sub, obj, act)
+	// the App must also have a scope for the request, a User
+	// permission is not enough on its own
+	resource := ScopeResource(obj)
+	if !adt.App.Scopes.Allow(resource, act) {
+		lgr.Info().Str("app", adt.App.ExternalID.String()).Str("obj", obj).Str("act", act).Msgf("App scope not granted (app: %s, obj: %s, act: %s)", adt.App.ExternalID.String(), obj, act)
+		return errs.E(errs.Unauthorized, fmt.Sprintf("app %s does not have a scope allowing %s for %s", adt.App.ExternalID.String(), act, resource))
+	}
+
+	lgr.Debug().Str("sub", sub).Str("dom", dom).Str("obj", obj).Str("act", act).Msgf("Authorized (sub: %s, dom: %s, obj: %s, act: %s)", sub, dom, obj, act)
 	return nil
 }
+
+// inheritedRoleAllows reports whether a role granted to the subject
+// in one of the ancestor Orgs allows the action on the object within
+// the domain
+func (a CasbinAuthorizer) inheritedRoleAllows(sub string, ancestors []secure.Identifier, dom, obj, act string) (bool, error) {
+	for _, ancestor := range ancestors {
+		roles, err := a.Enforcer.GetImplicitRolesForUser(sub, ancestor.String())
+		if err != nil {
+			return false, err
+		}
+		for _, role := range roles {
+			// a role is always linked to itself, so enforcing for the
+			// role applies the policy rules of the role in the domain
+			authorized, err := a.Enforcer.Enforce(role, dom, obj, act)
+			if err != nil {
+				return false, err
+			}
+			if authorized {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+var scopeResourceRegexp = regexp.MustCompile(`^/api/v[0-9]+/([^/]+)`)
+
+// ScopeResource returns the resource App scopes are granted for
+// given a route path, e.g. apps for /api/v1/apps/{extlID}/keys
+func ScopeResource(path string) string {
+	m := scopeResourceRegexp.FindStringSubmatch(path)
+	if m == nil {
+		return path
+	}
+	return m[1]
+}
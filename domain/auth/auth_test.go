@@ -1,6 +1,7 @@
 package auth_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -16,9 +17,11 @@ import (
 	"github.com/gilcrest/go-api-basic/domain/app"
 	"github.com/gilcrest/go-api-basic/domain/audit"
 	"github.com/gilcrest/go-api-basic/domain/auth"
+	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/logger"
 	"github.com/gilcrest/go-api-basic/domain/org"
 	"github.com/gilcrest/go-api-basic/domain/person"
+	"github.com/gilcrest/go-api-basic/domain/secure"
 	"github.com/gilcrest/go-api-basic/domain/user"
 )
 
@@ -62,16 +65,30 @@ func TestProvider_String(t *testing.T) {
 }
 
 func TestCasbinAuthorizer_Authorize(t *testing.T) {
-	t.Run("valid user", func(t *testing.T) {
-		c := qt.New(t)
+	lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
+
+	o := org.Org{ExternalID: secure.NewID()}
+	otherOrg := org.Org{ExternalID: secure.NewID()}
 
-		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
-		a := app.App{}
+	// initialize casbin enforcer using the default policy file and
+	// grant the user the admin role in the Org
+	casbinEnforcer, err := casbin.NewSyncedEnforcer("../../config/rbac_model.conf", "../../config/rbac_policy.csv")
+	if err != nil {
+		t.Fatal("casbin.NewSyncedEnforcer error")
+	}
+	_, err = casbinEnforcer.AddGroupingPolicy("dan@dangillis.dev", auth.AdminRole, o.ExternalID.String())
+	if err != nil {
+		t.Fatal("casbinEnforcer.AddGroupingPolicy error")
+	}
+	ca := auth.CasbinAuthorizer{Enforcer: casbinEnforcer}
+
+	// authorize runs Authorize for the user and the App's Org, it
+	// must be tested inside a handler as it uses mux.CurrentRoute
+	authorize := func(c *qt.C, a app.App, method string) error {
 		u := user.User{
 			ID:       uuid.Nil,
 			Username: "dan@dangillis.dev",
-			Org:      org.Org{},
+			Org:      a.Org,
 			Profile:  person.Profile{},
 		}
 		adt := audit.Audit{
@@ -79,28 +96,86 @@ func TestCasbinAuthorizer_Authorize(t *testing.T) {
 			User:   u,
 			Moment: time.Now(),
 		}
-		// initialize casbin enforcer (using config files for now, will migrate to db)
-		casbinEnforcer, err := casbin.NewEnforcer("../../config/rbac_model.conf", "../../config/rbac_policy.csv")
-		if err != nil {
-			c.Fatal("casbin.NewEnforcer error")
-		}
-		ca := auth.CasbinAuthorizer{Enforcer: casbinEnforcer}
 
-		// Authorize must be tested inside a handler as it uses mux.CurrentRoute
+		var authErr error
 		testAuthorizeHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			err = ca.Authorize(lgr, r, adt)
-			c.Assert(err, qt.IsNil)
+			authErr = ca.Authorize(lgr, r, adt)
 		})
 
+		req := httptest.NewRequest(method, "/api/v1/movies", nil)
 		rr := httptest.NewRecorder()
 
 		rtr := mux.NewRouter()
-		rtr.Handle("/api/v1/ping", testAuthorizeHandler).Methods(http.MethodGet)
+		rtr.Handle("/api/v1/movies", testAuthorizeHandler).Methods(method)
 		rtr.ServeHTTP(rr, req)
-
-		// If there is any issues with the Access Token, the body
-		// should be empty and the status code should be 401
 		c.Assert(rr.Code, qt.Equals, http.StatusOK)
 
+		return authErr
+	}
+
+	t.Run("valid user", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := authorize(c, app.App{Org: o, Scopes: app.Scopes{app.FullAccess}}, http.MethodGet)
+		c.Assert(err, qt.IsNil)
+	})
+
+	t.Run("read only app", func(t *testing.T) {
+		c := qt.New(t)
+
+		a := app.App{Org: o, Scopes: app.Scopes{"movies:read"}}
+		err := authorize(c, a, http.MethodGet)
+		c.Assert(err, qt.IsNil)
+
+		// the user is an admin, but the app cannot write
+		err = authorize(c, a, http.MethodPost)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+	})
+
+	t.Run("app without scope", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := authorize(c, app.App{Org: o, Scopes: app.Scopes{"orgs:admin"}}, http.MethodGet)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+	})
+
+	t.Run("role granted in another org", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := authorize(c, app.App{Org: otherOrg, Scopes: app.Scopes{app.FullAccess}}, http.MethodGet)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+
+		// the required permission is only sent when opted in to
+		var e *errs.Error
+		c.Assert(errors.As(err, &e), qt.IsTrue)
+		c.Assert(e.Permission, qt.IsNil)
+	})
+
+	t.Run("role granted in an ancestor org", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the user is an admin of the grandparent of the App's Org
+		subOrg := org.Org{ExternalID: secure.NewID(), Ancestors: []secure.Identifier{otherOrg.ExternalID, o.ExternalID}}
+		err := authorize(c, app.App{Org: subOrg, Scopes: app.Scopes{app.FullAccess}}, http.MethodPost)
+		c.Assert(err, qt.IsNil)
+
+		// roles are not inherited from an Org outside the ancestors
+		subOrg.Ancestors = []secure.Identifier{otherOrg.ExternalID}
+		err = authorize(c, app.App{Org: subOrg, Scopes: app.Scopes{app.FullAccess}}, http.MethodGet)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+	})
+
+	t.Run("permission detail", func(t *testing.T) {
+		c := qt.New(t)
+
+		ca.PermissionDetail = true
+		defer func() { ca.PermissionDetail = false }()
+
+		err := authorize(c, app.App{Org: otherOrg, Scopes: app.Scopes{app.FullAccess}}, http.MethodGet)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+
+		var e *errs.Error
+		c.Assert(errors.As(err, &e), qt.IsTrue)
+		c.Assert(e.Permission, qt.DeepEquals, &errs.Permission{Action: "read", Resource: "/api/v1/movies"})
 	})
 }
@@ -12,6 +12,7 @@ package errs
 import (
 	"fmt"
 	"runtime"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -31,6 +32,12 @@ type Error struct {
 	Code Code
 	// Realm is a description of a protected area, used in the WWW-Authenticate header.
 	Realm Realm
+	// Permission is the permission the user lacks, sent in the
+	// response body of Unauthorized errors when set.
+	Permission *Permission
+	// RetryAfter is how long the client should wait before retrying,
+	// sent in the Retry-After header of TooManyRequests errors.
+	RetryAfter RetryAfter
 	// The underlying error that triggered this one, if any.
 	Err error
 }
@@ -66,6 +73,18 @@ type Code string
 // will be set to the default set by the Default method
 type Realm string
 
+// RetryAfter is how long a client should wait before making another
+// request, used in the Retry-After header.
+type RetryAfter time.Duration
+
+// Permission describes the permission required for a request: the
+// action and the resource (route path template). It never describes
+// the policy which denied the request.
+type Permission struct {
+	Action   string
+	Resource string
+}
+
 // Kinds of errors.
 //
 // The values of the error kinds are common between both
@@ -93,9 +112,17 @@ const (
 	// Unauthorized is used when a user is authenticated, but is not authorized
 	// to access the resource.
 	//
-	// For Unauthorized errors, the response body should be empty.
-	// The error is logged and http.StatusForbidden (403) is sent.
+	// For Unauthorized errors, the response body is empty unless the
+	// error has a Permission, in which case the required action and
+	// resource are sent. The error is logged and http.StatusForbidden
+	// (403) is sent.
 	Unauthorized
+	// TooManyRequests is used when a client has exceeded its rate
+	// limit.
+	//
+	// The error is logged and http.StatusTooManyRequests (429) is
+	// sent with a Retry-After header when the error has a RetryAfter.
+	TooManyRequests
 )
 
 func (k Kind) String() string {
@@ -128,6 +155,8 @@ func (k Kind) String() string {
 		return "unauthenticated_request"
 	case Unauthorized:
 		return "unauthorized_request"
+	case TooManyRequests:
+		return "too_many_requests"
 	}
 	return "unknown_error_kind"
 }
@@ -189,6 +218,10 @@ func E(args ...interface{}) error {
 			e.Param = arg
 		case Realm:
 			e.Realm = arg
+		case Permission:
+			e.Permission = &arg
+		case RetryAfter:
+			e.RetryAfter = arg
 		default:
 			_, file, line, _ := runtime.Caller(1)
 			return fmt.Errorf("errors.E: bad call from %s:%d: %v, unknown type %T, value %v in error call", file, line, args, arg, arg)
@@ -232,6 +265,18 @@ func E(args ...interface{}) error {
 		prev.Realm = ""
 	}
 
+	// If this error has no Permission, pull up the inner one.
+	if e.Permission == nil {
+		e.Permission = prev.Permission
+		prev.Permission = nil
+	}
+
+	// If this error has no RetryAfter, pull up the inner one.
+	if e.RetryAfter == 0 {
+		e.RetryAfter = prev.RetryAfter
+		prev.RetryAfter = 0
+	}
+
 	return e
 }
 
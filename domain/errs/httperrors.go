@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -21,6 +23,10 @@ type ServiceError struct {
 	Code    string `json:"code,omitempty"`
 	Param   string `json:"param,omitempty"`
 	Message string `json:"message,omitempty"`
+	// Action and Resource are the required permission, sent for
+	// Unauthorized errors with a Permission only
+	Action   string `json:"action,omitempty"`
+	Resource string `json:"resource,omitempty"`
 }
 
 // HTTPErrorResponse takes a writer, error and a logger, performs a
@@ -46,6 +52,9 @@ func HTTPErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err error) {
 		case Unauthorized:
 			unauthorizedErrorResponse(w, lgr, e)
 			return
+		case TooManyRequests:
+			tooManyRequestsErrorResponse(w, lgr, e)
+			return
 		default:
 			typicalErrorResponse(w, lgr, e)
 			return
@@ -142,14 +151,72 @@ func unauthenticatedErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err
 	w.WriteHeader(http.StatusUnauthorized)
 }
 
-// unauthorizedErrorResponse responds with http status code 403 (Forbidden)
-// and an empty response body.
+// unauthorizedErrorResponse responds with http status code 403 (Forbidden).
+// The response body is empty, unless the error has a Permission, in
+// which case the required action and resource are sent as json.
 func unauthorizedErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err *Error) {
 	lgr.Error().Stack().Err(err.Err).
 		Int("http_statuscode", http.StatusForbidden).
 		Msg("Unauthorized Request")
 
+	if err.Permission == nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	er := ErrResponse{
+		Error: ServiceError{
+			Kind:     Unauthorized.String(),
+			Code:     string(err.Code),
+			Message:  fmt.Sprintf("%s permission required for %s", err.Permission.Action, err.Permission.Resource),
+			Action:   err.Permission.Action,
+			Resource: err.Permission.Resource,
+		},
+	}
+
+	// Marshal errResponse struct to JSON for the response body
+	errJSON, _ := json.Marshal(er)
+	ej := string(errJSON)
+
+	// Write Content-Type headers
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Write HTTP Statuscode
 	w.WriteHeader(http.StatusForbidden)
+
+	// Write response body (json)
+	fmt.Fprintln(w, ej)
+}
+
+// tooManyRequestsErrorResponse responds with http status code 429
+// (Too Many Requests), a Retry-After header when the error has a
+// RetryAfter and a json response body. The error is logged without
+// a stacktrace, a client over its limit can send many requests.
+func tooManyRequestsErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err *Error) {
+	lgr.Warn().Err(err.Err).
+		Int("http_statuscode", http.StatusTooManyRequests).
+		Msg("Too Many Requests")
+
+	if err.RetryAfter > 0 {
+		// Retry-After is sent in whole seconds, rounded up
+		secs := (time.Duration(err.RetryAfter) + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+	}
+
+	er := newErrResponse(err)
+
+	// Marshal errResponse struct to JSON for the response body
+	errJSON, _ := json.Marshal(er)
+	ej := string(errJSON)
+
+	// Write Content-Type headers
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Write HTTP Statuscode
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	// Write response body (json)
+	fmt.Fprintln(w, ej)
 }
 
 // nilErrorResponse responds with http status code 500 (Internal Server Error)
@@ -200,6 +267,8 @@ func httpErrorStatusCode(k Kind) int {
 	// error message will be sent to the caller
 	case Other, IO, Internal, Database, Unanticipated:
 		return http.StatusInternalServerError
+	case TooManyRequests:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
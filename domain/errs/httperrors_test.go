@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -35,6 +36,7 @@ func Test_httpErrorStatusCode(t *testing.T) {
 		{"Internal", args{k: Internal}, http.StatusInternalServerError},
 		{"Database", args{k: Database}, http.StatusInternalServerError},
 		{"Unanticipated", args{k: Unanticipated}, http.StatusInternalServerError},
+		{"TooManyRequests", args{k: TooManyRequests}, http.StatusTooManyRequests},
 		{"Default", args{k: 99}, http.StatusInternalServerError},
 	}
 	for _, tt := range tests {
@@ -58,6 +60,7 @@ func TestHTTPErrorResponse_StatusCode(t *testing.T) {
 
 	unauthenticatedErr := E(Unauthenticated, "some error from Google")
 	unauthorizedErr := E(Unauthorized, "some authorization error")
+	tooManyRequestsErr := E(TooManyRequests, "rate limit exceeded")
 
 	tests := []struct {
 		name string
@@ -68,6 +71,7 @@ func TestHTTPErrorResponse_StatusCode(t *testing.T) {
 		{"empty *Error", args{httptest.NewRecorder(), l, &Error{}}, http.StatusInternalServerError},
 		{"unauthenticated", args{httptest.NewRecorder(), l, unauthenticatedErr}, http.StatusUnauthorized},
 		{"unauthorized", args{httptest.NewRecorder(), l, unauthorizedErr}, http.StatusForbidden},
+		{"too many requests", args{httptest.NewRecorder(), l, tooManyRequestsErr}, http.StatusTooManyRequests},
 	}
 
 	for _, tt := range tests {
@@ -99,9 +103,11 @@ func TestHTTPErrorResponse_Body(t *testing.T) {
 		{"empty Error", args{httptest.NewRecorder(), lgr, &Error{}}, ""},
 		{"unauthenticated", args{httptest.NewRecorder(), lgr, E(Unauthenticated, "some error from Google")}, ""},
 		{"unauthorized", args{httptest.NewRecorder(), lgr, E(Unauthorized, "some authorization error")}, ""},
+		{"unauthorized with permission", args{httptest.NewRecorder(), lgr, E(Unauthorized, Permission{Action: "write", Resource: "/api/v1/movies"}, "some authorization error")}, `{"error":{"kind":"unauthorized_request","message":"write permission required for /api/v1/movies","action":"write","resource":"/api/v1/movies"}}`},
 		{"normal", args{httptest.NewRecorder(), lgr, E(Exist, Parameter("some_param"), Code("some_code"), errors.New("some error"))}, `{"error":{"kind":"item_already_exists","code":"some_code","param":"some_param","message":"some error"}}`},
 		{"not via E", args{httptest.NewRecorder(), lgr, errors.New("some error")}, "{\"error\":{\"kind\":\"unanticipated_error\",\"code\":\"Unanticipated\",\"message\":\"Unexpected error - contact support\"}}"},
 		{"nil error", args{httptest.NewRecorder(), lgr, nil}, ""},
+		{"too many requests", args{httptest.NewRecorder(), lgr, E(TooManyRequests, "rate limit exceeded")}, `{"error":{"kind":"too_many_requests","message":"rate limit exceeded"}}`},
 	}
 
 	for _, tt := range tests {
@@ -113,3 +119,27 @@ func TestHTTPErrorResponse_Body(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPErrorResponse_RetryAfter(t *testing.T) {
+	lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, false)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rounded up", E(TooManyRequests, RetryAfter(1500*time.Millisecond), "rate limit exceeded"), "2"},
+		{"pulled up from inner error", E(E(TooManyRequests, RetryAfter(3*time.Second), "rate limit exceeded")), "3"},
+		{"not set", E(TooManyRequests, "rate limit exceeded"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPErrorResponse(w, lgr, tt.err)
+			if got := w.Header().Get("Retry-After"); got != tt.want {
+				t.Errorf("Retry-After = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
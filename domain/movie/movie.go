@@ -5,31 +5,29 @@ package movie
 import (
 	"time"
 
-	"github.com/gilcrest/go-api-basic/domain/errs"
-	"github.com/gilcrest/go-api-basic/domain/user"
 	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
 )
 
-// NewMovie initializes a Movie struct for use in Movie creation
-func NewMovie(id uuid.UUID, extlID string, u user.User) (*Movie, error) {
+// NewMovie initializes a Movie struct for use in Movie creation.
+// The given audit.Audit is used as both the create and update audit.
+func NewMovie(id uuid.UUID, extlID string, adt audit.Audit) (*Movie, error) {
 	switch {
 	case id == uuid.Nil:
 		return nil, errs.E(errs.Validation, errs.Parameter("ID"), errs.MissingField("ID"))
 	case extlID == "":
 		return nil, errs.E(errs.Validation, errs.Parameter("extlID"), errs.MissingField("extlID"))
-	case !u.IsValid():
+	case !adt.User.IsValid():
 		return nil, errs.E(errs.Validation, errs.Parameter("User"), "User is invalid")
 	}
 
-	now := time.Now().UTC()
-
 	return &Movie{
-		ID:         id,
-		ExternalID: extlID,
-		CreateUser: u,
-		CreateTime: now,
-		UpdateUser: u,
-		UpdateTime: now,
+		ID:          id,
+		ExternalID:  extlID,
+		CreateAudit: adt,
+		UpdateAudit: adt,
 	}, nil
 }
 
@@ -43,10 +41,10 @@ type Movie struct {
 	RunTime    int
 	Director   string
 	Writer     string
-	CreateUser user.User
-	CreateTime time.Time
-	UpdateUser user.User
-	UpdateTime time.Time
+	// CreateAudit: the App, User and moment the Movie was created
+	CreateAudit audit.Audit
+	// UpdateAudit: the App, User and moment the Movie was last updated
+	UpdateAudit audit.Audit
 }
 
 // SetExternalID is a setter for a Movie External ID
@@ -98,15 +96,9 @@ func (m *Movie) SetWriter(w string) *Movie {
 	return m
 }
 
-// SetUpdateUser is a setter for a Movie update user
-func (m *Movie) SetUpdateUser(u user.User) *Movie {
-	m.UpdateUser = u
-	return m
-}
-
-// SetUpdateTime is a setter for a Movie update time
-func (m *Movie) SetUpdateTime() *Movie {
-	m.UpdateTime = time.Now().UTC()
+// SetUpdateAudit is a setter for a Movie update audit
+func (m *Movie) SetUpdateAudit(adt audit.Audit) *Movie {
+	m.UpdateAudit = adt
 	return m
 }
 
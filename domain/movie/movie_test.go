@@ -6,42 +6,52 @@ import (
 
 	qt "github.com/frankban/quicktest"
 	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/uuid"
 
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
 	"github.com/gilcrest/go-api-basic/domain/errs"
-	"github.com/gilcrest/go-api-basic/domain/user"
 	"github.com/gilcrest/go-api-basic/domain/user/usertest"
 )
 
+// newAudit returns an audit.Audit with a valid User for testing
+func newAudit(t *testing.T) audit.Audit {
+	t.Helper()
+
+	return audit.Audit{
+		App:    app.App{ID: uuid.New(), Name: "test app"},
+		User:   usertest.NewUser(t),
+		Moment: time.Now(),
+	}
+}
+
 func TestNewMovie(t *testing.T) {
 	c := qt.New(t)
 
 	id := uuid.New()
 	extlID := "ExternalID"
+	adt := newAudit(t)
+
+	invalidAdt := newAudit(t)
+	invalidAdt.User = usertest.NewInvalidUser(t)
 
 	m := &Movie{
-		ID:         id,
-		ExternalID: extlID,
-		Title:      "",
-		Rated:      "",
-		Released:   time.Time{},
-		RunTime:    0,
-		Director:   "",
-		Writer:     "",
-		CreateUser: usertest.NewUser(t),
-		CreateTime: time.Now().UTC(),
-		UpdateUser: usertest.NewUser(t),
-		UpdateTime: time.Now().UTC(),
+		ID:          id,
+		ExternalID:  extlID,
+		Title:       "",
+		Rated:       "",
+		Released:    time.Time{},
+		RunTime:     0,
+		Director:    "",
+		Writer:      "",
+		CreateAudit: adt,
+		UpdateAudit: adt,
 	}
 
-	ignoreFields := cmpopts.IgnoreFields(Movie{}, "CreateTime", "UpdateTime")
-	within1Second := cmpopts.EquateApproxTime(time.Second)
-
 	type args struct {
 		id     uuid.UUID
 		extlID string
-		u      user.User
+		adt    audit.Audit
 	}
 	tests := []struct {
 		name    string
@@ -49,23 +59,21 @@ func TestNewMovie(t *testing.T) {
 		want    *Movie
 		wantErr error
 	}{
-		{"typical", args{id, extlID, usertest.NewUser(t)}, m, nil},
-		{"nil uuid", args{uuid.Nil, extlID, usertest.NewUser(t)}, nil, errs.E(errs.Validation, errs.Parameter("ID"), errs.MissingField("ID"))},
-		{"empty External ID", args{id, "", usertest.NewUser(t)}, nil, errs.E(errs.Validation, errs.Parameter("extlID"), errs.MissingField("extlID"))},
-		{"invalid User", args{id, extlID, usertest.NewInvalidUser(t)}, nil, errs.E(errs.Validation, errs.Parameter("User"), "User is invalid")},
+		{"typical", args{id, extlID, adt}, m, nil},
+		{"nil uuid", args{uuid.Nil, extlID, adt}, nil, errs.E(errs.Validation, errs.Parameter("ID"), errs.MissingField("ID"))},
+		{"empty External ID", args{id, "", adt}, nil, errs.E(errs.Validation, errs.Parameter("extlID"), errs.MissingField("extlID"))},
+		{"invalid User", args{id, extlID, invalidAdt}, nil, errs.E(errs.Validation, errs.Parameter("User"), "User is invalid")},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewMovie(tt.args.id, tt.args.extlID, tt.args.u)
+			got, err := NewMovie(tt.args.id, tt.args.extlID, tt.args.adt)
 			if (err != nil) && (tt.wantErr == nil) {
 				t.Errorf("NewMovie() error = %v, nil expected", err)
 				return
 			}
 			c.Assert(err, qt.CmpEquals(cmp.Comparer(errs.Match)), tt.wantErr)
 			if got != nil {
-				c.Assert(got, qt.CmpEquals(ignoreFields), tt.want)
-				c.Assert(got.CreateTime, qt.CmpEquals(within1Second), tt.want.CreateTime)
-				c.Assert(got.UpdateTime, qt.CmpEquals(within1Second), tt.want.UpdateTime)
+				c.Assert(got, qt.DeepEquals, tt.want)
 			}
 		})
 	}
@@ -167,35 +175,17 @@ func TestSetWriter(t *testing.T) {
 	c.Assert(got, qt.DeepEquals, want)
 }
 
-func TestSetUpdateUser(t *testing.T) {
+func TestSetUpdateAudit(t *testing.T) {
 	c := qt.New(t)
 
-	u := usertest.NewUser(t)
+	adt := newAudit(t)
 
-	got := new(Movie).SetUpdateUser(u)
-	want := &Movie{UpdateUser: u}
+	got := new(Movie).SetUpdateAudit(adt)
+	want := &Movie{UpdateAudit: adt}
 
 	c.Assert(got, qt.DeepEquals, want)
 }
 
-func TestSetUpdateTime(t *testing.T) {
-	// initialize quicktest checker
-	c := qt.New(t)
-
-	ogt := time.Now().UTC()
-
-	// get a new movie
-	m := new(Movie)
-
-	// Call SetUpdateTime to update to now in utc
-	m.SetUpdateTime()
-
-	// should be within 1 second of ogt
-	within1Second := cmpopts.EquateApproxTime(time.Second)
-
-	c.Assert(ogt, qt.CmpEquals(within1Second), m.UpdateTime)
-}
-
 func TestMovie_IsValid(t *testing.T) {
 	c := qt.New(t)
 
@@ -203,18 +193,16 @@ func TestMovie_IsValid(t *testing.T) {
 
 	movieFunc := func() *Movie {
 		return &Movie{
-			ID:         uuid.New(),
-			ExternalID: "TROTLD",
-			Title:      "The Return of the Living Dead",
-			Rated:      "R",
-			Released:   rd,
-			RunTime:    91,
-			Director:   "Dan O'Bannon",
-			Writer:     "Russell Streiner",
-			CreateUser: usertest.NewUser(t),
-			CreateTime: time.Now().UTC(),
-			UpdateUser: usertest.NewUser(t),
-			UpdateTime: time.Now().UTC(),
+			ID:          uuid.New(),
+			ExternalID:  "TROTLD",
+			Title:       "The Return of the Living Dead",
+			Rated:       "R",
+			Released:    rd,
+			RunTime:     91,
+			Director:    "Dan O'Bannon",
+			Writer:      "Russell Streiner",
+			CreateAudit: newAudit(t),
+			UpdateAudit: newAudit(t),
 		}
 	}
 
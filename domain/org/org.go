@@ -20,4 +20,22 @@ type Org struct {
 	UpdateAppID  uuid.UUID
 	UpdateUserID uuid.UUID
 	UpdateTime   time.Time
+	// ArchiveTime is when the Org was archived (soft deleted), zero
+	// if the Org is not archived
+	ArchiveTime time.Time
+	// ParentID is the ID of the parent of a sub-organization,
+	// uuid.Nil for a top level Org
+	ParentID uuid.UUID
+	// Ancestors are the external IDs of the Org's ancestors, its
+	// parent first. They are only found for the Org of an
+	// authenticated App, where roles granted in an ancestor Org
+	// apply as well.
+	Ancestors []secure.Identifier
+	// Settings are the Org's settings and feature flags
+	Settings Settings
+}
+
+// Archived reports whether the Org has been archived
+func (o Org) Archived() bool {
+	return !o.ArchiveTime.IsZero()
 }
@@ -0,0 +1,199 @@
+package org
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// SettingsVersion is the schema version of the Settings document.
+// Documents written before the document was versioned have no
+// version and are read as version 1.
+const SettingsVersion = 1
+
+// Settings are the per Org settings and feature flags, stored as a
+// versioned JSON document. Every setting is optional, services read
+// a setting along with the default to use when it is not set.
+type Settings struct {
+	// Version is the schema version the document was written with
+	Version int `json:"version"`
+	// SelfRegistrationAllowed determines whether users authenticated
+	// by a provider may register themselves into the Org
+	SelfRegistrationAllowed *bool `json:"self_registration_allowed,omitempty"`
+	// AllowedAuthProviders are the (lower case) names of the
+	// providers users of the Org may authenticate with, every
+	// provider is allowed if none are set
+	AllowedAuthProviders []string `json:"allowed_auth_providers,omitempty"`
+	// DefaultAPIKeyLifetime is how long (e.g. 720h) a new API key is
+	// usable for when no deactivation date is requested
+	DefaultAPIKeyLifetime string `json:"default_api_key_lifetime,omitempty"`
+	// Features are feature flags, enabled or disabled for the Org
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// NewSettings initializes Settings with nothing set
+func NewSettings() Settings {
+	return Settings{Version: SettingsVersion}
+}
+
+// ParseSettings parses a Settings document, upgrading it to the
+// current version
+func ParseSettings(b []byte) (Settings, error) {
+	var s Settings
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return Settings{}, errs.E(errs.Internal, err)
+	}
+	if s.Version == 0 {
+		s.Version = 1
+	}
+	if s.Version > SettingsVersion {
+		return Settings{}, errs.E(errs.Internal, fmt.Sprintf("settings version %d is newer than the supported version %d", s.Version, SettingsVersion))
+	}
+	return s, nil
+}
+
+var featureNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// Validate ensures the Settings match the schema of the current
+// version
+func (s Settings) Validate() error {
+	if s.Version != SettingsVersion {
+		return errs.E(errs.Validation, errs.Parameter("version"), fmt.Sprintf("version must be %d", SettingsVersion))
+	}
+	for _, p := range s.AllowedAuthProviders {
+		if strings.TrimSpace(p) == "" || p != strings.ToLower(p) {
+			return errs.E(errs.Validation, errs.Parameter("allowed_auth_providers"), "allowed_auth_providers must be lower case provider names, e.g. google")
+		}
+	}
+	if s.DefaultAPIKeyLifetime != "" {
+		d, err := time.ParseDuration(s.DefaultAPIKeyLifetime)
+		if err != nil || d <= 0 {
+			return errs.E(errs.Validation, errs.Parameter("default_api_key_lifetime"), "default_api_key_lifetime must be a positive duration, e.g. 720h")
+		}
+	}
+	for name := range s.Features {
+		if !featureNameRegexp.MatchString(name) {
+			return errs.E(errs.Validation, errs.Parameter("features"), fmt.Sprintf("feature %q must start with a lower case letter followed by lower case letters, digits, _, . or -", name))
+		}
+	}
+	return nil
+}
+
+// Patch returns the Settings with a JSON merge patch (RFC 7396)
+// applied. Members of the patch replace those of the Settings and a
+// null member unsets a setting, so its default applies again. The
+// version cannot be patched. The patched Settings are validated.
+func (s Settings) Patch(patch []byte) (Settings, error) {
+	var p map[string]interface{}
+	err := json.Unmarshal(patch, &p)
+	if err != nil || p == nil {
+		return Settings{}, errs.E(errs.Validation, "settings patch must be a JSON object")
+	}
+	delete(p, "version")
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return Settings{}, errs.E(errs.Internal, err)
+	}
+	var doc interface{}
+	err = json.Unmarshal(b, &doc)
+	if err != nil {
+		return Settings{}, errs.E(errs.Internal, err)
+	}
+	b, err = json.Marshal(mergePatch(doc, p))
+	if err != nil {
+		return Settings{}, errs.E(errs.Internal, err)
+	}
+
+	// settings unknown to the schema are rejected
+	var patched Settings
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&patched)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Settings{}, errs.E(errs.Validation, errs.Parameter(typeErr.Field), fmt.Sprintf("%s cannot be a JSON %s", typeErr.Field, typeErr.Value))
+		}
+		return Settings{}, errs.E(errs.Validation, strings.Replace(err.Error(), "json: unknown field", "unknown setting", 1))
+	}
+
+	err = patched.Validate()
+	if err != nil {
+		return Settings{}, err
+	}
+
+	return patched, nil
+}
+
+// mergePatch applies a JSON merge patch to a decoded JSON document
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+			continue
+		}
+		d[k] = mergePatch(d[k], v)
+	}
+	return d
+}
+
+// SelfRegistration reports whether users authenticated by a provider
+// may register themselves into the Org, def if not set
+func (s Settings) SelfRegistration(def bool) bool {
+	if s.SelfRegistrationAllowed == nil {
+		return def
+	}
+	return *s.SelfRegistrationAllowed
+}
+
+// AuthProviderAllowed reports whether users of the Org may
+// authenticate with the provider. Every provider is allowed if no
+// providers are set.
+func (s Settings) AuthProviderAllowed(provider string) bool {
+	if len(s.AllowedAuthProviders) == 0 {
+		return true
+	}
+	provider = strings.ToLower(provider)
+	for _, p := range s.AllowedAuthProviders {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyLifetime returns how long a new API key is usable for when no
+// deactivation date is requested, def if not set
+func (s Settings) APIKeyLifetime(def time.Duration) time.Duration {
+	d, err := time.ParseDuration(s.DefaultAPIKeyLifetime)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// FeatureEnabled reports whether the feature flag is enabled for the
+// Org, def if not set
+func (s Settings) FeatureEnabled(name string, def bool) bool {
+	enabled, ok := s.Features[name]
+	if !ok {
+		return def
+	}
+	return enabled
+}
@@ -0,0 +1,82 @@
+package org
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+func TestParseSettings(t *testing.T) {
+	c := qt.New(t)
+
+	// documents written before versioning are read as version 1
+	s, err := ParseSettings([]byte(`{"self_registration_allowed": true}`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.Version, qt.Equals, 1)
+	c.Assert(s.SelfRegistration(false), qt.IsTrue)
+
+	_, err = ParseSettings([]byte(`{"version": 2}`))
+	c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+
+	_, err = ParseSettings([]byte(`[]`))
+	c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+}
+
+func TestSettings_Patch(t *testing.T) {
+	c := qt.New(t)
+
+	s, err := NewSettings().Patch([]byte(`{
+		"version": 7,
+		"self_registration_allowed": true,
+		"allowed_auth_providers": ["google"],
+		"default_api_key_lifetime": "720h",
+		"features": {"movie_reviews": true, "beta.search": false}
+	}`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.Version, qt.Equals, SettingsVersion)
+	c.Assert(s.SelfRegistration(false), qt.IsTrue)
+	c.Assert(s.AllowedAuthProviders, qt.DeepEquals, []string{"google"})
+	c.Assert(s.APIKeyLifetime(0), qt.Equals, 720*time.Hour)
+	c.Assert(s.FeatureEnabled("movie_reviews", false), qt.IsTrue)
+	c.Assert(s.FeatureEnabled("beta.search", true), qt.IsFalse)
+
+	// features are merged, null unsets a setting
+	s, err = s.Patch([]byte(`{"default_api_key_lifetime": null, "features": {"beta.search": true, "movie_reviews": null}}`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.SelfRegistration(false), qt.IsTrue)
+	c.Assert(s.APIKeyLifetime(time.Hour), qt.Equals, time.Hour)
+	c.Assert(s.Features, qt.DeepEquals, map[string]bool{"beta.search": true})
+
+	for _, patch := range []string{
+		`[]`,
+		`null`,
+		`{"unknown": true}`,
+		`{"self_registration_allowed": "yes"}`,
+		`{"allowed_auth_providers": ["Google"]}`,
+		`{"allowed_auth_providers": [""]}`,
+		`{"default_api_key_lifetime": "30 days"}`,
+		`{"default_api_key_lifetime": "-1h"}`,
+		`{"features": {"Movie Reviews": true}}`,
+	} {
+		_, err = s.Patch([]byte(patch))
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue, qt.Commentf("%s", patch))
+	}
+}
+
+func TestSettings_defaults(t *testing.T) {
+	c := qt.New(t)
+
+	s := NewSettings()
+	c.Assert(s.SelfRegistration(true), qt.IsTrue)
+	c.Assert(s.SelfRegistration(false), qt.IsFalse)
+	c.Assert(s.AuthProviderAllowed("google"), qt.IsTrue)
+	c.Assert(s.APIKeyLifetime(time.Minute), qt.Equals, time.Minute)
+	c.Assert(s.FeatureEnabled("movie_reviews", true), qt.IsTrue)
+
+	s.AllowedAuthProviders = []string{"apple"}
+	c.Assert(s.AuthProviderAllowed("Apple"), qt.IsTrue)
+	c.Assert(s.AuthProviderAllowed("google"), qt.IsFalse)
+}
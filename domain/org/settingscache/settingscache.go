@@ -0,0 +1,75 @@
+// Package settingscache is an in-memory cache of Org settings, keyed
+// by Org ID, so services can read a setting without a database round
+// trip on every request
+package settingscache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/domain/org"
+)
+
+// entry is the cached Settings of a single Org
+type entry struct {
+	settings org.Settings
+	expiry   time.Time
+}
+
+// Cache holds the Settings of each Org for at most its time to live.
+// Settings are deleted from the cache when they are changed, the
+// time to live bounds how stale the Settings can be in other
+// processes. It is safe for concurrent use.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	items map[uuid.UUID]entry
+}
+
+// New initializes a Cache holding Settings for at most ttl
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:   ttl,
+		now:   time.Now,
+		items: make(map[uuid.UUID]entry),
+	}
+}
+
+// Get returns the Settings cached for the Org, if present and not
+// expired
+func (c *Cache) Get(orgID uuid.UUID) (org.Settings, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[orgID]
+	if !ok {
+		return org.Settings{}, false
+	}
+	if !c.now().Before(e.expiry) {
+		delete(c.items, orgID)
+		return org.Settings{}, false
+	}
+
+	return e.settings, true
+}
+
+// Set caches the Settings of the Org
+func (c *Cache) Set(orgID uuid.UUID, s org.Settings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[orgID] = entry{settings: s, expiry: c.now().Add(c.ttl)}
+}
+
+// Delete removes the Settings cached for the Org. It should be
+// called whenever the Settings are changed or the Org is deleted.
+func (c *Cache) Delete(orgID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, orgID)
+}
@@ -0,0 +1,45 @@
+package settingscache
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/domain/org"
+)
+
+func TestCache(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	cache := New(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	orgID := uuid.New()
+	s := org.NewSettings()
+	s.AllowedAuthProviders = []string{"google"}
+
+	_, ok := cache.Get(orgID)
+	c.Assert(ok, qt.IsFalse)
+
+	cache.Set(orgID, s)
+	got, ok := cache.Get(orgID)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(got, qt.DeepEquals, s)
+
+	// other Orgs are not affected
+	_, ok = cache.Get(uuid.New())
+	c.Assert(ok, qt.IsFalse)
+
+	cache.Delete(orgID)
+	_, ok = cache.Get(orgID)
+	c.Assert(ok, qt.IsFalse)
+
+	// expired
+	cache.Set(orgID, s)
+	now = now.Add(time.Minute)
+	_, ok = cache.Get(orgID)
+	c.Assert(ok, qt.IsFalse)
+}
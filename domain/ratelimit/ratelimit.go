@@ -0,0 +1,116 @@
+// Package ratelimit limits the rate of requests using token buckets.
+// A bucket holds up to Limit tokens and is refilled at Limit tokens
+// per minute, every request takes a token from the bucket. A full
+// bucket allows a burst of Limit requests.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Window is the time an empty bucket takes to be refilled
+const Window = time.Minute
+
+// Result is the result of taking a token from a bucket
+type Result struct {
+	// Allowed reports whether a token was taken
+	Allowed bool
+	// Limit is the number of requests allowed per Window, 0 if
+	// requests are not limited
+	Limit int
+	// Remaining is the number of tokens left in the bucket
+	Remaining int
+	// Reset is the time until the bucket is full
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, if none
+	// was taken
+	RetryAfter time.Duration
+}
+
+// Bucket is the state of a token bucket
+type Bucket struct {
+	// Tokens is the number of tokens in the bucket as of Updated
+	Tokens float64
+	// Updated is the time the bucket was last taken from, the zero
+	// value is a full bucket
+	Updated time.Time
+}
+
+// Take takes a token from the bucket, refilling it for the time
+// passed since it was last taken from. The bucket is returned with
+// its new state.
+func (b Bucket) Take(limit int, now time.Time) (Bucket, Result) {
+	capacity := float64(limit)
+	// tokens added per second
+	rate := capacity / Window.Seconds()
+
+	tokens := capacity
+	if !b.Updated.IsZero() {
+		elapsed := now.Sub(b.Updated).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+	}
+
+	r := Result{Limit: limit}
+	if tokens >= 1 {
+		tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	r.Remaining = int(math.Floor(tokens))
+	r.Reset = seconds((capacity - tokens) / rate)
+
+	return Bucket{Tokens: tokens, Updated: now}, r
+}
+
+// seconds converts a number of seconds to a time.Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// MemoryStore keeps token buckets in memory. It is safe for
+// concurrent use, but its buckets are not shared with other
+// instances of the server.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]Bucket
+	lastPrune time.Time
+}
+
+// NewMemoryStore initializes a MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]Bucket)}
+}
+
+// Take takes a token from the bucket for the key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit int, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+
+	b, r := s.buckets[key].Take(limit, now)
+	s.buckets[key] = b
+
+	return r, nil
+}
+
+// prune removes the buckets not taken from for a Window, at most
+// once per Window. Those buckets are full, the same as a new bucket.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < Window {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.Updated) >= Window {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastPrune = now
+}
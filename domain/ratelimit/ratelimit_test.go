@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBucket_Take(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// a new bucket is full, allowing a burst of limit requests
+	var (
+		b Bucket
+		r Result
+	)
+	for i := 0; i < 60; i++ {
+		b, r = b.Take(60, now)
+		c.Assert(r.Allowed, qt.IsTrue)
+	}
+	c.Assert(r.Remaining, qt.Equals, 0)
+	c.Assert(r.Reset, qt.Equals, time.Minute)
+
+	b, r = b.Take(60, now)
+	c.Assert(r.Allowed, qt.IsFalse)
+	c.Assert(r.Limit, qt.Equals, 60)
+	c.Assert(r.RetryAfter, qt.Equals, time.Second)
+
+	// one token is added per second
+	b, r = b.Take(60, now.Add(time.Second))
+	c.Assert(r.Allowed, qt.IsTrue)
+	c.Assert(r.Remaining, qt.Equals, 0)
+
+	// the bucket is never filled past the limit
+	_, r = b.Take(60, now.Add(time.Hour))
+	c.Assert(r.Allowed, qt.IsTrue)
+	c.Assert(r.Remaining, qt.Equals, 59)
+	c.Assert(r.Reset, qt.Equals, time.Second)
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+
+	r, err := s.Take(ctx, "a", 1, now)
+	c.Assert(err, qt.IsNil)
+	c.Assert(r.Allowed, qt.IsTrue)
+
+	r, err = s.Take(ctx, "a", 1, now)
+	c.Assert(err, qt.IsNil)
+	c.Assert(r.Allowed, qt.IsFalse)
+
+	// buckets are kept per key
+	r, err = s.Take(ctx, "b", 1, now)
+	c.Assert(err, qt.IsNil)
+	c.Assert(r.Allowed, qt.IsTrue)
+
+	// idle buckets are pruned
+	_, err = s.Take(ctx, "c", 1, now.Add(2*Window))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.buckets, qt.HasLen, 1)
+}
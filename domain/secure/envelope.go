@@ -0,0 +1,188 @@
+package secure
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// envelopeVersion is the version of the envelope format written by
+// EnvelopeEncrypt, formatted as version:hex(wrapped data key):hex(nonce|ciphertext|tag)
+const envelopeVersion string = "e1"
+
+// KMS is a key management service holding key-encryption keys.
+// Data is encrypted with a data key of its own, which is wrapped
+// (encrypted) by the KMS and stored alongside the data. With a remote
+// KMS, the key-encryption keys never enter the process, so a memory
+// dump only holds the data keys in use at the time.
+type KMS interface {
+	// WrapKey encrypts a data key with the primary key-encryption key
+	WrapKey(ctx context.Context, dataKey []byte) (string, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey
+	UnwrapKey(ctx context.Context, wrapped string) ([]byte, error)
+}
+
+// PrimaryWrapper is implemented by a KMS which can tell whether a
+// data key is wrapped with its primary key-encryption key. Data keys
+// wrapped by any other KMS are always rewrapped by RewrapDataKey.
+type PrimaryWrapper interface {
+	WrappedWithPrimary(wrapped string) (bool, error)
+}
+
+// LocalKMS is a KMS holding its key-encryption keys in memory, read
+// from a file. It is meant for development and tests, it gives none
+// of the protection of a remote KMS.
+type LocalKMS struct {
+	keyring *Keyring
+}
+
+// NewLocalKMS initializes a LocalKMS given a Keyring of
+// key-encryption keys
+func NewLocalKMS(kr *Keyring) LocalKMS {
+	return LocalKMS{keyring: kr}
+}
+
+// NewLocalKMSFromFile initializes a LocalKMS given the path to a file
+// holding a keyring of key-encryption keys, in the format read by
+// ParseKeyring
+func NewLocalKMSFromFile(path string) (LocalKMS, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return LocalKMS{}, errs.E(errs.IO, err)
+	}
+	kr, err := ParseKeyring(strings.TrimSpace(string(b)))
+	if err != nil {
+		return LocalKMS{}, err
+	}
+	return NewLocalKMS(kr), nil
+}
+
+// WrapKey encrypts the data key with the primary key of the keyring
+func (k LocalKMS) WrapKey(ctx context.Context, dataKey []byte) (string, error) {
+	return k.keyring.Encrypt(dataKey)
+}
+
+// UnwrapKey decrypts the data key with the keyring key it was
+// wrapped with
+func (k LocalKMS) UnwrapKey(ctx context.Context, wrapped string) ([]byte, error) {
+	return k.keyring.Decrypt(wrapped)
+}
+
+// WrappedWithPrimary reports whether the data key is wrapped with the
+// primary key of the keyring
+func (k LocalKMS) WrappedWithPrimary(wrapped string) (bool, error) {
+	id, versioned, err := CiphertextKeyID(wrapped)
+	if err != nil {
+		return false, err
+	}
+	return versioned && id == k.keyring.PrimaryID(), nil
+}
+
+// DataKey is a key for encrypting a single record. Only Wrapped is
+// stored, Key is discarded once the record is encrypted.
+type DataKey struct {
+	Key     *[32]byte
+	Wrapped string
+}
+
+// NewDataKey generates a random data key and wraps it with the KMS
+func NewDataKey(ctx context.Context, kms KMS) (DataKey, error) {
+	key, err := NewEncryptionKey()
+	if err != nil {
+		return DataKey{}, err
+	}
+	wrapped, err := kms.WrapKey(ctx, key[:])
+	if err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{Key: key, Wrapped: wrapped}, nil
+}
+
+// UnwrapDataKey decrypts a wrapped data key with the KMS
+func UnwrapDataKey(ctx context.Context, kms KMS, wrapped string) (*[32]byte, error) {
+	b, err := kms.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, errs.E(errs.Internal, "data key byte length must be exactly 32 bytes")
+	}
+	key := [32]byte{}
+	copy(key[:], b)
+	return &key, nil
+}
+
+// RewrapDataKey unwraps a data key and wraps it again with the primary
+// key-encryption key of the KMS, so older key-encryption keys can be
+// removed from it. The data key itself is unchanged. ok is false if
+// the data key is already wrapped with the primary key-encryption key.
+func RewrapDataKey(ctx context.Context, kms KMS, wrapped string) (rewrapped string, ok bool, err error) {
+	if pw, isPW := kms.(PrimaryWrapper); isPW {
+		var primary bool
+		primary, err = pw.WrappedWithPrimary(wrapped)
+		if err != nil {
+			return "", false, err
+		}
+		if primary {
+			return "", false, nil
+		}
+	}
+
+	key, err := kms.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err = kms.WrapKey(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	return rewrapped, true, nil
+}
+
+// EnvelopeEncrypt encrypts data with a new data key and returns the
+// ciphertext together with the data key wrapped by the KMS
+func EnvelopeEncrypt(ctx context.Context, kms KMS, plaintext []byte) (string, error) {
+	dk, err := NewDataKey(ctx, kms)
+	if err != nil {
+		return "", err
+	}
+	ct, err := Encrypt(plaintext, dk.Key)
+	if err != nil {
+		return "", err
+	}
+	return envelopeVersion + ":" + hex.EncodeToString([]byte(dk.Wrapped)) + ":" + hex.EncodeToString(ct), nil
+}
+
+// EnvelopeDecrypt unwraps the data key of a ciphertext written by
+// EnvelopeEncrypt with the KMS and decrypts the data with it
+func EnvelopeDecrypt(ctx context.Context, kms KMS, ciphertext string) ([]byte, error) {
+	parts := strings.Split(ciphertext, ":")
+	if len(parts) != 3 || parts[0] != envelopeVersion {
+		return nil, errs.E(errs.Internal, "malformed envelope ciphertext")
+	}
+	wrapped, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+	ct, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+
+	key, err := UnwrapDataKey(ctx, kms, string(wrapped))
+	if err != nil {
+		return nil, err
+	}
+
+	return Decrypt(ct, key)
+}
+
+// IsEnvelope reports whether the ciphertext was written by
+// EnvelopeEncrypt
+func IsEnvelope(ciphertext string) bool {
+	return strings.HasPrefix(ciphertext, envelopeVersion+":")
+}
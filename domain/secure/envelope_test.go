@@ -0,0 +1,87 @@
+package secure_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+func TestEnvelopeEncrypt(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "kms.key")
+	err := os.WriteFile(path, []byte("kek1:"+testKey1+"\n"), 0600)
+	c.Assert(err, qt.IsNil)
+	kms, err := secure.NewLocalKMSFromFile(path)
+	c.Assert(err, qt.IsNil)
+
+	ct, err := secure.EnvelopeEncrypt(ctx, kms, []byte("so secret"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(secure.IsEnvelope(ct), qt.IsTrue)
+	c.Assert(strings.Contains(ct, "so secret"), qt.IsFalse)
+
+	pt, err := secure.EnvelopeDecrypt(ctx, kms, ct)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(pt), qt.Equals, "so secret")
+
+	// each record has its own data key
+	ct2, err := secure.EnvelopeEncrypt(ctx, kms, []byte("so secret"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(strings.Split(ct2, ":")[1], qt.Not(qt.Equals), strings.Split(ct, ":")[1])
+
+	// after the key-encryption key is rotated, data keys wrapped
+	// with the old key are still unwrapped
+	kr, err := secure.ParseKeyring("kek2:" + testKey2 + ",kek1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	pt, err = secure.EnvelopeDecrypt(ctx, secure.NewLocalKMS(kr), ct)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(pt), qt.Equals, "so secret")
+
+	// without the key-encryption key, the data cannot be decrypted
+	other, err := secure.ParseKeyring("kek1:" + testKey2)
+	c.Assert(err, qt.IsNil)
+	_, err = secure.EnvelopeDecrypt(ctx, secure.NewLocalKMS(other), ct)
+	c.Assert(err, qt.IsNotNil)
+
+	_, err = secure.EnvelopeDecrypt(ctx, kms, "v1:kek1:abcd")
+	c.Assert(err, qt.IsNotNil)
+}
+
+func TestRewrapDataKey(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	old, err := secure.ParseKeyring("kek1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	dk, err := secure.NewDataKey(ctx, secure.NewLocalKMS(old))
+	c.Assert(err, qt.IsNil)
+
+	// kek2 is the new primary key-encryption key
+	kr, err := secure.ParseKeyring("kek2:" + testKey2 + ",kek1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	kms := secure.NewCachingKMS(secure.NewLocalKMS(kr), time.Minute)
+
+	rewrapped, ok, err := secure.RewrapDataKey(ctx, kms, dk.Wrapped)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+
+	// the data key is unchanged, and no longer needs kek1
+	current, err := secure.ParseKeyring("kek2:" + testKey2)
+	c.Assert(err, qt.IsNil)
+	key, err := secure.UnwrapDataKey(ctx, secure.NewLocalKMS(current), rewrapped)
+	c.Assert(err, qt.IsNil)
+	c.Assert(key, qt.DeepEquals, dk.Key)
+
+	// already wrapped with the primary key-encryption key
+	_, ok, err = secure.RewrapDataKey(ctx, kms, rewrapped)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+}
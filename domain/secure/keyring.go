@@ -0,0 +1,140 @@
+package secure
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// ciphertextVersion is the version of the ciphertext format written
+// by Keyring, the version and key ID prefix every ciphertext as
+// version:keyID:hex(nonce|ciphertext|tag)
+const ciphertextVersion string = "v1"
+
+// DefaultKeyID is the ID given to a key parsed without one, e.g. an
+// ENCRYPT_KEY set before keys had IDs
+const DefaultKeyID string = "default"
+
+var keyIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Keyring holds encryption keys by ID. Data is always encrypted with
+// the primary key and decrypted with the key whose ID prefixes the
+// ciphertext, so keys can be rotated without losing stored data.
+type Keyring struct {
+	primaryID string
+	// ids are the key IDs in the order the keys were given
+	ids  []string
+	keys map[string]*[32]byte
+}
+
+// ParseKeyring parses a comma separated list of keys, each formatted
+// as id:hexkey. The first key is the primary key. A single hex key
+// without an ID is given the DefaultKeyID.
+func ParseKeyring(s string) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string]*[32]byte)}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, hexKey := DefaultKeyID, entry
+		if parts := strings.SplitN(entry, ":", 2); len(parts) == 2 {
+			id, hexKey = parts[0], parts[1]
+		}
+		if !keyIDRegexp.MatchString(id) {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("invalid encryption key ID %q, IDs may only contain letters, digits, _ and -", id))
+		}
+		if _, ok := kr.keys[id]; ok {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("duplicate encryption key ID %q", id))
+		}
+		key, err := ParseEncryptionKey(hexKey)
+		if err != nil {
+			return nil, err
+		}
+		if kr.primaryID == "" {
+			kr.primaryID = id
+		}
+		kr.ids = append(kr.ids, id)
+		kr.keys[id] = key
+	}
+
+	if kr.primaryID == "" {
+		return nil, errs.E(errs.Internal, "no encryption key found")
+	}
+
+	return kr, nil
+}
+
+// PrimaryID returns the ID of the key data is encrypted with
+func (kr *Keyring) PrimaryID() string {
+	return kr.primaryID
+}
+
+// Encrypt encrypts data with the primary key and returns the
+// ciphertext prefixed with the format version and key ID
+func (kr *Keyring) Encrypt(plaintext []byte) (string, error) {
+	ct, err := Encrypt(plaintext, kr.keys[kr.primaryID])
+	if err != nil {
+		return "", err
+	}
+	return ciphertextVersion + ":" + kr.primaryID + ":" + hex.EncodeToString(ct), nil
+}
+
+// Decrypt decrypts a ciphertext written by Encrypt with the key
+// matching its key ID. A hex ciphertext without a version and key
+// ID, written before keys had IDs, is decrypted with whichever key
+// it was encrypted with.
+func (kr *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	id, ok, err := CiphertextKeyID(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return kr.decryptLegacy(ciphertext)
+	}
+
+	key, found := kr.keys[id]
+	if !found {
+		return nil, errs.E(errs.Internal, fmt.Sprintf("no encryption key found with ID %q", id))
+	}
+	ct, err := hex.DecodeString(ciphertext[len(ciphertextVersion)+len(id)+2:])
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+
+	return Decrypt(ct, key)
+}
+
+// decryptLegacy decrypts an unversioned ciphertext by trying each
+// key, primary first. AES-GCM authenticates the ciphertext, so only
+// the key it was encrypted with succeeds.
+func (kr *Keyring) decryptLegacy(ciphertext string) ([]byte, error) {
+	ct, err := hex.DecodeString(ciphertext)
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+	for _, id := range kr.ids {
+		plaintext, err := Decrypt(ct, kr.keys[id])
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errs.E(errs.Internal, "ciphertext could not be decrypted with any encryption key")
+}
+
+// CiphertextKeyID returns the ID of the key a ciphertext was
+// encrypted with. ok is false for an unversioned ciphertext.
+func CiphertextKeyID(ciphertext string) (id string, ok bool, err error) {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) == 1 {
+		return "", false, nil
+	}
+	if len(parts) != 3 || parts[0] != ciphertextVersion || !keyIDRegexp.MatchString(parts[1]) {
+		return "", false, errs.E(errs.Internal, "malformed ciphertext")
+	}
+	return parts[1], true, nil
+}
@@ -0,0 +1,81 @@
+package secure_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+const (
+	testKey1 = "f2c100b5661c3b6dc80ba64c499ed7b51482e557e99eeda6126ecc37f2b0381d"
+	testKey2 = "31f8cbffe80df0067fbfac4abf0bb76c51d44cb82d2556743e6bf1a5e25d4e06"
+)
+
+func TestParseKeyring(t *testing.T) {
+	t.Run("ids", func(t *testing.T) {
+		c := qt.New(t)
+
+		kr, err := secure.ParseKeyring("k2:" + testKey2 + ", k1:" + testKey1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(kr.PrimaryID(), qt.Equals, "k2")
+	})
+
+	t.Run("single key without id", func(t *testing.T) {
+		c := qt.New(t)
+
+		kr, err := secure.ParseKeyring(testKey1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(kr.PrimaryID(), qt.Equals, secure.DefaultKeyID)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := qt.New(t)
+
+		for _, s := range []string{"", "k1:" + testKey1 + ",k1:" + testKey2, "k 1:" + testKey1, "k1:abc"} {
+			_, err := secure.ParseKeyring(s)
+			c.Assert(err, qt.IsNotNil, qt.Commentf("keyring %q", s))
+		}
+	})
+}
+
+func TestKeyring_Decrypt(t *testing.T) {
+	c := qt.New(t)
+
+	old, err := secure.ParseKeyring("k1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	ct, err := old.Encrypt([]byte("so secret"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(strings.HasPrefix(ct, "v1:k1:"), qt.IsTrue)
+
+	// after rotation, data encrypted with the old key is decrypted
+	// with it and new data is encrypted with the new primary key
+	kr, err := secure.ParseKeyring("k2:" + testKey2 + ",k1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	pt, err := kr.Decrypt(ct)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(pt), qt.Equals, "so secret")
+
+	ct, err = kr.Encrypt(pt)
+	c.Assert(err, qt.IsNil)
+	id, ok, err := secure.CiphertextKeyID(ct)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(id, qt.Equals, "k2")
+
+	// the old keyring does not have the new key
+	_, err = old.Decrypt(ct)
+	c.Assert(err, qt.IsNotNil)
+
+	// unversioned ciphertexts are decrypted with whichever key works
+	ek, err := secure.ParseEncryptionKey(testKey1)
+	c.Assert(err, qt.IsNil)
+	legacy, err := secure.Encrypt([]byte("so legacy"), ek)
+	c.Assert(err, qt.IsNil)
+	pt, err = kr.Decrypt(hex.EncodeToString(legacy))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(pt), qt.Equals, "so legacy")
+}
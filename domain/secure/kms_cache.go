@@ -0,0 +1,93 @@
+package secure
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxCachedDataKeys is the most data keys a CachingKMS holds, expired
+// keys are dropped once it is reached
+const maxCachedDataKeys int = 4096
+
+// cachedDataKey is an unwrapped data key held by a CachingKMS
+type cachedDataKey struct {
+	key    []byte
+	expiry time.Time
+}
+
+// CachingKMS is a KMS which holds the data keys it unwraps for a short
+// time, keyed by their wrapped value, so a data key used on every
+// request (e.g. the data key an API key is hashed with) is not sent
+// to the KMS each time. Data keys are held in memory for at most the
+// time to live. It is safe for concurrent use.
+type CachingKMS struct {
+	kms KMS
+	ttl time.Duration
+
+	mu    sync.Mutex
+	items map[string]cachedDataKey
+}
+
+// NewCachingKMS initializes a CachingKMS holding the data keys
+// unwrapped by kms for at most ttl
+func NewCachingKMS(kms KMS, ttl time.Duration) *CachingKMS {
+	return &CachingKMS{
+		kms:   kms,
+		ttl:   ttl,
+		items: make(map[string]cachedDataKey),
+	}
+}
+
+// WrapKey encrypts a data key with the underlying KMS
+func (k *CachingKMS) WrapKey(ctx context.Context, dataKey []byte) (string, error) {
+	return k.kms.WrapKey(ctx, dataKey)
+}
+
+// WrappedWithPrimary reports whether the data key is wrapped with the
+// primary key-encryption key of the underlying KMS, always false if
+// the underlying KMS cannot tell (see PrimaryWrapper)
+func (k *CachingKMS) WrappedWithPrimary(wrapped string) (bool, error) {
+	pw, ok := k.kms.(PrimaryWrapper)
+	if !ok {
+		return false, nil
+	}
+	return pw.WrappedWithPrimary(wrapped)
+}
+
+// UnwrapKey returns the cached data key for the wrapped value, if
+// present and not expired, otherwise the data key is unwrapped by the
+// underlying KMS and cached
+func (k *CachingKMS) UnwrapKey(ctx context.Context, wrapped string) ([]byte, error) {
+	now := time.Now()
+
+	k.mu.Lock()
+	e, ok := k.items[wrapped]
+	if ok && now.Before(e.expiry) {
+		k.mu.Unlock()
+		return append([]byte(nil), e.key...), nil
+	}
+	delete(k.items, wrapped)
+	k.mu.Unlock()
+
+	key, err := k.kms.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.items) >= maxCachedDataKeys {
+		for w, e := range k.items {
+			if !now.Before(e.expiry) {
+				delete(k.items, w)
+			}
+		}
+	}
+	if len(k.items) < maxCachedDataKeys {
+		k.items[wrapped] = cachedDataKey{key: append([]byte(nil), key...), expiry: now.Add(k.ttl)}
+	}
+
+	return key, nil
+}
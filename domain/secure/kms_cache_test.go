@@ -0,0 +1,83 @@
+package secure_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+// countingKMS counts the data keys unwrapped by the KMS
+type countingKMS struct {
+	secure.KMS
+	unwrapped int
+}
+
+func (k *countingKMS) UnwrapKey(ctx context.Context, wrapped string) ([]byte, error) {
+	k.unwrapped++
+	return k.KMS.UnwrapKey(ctx, wrapped)
+}
+
+func TestCachingKMS(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	kr, err := secure.ParseKeyring("kek1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	ck := &countingKMS{KMS: secure.NewLocalKMS(kr)}
+
+	t.Run("cached", func(t *testing.T) {
+		c := qt.New(t)
+
+		kms := secure.NewCachingKMS(ck, time.Minute)
+		dk, err := secure.NewDataKey(ctx, kms)
+		c.Assert(err, qt.IsNil)
+
+		ck.unwrapped = 0
+		for i := 0; i < 3; i++ {
+			key, err := secure.UnwrapDataKey(ctx, kms, dk.Wrapped)
+			c.Assert(err, qt.IsNil)
+			c.Assert(key, qt.DeepEquals, dk.Key)
+		}
+		c.Assert(ck.unwrapped, qt.Equals, 1)
+
+		// each wrapped data key is cached on its own
+		dk2, err := secure.NewDataKey(ctx, kms)
+		c.Assert(err, qt.IsNil)
+		key, err := secure.UnwrapDataKey(ctx, kms, dk2.Wrapped)
+		c.Assert(err, qt.IsNil)
+		c.Assert(key, qt.DeepEquals, dk2.Key)
+		c.Assert(ck.unwrapped, qt.Equals, 2)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		c := qt.New(t)
+
+		kms := secure.NewCachingKMS(ck, time.Millisecond)
+		dk, err := secure.NewDataKey(ctx, kms)
+		c.Assert(err, qt.IsNil)
+
+		ck.unwrapped = 0
+		_, err = secure.UnwrapDataKey(ctx, kms, dk.Wrapped)
+		c.Assert(err, qt.IsNil)
+		time.Sleep(10 * time.Millisecond)
+		_, err = secure.UnwrapDataKey(ctx, kms, dk.Wrapped)
+		c.Assert(err, qt.IsNil)
+		c.Assert(ck.unwrapped, qt.Equals, 2)
+	})
+
+	t.Run("error not cached", func(t *testing.T) {
+		c := qt.New(t)
+
+		kms := secure.NewCachingKMS(ck, time.Minute)
+		ck.unwrapped = 0
+		_, err := kms.UnwrapKey(ctx, "not a wrapped key")
+		c.Assert(err, qt.IsNotNil)
+		_, err = kms.UnwrapKey(ctx, "not a wrapped key")
+		c.Assert(err, qt.IsNotNil)
+		c.Assert(ck.unwrapped, qt.Equals, 2)
+	})
+}
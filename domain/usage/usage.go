@@ -0,0 +1,182 @@
+// Package usage meters the requests made by Apps. Requests are
+// aggregated in memory by hour, App, User, route and status and
+// flushed in batches to a Store by a background goroutine, so that
+// metering adds no database call to a request.
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Record is a single request to be metered
+type Record struct {
+	AppID    uuid.UUID
+	Username string
+	Method   string
+	// Route is the path template of the request, e.g.
+	// /api/v1/apps/{extlID}, not the path itself
+	Route    string
+	Status   int
+	Duration time.Duration
+	Time     time.Time
+}
+
+// Key identifies a row of aggregated usage
+type Key struct {
+	AppID    uuid.UUID
+	Hour     time.Time
+	Username string
+	Method   string
+	Route    string
+	Status   int
+}
+
+// Counts is the usage aggregated for a Key
+type Counts struct {
+	Requests int64
+	Duration time.Duration
+}
+
+// Usage is the usage aggregated for a Key
+type Usage struct {
+	Key
+	Counts
+}
+
+// Store saves aggregated usage, adding to the usage already saved
+// for the same Key
+type Store interface {
+	SaveUsage(ctx context.Context, uu []Usage) error
+}
+
+// Hour truncates t to the start of its hour in UTC
+func Hour(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// MonthStart returns the start of the month of t in UTC, monthly
+// quotas are counted from it
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Meter aggregates Records in memory until they are flushed to its
+// Store. It is safe for concurrent use.
+type Meter struct {
+	store    Store
+	interval time.Duration
+	lgr      zerolog.Logger
+
+	mu      sync.Mutex
+	pending map[Key]Counts
+}
+
+// NewMeter initializes a Meter which flushes to the Store every
+// interval once Run is called
+func NewMeter(store Store, interval time.Duration, lgr zerolog.Logger) *Meter {
+	return &Meter{
+		store:    store,
+		interval: interval,
+		lgr:      lgr,
+		pending:  make(map[Key]Counts),
+	}
+}
+
+// Record adds a request to the pending usage
+func (m *Meter) Record(r Record) {
+	k := Key{
+		AppID:    r.AppID,
+		Hour:     Hour(r.Time),
+		Username: r.Username,
+		Method:   r.Method,
+		Route:    r.Route,
+		Status:   r.Status,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.pending[k]
+	c.Requests++
+	c.Duration += r.Duration
+	m.pending[k] = c
+}
+
+// Pending returns the number of requests of the App recorded since
+// the time given which have not been flushed yet
+func (m *Meter) Pending(appID uuid.UUID, since time.Time) int64 {
+	since = Hour(since)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for k, c := range m.pending {
+		if k.AppID == appID && !k.Hour.Before(since) {
+			n += c.Requests
+		}
+	}
+	return n
+}
+
+// Flush saves the pending usage to the Store. If the Store returns
+// an error, the usage is kept to be flushed again.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	batch := m.pending
+	m.pending = make(map[Key]Counts)
+	m.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	uu := make([]Usage, 0, len(batch))
+	for k, c := range batch {
+		uu = append(uu, Usage{Key: k, Counts: c})
+	}
+
+	err := m.store.SaveUsage(ctx, uu)
+	if err != nil {
+		m.mu.Lock()
+		for k, c := range batch {
+			p := m.pending[k]
+			p.Requests += c.Requests
+			p.Duration += c.Duration
+			m.pending[k] = p
+		}
+		m.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Run flushes the pending usage every interval until the context is
+// done, then flushes one last time and returns
+func (m *Meter) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				m.lgr.Error().Err(err).Msg("usage flush failed, usage kept for the next flush")
+			}
+		case <-ctx.Done():
+			fctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := m.Flush(fctx); err != nil {
+				m.lgr.Error().Err(err).Msg("final usage flush failed, usage lost")
+			}
+			cancel()
+			return
+		}
+	}
+}
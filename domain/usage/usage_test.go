@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type mockStore struct {
+	saved []Usage
+	err   error
+}
+
+func (m *mockStore) SaveUsage(ctx context.Context, uu []Usage) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.saved = append(m.saved, uu...)
+	return nil
+}
+
+func TestMeter(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	appID := uuid.New()
+	now := time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC)
+
+	store := &mockStore{}
+	m := NewMeter(store, time.Minute, zerolog.Nop())
+
+	rec := Record{AppID: appID, Username: "gilcrest", Method: "GET", Route: "/api/v1/movies", Status: 200, Duration: time.Millisecond, Time: now}
+	m.Record(rec)
+	m.Record(rec)
+	rec.Status = 404
+	m.Record(rec)
+	// another App
+	m.Record(Record{AppID: uuid.New(), Time: now})
+
+	c.Assert(m.Pending(appID, MonthStart(now)), qt.Equals, int64(3))
+	c.Assert(m.Pending(appID, now.Add(time.Hour)), qt.Equals, int64(0))
+
+	// usage is kept when the store fails
+	store.err = errors.New("connection refused")
+	c.Assert(m.Flush(ctx), qt.IsNotNil)
+	c.Assert(m.Pending(appID, MonthStart(now)), qt.Equals, int64(3))
+
+	store.err = nil
+	c.Assert(m.Flush(ctx), qt.IsNil)
+	c.Assert(m.Pending(appID, MonthStart(now)), qt.Equals, int64(0))
+	c.Assert(store.saved, qt.HasLen, 3)
+
+	want := Usage{
+		Key:    Key{AppID: appID, Hour: Hour(now), Username: "gilcrest", Method: "GET", Route: "/api/v1/movies", Status: 200},
+		Counts: Counts{Requests: 2, Duration: 2 * time.Millisecond},
+	}
+	c.Assert(store.saved, qt.Any(qt.DeepEquals), want)
+}
+
+func TestMonthStart(t *testing.T) {
+	c := qt.New(t)
+
+	got := MonthStart(time.Date(2021, 6, 15, 10, 30, 0, 0, time.UTC))
+	c.Assert(got, qt.Equals, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+}
@@ -4,12 +4,14 @@ package user
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/org"
 	"github.com/gilcrest/go-api-basic/domain/person"
+	"github.com/gilcrest/go-api-basic/domain/secure"
 )
 
 // User holds details of a User from Google
@@ -17,6 +19,9 @@ type User struct {
 	// ID: unique identifier of the User
 	ID uuid.UUID
 
+	// ExternalID: unique external identifier of the User
+	ExternalID secure.Identifier
+
 	// username: unique (within an Org) username of the User
 	Username string
 
@@ -25,6 +30,39 @@ type User struct {
 
 	// profile: The profile of the user
 	Profile person.Profile
+
+	// Active: whether the User may authenticate. Inactive Users are
+	// kept for audit history but are rejected during authentication.
+	Active bool
+
+	// ServicePrincipal: whether the User stands in for a service
+	// account App calling without a User. A service principal has
+	// no ID or profile and is not stored.
+	ServicePrincipal bool
+}
+
+// ServicePrincipalPrefix is the prefix of the username of a
+// service principal, the rest of the username is the App external ID
+const ServicePrincipalPrefix string = "app:"
+
+// IsServicePrincipalUsername reports whether the username has the
+// service principal prefix. Such usernames are reserved for service
+// principals, no registered User can have one.
+func IsServicePrincipalUsername(username string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(username)), ServicePrincipalPrefix)
+}
+
+// NewServicePrincipal initializes the service principal User of a
+// service account App. The username of the service principal is the
+// casbin subject roles are granted to for the App, e.g.
+// app:d3r4ZbyNnMbcVqeo6tqh.
+func NewServicePrincipal(o org.Org, appExtlID secure.Identifier) User {
+	return User{
+		Username:         ServicePrincipalPrefix + appExtlID.String(),
+		Org:              o,
+		Active:           true,
+		ServicePrincipal: true,
+	}
 }
 
 // IsValid determines whether the User has proper data to be considered valid
@@ -32,6 +70,8 @@ func (u User) IsValid() bool {
 	switch {
 	case u.Username == "":
 		return false
+	case u.ServicePrincipal:
+		return true
 	case u.Profile.FirstName == "":
 		return false
 	case u.Profile.LastName == "":
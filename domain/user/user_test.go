@@ -12,6 +12,7 @@ import (
 
 	"github.com/gilcrest/go-api-basic/domain/org"
 	"github.com/gilcrest/go-api-basic/domain/person"
+	"github.com/gilcrest/go-api-basic/domain/secure"
 )
 
 // TODO - these tests were built before I had the concept of Profiles, Orgs, etc. - need updating
@@ -110,6 +111,29 @@ func TestUser_IsValid(t *testing.T) {
 	}
 }
 
+func TestNewServicePrincipal(t *testing.T) {
+	c := qt.New(t)
+
+	o := org.Org{ExternalID: secure.NewID()}
+	appExtlID := secure.NewID()
+
+	u := NewServicePrincipal(o, appExtlID)
+	c.Assert(u.Username, qt.Equals, ServicePrincipalPrefix+appExtlID.String())
+	c.Assert(u.Org, qt.DeepEquals, o)
+	c.Assert(u.ID, qt.Equals, uuid.Nil)
+	// a service principal has no profile, but is valid
+	c.Assert(u.IsValid(), qt.IsTrue)
+}
+
+func TestIsServicePrincipalUsername(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(IsServicePrincipalUsername(ServicePrincipalPrefix+secure.NewID().String()), qt.IsTrue)
+	c.Assert(IsServicePrincipalUsername(" APP:otto"), qt.IsTrue)
+	c.Assert(IsServicePrincipalUsername("otto.maddox@example.com"), qt.IsFalse)
+	c.Assert(IsServicePrincipalUsername("apple@example.com"), qt.IsFalse)
+}
+
 func TestFromRequest(t *testing.T) {
 	c := qt.New(t)
 
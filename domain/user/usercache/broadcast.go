@@ -0,0 +1,51 @@
+package usercache
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Publisher announces a changed User to the other instances of the
+// server, e.g. userstore.Watcher
+type Publisher interface {
+	Publish(id uuid.UUID) error
+}
+
+// Broadcast is an LRU which also publishes the Users deleted from it,
+// so every instance of the server removes them from its own cache.
+// Users announced by other instances are removed with Receive.
+type Broadcast struct {
+	*LRU
+	pub Publisher
+	lgr zerolog.Logger
+}
+
+// NewBroadcast initializes a Broadcast publishing the Users deleted
+// from lru with pub
+func NewBroadcast(lru *LRU, pub Publisher, lgr zerolog.Logger) *Broadcast {
+	return &Broadcast{LRU: lru, pub: pub, lgr: lgr}
+}
+
+// DeleteUser removes every entry cached for the User with the given
+// ID and announces the change to the other instances. As the User
+// has already been removed locally, a failure to publish is logged
+// and the other instances keep the User until their entries expire.
+func (b *Broadcast) DeleteUser(id uuid.UUID) {
+	b.LRU.DeleteUser(id)
+
+	err := b.pub.Publish(id)
+	if err != nil {
+		b.lgr.Error().Err(err).Str("user_id", id.String()).Msg("identity cache invalidation not published")
+	}
+}
+
+// Receive removes a User announced by another instance from the
+// cache. uuid.Nil means changes may have been missed and the whole
+// cache is purged.
+func (b *Broadcast) Receive(id uuid.UUID) {
+	if id == uuid.Nil {
+		b.LRU.Purge()
+		return
+	}
+	b.LRU.DeleteUser(id)
+}
@@ -0,0 +1,64 @@
+package usercache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/go-api-basic/domain/user"
+)
+
+// recordingPublisher records the published User IDs
+type recordingPublisher struct {
+	ids []uuid.UUID
+	err error
+}
+
+func (p *recordingPublisher) Publish(id uuid.UUID) error {
+	p.ids = append(p.ids, id)
+	return p.err
+}
+
+func TestBroadcast(t *testing.T) {
+	t.Run("delete user publishes", func(t *testing.T) {
+		c := qt.New(t)
+
+		pub := &recordingPublisher{err: errors.New("connection lost")}
+		b := NewBroadcast(NewLRU(10, time.Minute), pub, zerolog.Nop())
+		u := user.User{ID: uuid.New()}
+		b.Set("a", u, time.Time{}, b.Generation())
+
+		b.DeleteUser(u.ID)
+
+		// removed locally even if publishing fails
+		_, ok := b.Get("a")
+		c.Assert(ok, qt.IsFalse)
+		c.Assert(pub.ids, qt.DeepEquals, []uuid.UUID{u.ID})
+	})
+
+	t.Run("receive", func(t *testing.T) {
+		c := qt.New(t)
+
+		pub := &recordingPublisher{}
+		b := NewBroadcast(NewLRU(10, time.Minute), pub, zerolog.Nop())
+		u := user.User{ID: uuid.New()}
+		b.Set("a", u, time.Time{}, b.Generation())
+		b.Set("b", user.User{ID: uuid.New()}, time.Time{}, b.Generation())
+
+		b.Receive(u.ID)
+		_, ok := b.Get("a")
+		c.Assert(ok, qt.IsFalse)
+		_, ok = b.Get("b")
+		c.Assert(ok, qt.IsTrue)
+
+		b.Receive(uuid.Nil)
+		c.Assert(b.Stats().Size, qt.Equals, 0)
+
+		// received changes are not published again
+		c.Assert(pub.ids, qt.HasLen, 0)
+	})
+}
@@ -0,0 +1,193 @@
+// Package usercache is a bounded, in-memory cache of authenticated
+// user identities, keyed by a hash of the credentials used to
+// authenticate them
+package usercache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/domain/user"
+)
+
+// Key returns the cache key for the given provider, token and Org.
+// The token is hashed so that raw bearer tokens are never held as
+// map keys in memory.
+func Key(provider string, token string, orgID uuid.UUID) string {
+	h := sha256.New()
+	h.Write([]byte(provider))
+	h.Write([]byte{0})
+	h.Write([]byte(token))
+	h.Write([]byte{0})
+	h.Write(orgID[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Stats are the cache hit and miss counts and current size
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
+
+// entry is a single cached User
+type entry struct {
+	key    string
+	user   user.User
+	expiry time.Time
+}
+
+// LRU is a least recently used cache of Users with a maximum size
+// and a maximum time to live for each entry. It is safe for
+// concurrent use.
+type LRU struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu     sync.Mutex
+	ll     *list.List
+	items  map[string]*list.Element
+	byUser map[uuid.UUID]map[string]struct{}
+	gen    uint64
+	hits   uint64
+	misses uint64
+}
+
+// NewLRU initializes an LRU holding at most size entries, each for
+// at most ttl
+func NewLRU(size int, ttl time.Duration) *LRU {
+	if size <= 0 {
+		size = 1
+	}
+	return &LRU{
+		size:   size,
+		ttl:    ttl,
+		now:    time.Now,
+		ll:     list.New(),
+		items:  make(map[string]*list.Element),
+		byUser: make(map[uuid.UUID]map[string]struct{}),
+	}
+}
+
+// Get returns the User cached for key, if present and not expired
+func (c *LRU) Get(key string) (user.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return user.User{}, false
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expiry) {
+		c.remove(el)
+		c.misses++
+		return user.User{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	c.hits++
+	return e.user, true
+}
+
+// Generation returns the current generation of the cache, which
+// changes whenever Users are removed from it. Read it before looking
+// up a User and pass it to Set, so a User changed during the lookup
+// is not cached.
+func (c *LRU) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// Set caches u for key until expiry or the cache TTL, whichever is
+// sooner. A zero expiry means only the cache TTL applies. Nothing is
+// cached if Users have been removed since generation gen, as u may
+// be one of them and already stale.
+func (c *LRU) Set(key string, u user.User, expiry time.Time, gen uint64) {
+	maxExpiry := c.now().Add(c.ttl)
+	if expiry.IsZero() || expiry.After(maxExpiry) {
+		expiry = maxExpiry
+	}
+	if !c.now().Before(expiry) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, user: u, expiry: expiry})
+	keys, ok := c.byUser[u.ID]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.byUser[u.ID] = keys
+	}
+	keys[key] = struct{}{}
+
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+// DeleteUser removes every entry cached for the User with the given
+// ID. It should be called whenever a User is changed or deleted.
+func (c *LRU) DeleteUser(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for key := range c.byUser[id] {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// Purge removes every entry from the cache. It should be called when
+// changes to Users may have been missed.
+func (c *LRU) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.byUser = make(map[uuid.UUID]map[string]struct{})
+}
+
+// Stats returns the cache hit and miss counts and current size
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{Hits: c.hits, Misses: c.misses, Size: c.ll.Len()}
+}
+
+// remove removes the list element and its indexes. The caller
+// must hold the lock.
+func (c *LRU) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*entry)
+	delete(c.items, e.key)
+	if keys, ok := c.byUser[e.user.ID]; ok {
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.byUser, e.user.ID)
+		}
+	}
+}
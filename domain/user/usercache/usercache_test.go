@@ -0,0 +1,133 @@
+package usercache
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/domain/user"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("get set", func(t *testing.T) {
+		c := qt.New(t)
+
+		lru := NewLRU(2, time.Minute)
+		u := user.User{ID: uuid.New(), Username: "otto"}
+
+		_, ok := lru.Get("a")
+		c.Assert(ok, qt.IsFalse)
+
+		lru.Set("a", u, time.Time{}, lru.Generation())
+		got, ok := lru.Get("a")
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(got, qt.DeepEquals, u)
+		c.Assert(lru.Stats(), qt.Equals, Stats{Hits: 1, Misses: 1, Size: 1})
+	})
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		c := qt.New(t)
+
+		lru := NewLRU(2, time.Minute)
+		lru.Set("a", user.User{ID: uuid.New()}, time.Time{}, lru.Generation())
+		lru.Set("b", user.User{ID: uuid.New()}, time.Time{}, lru.Generation())
+		_, _ = lru.Get("a")
+		lru.Set("c", user.User{ID: uuid.New()}, time.Time{}, lru.Generation())
+
+		_, ok := lru.Get("b")
+		c.Assert(ok, qt.IsFalse)
+		_, ok = lru.Get("a")
+		c.Assert(ok, qt.IsTrue)
+		_, ok = lru.Get("c")
+		c.Assert(ok, qt.IsTrue)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		c := qt.New(t)
+
+		now := time.Now()
+		lru := NewLRU(10, time.Hour)
+		lru.now = func() time.Time { return now }
+
+		// token expiry sooner than the TTL wins
+		lru.Set("a", user.User{ID: uuid.New()}, now.Add(time.Minute), lru.Generation())
+		// already expired tokens are not cached
+		lru.Set("b", user.User{ID: uuid.New()}, now.Add(-time.Minute), lru.Generation())
+		// TTL applies when the token expires later
+		lru.Set("c", user.User{ID: uuid.New()}, now.Add(24*time.Hour), lru.Generation())
+
+		lru.now = func() time.Time { return now.Add(2 * time.Minute) }
+		_, ok := lru.Get("a")
+		c.Assert(ok, qt.IsFalse)
+		_, ok = lru.Get("b")
+		c.Assert(ok, qt.IsFalse)
+		_, ok = lru.Get("c")
+		c.Assert(ok, qt.IsTrue)
+
+		lru.now = func() time.Time { return now.Add(2 * time.Hour) }
+		_, ok = lru.Get("c")
+		c.Assert(ok, qt.IsFalse)
+		c.Assert(lru.Stats().Size, qt.Equals, 0)
+	})
+
+	t.Run("delete user", func(t *testing.T) {
+		c := qt.New(t)
+
+		lru := NewLRU(10, time.Minute)
+		u := user.User{ID: uuid.New()}
+		other := user.User{ID: uuid.New()}
+		lru.Set("a", u, time.Time{}, lru.Generation())
+		lru.Set("b", u, time.Time{}, lru.Generation())
+		lru.Set("c", other, time.Time{}, lru.Generation())
+
+		lru.DeleteUser(u.ID)
+
+		_, ok := lru.Get("a")
+		c.Assert(ok, qt.IsFalse)
+		_, ok = lru.Get("b")
+		c.Assert(ok, qt.IsFalse)
+		_, ok = lru.Get("c")
+		c.Assert(ok, qt.IsTrue)
+	})
+
+	t.Run("stale set", func(t *testing.T) {
+		c := qt.New(t)
+
+		lru := NewLRU(10, time.Minute)
+		u := user.User{ID: uuid.New()}
+
+		// the User is deleted while it is being looked up
+		gen := lru.Generation()
+		lru.DeleteUser(u.ID)
+		lru.Set("a", u, time.Time{}, gen)
+
+		_, ok := lru.Get("a")
+		c.Assert(ok, qt.IsFalse)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		c := qt.New(t)
+
+		lru := NewLRU(10, time.Minute)
+		lru.Set("a", user.User{ID: uuid.New()}, time.Time{}, lru.Generation())
+		lru.Set("b", user.User{ID: uuid.New()}, time.Time{}, lru.Generation())
+
+		lru.Purge()
+
+		c.Assert(lru.Stats().Size, qt.Equals, 0)
+		_, ok := lru.Get("a")
+		c.Assert(ok, qt.IsFalse)
+	})
+}
+
+func TestKey(t *testing.T) {
+	c := qt.New(t)
+
+	org1, org2 := uuid.New(), uuid.New()
+	c.Assert(Key("google", "token", org1), qt.Equals, Key("google", "token", org1))
+	c.Assert(Key("google", "token", org1), qt.Not(qt.Equals), Key("google", "token", org2))
+	c.Assert(Key("google", "token", org1), qt.Not(qt.Equals), Key("apple", "token", org1))
+	c.Assert(Key("google", "token", org1), qt.Not(qt.Contains), "token")
+}
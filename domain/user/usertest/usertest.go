@@ -41,6 +41,7 @@ func NewUser(t *testing.T) user.User {
 			ProfileLink:       "",
 			ProfileSource:     "",
 		},
+		Active: true,
 	}
 }
 
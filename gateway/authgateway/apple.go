@@ -0,0 +1,115 @@
+package authgateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+const (
+	// AppleIssuer is the iss claim value for Apple identity tokens
+	AppleIssuer string = "https://appleid.apple.com"
+	// AppleJWKSURL is where Apple publishes the public keys used
+	// to sign identity tokens
+	AppleJWKSURL string = "https://appleid.apple.com/auth/keys"
+)
+
+// flexBool is a boolean claim which Apple sends as either a JSON
+// boolean or a string ("true"/"false")
+type flexBool bool
+
+// UnmarshalJSON allows flexBool to be decoded from a bool or a string
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var v bool
+	if err := json.Unmarshal(data, &v); err == nil {
+		*b = flexBool(v)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b = flexBool(v)
+	return nil
+}
+
+// appleIDTokenClaims are the claims in an Apple identity token
+type appleIDTokenClaims struct {
+	registeredClaims
+	Email          string   `json:"email"`
+	EmailVerified  flexBool `json:"email_verified"`
+	IsPrivateEmail flexBool `json:"is_private_email"`
+}
+
+// AppleTokenConverter is used to convert an Apple identity token
+// (sent as the bearer token) to a Userinfo struct. The token is
+// verified against the keys published in Apple's cached JWKS.
+type AppleTokenConverter struct {
+	// ClientID is the Apple Services ID or App bundle ID the token
+	// was issued to and is checked against the aud claim. If empty,
+	// every token is rejected.
+	ClientID string
+	// Issuer is the expected iss claim. AppleIssuer is used if empty
+	Issuer string
+
+	jwks *jwksCache
+}
+
+// NewAppleTokenConverter initializes an AppleTokenConverter.
+// client is used to retrieve Apple's JWKS (http.DefaultClient if nil).
+func NewAppleTokenConverter(clientID string, client *http.Client) *AppleTokenConverter {
+	return &AppleTokenConverter{
+		ClientID: clientID,
+		jwks:     newJWKSCache(AppleJWKSURL, client, 0),
+	}
+}
+
+// Convert verifies the Apple identity token and converts its claims
+// to a Userinfo struct
+func (c *AppleTokenConverter) Convert(ctx context.Context, realm string, token oauth2.Token) (Userinfo, error) {
+	if c.ClientID == "" || c.jwks == nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "sign in with apple is not configured")
+	}
+	issuer := c.Issuer
+	if issuer == "" {
+		issuer = AppleIssuer
+	}
+
+	var claims appleIDTokenClaims
+	err := c.jwks.verify(ctx, token.AccessToken, &claims)
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	err = claims.validate(issuer, []string{c.ClientID}, time.Now())
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "apple identity token has no verified email")
+	}
+
+	return newUserInfoFromApple(claims), nil
+}
+
+// newUserInfoFromApple initializes the Userinfo struct given the
+// claims from an Apple identity token. Apple only sends the user's
+// name on first sign in to the client, so it is not available here.
+func newUserInfoFromApple(claims appleIDTokenClaims) Userinfo {
+	return Userinfo{
+		Username: claims.Email,
+		Email:    claims.Email,
+		Id:       claims.Subject,
+	}
+}
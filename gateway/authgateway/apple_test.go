@@ -0,0 +1,158 @@
+package authgateway
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+const testKid = "test-kid"
+
+// newTestJWKSServer starts an httptest server which serves a JWKS
+// containing the public half of key
+func newTestJWKSServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	set := jsonWebKeySet{Keys: []jsonWebKey{{
+		Kty: "RSA",
+		Kid: testKid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// signTestJWT returns a compact serialized RS256 JWT for claims
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims interface{}) string {
+	t.Helper()
+
+	hb, err := json.Marshal(jwtHeader{Alg: "RS256", Kid: kid, Typ: "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(pb)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAppleTokenConverter_Convert(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestJWKSServer(t, key)
+
+	const clientID = "dev.gab.service"
+
+	newClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":            AppleIssuer,
+			"aud":            clientID,
+			"sub":            "001234.abcdef",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"email":          "otto.maddox@example.com",
+			"email_verified": "true",
+		}
+	}
+
+	converter := &AppleTokenConverter{ClientID: clientID, jwks: newJWKSCache(srv.URL, srv.Client(), 0)}
+
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+
+		token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, newClaims())}
+		got, err := converter.Convert(context.Background(), "go-api-basic", token)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, Userinfo{
+			Username: "otto.maddox@example.com",
+			Email:    "otto.maddox@example.com",
+			Id:       "001234.abcdef",
+		})
+	})
+
+	tests := []struct {
+		name   string
+		kid    string
+		mutate func(m map[string]interface{})
+	}{
+		{"wrong audience", testKid, func(m map[string]interface{}) { m["aud"] = "some.other.client" }},
+		{"wrong issuer", testKid, func(m map[string]interface{}) { m["iss"] = "https://accounts.google.com" }},
+		{"expired", testKid, func(m map[string]interface{}) { m["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"unverified email", testKid, func(m map[string]interface{}) { m["email_verified"] = false }},
+		{"unknown kid", "unknown", func(m map[string]interface{}) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			claims := newClaims()
+			tt.mutate(claims)
+			token := oauth2.Token{AccessToken: signTestJWT(t, key, tt.kid, claims)}
+
+			_, err := converter.Convert(context.Background(), "go-api-basic", token)
+			c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+		})
+	}
+
+	t.Run("bad signature", func(t *testing.T) {
+		c := qt.New(t)
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := oauth2.Token{AccessToken: signTestJWT(t, otherKey, testKid, newClaims())}
+
+		_, err = converter.Convert(context.Background(), "go-api-basic", token)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+	})
+	t.Run("no client ID", func(t *testing.T) {
+		c := qt.New(t)
+
+		// without a client ID configured, a token issued to any
+		// client (or to none) is rejected
+		unconfigured := &AppleTokenConverter{jwks: newJWKSCache(srv.URL, srv.Client(), 0)}
+		for _, aud := range []string{clientID, ""} {
+			claims := newClaims()
+			claims["aud"] = aud
+			token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, claims)}
+
+			_, err := unconfigured.Convert(context.Background(), "go-api-basic", token)
+			c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue, qt.Commentf("aud %q", aud))
+		}
+	})
+}
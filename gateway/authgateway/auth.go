@@ -4,6 +4,9 @@ package authgateway
 
 import (
 	"context"
+	"expvar"
+	"net/http"
+	"time"
 
 	"golang.org/x/oauth2"
 	googleoauth "google.golang.org/api/oauth2/v2"
@@ -13,7 +16,8 @@ import (
 )
 
 // Userinfo contains common fields from the various Oauth2 providers.
-// Currently only using Google, so looks exactly like Google's.
+// Modeled after Google's Userinfo, other providers (e.g. Apple)
+// populate only the fields they have available.
 // TODO - maybe make this look like an OpenID Connect struct
 type Userinfo struct {
 	// Username: For most providers, the username is the email.
@@ -51,13 +55,105 @@ type Userinfo struct {
 	Picture string `json:"picture,omitempty"`
 }
 
-// GoogleOauth2TokenConverter is used to convert an oauth2.Token to a User
-// through Google's API
-type GoogleOauth2TokenConverter struct{}
+const (
+	// GoogleJWKSURL is where Google publishes the public keys used
+	// to sign ID tokens
+	GoogleJWKSURL string = "https://www.googleapis.com/oauth2/v3/certs"
+	// googleIssuer and googleIssuerNoScheme are the iss claim values
+	// Google uses for ID tokens
+	googleIssuer         string = "https://accounts.google.com"
+	googleIssuerNoScheme string = "accounts.google.com"
+)
+
+// googleTokenPaths counts the path used to convert Google tokens,
+// either local JWT verification of an ID token ("jwt") or a call to
+// the Google Userinfo API for an opaque access token ("userinfo").
+// It is published through expvar as google_token_conversions.
+var googleTokenPaths = expvar.NewMap("google_token_conversions")
+
+// GoogleOauth2TokenConverter is used to convert an oauth2.Token to a User.
+// Google ID tokens (JWTs) are verified locally against Google's cached
+// JWKS, opaque access tokens are sent to Google's Userinfo API.
+type GoogleOauth2TokenConverter struct {
+	// ClientIDs are the OAuth2 client IDs ID tokens may be issued to
+	// (the aud claim). If empty, ID tokens cannot be verified locally
+	// and every token is sent to the Userinfo API.
+	ClientIDs []string
+
+	jwks *jwksCache
+}
+
+// NewGoogleOauth2TokenConverter initializes a GoogleOauth2TokenConverter.
+// client is used to retrieve Google's JWKS (http.DefaultClient if nil).
+func NewGoogleOauth2TokenConverter(clientIDs []string, client *http.Client) *GoogleOauth2TokenConverter {
+	return &GoogleOauth2TokenConverter{
+		ClientIDs: clientIDs,
+		jwks:      newJWKSCache(GoogleJWKSURL, client, 0),
+	}
+}
+
+// Convert converts the token to a Userinfo struct, verifying it locally
+// if it is an ID token, otherwise calling the Google Userinfo API
+func (c *GoogleOauth2TokenConverter) Convert(ctx context.Context, realm string, token oauth2.Token) (Userinfo, error) {
+	if c.jwks != nil && len(c.ClientIDs) > 0 && isJWT(token.AccessToken) {
+		googleTokenPaths.Add("jwt", 1)
+		return c.verifyIDToken(ctx, realm, token.AccessToken)
+	}
+
+	googleTokenPaths.Add("userinfo", 1)
+	return googleUserinfo(ctx, realm, token)
+}
+
+// googleIDTokenClaims are the claims in a Google ID token
+type googleIDTokenClaims struct {
+	registeredClaims
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Hd            string   `json:"hd"`
+	Name          string   `json:"name"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+	Picture       string   `json:"picture"`
+	Locale        string   `json:"locale"`
+}
+
+// verifyIDToken verifies a Google ID token using the cached JWKS
+func (c *GoogleOauth2TokenConverter) verifyIDToken(ctx context.Context, realm string, rawToken string) (Userinfo, error) {
+	var claims googleIDTokenClaims
+	err := c.jwks.verify(ctx, rawToken, &claims)
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	// Google ID tokens may have an iss with or without the scheme
+	issuer := googleIssuer
+	if claims.Issuer == googleIssuerNoScheme {
+		issuer = googleIssuerNoScheme
+	}
+	err = claims.validate(issuer, c.ClientIDs, time.Now())
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "google ID token has no verified email")
+	}
+
+	return Userinfo{
+		Username:   claims.Email,
+		Email:      claims.Email,
+		FamilyName: claims.FamilyName,
+		GivenName:  claims.GivenName,
+		Hd:         claims.Hd,
+		Id:         claims.Subject,
+		Locale:     claims.Locale,
+		Name:       claims.Name,
+		Picture:    claims.Picture,
+	}, nil
+}
 
-// Convert calls the Google Userinfo API with the access token and converts
-// the Userinfo struct to a User struct
-func (c GoogleOauth2TokenConverter) Convert(ctx context.Context, realm string, token oauth2.Token) (Userinfo, error) {
+// googleUserinfo calls the Google Userinfo API with the access token and
+// converts the response to a Userinfo struct
+func googleUserinfo(ctx context.Context, realm string, token oauth2.Token) (Userinfo, error) {
 	oauthService, err := googleoauth.NewService(ctx, option.WithTokenSource(oauth2.StaticTokenSource(&token)))
 	if err != nil {
 		return Userinfo{}, errs.E(err)
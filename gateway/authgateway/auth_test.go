@@ -0,0 +1,98 @@
+package authgateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"strconv"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+func TestGoogleOauth2TokenConverter_Convert(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestJWKSServer(t, key)
+
+	const clientID = "123.apps.googleusercontent.com"
+
+	converter := &GoogleOauth2TokenConverter{
+		ClientIDs: []string{clientID},
+		jwks:      newJWKSCache(srv.URL, srv.Client(), 0),
+	}
+
+	newClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":            googleIssuerNoScheme,
+			"aud":            clientID,
+			"sub":            "1100222",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"email":          "otto.maddox@example.com",
+			"email_verified": true,
+			"given_name":     "Otto",
+			"family_name":    "Maddox",
+			"hd":             "example.com",
+		}
+	}
+
+	jwtCount := func() int64 {
+		v := googleTokenPaths.Get("jwt")
+		if v == nil {
+			return 0
+		}
+		n, _ := strconv.ParseInt(v.String(), 10, 64)
+		return n
+	}
+
+	t.Run("valid id token", func(t *testing.T) {
+		c := qt.New(t)
+
+		before := jwtCount()
+		token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, newClaims())}
+		got, err := converter.Convert(context.Background(), "go-api-basic", token)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, Userinfo{
+			Username:   "otto.maddox@example.com",
+			Email:      "otto.maddox@example.com",
+			GivenName:  "Otto",
+			FamilyName: "Maddox",
+			Hd:         "example.com",
+			Id:         "1100222",
+		})
+		c.Assert(jwtCount(), qt.Equals, before+1)
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		c := qt.New(t)
+
+		claims := newClaims()
+		claims["aud"] = "456.apps.googleusercontent.com"
+		token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, claims)}
+		_, err := converter.Convert(context.Background(), "go-api-basic", token)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		c := qt.New(t)
+
+		claims := newClaims()
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, claims)}
+		_, err := converter.Convert(context.Background(), "go-api-basic", token)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+	})
+}
+
+func Test_isJWT(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(isJWT("ya29.a0ARrdaM-opaque-access-token"), qt.IsFalse)
+	c.Assert(isJWT("eyJhbGciOiJSUzI1NiIsImtpZCI6ImFiYyJ9.eyJzdWIiOiIxIn0.c2ln"), qt.IsTrue)
+}
@@ -0,0 +1,336 @@
+package authgateway
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// clockSkew is the leeway allowed when validating time based claims
+const clockSkew = time.Minute
+
+// jsonWebKey is a single JSON Web Key as defined by RFC 7517.
+// Only RSA public keys are supported.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// rsaPublicKey builds an *rsa.PublicKey from the key modulus and exponent
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, errs.E(errs.Unauthenticated, fmt.Sprintf("unsupported key type %s", k.Kty))
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, errs.E(errs.Unauthenticated, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, errs.E(errs.Unauthenticated, err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// jsonWebKeySet is a JSON Web Key Set (JWKS) as published by an
+// identity provider
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// find returns the key with the matching key ID, if present
+func (s jsonWebKeySet) find(kid string) (jsonWebKey, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return jsonWebKey{}, false
+}
+
+// fetchJWKS retrieves the JSON Web Key Set published at url
+func fetchJWKS(ctx context.Context, client *http.Client, url string) (jsonWebKeySet, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return jsonWebKeySet{}, errs.E(errs.Internal, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return jsonWebKeySet{}, errs.E(errs.IO, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return jsonWebKeySet{}, errs.E(errs.IO, fmt.Sprintf("unexpected status %d retrieving JWKS from %s", resp.StatusCode, url))
+	}
+
+	var set jsonWebKeySet
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return jsonWebKeySet{}, errs.E(errs.IO, err)
+	}
+
+	return set, nil
+}
+
+// defaultJWKSCacheTTL is how long a retrieved JWKS is used before
+// it is retrieved again
+const defaultJWKSCacheTTL = time.Hour
+
+// jwksCache holds a JWKS retrieved from a URL in memory so that
+// it is not retrieved for every token verified. The set is
+// retrieved again after the TTL expires or when a token is
+// signed with a key ID not found in the cached set (keys rotate).
+type jwksCache struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	set       jsonWebKeySet
+	fetchedAt time.Time
+}
+
+// newJWKSCache initializes a jwksCache for the given url
+func newJWKSCache(url string, client *http.Client, ttl time.Duration) *jwksCache {
+	if ttl <= 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+	return &jwksCache{url: url, client: client, ttl: ttl}
+}
+
+// keySet returns the cached key set, retrieving it if it has not
+// yet been retrieved, the TTL has expired or kid is not in the set
+func (c *jwksCache) keySet(ctx context.Context, kid string) (jsonWebKeySet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, found := c.set.find(kid)
+	if found && time.Since(c.fetchedAt) < c.ttl {
+		return c.set, nil
+	}
+
+	// do not hammer the JWKS endpoint with unknown key IDs,
+	// only retrieve again for an unknown kid once per minute
+	if !found && !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < time.Minute && time.Since(c.fetchedAt) < c.ttl {
+		return c.set, nil
+	}
+
+	set, err := fetchJWKS(ctx, c.client, c.url)
+	if err != nil {
+		return jsonWebKeySet{}, err
+	}
+	c.set = set
+	c.fetchedAt = time.Now()
+
+	return c.set, nil
+}
+
+// verify verifies rawToken against the cached key set and decodes
+// the token payload into claims
+func (c *jwksCache) verify(ctx context.Context, rawToken string, claims interface{}) error {
+	hdr, err := parseJWTHeader(rawToken)
+	if err != nil {
+		return err
+	}
+
+	set, err := c.keySet(ctx, hdr.Kid)
+	if err != nil {
+		return err
+	}
+
+	return verifyJWT(rawToken, set, claims)
+}
+
+// jwtHeader is the JOSE header of a JSON Web Token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// isJWT reports whether rawToken looks like a compact serialized
+// JWT (as opposed to an opaque token)
+func isJWT(rawToken string) bool {
+	_, err := parseJWTHeader(rawToken)
+	return err == nil
+}
+
+// TokenExpiry returns the exp claim of a compact serialized JWT
+// without verifying the token. ok is false for opaque tokens or
+// tokens with no exp claim. It must only be used for tokens which
+// have already been verified.
+func TokenExpiry(rawToken string) (expiry time.Time, ok bool) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	pb, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var rc registeredClaims
+	if json.Unmarshal(pb, &rc) != nil || rc.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(rc.ExpiresAt, 0), true
+}
+
+// parseJWTHeader decodes the JOSE header of a compact serialized
+// JWT without verifying the token
+func parseJWTHeader(rawToken string) (jwtHeader, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return jwtHeader{}, errs.E(errs.Unauthenticated, "token is not a JWT")
+	}
+
+	hb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return jwtHeader{}, errs.E(errs.Unauthenticated, err)
+	}
+	var hdr jwtHeader
+	err = json.Unmarshal(hb, &hdr)
+	if err != nil {
+		return jwtHeader{}, errs.E(errs.Unauthenticated, err)
+	}
+
+	return hdr, nil
+}
+
+// audience is the aud claim, which may be sent as either a single
+// string or an array of strings
+type audience []string
+
+// UnmarshalJSON allows audience to be decoded from a string or an array
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// contains reports whether aud is one of the audience values
+func (a audience) contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether any of auds is one of the audience
+// values. An empty aud never matches.
+func (a audience) containsAny(auds []string) bool {
+	for _, aud := range auds {
+		if aud != "" && a.contains(aud) {
+			return true
+		}
+	}
+	return false
+}
+
+// registeredClaims are the JWT claims defined by RFC 7519 that
+// are validated for every token
+type registeredClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	IssuedAt  int64    `json:"iat"`
+}
+
+// validate checks the issuer, audience and expiration of the claims.
+// The token must be issued to one of auds, a token is never valid if
+// no audience is expected.
+func (c registeredClaims) validate(issuer string, auds []string, now time.Time) error {
+	switch {
+	case c.Issuer != issuer:
+		return errs.E(errs.Unauthenticated, fmt.Sprintf("token issuer %s does not match %s", c.Issuer, issuer))
+	case !c.Audience.containsAny(auds):
+		return errs.E(errs.Unauthenticated, "token audience does not match")
+	case c.ExpiresAt == 0:
+		return errs.E(errs.Unauthenticated, "token has no expiration")
+	case now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)):
+		return errs.E(errs.Unauthenticated, "token is expired")
+	case c.IssuedAt != 0 && now.Add(clockSkew).Before(time.Unix(c.IssuedAt, 0)):
+		return errs.E(errs.Unauthenticated, "token issued in the future")
+	}
+	return nil
+}
+
+// verifyJWT verifies the RS256 signature of a compact serialized
+// JWT using the matching key from the key set and decodes the
+// token payload into claims
+func verifyJWT(rawToken string, set jsonWebKeySet, claims interface{}) error {
+	hdr, err := parseJWTHeader(rawToken)
+	if err != nil {
+		return err
+	}
+	if hdr.Alg != "RS256" {
+		return errs.E(errs.Unauthenticated, fmt.Sprintf("unsupported signing algorithm %s", hdr.Alg))
+	}
+
+	jwk, ok := set.find(hdr.Kid)
+	if !ok {
+		return errs.E(errs.Unauthenticated, fmt.Sprintf("no key found for kid %s", hdr.Kid))
+	}
+	pub, err := jwk.rsaPublicKey()
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(rawToken, ".")
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errs.E(errs.Unauthenticated, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+	if err != nil {
+		return errs.E(errs.Unauthenticated, err)
+	}
+
+	pb, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return errs.E(errs.Unauthenticated, err)
+	}
+	err = json.Unmarshal(pb, claims)
+	if err != nil {
+		return errs.E(errs.Unauthenticated, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package authgateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// OIDCClaimMapping maps Userinfo fields to the claim names a
+// provider uses for them. Any empty field uses the standard
+// OpenID Connect claim name. The username is always the verified
+// email, as is the case for the Google and Apple providers, so one
+// provider cannot assert the username of a user of another.
+type OIDCClaimMapping struct {
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	Locale        string `json:"locale"`
+}
+
+// withDefaults returns the mapping with any empty claim name set
+// to the standard OpenID Connect claim
+func (m OIDCClaimMapping) withDefaults() OIDCClaimMapping {
+	def := func(v, d string) string {
+		if v == "" {
+			return d
+		}
+		return v
+	}
+	m.Email = def(m.Email, "email")
+	m.EmailVerified = def(m.EmailVerified, "email_verified")
+	m.GivenName = def(m.GivenName, "given_name")
+	m.FamilyName = def(m.FamilyName, "family_name")
+	m.Name = def(m.Name, "name")
+	m.Picture = def(m.Picture, "picture")
+	m.Locale = def(m.Locale, "locale")
+	return m
+}
+
+// OIDCProviderConfig configures a single OpenID Connect provider
+// (e.g. Okta, Auth0, Keycloak)
+type OIDCProviderConfig struct {
+	// Name is the X-AUTH-PROVIDER header value which resolves
+	// to this provider (case-insensitive)
+	Name string `json:"name"`
+	// Issuer is the issuer URL. The discovery document is
+	// retrieved from Issuer + /.well-known/openid-configuration
+	// and the iss claim of each token must match it exactly
+	Issuer string `json:"issuer"`
+	// Audiences are the accepted aud claim values, a token must
+	// have at least one of them
+	Audiences []string `json:"audiences"`
+	// Claims maps provider claims to Userinfo fields
+	Claims OIDCClaimMapping `json:"claims"`
+}
+
+// Validate determines whether the OIDCProviderConfig has proper data
+func (c OIDCProviderConfig) Validate() error {
+	switch {
+	case c.Name == "":
+		return errs.E(errs.Validation, errs.Parameter("name"), "OIDC provider name is required")
+	case !strings.HasPrefix(c.Issuer, "https://") && !strings.HasPrefix(c.Issuer, "http://"):
+		return errs.E(errs.Validation, errs.Parameter("issuer"), fmt.Sprintf("OIDC provider %s issuer must be a URL", c.Name))
+	case len(c.Audiences) == 0:
+		return errs.E(errs.Validation, errs.Parameter("audiences"), fmt.Sprintf("OIDC provider %s must have at least one audience", c.Name))
+	}
+	return nil
+}
+
+// LoadOIDCProviderConfigs reads a JSON array of OIDCProviderConfig
+// from the file at path
+func LoadOIDCProviderConfigs(path string) ([]OIDCProviderConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.E(errs.IO, err)
+	}
+
+	var configs []OIDCProviderConfig
+	err = json.Unmarshal(b, &configs)
+	if err != nil {
+		return nil, errs.E(errs.Validation, err)
+	}
+
+	return configs, nil
+}
+
+// oidcDiscoveryPath is appended to the issuer URL to retrieve
+// the provider's discovery document
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+// oidcDiscovery is the subset of the OpenID Provider Metadata
+// used to verify tokens
+type oidcDiscovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// oidcProvider is a configured provider along with its cached
+// discovery document and JWKS
+type oidcProvider struct {
+	config OIDCProviderConfig
+	client *http.Client
+	ttl    time.Duration
+
+	mu   sync.Mutex
+	jwks *jwksCache
+}
+
+// keys returns the provider's jwksCache, retrieving the discovery
+// document the first time it is called
+func (p *oidcProvider) keys(ctx context.Context) (*jwksCache, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.jwks != nil {
+		return p.jwks, nil
+	}
+
+	d, err := fetchOIDCDiscovery(ctx, p.client, p.config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	if d.Issuer != p.config.Issuer {
+		return nil, errs.E(errs.Internal, fmt.Sprintf("discovery issuer %s does not match configured issuer %s", d.Issuer, p.config.Issuer))
+	}
+	p.jwks = newJWKSCache(d.JWKSURI, p.client, p.ttl)
+
+	return p.jwks, nil
+}
+
+// fetchOIDCDiscovery retrieves the discovery document for issuer
+func fetchOIDCDiscovery(ctx context.Context, client *http.Client, issuer string) (oidcDiscovery, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := strings.TrimSuffix(issuer, "/") + oidcDiscoveryPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return oidcDiscovery{}, errs.E(errs.Internal, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return oidcDiscovery{}, errs.E(errs.IO, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return oidcDiscovery{}, errs.E(errs.IO, fmt.Sprintf("unexpected status %d retrieving discovery document from %s", resp.StatusCode, url))
+	}
+
+	var d oidcDiscovery
+	err = json.NewDecoder(resp.Body).Decode(&d)
+	if err != nil {
+		return oidcDiscovery{}, errs.E(errs.IO, err)
+	}
+	if d.JWKSURI == "" {
+		return oidcDiscovery{}, errs.E(errs.IO, fmt.Sprintf("discovery document from %s has no jwks_uri", url))
+	}
+
+	return d, nil
+}
+
+// OIDCTokenConverter verifies JWT bearer tokens (ID or access tokens)
+// issued by any of its configured OpenID Connect providers and
+// converts their claims to a Userinfo struct. Tokens are verified
+// locally using each provider's cached discovery document and JWKS.
+type OIDCTokenConverter struct {
+	providers map[string]*oidcProvider
+}
+
+// NewOIDCTokenConverter initializes an OIDCTokenConverter given
+// provider configuration. client is used to retrieve discovery
+// documents and JWKS (http.DefaultClient if nil) and ttl is how
+// long a retrieved JWKS is cached (one hour if zero).
+func NewOIDCTokenConverter(configs []OIDCProviderConfig, client *http.Client, ttl time.Duration) (*OIDCTokenConverter, error) {
+	providers := make(map[string]*oidcProvider, len(configs))
+	for _, cfg := range configs {
+		err := cfg.Validate()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(cfg.Name)
+		if _, ok := providers[name]; ok {
+			return nil, errs.E(errs.Validation, errs.Parameter("name"), fmt.Sprintf("OIDC provider %s configured more than once", cfg.Name))
+		}
+		cfg.Claims = cfg.Claims.withDefaults()
+		providers[name] = &oidcProvider{config: cfg, client: client, ttl: ttl}
+	}
+
+	return &OIDCTokenConverter{providers: providers}, nil
+}
+
+// Supports reports whether provider (an X-AUTH-PROVIDER header
+// value) resolves to a configured OpenID Connect provider
+func (c *OIDCTokenConverter) Supports(provider string) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.providers[strings.ToLower(provider)]
+	return ok
+}
+
+// Convert verifies the bearer token with the named provider and
+// converts its claims to a Userinfo struct
+func (c *OIDCTokenConverter) Convert(ctx context.Context, realm string, provider string, token oauth2.Token) (Userinfo, error) {
+	if !c.Supports(provider) {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "provider not recognized")
+	}
+	p := c.providers[strings.ToLower(provider)]
+
+	keys, err := p.keys(ctx)
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	var payload json.RawMessage
+	err = keys.verify(ctx, token.AccessToken, &payload)
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	var rc registeredClaims
+	err = json.Unmarshal(payload, &rc)
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+	err = rc.validate(p.config.Issuer, p.config.Audiences, time.Now())
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	var claims map[string]interface{}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	uInfo := newUserinfoFromOIDCClaims(claims, rc.Subject, p.config.Claims)
+	if uInfo.Username == "" {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), fmt.Sprintf("token has no %s claim", p.config.Claims.Email))
+	}
+
+	// the email is the identity, only an email the provider says
+	// is verified can be trusted
+	v, ok := claims[p.config.Claims.EmailVerified]
+	if !ok {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), fmt.Sprintf("token has no %s claim", p.config.Claims.EmailVerified))
+	}
+	var verified flexBool
+	b, _ := json.Marshal(v)
+	if json.Unmarshal(b, &verified) != nil || !verified {
+		return Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "token email is not verified")
+	}
+
+	return uInfo, nil
+}
+
+// newUserinfoFromOIDCClaims initializes the Userinfo struct given
+// the token claims and claim mapping
+func newUserinfoFromOIDCClaims(claims map[string]interface{}, sub string, m OIDCClaimMapping) Userinfo {
+	str := func(name string) string {
+		s, _ := claims[name].(string)
+		return s
+	}
+
+	return Userinfo{
+		Username:   str(m.Email),
+		Email:      str(m.Email),
+		FamilyName: str(m.FamilyName),
+		GivenName:  str(m.GivenName),
+		Id:         sub,
+		Locale:     str(m.Locale),
+		Name:       str(m.Name),
+		Picture:    str(m.Picture),
+	}
+}
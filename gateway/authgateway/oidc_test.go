@@ -0,0 +1,161 @@
+package authgateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// newTestOIDCServer starts an httptest server acting as an OpenID
+// Connect provider, serving a discovery document and a JWKS with
+// the public half of key. The returned counter is incremented each
+// time the JWKS is retrieved.
+func newTestOIDCServer(t *testing.T, key *rsa.PrivateKey) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var jwksHits int32
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc(oidcDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidcDiscovery{Issuer: srv.URL, JWKSURI: srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&jwksHits, 1)
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "RSA",
+			Kid: testKid,
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	return srv, &jwksHits
+}
+
+func TestOIDCTokenConverter_Convert(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, jwksHits := newTestOIDCServer(t, key)
+
+	converter, err := NewOIDCTokenConverter([]OIDCProviderConfig{{
+		Name:      "Keycloak",
+		Issuer:    srv.URL,
+		Audiences: []string{"account", "go-api-basic"},
+		Claims:    OIDCClaimMapping{GivenName: "first_name"},
+	}}, srv.Client(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":                srv.URL,
+			"aud":                []string{"go-api-basic"},
+			"sub":                "f6a1c2",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"email":              "otto.maddox@example.com",
+			"email_verified":     true,
+			"preferred_username": "otto",
+			"first_name":         "Otto",
+			"family_name":        "Maddox",
+		}
+	}
+
+	t.Run("supports", func(t *testing.T) {
+		c := qt.New(t)
+		c.Assert(converter.Supports("keycloak"), qt.IsTrue)
+		c.Assert(converter.Supports("okta"), qt.IsFalse)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+
+		token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, newClaims())}
+		got, err := converter.Convert(context.Background(), "go-api-basic", "KEYCLOAK", token)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, Userinfo{
+			Username:   "otto.maddox@example.com",
+			Email:      "otto.maddox@example.com",
+			GivenName:  "Otto",
+			FamilyName: "Maddox",
+			Id:         "f6a1c2",
+		})
+
+		// the JWKS is cached after the first token
+		_, err = converter.Convert(context.Background(), "go-api-basic", "keycloak", token)
+		c.Assert(err, qt.IsNil)
+		c.Assert(atomic.LoadInt32(jwksHits), qt.Equals, int32(1))
+	})
+
+	tests := []struct {
+		name     string
+		provider string
+		mutate   func(m map[string]interface{})
+	}{
+		{"unknown provider", "okta", func(m map[string]interface{}) {}},
+		{"wrong audience", "keycloak", func(m map[string]interface{}) { m["aud"] = "someone-else" }},
+		{"wrong issuer", "keycloak", func(m map[string]interface{}) { m["iss"] = "https://evil.example.com" }},
+		{"expired", "keycloak", func(m map[string]interface{}) { m["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"unverified email", "keycloak", func(m map[string]interface{}) { m["email_verified"] = "false" }},
+		{"no email_verified claim", "keycloak", func(m map[string]interface{}) { delete(m, "email_verified") }},
+		{"no email claim", "keycloak", func(m map[string]interface{}) { delete(m, "email") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			claims := newClaims()
+			tt.mutate(claims)
+			token := oauth2.Token{AccessToken: signTestJWT(t, key, testKid, claims)}
+
+			_, err := converter.Convert(context.Background(), "go-api-basic", tt.provider, token)
+			c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+		})
+	}
+}
+
+func TestNewOIDCTokenConverter(t *testing.T) {
+	c := qt.New(t)
+
+	valid := OIDCProviderConfig{Name: "okta", Issuer: "https://dev-000000.okta.com/oauth2/default", Audiences: []string{"api://default"}}
+
+	_, err := NewOIDCTokenConverter([]OIDCProviderConfig{valid}, nil, 0)
+	c.Assert(err, qt.IsNil)
+
+	_, err = NewOIDCTokenConverter([]OIDCProviderConfig{valid, valid}, nil, 0)
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+
+	noAud := valid
+	noAud.Audiences = nil
+	_, err = NewOIDCTokenConverter([]OIDCProviderConfig{noAud}, nil, 0)
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
+
+func TestLoadOIDCProviderConfigs(t *testing.T) {
+	c := qt.New(t)
+
+	configs, err := LoadOIDCProviderConfigs("../../config/oidc.example.json")
+	c.Assert(err, qt.IsNil)
+	c.Assert(configs, qt.HasLen, 3)
+
+	_, err = NewOIDCTokenConverter(configs, nil, 0)
+	c.Assert(err, qt.IsNil)
+}
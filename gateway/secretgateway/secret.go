@@ -0,0 +1,219 @@
+// Package secretgateway retrieves secrets, such as the encryption
+// key and database password, from where they are kept at deployment:
+// environment variables, files (e.g. Docker or Kubernetes secrets
+// mounts) or the output of a command (e.g. a cloud secret manager
+// CLI). Cache keeps the secrets in memory and refreshes them
+// periodically, so rotated secrets are picked up without a restart.
+package secretgateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+// Provider retrieves secrets by name
+type Provider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// EnvProvider retrieves secrets from environment variables named
+// after the secret
+type EnvProvider struct{}
+
+// Secret returns the value of the environment variable
+func (EnvProvider) Secret(ctx context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return "", errs.E(errs.NotExist, fmt.Sprintf("no environment variable found for secret %s", name))
+	}
+	return v, nil
+}
+
+// StaticProvider retrieves secrets from a map, e.g. of values given
+// as command line flags. Its secrets never change.
+type StaticProvider map[string]string
+
+// Secret returns the value of the secret in the map
+func (sp StaticProvider) Secret(ctx context.Context, name string) (string, error) {
+	v := sp[name]
+	if v == "" {
+		return "", errs.E(errs.NotExist, fmt.Sprintf("no value found for secret %s", name))
+	}
+	return v, nil
+}
+
+// FileProvider retrieves secrets from files named after the secret
+// in Dir, e.g. /run/secrets/DB_PASSWORD for a Docker secret or a
+// Kubernetes secret mounted as a volume. Leading and trailing
+// whitespace (typically a trailing newline) is trimmed.
+type FileProvider struct {
+	Dir string
+}
+
+// Secret returns the trimmed contents of the secret's file
+func (fp FileProvider) Secret(ctx context.Context, name string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(fp.Dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errs.E(errs.NotExist, fmt.Sprintf("no file found for secret %s in %s", name, fp.Dir))
+		}
+		return "", errs.E(errs.IO, err)
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "" {
+		return "", errs.E(errs.NotExist, fmt.Sprintf("file for secret %s in %s is empty", name, fp.Dir))
+	}
+	return v, nil
+}
+
+// execTimeout is how long an ExecProvider command may run
+const execTimeout = 30 * time.Second
+
+// ExecProvider retrieves secrets by running a command with the secret
+// name appended to its arguments, e.g. a wrapper script around a
+// cloud secret manager CLI. The trimmed standard output of the
+// command is the secret.
+type ExecProvider struct {
+	Command string
+	Args    []string
+}
+
+// NewExecProvider initializes an ExecProvider given a command line,
+// split on whitespace into the command and its arguments
+func NewExecProvider(cmdline string) (ExecProvider, error) {
+	fields := strings.Fields(cmdline)
+	if len(fields) == 0 {
+		return ExecProvider{}, errs.E(errs.Internal, "no secret command found")
+	}
+	return ExecProvider{Command: fields[0], Args: fields[1:]}, nil
+}
+
+// Secret runs the command and returns its trimmed output
+func (ep ExecProvider) Secret(ctx context.Context, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ep.Command, append(ep.Args, name)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", errs.E(errs.IO, fmt.Sprintf("secret command for %s failed: %v: %s", name, err, strings.TrimSpace(stderr.String())))
+	}
+
+	v := strings.TrimSpace(stdout.String())
+	if v == "" {
+		return "", errs.E(errs.NotExist, fmt.Sprintf("secret command returned nothing for %s", name))
+	}
+	return v, nil
+}
+
+// Cache holds the latest values of a set of secrets retrieved from a
+// Provider
+type Cache struct {
+	provider Provider
+	names    []string
+
+	mu       sync.RWMutex
+	values   map[string]string
+	onChange []func(name, value string)
+}
+
+// NewCache retrieves the named secrets from the Provider and returns
+// a Cache holding them. A secret the Provider does not have (an error
+// of Kind errs.NotExist) is held as an empty string, any other error
+// is returned.
+func NewCache(ctx context.Context, p Provider, names ...string) (*Cache, error) {
+	c := &Cache{
+		provider: p,
+		names:    names,
+		values:   make(map[string]string, len(names)),
+	}
+	for _, name := range names {
+		v, err := p.Secret(ctx, name)
+		if err != nil && !errs.KindIs(errs.NotExist, err) {
+			return nil, err
+		}
+		c.values[name] = v
+	}
+	return c, nil
+}
+
+// Get returns the latest value of the secret
+func (c *Cache) Get(name string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values[name]
+}
+
+// OnChange registers a function called with the new value whenever a
+// secret changes on Refresh
+func (c *Cache) OnChange(f func(name, value string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, f)
+}
+
+// Refresh retrieves each secret from the Provider again, calling the
+// OnChange functions for those which changed. A secret which cannot
+// be retrieved keeps its previous value and the first error is
+// returned once all secrets have been tried. A secret which is still
+// empty is not an error.
+func (c *Cache) Refresh(ctx context.Context) error {
+	var firstErr error
+	for _, name := range c.names {
+		v, err := c.provider.Secret(ctx, name)
+		if err != nil {
+			if errs.KindIs(errs.NotExist, err) && c.Get(name) == "" {
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		changed := c.values[name] != v
+		c.values[name] = v
+		onChange := c.onChange
+		c.mu.Unlock()
+
+		if changed {
+			for _, f := range onChange {
+				f(name, v)
+			}
+		}
+	}
+	return firstErr
+}
+
+// Run refreshes the secrets every interval until ctx is done. Errors
+// are logged and the previous values kept.
+func (c *Cache) Run(ctx context.Context, interval time.Duration, lgr zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				lgr.Error().Err(err).Msg("secret refresh error")
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package secretgateway_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/gateway/secretgateway"
+)
+
+func TestEnvProvider_Secret(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	c.Setenv("GAB_TEST_SECRET", "so secret")
+	v, err := secretgateway.EnvProvider{}.Secret(ctx, "GAB_TEST_SECRET")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "so secret")
+
+	c.Setenv("GAB_TEST_SECRET", "")
+	_, err = secretgateway.EnvProvider{}.Secret(ctx, "GAB_TEST_SECRET")
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+}
+
+func TestFileProvider_Secret(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "DB_PASSWORD"), []byte("so secret\n"), 0600)
+	c.Assert(err, qt.IsNil)
+
+	fp := secretgateway.FileProvider{Dir: dir}
+	v, err := fp.Secret(ctx, "DB_PASSWORD")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "so secret")
+
+	_, err = fp.Secret(ctx, "ENCRYPT_KEY")
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+}
+
+func TestExecProvider_Secret(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	ep, err := secretgateway.NewExecProvider("echo secret for")
+	c.Assert(err, qt.IsNil)
+	v, err := ep.Secret(ctx, "DB_PASSWORD")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, "secret for DB_PASSWORD")
+
+	ep, err = secretgateway.NewExecProvider("false")
+	c.Assert(err, qt.IsNil)
+	_, err = ep.Secret(ctx, "DB_PASSWORD")
+	c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+
+	_, err = secretgateway.NewExecProvider(" ")
+	c.Assert(err, qt.IsNotNil)
+}
+
+func TestCache_Refresh(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	p := secretgateway.StaticProvider{"DB_PASSWORD": "old"}
+	cache, err := secretgateway.NewCache(ctx, p, "DB_PASSWORD", "ENCRYPT_KEY")
+	c.Assert(err, qt.IsNil)
+	c.Assert(cache.Get("DB_PASSWORD"), qt.Equals, "old")
+	// secrets the provider does not have are empty
+	c.Assert(cache.Get("ENCRYPT_KEY"), qt.Equals, "")
+
+	var changed []string
+	cache.OnChange(func(name, value string) {
+		changed = append(changed, name+"="+value)
+	})
+
+	// unchanged secrets do not call OnChange
+	c.Assert(cache.Refresh(ctx), qt.IsNil)
+	c.Assert(changed, qt.HasLen, 0)
+
+	p["DB_PASSWORD"] = "new"
+	p["ENCRYPT_KEY"] = "key"
+	c.Assert(cache.Refresh(ctx), qt.IsNil)
+	c.Assert(changed, qt.DeepEquals, []string{"DB_PASSWORD=new", "ENCRYPT_KEY=key"})
+	c.Assert(cache.Get("DB_PASSWORD"), qt.Equals, "new")
+
+	// a secret which can no longer be retrieved keeps its value
+	delete(p, "DB_PASSWORD")
+	c.Assert(cache.Refresh(ctx), qt.Not(qt.IsNil))
+	c.Assert(cache.Get("DB_PASSWORD"), qt.Equals, "new")
+}
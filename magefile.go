@@ -69,6 +69,20 @@ func DBDown() error {
 	return nil
 }
 
+// RotateKeys re-encrypts all stored ciphertexts with the primary
+// (first) key of the ENCRYPT_KEY keyring, and rewraps all stored data
+// keys with the primary key of the KMS, in a single transaction. To
+// rotate, add a new key to the front of the keyring (or KMS key
+// file), run RotateKeys, then remove the old key.
+func RotateKeys() error {
+	err := commands.OverrideEnv()
+	if err != nil {
+		return err
+	}
+
+	return commands.RotateKeys([]string{"rotate-keys"})
+}
+
 // TestAll runs all tests for the app
 func TestAll() error {
 	err := commands.OverrideEnv()
@@ -8,6 +8,17 @@ import (
 )
 
 func main() {
+	// srvr rotate-keys re-encrypts stored ciphertexts with the
+	// primary encryption key, and rewraps stored data keys with the
+	// primary key-encryption key, instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		if err := commands.RotateKeys(append([]string{os.Args[0]}, os.Args[2:]...)); err != nil {
+			fmt.Fprintf(os.Stderr, "error from commands.RotateKeys(): %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := commands.Run(os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "error from commands.Run(): %s\n", err)
 		os.Exit(1)
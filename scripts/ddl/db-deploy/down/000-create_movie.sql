@@ -1,2 +0,0 @@
-drop function if exists demo.create_movie(uuid, varchar, varchar, varchar, date, integer, varchar, varchar, uuid,
-                                          varchar);
@@ -0,0 +1 @@
+drop table if exists demo.casbin_rule;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists demo.rate_limit_bucket;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists demo.app_usage;
\ No newline at end of file
@@ -0,0 +1,53 @@
+create table demo.movie
+(
+    movie_id         uuid                     not null,
+    movie_extl_id    varchar                  not null,
+    title            varchar                  not null,
+    rated            varchar                  not null,
+    released         date                     not null,
+    run_time         integer                  not null,
+    director         varchar                  not null,
+    writer           varchar                  not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint movie_pk
+        primary key (movie_id),
+    constraint movie_create_app_fk
+        foreign key (create_app_id) references demo.app
+            deferrable initially deferred,
+    constraint movie_update_app_fk
+        foreign key (update_app_id) references demo.app
+            deferrable initially deferred,
+    constraint movie_create_user_fk
+        foreign key (create_user_id) references demo.app_user
+            deferrable initially deferred,
+    constraint movie_update_user_fk
+        foreign key (update_user_id) references demo.app_user
+            deferrable initially deferred
+);
+
+comment on table demo.movie is 'movie stores data about movies for the demo';
+
+comment on column demo.movie.movie_extl_id is 'Movie Unique External ID to be given to outside callers.';
+
+comment on column demo.movie.create_app_id is 'The application which created this record.';
+
+comment on column demo.movie.create_user_id is 'The user which created this record.';
+
+comment on column demo.movie.create_timestamp is 'The timestamp representing when this record was created.';
+
+comment on column demo.movie.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column demo.movie.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column demo.movie.update_timestamp is 'The timestamp representing when the record was updated most recently.';
+
+alter table demo.movie
+    owner to demo_user;
+
+create unique index movie_movie_extl_id_uindex
+    on demo.movie (movie_extl_id);
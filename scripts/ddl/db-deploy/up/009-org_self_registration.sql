@@ -0,0 +1,4 @@
+alter table demo.org
+    add self_registration_allowed boolean default false not null;
+
+comment on column demo.org.self_registration_allowed is 'If true, users authenticated by a provider may register themselves into the organization.';
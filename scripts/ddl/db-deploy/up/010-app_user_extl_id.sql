@@ -0,0 +1,19 @@
+alter table demo.app_user
+    add user_extl_id varchar;
+
+update demo.app_user
+   set user_extl_id = substr(md5(random()::text || user_id::text), 1, 20)
+ where user_extl_id is null;
+
+alter table demo.app_user
+    alter column user_extl_id set not null;
+
+alter table demo.app_user
+    add active boolean default true not null;
+
+create unique index user_user_extl_id_uindex
+    on demo.app_user (user_extl_id);
+
+comment on column demo.app_user.user_extl_id is 'User Unique External ID to be given to outside callers.';
+
+comment on column demo.app_user.active is 'If false, the user has been deactivated and can no longer authenticate.';
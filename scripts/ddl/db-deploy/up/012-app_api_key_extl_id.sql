@@ -0,0 +1,19 @@
+alter table demo.app_api_key
+    add api_key_extl_id varchar;
+
+update demo.app_api_key
+   set api_key_extl_id = substr(md5(random()::text || api_key), 1, 20)
+ where api_key_extl_id is null;
+
+alter table demo.app_api_key
+    alter column api_key_extl_id set not null;
+
+alter table demo.app_api_key
+    alter column deactv_date type timestamp with time zone using deactv_date::timestamp with time zone;
+
+create unique index app_api_key_extl_id_uindex
+    on demo.app_api_key (api_key_extl_id);
+
+comment on column demo.app_api_key.api_key_extl_id is 'API key unique External ID, used to manage the key without sending it';
+
+comment on column demo.app_api_key.deactv_date is 'the time the API key is no longer usable, set to the current time when the key is revoked';
@@ -0,0 +1,28 @@
+alter table demo.app_api_key
+    add api_key_prefix varchar;
+
+alter table demo.app_api_key
+    add api_key_hash varchar;
+
+alter table demo.app_api_key
+    alter column api_key drop not null;
+
+alter table demo.app_api_key
+    drop constraint app_key_pk;
+
+alter table demo.app_api_key
+    add constraint app_api_key_pk
+        primary key (api_key_extl_id);
+
+alter table demo.app_api_key
+    add constraint app_api_key_hashed_ck
+        check (api_key is not null or (api_key_prefix is not null and api_key_hash is not null));
+
+comment on column demo.app_api_key.api_key is 'legacy encrypted key, emptied once the key is migrated to a prefix and hash';
+
+comment on column demo.app_api_key.api_key_prefix is 'leading characters of the key, used to look the key up';
+
+comment on column demo.app_api_key.api_key_hash is 'hex encoded HMAC-SHA256 of the key, keyed with the server API key secret';
+
+create index app_api_key_prefix_index
+    on demo.app_api_key (api_key_prefix);
@@ -0,0 +1,8 @@
+alter table demo.app
+    add scopes varchar[] default '{}' not null;
+
+comment on column demo.app.scopes is 'scopes (resource:level) limiting the requests the app can make, regardless of the user';
+
+-- apps created before scopes existed keep full access
+update demo.app
+set scopes = '{*:admin}';
@@ -0,0 +1,4 @@
+alter table demo.app
+    add service_account boolean default false not null;
+
+comment on column demo.app.service_account is 'if true, the app may authenticate without a user on routes allowing it, acting as its own service principal';
@@ -0,0 +1,19 @@
+create table demo.rate_limit_bucket
+(
+    bucket_key       varchar          not null
+        constraint rate_limit_bucket_pk
+            primary key,
+    tokens           double precision not null,
+    update_timestamp timestamp with time zone not null
+);
+
+comment on table demo.rate_limit_bucket is 'rate limiting token buckets, shared by all instances of the server';
+
+comment on column demo.rate_limit_bucket.bucket_key is 'key of the bucket, the app external id and optionally the username';
+
+comment on column demo.rate_limit_bucket.tokens is 'tokens left in the bucket as of update_timestamp';
+
+comment on column demo.rate_limit_bucket.update_timestamp is 'time a token was last taken from the bucket';
+
+alter table demo.rate_limit_bucket
+    owner to demo_user;
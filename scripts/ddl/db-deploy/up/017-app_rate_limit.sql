@@ -0,0 +1,4 @@
+alter table demo.app
+    add rate_limit integer;
+
+comment on column demo.app.rate_limit is 'requests per minute allowed for the app, the server default if null';
@@ -0,0 +1,4 @@
+alter table demo.app
+    add monthly_quota bigint;
+
+comment on column demo.app.monthly_quota is 'requests per calendar month (UTC) allowed for the app, the server default if null';
@@ -0,0 +1,4 @@
+alter table demo.app_api_key
+    add api_key_data_key varchar;
+
+comment on column demo.app_api_key.api_key_data_key is 'data key the key is hashed with, wrapped by the KMS, null if the key is hashed with the server API key secret';
@@ -0,0 +1,4 @@
+alter table demo.org
+    add archive_timestamp timestamp with time zone;
+
+comment on column demo.org.archive_timestamp is 'The timestamp the org was archived (soft deleted), null if the org is not archived. The apps and users of an archived org are inactive.';
@@ -0,0 +1,14 @@
+create index org_create_timestamp_index
+    on demo.org (create_timestamp, org_extl_id);
+
+create index app_org_name_index
+    on demo.app (org_id, app_name, app_extl_id);
+
+create index app_org_create_timestamp_index
+    on demo.app (org_id, create_timestamp, app_extl_id);
+
+create index user_org_username_index
+    on demo.app_user (org_id, username, user_extl_id);
+
+create index user_org_create_timestamp_index
+    on demo.app_user (org_id, create_timestamp, user_extl_id);
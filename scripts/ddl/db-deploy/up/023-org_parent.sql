@@ -0,0 +1,15 @@
+alter table demo.org
+    add parent_org_id uuid;
+
+alter table demo.org
+    add constraint org_parent_org_fk
+        foreign key (parent_org_id) references demo.org;
+
+alter table demo.org
+    add constraint org_parent_org_ck
+        check (parent_org_id <> org_id);
+
+comment on column demo.org.parent_org_id is 'The parent organization of a sub-organization, null for a top level organization. Roles granted in an organization apply to its descendants.';
+
+create index org_parent_org_id_index
+    on demo.org (parent_org_id);
@@ -0,0 +1,14 @@
+alter table demo.org
+    add settings jsonb default '{"version": 1}'::jsonb not null;
+
+alter table demo.org
+    add constraint org_settings_ck
+        check (jsonb_typeof(settings) = 'object');
+
+update demo.org
+   set settings = jsonb_build_object('version', 1, 'self_registration_allowed', self_registration_allowed);
+
+alter table demo.org
+    drop column self_registration_allowed;
+
+comment on column demo.org.settings is 'The versioned settings and feature flags document of the organization, e.g. self_registration_allowed, allowed_auth_providers and default_api_key_lifetime. The schema is validated by the application.';
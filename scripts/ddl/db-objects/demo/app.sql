@@ -12,6 +12,10 @@ create table app
     update_app_id    uuid      not null,
     update_user_id   uuid,
     update_timestamp timestamp not null,
+    scopes           varchar[] default '{}'::character varying[] not null,
+    service_account  boolean   default false not null,
+    rate_limit       integer,
+    monthly_quota    bigint,
     constraint app_pk
         primary key (app_id),
     constraint app_self_ref1
@@ -31,9 +35,23 @@ create table app
 
 comment on table app is 'app stores data about applications that interact with the system';
 
+comment on column app.scopes is 'scopes (resource:level) limiting the requests the app can make, regardless of the user';
+
+comment on column app.service_account is 'if true, the app may authenticate without a user on routes allowing it, acting as its own service principal';
+
+comment on column app.rate_limit is 'requests per minute allowed for the app, the server default if null';
+
+comment on column app.monthly_quota is 'requests per calendar month (UTC) allowed for the app, the server default if null';
+
 alter table app
     owner to demo_user;
 
 create unique index app_app_extl_id_uindex
     on app (app_extl_id);
 
+create index app_org_name_index
+    on app (org_id, app_name, app_extl_id);
+
+create index app_org_create_timestamp_index
+    on app (org_id, create_timestamp, app_extl_id);
+
@@ -1,16 +1,20 @@
 create table app_api_key
 (
-    api_key          varchar                  not null,
+    api_key          varchar,
+    api_key_extl_id  varchar                  not null,
     app_id           uuid                     not null,
-    deactv_date      date                     not null,
+    deactv_date      timestamp with time zone not null,
     create_app_id    uuid                     not null,
     create_user_id   uuid,
     create_timestamp timestamp with time zone not null,
     update_app_id    uuid                     not null,
     update_user_id   uuid,
     update_timestamp timestamp with time zone not null,
-    constraint app_key_pk
-        primary key (api_key),
+    api_key_prefix   varchar,
+    api_key_hash     varchar,
+    api_key_data_key varchar,
+    constraint app_api_key_pk
+        primary key (api_key_extl_id),
     constraint app_key_app_app_id_fk
         foreign key (app_id) references app,
     constraint app_api_key_app_fk1
@@ -24,13 +28,30 @@ create table app_api_key
             deferrable initially deferred,
     constraint app_api_key_app_user_fk2
         foreign key (update_user_id) references app_user
-            deferrable initially deferred
+            deferrable initially deferred,
+    constraint app_api_key_hashed_ck
+        check (api_key is not null or (api_key_prefix is not null and api_key_hash is not null))
 );
 
-comment on column app_api_key.api_key is 'app_key is a hash of a key given to a user for an app';
+comment on column app_api_key.api_key is 'legacy encrypted key, emptied once the key is migrated to a prefix and hash';
+
+comment on column app_api_key.api_key_extl_id is 'API key unique External ID, used to manage the key without sending it';
 
 comment on column app_api_key.app_id is 'foreign key to app table';
 
+comment on column app_api_key.deactv_date is 'the time the API key is no longer usable, set to the current time when the key is revoked';
+
+comment on column app_api_key.api_key_prefix is 'leading characters of the key, used to look the key up';
+
+comment on column app_api_key.api_key_hash is 'hex encoded HMAC-SHA256 of the key, keyed with its data key or the server API key secret';
+
+comment on column app_api_key.api_key_data_key is 'data key the key is hashed with, wrapped by the KMS, null if the key is hashed with the server API key secret';
+
 alter table app_api_key
     owner to demo_user;
 
+create unique index app_api_key_extl_id_uindex
+    on app_api_key (api_key_extl_id);
+
+create index app_api_key_prefix_index
+    on app_api_key (api_key_prefix);
@@ -0,0 +1,28 @@
+create table app_usage
+(
+    app_id        uuid                     not null,
+    usage_hour    timestamp with time zone not null,
+    username      varchar default ''       not null,
+    http_method   varchar                  not null,
+    route         varchar                  not null,
+    status_code   integer                  not null,
+    request_count bigint                   not null,
+    duration_ms   bigint                   not null,
+    constraint app_usage_pk
+        primary key (app_id, usage_hour, username, http_method, route, status_code),
+    constraint app_usage_app_fk
+        foreign key (app_id) references app
+);
+
+comment on table app_usage is 'requests made by apps, aggregated by hour';
+
+comment on column app_usage.usage_hour is 'start of the hour (UTC) the requests were made in';
+
+comment on column app_usage.username is 'username of the user the requests were made for, empty if no user was authenticated';
+
+comment on column app_usage.route is 'path template of the requests, e.g. /api/v1/apps/{extlID}';
+
+comment on column app_usage.duration_ms is 'total duration of the requests in milliseconds';
+
+alter table app_usage
+    owner to demo_user;
@@ -1,9 +1,11 @@
 create table app_user
 (
     user_id           uuid      not null,
+    user_extl_id      varchar   not null,
     username          varchar   not null,
     org_id            uuid      not null,
     person_profile_id uuid      not null,
+    active            boolean   default true not null,
     create_app_id     uuid      not null,
     create_user_id    uuid,
     create_timestamp  timestamp not null,
@@ -33,3 +35,16 @@ alter table app_user
 create unique index user_org_uindex
     on app_user (username, org_id);
 
+create unique index user_user_extl_id_uindex
+    on app_user (user_extl_id);
+
+create index user_org_username_index
+    on app_user (org_id, username, user_extl_id);
+
+create index user_org_create_timestamp_index
+    on app_user (org_id, create_timestamp, user_extl_id);
+
+comment on column app_user.user_extl_id is 'User Unique External ID to be given to outside callers.';
+
+comment on column app_user.active is 'If false, the user has been deactivated and can no longer authenticate.';
+
@@ -0,0 +1,21 @@
+create table casbin_rule
+(
+    ptype varchar            not null,
+    v0    varchar default '' not null,
+    v1    varchar default '' not null,
+    v2    varchar default '' not null,
+    v3    varchar default '' not null,
+    v4    varchar default '' not null,
+    v5    varchar default '' not null,
+    constraint casbin_rule_pk
+        primary key (ptype, v0, v1, v2, v3, v4, v5)
+);
+
+comment on table casbin_rule is 'casbin authorization policy rules';
+
+comment on column casbin_rule.ptype is 'policy type (p for a policy rule, g for a role assignment)';
+
+comment on column casbin_rule.v0 is 'first rule value (e.g. subject), unused values are empty strings';
+
+alter table casbin_rule
+    owner to demo_user;
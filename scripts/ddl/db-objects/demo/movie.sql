@@ -1,24 +1,53 @@
 create table movie
 (
-    movie_id         uuid          not null,
-    extl_id          varchar(250)  not null,
-    title            varchar(1000) not null,
-    rated            varchar(10),
-    released         date,
-    run_time         integer,
-    director         varchar(1000),
-    writer           varchar(1000),
-    create_username  varchar,
-    create_timestamp timestamp with time zone,
-    update_username  varchar,
-    update_timestamp timestamp with time zone,
+    movie_id         uuid                     not null,
+    movie_extl_id    varchar                  not null,
+    title            varchar                  not null,
+    rated            varchar                  not null,
+    released         date                     not null,
+    run_time         integer                  not null,
+    director         varchar                  not null,
+    writer           varchar                  not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
     constraint movie_pk
-        primary key (movie_id)
+        primary key (movie_id),
+    constraint movie_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint movie_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred,
+    constraint movie_create_user_fk
+        foreign key (create_user_id) references app_user
+            deferrable initially deferred,
+    constraint movie_update_user_fk
+        foreign key (update_user_id) references app_user
+            deferrable initially deferred
 );
 
+comment on table movie is 'movie stores data about movies for the demo';
+
+comment on column movie.movie_extl_id is 'Movie Unique External ID to be given to outside callers.';
+
+comment on column movie.create_app_id is 'The application which created this record.';
+
+comment on column movie.create_user_id is 'The user which created this record.';
+
+comment on column movie.create_timestamp is 'The timestamp representing when this record was created.';
+
+comment on column movie.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column movie.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column movie.update_timestamp is 'The timestamp representing when the record was updated most recently.';
+
 alter table movie
     owner to demo_user;
 
-create unique index movie_extl_id_uindex
-    on movie (extl_id);
-
+create unique index movie_movie_extl_id_uindex
+    on movie (movie_extl_id);
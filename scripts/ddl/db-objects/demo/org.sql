@@ -1,16 +1,19 @@
 create table org
 (
-    org_id           uuid                     not null,
-    org_extl_id      varchar                  not null,
-    org_name         varchar                  not null,
-    org_description  varchar                  not null,
-    genesis_org      boolean                  not null,
-    create_app_id    uuid                     not null,
-    create_user_id   uuid,
-    create_timestamp timestamp with time zone not null,
-    update_app_id    uuid                     not null,
-    update_user_id   uuid,
-    update_timestamp timestamp with time zone not null,
+    org_id                    uuid                     not null,
+    org_extl_id               varchar                  not null,
+    org_name                  varchar                  not null,
+    org_description           varchar                  not null,
+    genesis_org               boolean                  not null,
+    create_app_id             uuid                     not null,
+    create_user_id            uuid,
+    create_timestamp          timestamp with time zone not null,
+    update_app_id             uuid                     not null,
+    update_user_id            uuid,
+    update_timestamp          timestamp with time zone not null,
+    archive_timestamp         timestamp with time zone,
+    parent_org_id             uuid,
+    settings                  jsonb                    default '{"version": 1}'::jsonb not null,
     constraint org_pk
         primary key (org_id),
     constraint org_create_user_fk
@@ -24,7 +27,13 @@ create table org
             deferrable initially deferred,
     constraint org_update_app_fk
         foreign key (update_app_id) references app
-            deferrable initially deferred
+            deferrable initially deferred,
+    constraint org_parent_org_fk
+        foreign key (parent_org_id) references org,
+    constraint org_parent_org_ck
+        check (parent_org_id <> org_id),
+    constraint org_settings_ck
+        check (jsonb_typeof(settings) = 'object')
 );
 
 comment on column org.org_id is 'Organization ID - Unique ID for table';
@@ -49,6 +58,12 @@ comment on column org.update_user_id is 'The user which performed the most recen
 
 comment on column org.update_timestamp is 'The timestamp representing when the record was updated most recently.';
 
+comment on column org.archive_timestamp is 'The timestamp the org was archived (soft deleted), null if the org is not archived. The apps and users of an archived org are inactive.';
+
+comment on column org.parent_org_id is 'The parent organization of a sub-organization, null for a top level organization. Roles granted in an organization apply to its descendants.';
+
+comment on column org.settings is 'The versioned settings and feature flags document of the organization, e.g. self_registration_allowed, allowed_auth_providers and default_api_key_lifetime. The schema is validated by the application.';
+
 alter table org
     owner to demo_user;
 
@@ -61,3 +76,8 @@ create unique index org_org_name_uindex
 create unique index org_org_extl_id_uindex
     on org (org_extl_id);
 
+create index org_create_timestamp_index
+    on org (create_timestamp, org_extl_id);
+
+create index org_parent_org_id_index
+    on org (parent_org_id);
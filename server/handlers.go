@@ -7,179 +7,215 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/hlog"
 
+	"github.com/gilcrest/go-api-basic/domain/app"
 	"github.com/gilcrest/go-api-basic/domain/audit"
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/service"
 )
 
-//// CreateMovie is a HandlerFunc used to create a Movie
-//func (s *Server) handleMovieCreate(w http.ResponseWriter, r *http.Request) {
-//	logger := *hlog.FromRequest(r)
-//
-//	u, err := user.FromRequest(r)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// Declare request body (rb) as an instance of service.MovieRequest
-//	rb := new(service.CreateMovieRequest)
-//
-//	// Decode JSON HTTP request body into a Decoder type
-//	// and unmarshal that into the MovieRequest struct in the
-//	// AddMovieHandler
-//	err = json.NewDecoder(r.Body).Decode(&rb)
-//	defer r.Body.Close()
-//	// Call decoderErr to determine if body is nil, json is malformed
-//	// or any other error
-//	err = decoderErr(err)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	response, err := s.CreateMovieService.Create(r.Context(), rb, u)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// Encode response struct to JSON for the response body
-//	err = json.NewEncoder(w).Encode(response)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
-//		return
-//	}
-//}
-//
-//// handleMovieUpdate handles PUT requests for the /movies/{id} endpoint
-//// and updates the given movie
-//func (s *Server) handleMovieUpdate(w http.ResponseWriter, r *http.Request) {
-//
-//	logger := *hlog.FromRequest(r)
-//
-//	u, err := user.FromRequest(r)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// gorilla mux Vars function returns the route variables for the
-//	// current request, if any. id is the external id given for the
-//	// movie
-//	vars := mux.Vars(r)
-//	extlid := vars["extlID"]
-//
-//	// Declare request body (rb) as an instance of service.MovieRequest
-//	rb := new(service.UpdateMovieRequest)
-//
-//	// Decode JSON HTTP request body into a Decoder type
-//	// and unmarshal that into requestData
-//	err = json.NewDecoder(r.Body).Decode(&rb)
-//	defer r.Body.Close()
-//	// Call DecoderErr to determine if body is nil, json is malformed
-//	// or any other error
-//	err = decoderErr(err)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// External ID is from path variable, need to set separate
-//	// from decoding response body
-//	rb.ExternalID = extlid
-//
-//	response, err := s.UpdateMovieService.Update(r.Context(), rb, u)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// Encode response struct to JSON for the response body
-//	err = json.NewEncoder(w).Encode(response)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
-//		return
-//	}
-//}
-//
-//// handleMovieDelete handles DELETE requests for the /movies/{id} endpoint
-//// and updates the given movie
-//func (s *Server) handleMovieDelete(w http.ResponseWriter, r *http.Request) {
-//
-//	logger := *hlog.FromRequest(r)
-//
-//	// gorilla mux Vars function returns the route variables for the
-//	// current request, if any. id is the external id given for the
-//	// movie
-//	vars := mux.Vars(r)
-//	extlID := vars["extlID"]
-//
-//	response, err := s.DeleteMovieService.Delete(r.Context(), extlID)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// Encode response struct to JSON for the response body
-//	err = json.NewEncoder(w).Encode(response)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
-//		return
-//	}
-//}
-//
-//// handleFindMovieByID handles GET requests for the /movies/{id} endpoint
-//// and finds a movie by its ID
-//func (s *Server) handleFindMovieByID(w http.ResponseWriter, r *http.Request) {
-//
-//	logger := *hlog.FromRequest(r)
-//
-//	// gorilla mux Vars function returns the route variables for the
-//	// current request, if any. id is the external id given for the
-//	// movie
-//	vars := mux.Vars(r)
-//	extlID := vars["extlID"]
-//
-//	response, err := s.FindMovieService.FindMovieByID(r.Context(), extlID)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// Encode response struct to JSON for the response body
-//	err = json.NewEncoder(w).Encode(response)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
-//		return
-//	}
-//}
-//
-//// handleFindAllMovies handles GET requests for the /movies endpoint and finds
-//// all movies
-//func (s *Server) handleFindAllMovies(w http.ResponseWriter, r *http.Request) {
-//
-//	logger := *hlog.FromRequest(r)
-//
-//	response, err := s.FindMovieService.FindAllMovies(r.Context())
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, err)
-//		return
-//	}
-//
-//	// Encode response struct to JSON for the response body
-//	err = json.NewEncoder(w).Encode(response)
-//	if err != nil {
-//		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
-//		return
-//	}
-//}
-
-// handleOrgFindAll is a HandlerFunc used to find a list of Orgs
+// handleMovieCreate is a HandlerFunc used to create a Movie
+func (s *Server) handleMovieCreate(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.MovieRequest
+	rb := new(service.CreateMovieRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the MovieRequest struct in the
+	// AddMovieHandler
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.CreateMovieService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMovieUpdate handles PUT requests for the /movies/{id} endpoint
+// and updates the given movie
+func (s *Server) handleMovieUpdate(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlid := vars["extlID"]
+
+	// Declare request body (rb) as an instance of service.MovieRequest
+	rb := new(service.UpdateMovieRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into requestData
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.ExternalID = extlid
+
+	response, err := s.UpdateMovieService.Update(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMovieDelete handles DELETE requests for the /movies/{id} endpoint
+// and deletes the given movie
+func (s *Server) handleMovieDelete(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.DeleteMovieService.Delete(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleFindMovieByID handles GET requests for the /movies/{id} endpoint
+// and finds a movie by its ID
+func (s *Server) handleFindMovieByID(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.FindMovieService.FindMovieByID(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleFindAllMovies handles GET requests for the /movies endpoint and finds
+// all movies
+func (s *Server) handleFindAllMovies(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	response, err := s.FindMovieService.FindAllMovies(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// listRequest returns the list query parameters of a request: limit,
+// cursor, sort, name_prefix and created_after
+func listRequest(r *http.Request) (service.ListRequest, error) {
+	var (
+		lr  service.ListRequest
+		err error
+	)
+	lr.Limit, err = queryInt(r, "limit")
+	if err != nil {
+		return service.ListRequest{}, err
+	}
+	lr.CreatedAfter, err = queryTime(r, "created_after")
+	if err != nil {
+		return service.ListRequest{}, err
+	}
+	q := r.URL.Query()
+	lr.Cursor = q.Get("cursor")
+	lr.Sort = q.Get("sort")
+	lr.NamePrefix = q.Get("name_prefix")
+	return lr, nil
+}
+
+// handleOrgFindAll is a HandlerFunc used to page through the Orgs
+// using the list query parameters (see listRequest)
 func (s *Server) handleOrgFindAll(w http.ResponseWriter, r *http.Request) {
 	logger := *hlog.FromRequest(r)
 
-	response, err := s.FindOrgService.FindAll(r.Context())
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	lr, err := listRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.FindOrgService.FindAll(r.Context(), lr, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, err)
 		return
@@ -197,12 +233,18 @@ func (s *Server) handleOrgFindAll(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleOrgFindByExtlID(w http.ResponseWriter, r *http.Request) {
 	logger := *hlog.FromRequest(r)
 
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
 	// gorilla mux Vars function returns the route variables for the
 	// current request, if any. ID is the external id given for the resource
 	vars := mux.Vars(r)
 	extlID := vars["extlID"]
 
-	response, err := s.FindOrgService.FindByExternalID(r.Context(), extlID)
+	response, err := s.FindOrgService.FindByExternalID(r.Context(), extlID, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, err)
 		return
@@ -301,8 +343,10 @@ func (s *Server) handleOrgUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleAppCreate is a HandlerFunc used to create an App
-func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
+// handleOrgDelete is a HandlerFunc used to delete or archive an Org.
+// The mode query parameter chooses between archive (the default) and
+// delete, dry_run=true reports what would be affected.
+func (s *Server) handleOrgDelete(w http.ResponseWriter, r *http.Request) {
 	logger := *hlog.FromRequest(r)
 
 	adt, err := audit.FromRequest(r)
@@ -311,23 +355,20 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Declare request body (rb)
-	rb := new(service.CreateAppRequest)
-
-	// Decode JSON HTTP request body into a Decoder type
-	// and unmarshal that into the MovieRequest struct in the
-	// AddMovieHandler
-	err = json.NewDecoder(r.Body).Decode(&rb)
-	defer r.Body.Close()
-	// Call decoderErr to determine if body is nil, json is malformed
-	// or any other error
-	err = decoderErr(err)
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	dor := &service.DeleteOrgRequest{
+		ExternalID: vars["extlID"],
+		Mode:       r.URL.Query().Get("mode"),
+	}
+	dor.DryRun, err = queryBool(r, "dry_run")
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, err)
 		return
 	}
 
-	response, err := s.CreateAppService.Create(r.Context(), rb, adt)
+	response, err := s.DeleteOrgService.Delete(r.Context(), dor, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, err)
 		return
@@ -341,102 +382,940 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleLoggerRead handles GET requests for the /logger endpoint
-func (s *Server) handleLoggerRead(w http.ResponseWriter, r *http.Request) {
-	lgr := *hlog.FromRequest(r)
-
-	response := s.LoggerService.Read()
+// handleOrgMove is a HandlerFunc used to move an Org under another
+// parent, or to the top level when no parent is given
+func (s *Server) handleOrgMove(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
 
-	// Encode response struct to JSON for the response body
-	err := json.NewEncoder(w).Encode(response)
+	adt, err := audit.FromRequest(r)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, logger, err)
 		return
 	}
-}
 
-// handleLoggerUpdate handles PUT requests for the /logger endpoint
-// and updates the logger globals
-func (s *Server) handleLoggerUpdate(w http.ResponseWriter, r *http.Request) {
-	lgr := *hlog.FromRequest(r)
+	// Declare request body (rb) as an instance of service.MoveOrgRequest
+	rb := new(service.MoveOrgRequest)
 
-	// Declare rb as an instance of service.LoggerRequest
-	rb := new(service.LoggerRequest)
-
-	// Decode JSON HTTP request body into a json.Decoder type
-	// and unmarshal that into rb
-	err := json.NewDecoder(r.Body).Decode(&rb)
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the MoveOrgRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
 	defer r.Body.Close()
-	// Call DecoderErr to determine if body is nil, json is malformed
+	// Call decoderErr to determine if body is nil, json is malformed
 	// or any other error
 	err = decoderErr(err)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, err)
+		errs.HTTPErrorResponse(w, logger, err)
 		return
 	}
 
-	response, err := s.LoggerService.Update(rb)
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	response, err := s.MoveOrgService.Move(r.Context(), rb, adt)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, err)
+		errs.HTTPErrorResponse(w, logger, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// Ping handles GET requests for the /ping endpoint
-func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
-	// pull logger from request context
+// handleOrgSubtree is a HandlerFunc used to find an Org and all of
+// its descendants
+func (s *Server) handleOrgSubtree(w http.ResponseWriter, r *http.Request) {
 	logger := *hlog.FromRequest(r)
 
-	// pull the context from the http request
-	ctx := r.Context()
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
 
-	response := s.PingService.Ping(ctx, logger)
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.FindOrgService.FindSubtree(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
 
 	// Encode response struct to JSON for the response body
-	err := json.NewEncoder(w).Encode(response)
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleSeed handles POST requests for the /seed endpoint
-// and updates the logger globals
-func (s *Server) handleSeed(w http.ResponseWriter, r *http.Request) {
-	lgr := *hlog.FromRequest(r)
-
-	// Declare rb as an instance of service.LoggerRequest
-	rb := new(service.SeedRequest)
+// handleOrgSettingsFind is a HandlerFunc used to find the Settings
+// of an Org
+func (s *Server) handleOrgSettingsFind(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
 
-	// Decode JSON HTTP request body into a json.Decoder type
-	// and unmarshal that into rb
-	err := json.NewDecoder(r.Body).Decode(&rb)
-	defer r.Body.Close()
-	// Call DecoderErr to determine if body is nil, json is malformed
-	// or any other error
-	err = decoderErr(err)
+	adt, err := audit.FromRequest(r)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, err)
+		errs.HTTPErrorResponse(w, logger, err)
 		return
 	}
 
-	response, err := s.SeedService.Seed(r.Context(), rb)
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.OrgSettingsService.Find(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgSettingsPatch is a HandlerFunc used to patch the Settings
+// of an Org with a JSON merge patch
+func (s *Server) handleOrgSettingsPatch(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.PatchOrgSettingsRequest
+	rb := new(service.PatchOrgSettingsRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal the merge patch as is into the request
+	err = json.NewDecoder(r.Body).Decode(&rb.Patch)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	response, err := s.OrgSettingsService.Patch(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppCreate is a HandlerFunc used to create an App
+func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb)
+	rb := new(service.CreateAppRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the MovieRequest struct in the
+	// AddMovieHandler
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.CreateAppService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppFindAll is a HandlerFunc used to page through the Apps of
+// the caller's Org using the list query parameters (see listRequest)
+func (s *Server) handleAppFindAll(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	lr, err := listRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.FindOrgAppService.FindAll(r.Context(), lr, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppFindByExtlID is a HandlerFunc used to find an App of the
+// caller's Org by External ID
+func (s *Server) handleAppFindByExtlID(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.FindOrgAppService.FindByExternalID(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppUpdate is a HandlerFunc used to update an App of the
+// caller's Org
+func (s *Server) handleAppUpdate(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.UpdateAppRequest
+	rb := new(service.UpdateAppRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the UpdateAppRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	response, err := s.UpdateAppService.Update(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppDelete is a HandlerFunc used to delete an App of the
+// caller's Org. Apps are deactivated along with their API keys,
+// they are never hard deleted.
+func (s *Server) handleAppDelete(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.DeleteAppService.Delete(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAPIKeyFindAll is a HandlerFunc used to list the (masked)
+// API keys of an App
+func (s *Server) handleAPIKeyFindAll(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	appExtlID := vars["extlID"]
+
+	response, err := s.APIKeyService.FindAll(r.Context(), appExtlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAPIKeyCreate is a HandlerFunc used to mint a new API key for
+// an App
+func (s *Server) handleAPIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.CreateAPIKeyRequest
+	rb := new(service.CreateAPIKeyRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the CreateAPIKeyRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.AppExternalID = vars["extlID"]
+
+	response, err := s.APIKeyService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAPIKeyRevoke is a HandlerFunc used to revoke an API key of
+// an App. The key is unusable immediately.
+func (s *Server) handleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	appExtlID := vars["extlID"]
+	keyExtlID := vars["keyExtlID"]
+
+	response, err := s.APIKeyService.Revoke(r.Context(), appExtlID, keyExtlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAPIKeyRoll is a HandlerFunc used to replace an API key of an
+// App with a new one, keeping the old key usable for an overlap window
+func (s *Server) handleAPIKeyRoll(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.RollAPIKeyRequest
+	rb := new(service.RollAPIKeyRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the RollAPIKeyRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	rb.AppExternalID = vars["extlID"]
+	rb.KeyExternalID = vars["keyExtlID"]
+
+	response, err := s.APIKeyService.Roll(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppUsage is a HandlerFunc used to report the usage of an App
+// of the caller's Org using the granularity (hour or day), from and
+// to (RFC 3339) query parameters
+func (s *Server) handleAppUsage(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+
+	fur := service.FindAppUsageRequest{
+		AppExtlID:   vars["extlID"],
+		Granularity: r.URL.Query().Get("granularity"),
+	}
+	fur.From, err = queryTime(r, "from")
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+	fur.To, err = queryTime(r, "to")
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.FindAppUsageService.Find(r.Context(), fur, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleLoggerRead handles GET requests for the /logger endpoint
+func (s *Server) handleLoggerRead(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response := s.LoggerService.Read()
+
+	// Encode response struct to JSON for the response body
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleLoggerUpdate handles PUT requests for the /logger endpoint
+// and updates the logger globals
+func (s *Server) handleLoggerUpdate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// Declare rb as an instance of service.LoggerRequest
+	rb := new(service.LoggerRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err := json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
+	response, err := s.LoggerService.Update(rb)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// Ping handles GET requests for the /ping endpoint
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	// pull logger from request context
+	logger := *hlog.FromRequest(r)
+
+	// pull the context from the http request
+	ctx := r.Context()
+
+	response := s.PingService.Ping(ctx, logger)
+
+	// Encode response struct to JSON for the response body
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleSeed handles POST requests for the /seed endpoint
+// and updates the logger globals
+func (s *Server) handleSeed(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// Declare rb as an instance of service.LoggerRequest
+	rb := new(service.SeedRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err := json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.SeedService.Seed(r.Context(), rb)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserRegister is a HandlerFunc used by a provider authenticated
+// identity to register itself as a User in the App's Org
+func (s *Server) handleUserRegister(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	a, err := app.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	params, err := newFindUserParams(r, a)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	uInfo, err := s.FindUserService.FindUserinfo(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.RegisterUserRequest
+	rb := new(service.RegisterUserRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the RegisterUserRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.RegisterUserService.Register(r.Context(), rb, a, uInfo)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
 	// Encode response struct to JSON for the response body
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserFindAll is a HandlerFunc used to page through the Users
+// of the caller's Org using the list query parameters (see listRequest)
+func (s *Server) handleUserFindAll(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	lr, err := listRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.FindOrgUserService.FindAll(r.Context(), adt.User.Org, lr)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserFindByExtlID is a HandlerFunc used to find a User of the
+// caller's Org by External ID
+func (s *Server) handleUserFindByExtlID(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.FindOrgUserService.FindByExternalID(r.Context(), adt.User.Org, extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserUpdate is a HandlerFunc used to update the profile of a
+// User of the caller's Org
+func (s *Server) handleUserUpdate(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.UpdateUserRequest
+	rb := new(service.UpdateUserRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the UpdateUserRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	response, err := s.UpdateUserService.Update(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserDeactivate is a HandlerFunc used to deactivate a User of
+// the caller's Org. Users are never hard deleted.
+func (s *Server) handleUserDeactivate(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.DeactivateUserService.Deactivate(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePolicyFindAll is a HandlerFunc used to list the casbin policy
+// rules and role assignments of the App's Org
+func (s *Server) handlePolicyFindAll(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	a, err := app.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response := s.PolicyService.FindAll(a.Org)
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePolicyAdd is a HandlerFunc used to add a casbin policy rule or role
+// assignment to the App's Org
+func (s *Server) handlePolicyAdd(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	a, err := app.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.PolicyRequest
+	rb := new(service.PolicyRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the PolicyRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.PolicyService.Add(r.Context(), a.Org, rb)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePolicyRemove is a HandlerFunc used to remove a casbin policy rule or
+// role assignment from the App's Org
+func (s *Server) handlePolicyRemove(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	a, err := app.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.PolicyRequest
+	rb := new(service.PolicyRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the PolicyRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.PolicyService.Remove(r.Context(), a.Org, rb)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMePermissions is a HandlerFunc used to list the roles of the
+// current User within the App's Org and the actions they may take
+func (s *Server) handleMePermissions(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := audit.FromRequest(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	response, err := s.PolicyService.FindPermissions(adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
 		return
 	}
 }
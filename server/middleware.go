@@ -1,11 +1,15 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
 	"github.com/rs/zerolog/hlog"
 	"golang.org/x/oauth2"
@@ -14,6 +18,7 @@ import (
 	"github.com/gilcrest/go-api-basic/domain/audit"
 	"github.com/gilcrest/go-api-basic/domain/auth"
 	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/usage"
 	"github.com/gilcrest/go-api-basic/domain/user"
 	"github.com/gilcrest/go-api-basic/service"
 )
@@ -40,7 +45,9 @@ func (s *Server) jsonContentTypeResponseHandler(h http.Handler) http.Handler {
 // appHandler middleware is used to parse the request app id and api key
 // from the X-APP-ID and X-API-KEY headers, retrieve and validate
 // their veracity, retrieve the App details from the datastore and
-// finally set the App to the request context.
+// finally set the App to the request context. The App's scopes are
+// set to the context with it, they are checked by the
+// authorizeUserHandler middleware.
 func (s *Server) appHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lgr := *hlog.FromRequest(r)
@@ -86,10 +93,9 @@ func (s *Server) userHandler(h http.Handler) http.Handler {
 		ctx := r.Context()
 
 		var (
-			a     app.App
-			u     user.User
-			token oauth2.Token
-			err   error
+			a   app.App
+			u   user.User
+			err error
 		)
 		a, err = app.FromRequest(r)
 		if err != nil {
@@ -97,40 +103,205 @@ func (s *Server) userHandler(h http.Handler) http.Handler {
 			return
 		}
 
-		providerVal, err := xHeader(defaultRealm, r.Header, authProviderHeaderKey)
+		params, err := newFindUserParams(r, a)
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
-		provider := auth.NewProvider(providerVal)
 
-		token, err = authHeader(defaultRealm, r.Header)
+		u, err = s.FindUserService.FindUserByOauth2Token(ctx, params)
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
 
-		params := service.FindUserParams{
-			Realm:    defaultRealm,
-			App:      a,
-			Provider: provider,
-			Token:    token,
+		// add User to context
+		ctx = user.CtxWithUser(ctx, u)
+
+		// call original, adding User to request context
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// serviceAccountHandler middleware is used in place of userHandler
+// on routes which allow machine-to-machine requests. If no
+// Authorization header is sent and the App is a service account, the
+// service principal of the App is set to the request context as the
+// User. Otherwise, the request is handled by userHandler.
+func (s *Server) serviceAccountHandler(h http.Handler) http.Handler {
+	uh := s.userHandler(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		if _, ok := r.Header["Authorization"]; ok {
+			uh.ServeHTTP(w, r)
+			return
 		}
 
-		u, err = s.FindUserService.FindUserByOauth2Token(ctx, params)
+		a, err := app.FromRequest(r)
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
 
-		// add User to context
-		ctx = user.CtxWithUser(ctx, u)
+		// an App which is not a service account must send a User
+		if !a.ServiceAccount {
+			uh.ServeHTTP(w, r)
+			return
+		}
 
-		// call original, adding User to request context
+		// add service principal to context as the User
+		ctx := user.CtxWithUser(r.Context(), user.NewServicePrincipal(a.Org, a.ExternalID))
+
+		// call original, adding service principal to request context
 		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// rateLimitHandler middleware is used to limit the rate of requests
+// of the App in the request context, and of the User if one has been
+// set. The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers are sent with every limited response. If the limit has been
+// reached, a 429 Too Many Requests response is sent with a
+// Retry-After header. If the limiter store cannot be reached the
+// request is allowed, a broken limiter should not take down the API.
+func (s *Server) rateLimitHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		if s.RateLimitService == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		a, err := app.FromRequest(r)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+
+		// the User is optional, e.g. when registering
+		u, _ := user.FromRequest(r)
+
+		rl, err := s.RateLimitService.Allow(r.Context(), a, u)
+		if rl.Limit > 0 {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(rl.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(rl.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(rl.Reset.Seconds()))))
+		}
+		if err != nil {
+			if errs.KindIs(errs.TooManyRequests, err) {
+				errs.HTTPErrorResponse(w, lgr, err)
+				return
+			}
+			lgr.Error().Err(err).Msg("rate limiter unavailable, request allowed")
+		}
+
+		h.ServeHTTP(w, r) // call original
+	})
+}
+
+// usageHandler middleware is used to meter the requests of the App
+// in the request context, by User (if one has been set), route and
+// status. Before the request is handled, the monthly quota of the App
+// is checked and a 429 Too Many Requests response is sent if it has
+// been used up. As with rate limiting, the request is allowed if the
+// quota cannot be checked.
+func (s *Server) usageHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		if s.UsageMeter == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		a, err := app.FromRequest(r)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+
+		// the User is optional, e.g. when registering
+		u, _ := user.FromRequest(r)
+
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			s.UsageMeter.Record(usage.Record{
+				AppID:    a.ID,
+				Username: u.Username,
+				Method:   r.Method,
+				Route:    routeTemplate(r),
+				Status:   sr.status,
+				Duration: time.Since(start),
+				Time:     start,
+			})
+		}()
+
+		if s.QuotaService != nil {
+			err = s.QuotaService.Check(r.Context(), a)
+			if err != nil {
+				if errs.KindIs(errs.TooManyRequests, err) {
+					errs.HTTPErrorResponse(sr, lgr, err)
+					return
+				}
+				lgr.Error().Err(err).Msg("quota check failed, request allowed")
+			}
+		}
+
+		h.ServeHTTP(sr, r) // call original
+	})
+}
+
+// statusRecorder is a http.ResponseWriter which records the status
+// code of the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it to the
+// underlying http.ResponseWriter
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// routeTemplate returns the path template of the route matched for
+// the request, e.g. /api/v1/apps/{extlID}, or the path if no route
+// was matched
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// newFindUserParams initializes service.FindUserParams for the App
+// given the provider and bearer token request headers
+func newFindUserParams(r *http.Request, a app.App) (service.FindUserParams, error) {
+	providerVal, err := xHeader(defaultRealm, r.Header, authProviderHeaderKey)
+	if err != nil {
+		return service.FindUserParams{}, err
+	}
+
+	token, err := authHeader(defaultRealm, r.Header)
+	if err != nil {
+		return service.FindUserParams{}, err
+	}
+
+	return service.FindUserParams{
+		Realm:        defaultRealm,
+		App:          a,
+		Provider:     auth.NewProvider(providerVal),
+		ProviderName: providerVal,
+		Token:        token,
+	}, nil
+}
+
 // authorizeUserHandler middleware is used authorize a User for a request path and http method
 func (s *Server) authorizeUserHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -146,6 +317,12 @@ func (s *Server) authorizeUserHandler(h http.Handler) http.Handler {
 		// authorize user can access the path/method
 		err = s.AuthorizeService.Authorize(lgr, r, adt)
 		if err != nil {
+			// when the required permission is sent, point the
+			// client to the permissions they do have
+			var e *errs.Error
+			if errors.As(err, &e) && e.Permission != nil {
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="describedby"`, pathPrefix+mePermissionsV1Path))
+			}
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
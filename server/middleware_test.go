@@ -11,12 +11,18 @@ import (
 	qt "github.com/frankban/quicktest"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 	"golang.org/x/oauth2"
 
 	"github.com/gilcrest/go-api-basic/domain/app"
 	"github.com/gilcrest/go-api-basic/domain/auth"
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/org"
+	"github.com/gilcrest/go-api-basic/domain/ratelimit"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+	"github.com/gilcrest/go-api-basic/domain/usage"
+	"github.com/gilcrest/go-api-basic/domain/user"
+	"github.com/gilcrest/go-api-basic/service"
 )
 
 type mockFindAppService struct{}
@@ -90,12 +96,13 @@ func TestServer_appHandler(t *testing.T) {
 				UpdateTime:   time.Time{},
 				APIKeys:      nil,
 			}
-			c.Assert(a, qt.Equals, wantApp)
+			c.Assert(a, qt.DeepEquals, wantApp)
 		})
 
 		rr := httptest.NewRecorder()
 
 		s := Server{}
+		s.FindAppService = mockFindAppService{}
 
 		handlers := s.appHandler(testAppHandler)
 		handlers.ServeHTTP(rr, req)
@@ -137,6 +144,162 @@ func TestServer_appHandler(t *testing.T) {
 	//})
 }
 
+func TestServer_serviceAccountHandler(t *testing.T) {
+	s := Server{}
+
+	a := app.App{ExternalID: secure.NewID(), Org: org.Org{ExternalID: secure.NewID()}}
+
+	// serve sends a request without an Authorization header for the
+	// App and returns the User set to the request context, if any
+	serve := func(a app.App) (*httptest.ResponseRecorder, user.User) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+		req = req.WithContext(app.CtxWithApp(req.Context(), a))
+
+		var u user.User
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, _ = user.FromRequest(r)
+		})
+		rr := httptest.NewRecorder()
+		s.serviceAccountHandler(h).ServeHTTP(rr, req)
+
+		return rr, u
+	}
+
+	t.Run("service account", func(t *testing.T) {
+		c := qt.New(t)
+
+		sa := a
+		sa.ServiceAccount = true
+		rr, u := serve(sa)
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(u.ServicePrincipal, qt.IsTrue)
+		c.Assert(u.Username, qt.Equals, user.ServicePrincipalPrefix+a.ExternalID.String())
+		c.Assert(u.Org.ExternalID, qt.DeepEquals, a.Org.ExternalID)
+	})
+
+	t.Run("not a service account", func(t *testing.T) {
+		c := qt.New(t)
+
+		rr, _ := serve(a)
+		c.Assert(rr.Code, qt.Equals, http.StatusUnauthorized)
+	})
+}
+
+type mockRateLimitStore struct{}
+
+func (m mockRateLimitStore) Take(ctx context.Context, key string, limit int, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errs.E(errs.Database, "connection refused")
+}
+
+func TestServer_rateLimitHandler(t *testing.T) {
+	a := app.App{ExternalID: secure.NewID(), RateLimit: 1}
+
+	// serve sends a request for the App and reports whether the
+	// request reached the handler
+	serve := func(s Server) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+		req = req.WithContext(app.CtxWithApp(req.Context(), a))
+
+		var called bool
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		})
+		rr := httptest.NewRecorder()
+		s.rateLimitHandler(h).ServeHTTP(rr, req)
+
+		return rr, called
+	}
+
+	t.Run("limited", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := Server{}
+		s.RateLimitService = service.RateLimitService{Store: ratelimit.NewMemoryStore(), DefaultLimit: 100}
+
+		rr, called := serve(s)
+		c.Assert(called, qt.IsTrue)
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get("RateLimit-Limit"), qt.Equals, "1")
+		c.Assert(rr.Header().Get("RateLimit-Remaining"), qt.Equals, "0")
+		c.Assert(rr.Header().Get("RateLimit-Reset"), qt.Equals, "60")
+
+		rr, called = serve(s)
+		c.Assert(called, qt.IsFalse)
+		c.Assert(rr.Code, qt.Equals, http.StatusTooManyRequests)
+		c.Assert(rr.Header().Get("Retry-After"), qt.Equals, "60")
+	})
+
+	t.Run("store unavailable", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := Server{}
+		s.RateLimitService = service.RateLimitService{Store: mockRateLimitStore{}, DefaultLimit: 100}
+
+		rr, called := serve(s)
+		c.Assert(called, qt.IsTrue)
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	})
+}
+
+type mockQuotaService struct {
+	err error
+}
+
+func (m mockQuotaService) Check(ctx context.Context, a app.App) error {
+	return m.err
+}
+
+func TestServer_usageHandler(t *testing.T) {
+	a := app.App{ID: uuid.New(), ExternalID: secure.NewID()}
+
+	// serve sends a request for the App and reports whether the
+	// request reached the handler, which responds with a 201
+	serve := func(s Server) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/movies", nil)
+		req = req.WithContext(app.CtxWithApp(req.Context(), a))
+
+		var called bool
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusCreated)
+		})
+		rr := httptest.NewRecorder()
+		s.usageHandler(h).ServeHTTP(rr, req)
+
+		return rr, called
+	}
+
+	t.Run("metered", func(t *testing.T) {
+		c := qt.New(t)
+
+		m := usage.NewMeter(nil, time.Minute, zerolog.Nop())
+		s := Server{}
+		s.UsageMeter = m
+		s.QuotaService = mockQuotaService{}
+
+		rr, called := serve(s)
+		c.Assert(called, qt.IsTrue)
+		c.Assert(rr.Code, qt.Equals, http.StatusCreated)
+		c.Assert(m.Pending(a.ID, time.Now()), qt.Equals, int64(1))
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		c := qt.New(t)
+
+		m := usage.NewMeter(nil, time.Minute, zerolog.Nop())
+		s := Server{}
+		s.UsageMeter = m
+		s.QuotaService = mockQuotaService{err: errs.E(errs.TooManyRequests, errs.RetryAfter(time.Hour), "monthly quota exceeded")}
+
+		rr, called := serve(s)
+		c.Assert(called, qt.IsFalse)
+		c.Assert(rr.Code, qt.Equals, http.StatusTooManyRequests)
+		c.Assert(rr.Header().Get("Retry-After"), qt.Equals, "3600")
+		// rejected requests are metered as well
+		c.Assert(m.Pending(a.ID, time.Now()), qt.Equals, int64(1))
+	})
+}
+
 func TestXHeader(t *testing.T) {
 	t.Run("x-app-id", func(t *testing.T) {
 		c := qt.New(t)
@@ -239,6 +402,38 @@ func TestXHeader(t *testing.T) {
 //	})
 //}
 
+func Test_newFindUserParams(t *testing.T) {
+	a := app.App{ID: uuid.New(), Name: "so random"}
+
+	t.Run("typical", func(t *testing.T) {
+		c := qt.New(t)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/users", nil)
+		c.Assert(err, qt.IsNil)
+		req.Header.Add(authProviderHeaderKey, "Okta")
+		req.Header.Add("Authorization", auth.BearerTokenType+" foobarbbq")
+
+		params, err := newFindUserParams(req, a)
+		c.Assert(err, qt.IsNil)
+		c.Assert(params.Realm, qt.Equals, defaultRealm)
+		c.Assert(params.App.ID, qt.Equals, a.ID)
+		c.Assert(params.Provider, qt.Equals, auth.Invalid)
+		c.Assert(params.ProviderName, qt.Equals, "Okta")
+		c.Assert(params.Token, qt.Equals, oauth2.Token{AccessToken: "foobarbbq", TokenType: auth.BearerTokenType})
+	})
+
+	t.Run("no provider header error", func(t *testing.T) {
+		c := qt.New(t)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/users", nil)
+		c.Assert(err, qt.IsNil)
+		req.Header.Add("Authorization", auth.BearerTokenType+" foobarbbq")
+
+		_, err = newFindUserParams(req, a)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+	})
+}
+
 func Test_authHeader(t *testing.T) {
 	c := qt.New(t)
 
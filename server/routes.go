@@ -16,10 +16,32 @@ const (
 	// enough pattern in these services, that I've chosen to make it
 	// a constant
 	extlIDPathDir string = "/{extlID}"
+	// movies V1 Path root
+	moviesV1PathRoot string = "/v1/movies"
 	// organization V1 Path root
 	orgsV1PathRoot string = "/v1/orgs"
+	// the parent of an org is moved at /v1/orgs/{extlID}/parent
+	parentPathDir string = "/parent"
+	// an org and its descendants are found at /v1/orgs/{extlID}/subtree
+	subtreePathDir string = "/subtree"
+	// the settings of an org are found at /v1/orgs/{extlID}/settings
+	settingsPathDir string = "/settings"
 	// app V1 Path root
 	appsV1PathRoot string = "/v1/apps"
+	// API keys of an app are found at /v1/apps/{extlID}/keys
+	apiKeysPathDir string = "/keys"
+	// keyExtlIDPathDir is the external id of an API key
+	keyExtlIDPathDir string = "/{keyExtlID}"
+	// rollPathDir is used to roll (replace) an API key
+	rollPathDir string = "/roll"
+	// usage of an app is found at /v1/apps/{extlID}/usage
+	usagePathDir string = "/usage"
+	// user V1 Path root
+	usersV1PathRoot string = "/v1/users"
+	// policy V1 Path root
+	policiesV1PathRoot string = "/v1/policies"
+	// current user permissions V1 Path
+	mePermissionsV1Path string = "/v1/me/permissions"
 	// logger V1 Path root
 	loggerV1PathRoot string = "/v1/logger"
 	// ping V1 Path root
@@ -27,67 +49,86 @@ const (
 )
 
 // register routes/middleware/handlers to the Server router
+//
+// Routes which allow machine-to-machine requests from a service
+// account App without a User use the serviceAccountHandler
+// middleware in place of the userHandler middleware. Every route
+// which identifies an App is rate limited by the rateLimitHandler
+// middleware, after the User (if any) is known. The usageHandler
+// middleware meters those routes and enforces monthly quotas.
 func (s *Server) registerRoutes() {
 
-	//// Match only POST requests at /api/v1/movies
-	//// with Content-Type header = application/json
-	//s.router.Handle(moviesV1PathRoot,
-	//	s.loggerChain().
-	//		Append(s.appHandler).
-	//		Append(s.userHandler).
-	//		Append(s.authorizeUserHandler).
-	//		Append(s.jsonContentTypeResponseHandler).
-	//		ThenFunc(s.handleMovieCreate)).
-	//	Methods(http.MethodPost).
-	//	Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
-	//
-	//// Match only PUT requests having an ID at /api/v1/movies/{extlID}
-	//// with the Content-Type header = application/json
-	//s.router.Handle(moviesV1PathRoot+extlIDPathDir,
-	//	s.loggerChain().
-	//		Append(s.appHandler).
-	//		Append(s.userHandler).
-	//		Append(s.authorizeUserHandler).
-	//		Append(s.jsonContentTypeResponseHandler).
-	//		ThenFunc(s.handleMovieUpdate)).
-	//	Methods(http.MethodPut).
-	//	Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
-	//
-	//// Match only DELETE requests having an ID at /api/v1/movies/{extlID}
-	//s.router.Handle(moviesV1PathRoot+extlIDPathDir,
-	//	s.loggerChain().
-	//		Append(s.appHandler).
-	//		Append(s.userHandler).
-	//		Append(s.authorizeUserHandler).
-	//		Append(s.jsonContentTypeResponseHandler).
-	//		ThenFunc(s.handleMovieDelete)).
-	//	Methods(http.MethodDelete)
-	//
-	//// Match only GET requests having an ID at /api/v1/movies/{extlID}
-	//s.router.Handle(moviesV1PathRoot+extlIDPathDir,
-	//	s.loggerChain().
-	//		Append(s.appHandler).
-	//		Append(s.userHandler).
-	//		Append(s.authorizeUserHandler).
-	//		Append(s.jsonContentTypeResponseHandler).
-	//		ThenFunc(s.handleFindMovieByID)).
-	//	Methods(http.MethodGet)
-	//
-	//// Match only GET requests /api/v1/movies
-	//s.router.Handle(moviesV1PathRoot,
-	//	s.loggerChain().
-	//		Append(s.appHandler).
-	//		Append(s.userHandler).
-	//		Append(s.authorizeUserHandler).
-	//		Append(s.jsonContentTypeResponseHandler).
-	//		ThenFunc(s.handleFindAllMovies)).
-	//	Methods(http.MethodGet)
+	// Match only POST requests at /api/v1/movies
+	// with Content-Type header = application/json
+	s.router.Handle(moviesV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.serviceAccountHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only PUT requests having an ID at /api/v1/movies/{extlID}
+	// with the Content-Type header = application/json
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.serviceAccountHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests having an ID at /api/v1/movies/{extlID}
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.serviceAccountHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only GET requests having an ID at /api/v1/movies/{extlID}
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.serviceAccountHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleFindMovieByID)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests /api/v1/movies
+	s.router.Handle(moviesV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.serviceAccountHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleFindAllMovies)).
+		Methods(http.MethodGet)
 
 	// Match only GET requests at /api/v1/orgs
 	s.router.Handle(orgsV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgFindAll)).
@@ -98,6 +139,8 @@ func (s *Server) registerRoutes() {
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgFindByExtlID)).
@@ -109,6 +152,8 @@ func (s *Server) registerRoutes() {
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgCreate)).
@@ -121,29 +166,337 @@ func (s *Server) registerRoutes() {
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgUpdate)).
 		Methods(http.MethodPut).
 		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
 
+	// Match only DELETE requests at /api/v1/orgs/{extlID}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only PUT requests at /api/v1/orgs/{extlID}/parent
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+parentPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgMove)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/subtree
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+subtreePathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgSubtree)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/settings
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+settingsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgSettingsFind)).
+		Methods(http.MethodGet)
+
+	// Match only PATCH requests at /api/v1/orgs/{extlID}/settings
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+settingsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgSettingsPatch)).
+		Methods(http.MethodPatch).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
 	// Match only POST requests at /api/v1/apps
 	// with Content-Type header = application/json
 	s.router.Handle(appsV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleAppCreate)).
 		Methods(http.MethodPost).
 		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
 
+	// Match only GET requests at /api/v1/apps
+	s.router.Handle(appsV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/apps/{extlID}
+	s.router.Handle(appsV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppFindByExtlID)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/apps/{extlID}
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/apps/{extlID}
+	// Apps are deactivated, not deleted
+	s.router.Handle(appsV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only GET requests at /api/v1/apps/{extlID}/keys
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+apiKeysPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/apps/{extlID}/keys
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+apiKeysPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/apps/{extlID}/keys/{keyExtlID}
+	// API keys are revoked, not deleted
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+apiKeysPathDir+keyExtlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyRevoke)).
+		Methods(http.MethodDelete)
+
+	// Match only POST requests at /api/v1/apps/{extlID}/keys/{keyExtlID}/roll
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+apiKeysPathDir+keyExtlIDPathDir+rollPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyRoll)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/apps/{extlID}/usage
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+usagePathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppUsage)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/users
+	// with Content-Type header = application/json
+	//
+	// The identity registering itself is not yet a User, so the
+	// userHandler and authorizeUserHandler middleware are not used.
+	// The Org's self registration policy is enforced by the service.
+	s.router.Handle(usersV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserRegister)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/users
+	s.router.Handle(usersV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/users/{extlID}
+	s.router.Handle(usersV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserFindByExtlID)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/users/{extlID}
+	// with Content-Type header = application/json
+	s.router.Handle(usersV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/users/{extlID}
+	// Users are deactivated, not deleted
+	s.router.Handle(usersV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserDeactivate)).
+		Methods(http.MethodDelete)
+
+	// Match only GET requests at /api/v1/policies
+	s.router.Handle(policiesV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePolicyFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/policies
+	// with Content-Type header = application/json
+	s.router.Handle(policiesV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePolicyAdd)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/policies
+	// with Content-Type header = application/json. Policy rules
+	// have no identifier, the rule to remove is sent in the body.
+	s.router.Handle(policiesV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePolicyRemove)).
+		Methods(http.MethodDelete).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/me/permissions
+	//
+	// Any User may list their own permissions, so the
+	// authorizeUserHandler middleware is not used.
+	s.router.Handle(mePermissionsV1Path,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMePermissions)).
+		Methods(http.MethodGet)
+
 	// Match only GET requests /api/v1/logger
 	s.router.Handle(loggerV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleLoggerRead)).
@@ -154,6 +507,8 @@ func (s *Server) registerRoutes() {
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleLoggerUpdate)).
@@ -165,6 +520,8 @@ func (s *Server) registerRoutes() {
 		s.loggerChain().
 			Append(s.appHandler).
 			Append(s.userHandler).
+			Append(s.usageHandler).
+			Append(s.rateLimitHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handlePing)).
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/casbin/casbin/v2"
 	qt "github.com/frankban/quicktest"
 	"github.com/gorilla/mux"
 )
@@ -31,11 +32,39 @@ func TestNewMuxRouter(t *testing.T) {
 		// use a slice literal to create the routes in order of how
 		// they are registered in NewMuxRouter
 		wantRoutes := []r{
+			{pathPrefix + moviesV1PathRoot, []string{http.MethodPost}},
+			{pathPrefix + moviesV1PathRoot + extlIDPathDir, []string{http.MethodPut}},
+			{pathPrefix + moviesV1PathRoot + extlIDPathDir, []string{http.MethodDelete}},
+			{pathPrefix + moviesV1PathRoot + extlIDPathDir, []string{http.MethodGet}},
+			{pathPrefix + moviesV1PathRoot, []string{http.MethodGet}},
 			{pathPrefix + orgsV1PathRoot, []string{http.MethodGet}},
 			{pathPrefix + orgsV1PathRoot + extlIDPathDir, []string{http.MethodGet}},
 			{pathPrefix + orgsV1PathRoot, []string{http.MethodPost}},
 			{pathPrefix + orgsV1PathRoot + extlIDPathDir, []string{http.MethodPut}},
+			{pathPrefix + orgsV1PathRoot + extlIDPathDir, []string{http.MethodDelete}},
+			{pathPrefix + orgsV1PathRoot + extlIDPathDir + parentPathDir, []string{http.MethodPut}},
+			{pathPrefix + orgsV1PathRoot + extlIDPathDir + subtreePathDir, []string{http.MethodGet}},
+			{pathPrefix + orgsV1PathRoot + extlIDPathDir + settingsPathDir, []string{http.MethodGet}},
+			{pathPrefix + orgsV1PathRoot + extlIDPathDir + settingsPathDir, []string{http.MethodPatch}},
 			{pathPrefix + appsV1PathRoot, []string{http.MethodPost}},
+			{pathPrefix + appsV1PathRoot, []string{http.MethodGet}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir, []string{http.MethodGet}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir, []string{http.MethodPut}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir, []string{http.MethodDelete}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir + apiKeysPathDir, []string{http.MethodGet}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir + apiKeysPathDir, []string{http.MethodPost}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir + apiKeysPathDir + keyExtlIDPathDir, []string{http.MethodDelete}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir + apiKeysPathDir + keyExtlIDPathDir + rollPathDir, []string{http.MethodPost}},
+			{pathPrefix + appsV1PathRoot + extlIDPathDir + usagePathDir, []string{http.MethodGet}},
+			{pathPrefix + usersV1PathRoot, []string{http.MethodPost}},
+			{pathPrefix + usersV1PathRoot, []string{http.MethodGet}},
+			{pathPrefix + usersV1PathRoot + extlIDPathDir, []string{http.MethodGet}},
+			{pathPrefix + usersV1PathRoot + extlIDPathDir, []string{http.MethodPut}},
+			{pathPrefix + usersV1PathRoot + extlIDPathDir, []string{http.MethodDelete}},
+			{pathPrefix + policiesV1PathRoot, []string{http.MethodGet}},
+			{pathPrefix + policiesV1PathRoot, []string{http.MethodPost}},
+			{pathPrefix + policiesV1PathRoot, []string{http.MethodDelete}},
+			{pathPrefix + mePermissionsV1Path, []string{http.MethodGet}},
 			{pathPrefix + loggerV1PathRoot, []string{http.MethodGet}},
 			{pathPrefix + loggerV1PathRoot, []string{http.MethodPut}},
 			{pathPrefix + pingV1PathRoot, []string{http.MethodGet}},
@@ -69,4 +98,62 @@ func TestNewMuxRouter(t *testing.T) {
 		c.Assert(gotRoutes, qt.DeepEquals, wantRoutes)
 
 	})
+
+	t.Run("policy rules", func(t *testing.T) {
+		c := qt.New(t)
+
+		rtr := NewMuxRouter()
+		s := Server{router: rtr}
+		s.registerRoutes()
+
+		e, err := casbin.NewEnforcer("../config/rbac_model.conf", "../config/rbac_policy.csv")
+		c.Assert(err, qt.IsNil)
+
+		// routes which the authorizeUserHandler middleware is not
+		// used for
+		unauthorized := map[string]bool{
+			http.MethodPost + " " + pathPrefix + usersV1PathRoot:    true,
+			http.MethodGet + " " + pathPrefix + mePermissionsV1Path: true,
+			http.MethodPost + " " + pathPrefix + "/v1/seed":         true,
+		}
+		// routes no role is granted in the default policy, they are
+		// granted at runtime through /api/v1/policies
+		notGranted := map[string]bool{
+			http.MethodGet + " " + pathPrefix + orgsV1PathRoot:                 true,
+			http.MethodGet + " " + pathPrefix + orgsV1PathRoot + extlIDPathDir: true,
+			http.MethodPost + " " + pathPrefix + orgsV1PathRoot:                true,
+			http.MethodPut + " " + pathPrefix + orgsV1PathRoot + extlIDPathDir: true,
+			http.MethodPost + " " + pathPrefix + appsV1PathRoot:                true,
+			http.MethodGet + " " + pathPrefix + pingV1PathRoot:                 true,
+		}
+
+		// every other route must have a policy rule for the action
+		// the request method is authorized as
+		err = rtr.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			pathTemplate, err := route.GetPathTemplate()
+			c.Assert(err, qt.IsNil)
+			methods, err := route.GetMethods()
+			c.Assert(err, qt.IsNil)
+
+			for _, method := range methods {
+				key := method + " " + pathTemplate
+				if unauthorized[key] || notGranted[key] {
+					continue
+				}
+
+				act := "write"
+				switch method {
+				case http.MethodGet:
+					act = "read"
+				case http.MethodDelete:
+					act = "delete"
+				}
+
+				rules := e.GetFilteredPolicy(2, pathTemplate, act)
+				c.Assert(rules, qt.Not(qt.HasLen), 0, qt.Commentf("no policy rule for %s (%s)", key, act))
+			}
+			return nil
+		})
+		c.Assert(err, qt.IsNil)
+	})
 }
@@ -34,6 +34,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -160,3 +161,45 @@ func decoderErr(err error) error {
 	}
 	return nil
 }
+
+// queryInt returns the integer value of the named query parameter,
+// zero if it is not present
+func queryInt(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errs.E(errs.Validation, errs.Parameter(name), "must be an integer")
+	}
+	return i, nil
+}
+
+// queryBool returns the boolean value of the named query parameter,
+// or false if it is not sent
+func queryBool(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errs.E(errs.Validation, errs.Parameter(name), "must be true or false")
+	}
+	return b, nil
+}
+
+// queryTime returns the RFC 3339 time value of the named query
+// parameter, or the zero time if it is not sent
+func queryTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, errs.E(errs.Validation, errs.Parameter(name), "must be an RFC 3339 time, e.g. 2021-06-01T00:00:00Z")
+	}
+	return t, nil
+}
@@ -129,3 +129,21 @@ func TestDecoderErr(t *testing.T) {
 		c.Assert(err != nil, qt.Equals, true)
 	})
 }
+
+func TestQueryInt(t *testing.T) {
+	c := qt.New(t)
+
+	r, err := http.NewRequest(http.MethodGet, "/fake?limit=25&offset=abc", nil)
+	c.Assert(err, qt.IsNil)
+
+	got, err := queryInt(r, "limit")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, 25)
+
+	got, err = queryInt(r, "missing")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, 0)
+
+	_, err = queryInt(r, "offset")
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
@@ -8,30 +8,34 @@ import (
 
 	"github.com/gilcrest/go-api-basic/domain/app"
 	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/org"
+	"github.com/gilcrest/go-api-basic/domain/ratelimit"
+	"github.com/gilcrest/go-api-basic/domain/usage"
 	"github.com/gilcrest/go-api-basic/domain/user"
+	"github.com/gilcrest/go-api-basic/gateway/authgateway"
 	"github.com/gilcrest/go-api-basic/service"
 )
 
-//// CreateMovieService creates a Movie
-//type CreateMovieService interface {
-//	Create(ctx context.Context, r *service.CreateMovieRequest, u user.User) (service.MovieResponse, error)
-//}
-//
-//// UpdateMovieService is a service for updating a Movie
-//type UpdateMovieService interface {
-//	Update(ctx context.Context, r *service.UpdateMovieRequest, u user.User) (service.MovieResponse, error)
-//}
-//
-//// DeleteMovieService is a service for deleting a Movie
-//type DeleteMovieService interface {
-//	Delete(ctx context.Context, extlID string) (service.DeleteMovieResponse, error)
-//}
-//
-//// FindMovieService interface reads a Movie form the database
-//type FindMovieService interface {
-//	FindMovieByID(ctx context.Context, extlID string) (service.MovieResponse, error)
-//	FindAllMovies(ctx context.Context) ([]service.MovieResponse, error)
-//}
+// CreateMovieService creates a Movie
+type CreateMovieService interface {
+	Create(ctx context.Context, r *service.CreateMovieRequest, adt audit.Audit) (service.MovieResponse, error)
+}
+
+// UpdateMovieService is a service for updating a Movie
+type UpdateMovieService interface {
+	Update(ctx context.Context, r *service.UpdateMovieRequest, adt audit.Audit) (service.MovieResponse, error)
+}
+
+// DeleteMovieService is a service for deleting a Movie
+type DeleteMovieService interface {
+	Delete(ctx context.Context, extlID string) (service.DeleteMovieResponse, error)
+}
+
+// FindMovieService interface reads a Movie form the database
+type FindMovieService interface {
+	FindMovieByID(ctx context.Context, extlID string) (service.MovieResponse, error)
+	FindAllMovies(ctx context.Context) ([]service.MovieResponse, error)
+}
 
 // CreateOrgService creates an Org
 type CreateOrgService interface {
@@ -43,10 +47,27 @@ type UpdateOrgService interface {
 	Update(ctx context.Context, r *service.UpdateOrgRequest, adt audit.Audit) (service.OrgResponse, error)
 }
 
+// DeleteOrgService deletes or archives an Org
+type DeleteOrgService interface {
+	Delete(ctx context.Context, r *service.DeleteOrgRequest, adt audit.Audit) (service.DeleteOrgResponse, error)
+}
+
+// MoveOrgService moves an Org within the Org hierarchy
+type MoveOrgService interface {
+	Move(ctx context.Context, r *service.MoveOrgRequest, adt audit.Audit) (service.OrgResponse, error)
+}
+
+// OrgSettingsService reads and patches the Settings of an Org
+type OrgSettingsService interface {
+	Find(ctx context.Context, extlID string, adt audit.Audit) (service.OrgSettingsResponse, error)
+	Patch(ctx context.Context, r *service.PatchOrgSettingsRequest, adt audit.Audit) (service.OrgSettingsResponse, error)
+}
+
 // FindOrgService retrieves Org information from the datastore
 type FindOrgService interface {
-	FindAll(ctx context.Context) ([]service.OrgResponse, error)
-	FindByExternalID(ctx context.Context, extlID string) (service.OrgResponse, error)
+	FindAll(ctx context.Context, r service.ListRequest, adt audit.Audit) (service.OrgListResponse, error)
+	FindByExternalID(ctx context.Context, extlID string, adt audit.Audit) (service.OrgResponse, error)
+	FindSubtree(ctx context.Context, extlID string, adt audit.Audit) ([]service.OrgTreeResponse, error)
 }
 
 // CreateAppService creates an App
@@ -54,6 +75,36 @@ type CreateAppService interface {
 	Create(ctx context.Context, r *service.CreateAppRequest, adt audit.Audit) (service.AppResponse, error)
 }
 
+// FindOrgAppService retrieves the Apps of the caller's Org
+type FindOrgAppService interface {
+	FindAll(ctx context.Context, r service.ListRequest, adt audit.Audit) (service.AppListResponse, error)
+	FindByExternalID(ctx context.Context, extlID string, adt audit.Audit) (service.AppResponse, error)
+}
+
+// UpdateAppService updates an App
+type UpdateAppService interface {
+	Update(ctx context.Context, r *service.UpdateAppRequest, adt audit.Audit) (service.AppResponse, error)
+}
+
+// DeleteAppService deletes an App
+type DeleteAppService interface {
+	Delete(ctx context.Context, extlID string, adt audit.Audit) (service.AppResponse, error)
+}
+
+// APIKeyService lists, creates, revokes and rolls the API keys of
+// an App
+type APIKeyService interface {
+	FindAll(ctx context.Context, appExtlID string, adt audit.Audit) ([]service.APIKeyResponse, error)
+	Create(ctx context.Context, r *service.CreateAPIKeyRequest, adt audit.Audit) (service.APIKeyResponse, error)
+	Revoke(ctx context.Context, appExtlID, keyExtlID string, adt audit.Audit) (service.APIKeyResponse, error)
+	Roll(ctx context.Context, r *service.RollAPIKeyRequest, adt audit.Audit) (service.RollAPIKeyResponse, error)
+}
+
+// FindAppUsageService reports the usage of an App
+type FindAppUsageService interface {
+	Find(ctx context.Context, r service.FindAppUsageRequest, adt audit.Audit) (service.AppUsageResponse, error)
+}
+
 // FindAppService retrieves an App
 type FindAppService interface {
 	// FindAppByAPIKey finds an app given its External ID and determines
@@ -64,6 +115,30 @@ type FindAppService interface {
 // FindUserService retrieves a User
 type FindUserService interface {
 	FindUserByOauth2Token(ctx context.Context, params service.FindUserParams) (user.User, error)
+	// FindUserinfo retrieves the identity from the provider only,
+	// the identity need not be registered as a User
+	FindUserinfo(ctx context.Context, params service.FindUserParams) (authgateway.Userinfo, error)
+}
+
+// RegisterUserService registers a provider identity as a User
+type RegisterUserService interface {
+	Register(ctx context.Context, r *service.RegisterUserRequest, a app.App, uInfo authgateway.Userinfo) (service.UserResponse, error)
+}
+
+// FindOrgUserService retrieves the Users of an Org
+type FindOrgUserService interface {
+	FindAll(ctx context.Context, o org.Org, r service.ListRequest) (service.UserListResponse, error)
+	FindByExternalID(ctx context.Context, o org.Org, extlID string) (service.UserResponse, error)
+}
+
+// UpdateUserService updates a User
+type UpdateUserService interface {
+	Update(ctx context.Context, r *service.UpdateUserRequest, adt audit.Audit) (service.UserResponse, error)
+}
+
+// DeactivateUserService deactivates a User
+type DeactivateUserService interface {
+	Deactivate(ctx context.Context, extlID string, adt audit.Audit) (service.UserResponse, error)
 }
 
 // AuthorizeService determines whether an app and a user can perform
@@ -72,6 +147,29 @@ type AuthorizeService interface {
 	Authorize(lgr zerolog.Logger, r *http.Request, sub audit.Audit) error
 }
 
+// RateLimitService limits the rate of requests of an App and User
+type RateLimitService interface {
+	Allow(ctx context.Context, a app.App, u user.User) (ratelimit.Result, error)
+}
+
+// UsageMeter records the requests of Apps
+type UsageMeter interface {
+	Record(r usage.Record)
+}
+
+// QuotaService checks the monthly quota of an App
+type QuotaService interface {
+	Check(ctx context.Context, a app.App) error
+}
+
+// PolicyService reads and changes the authorization policy
+type PolicyService interface {
+	FindAll(o org.Org) []service.PolicyResponse
+	Add(ctx context.Context, o org.Org, r *service.PolicyRequest) (service.PolicyResponse, error)
+	Remove(ctx context.Context, o org.Org, r *service.PolicyRequest) (service.PolicyResponse, error)
+	FindPermissions(adt audit.Audit) (service.PermissionsResponse, error)
+}
+
 // LoggerService reads and updates the logger state
 type LoggerService interface {
 	Read() service.LoggerResponse
@@ -90,14 +188,34 @@ type SeedService interface {
 
 // Services are used by the application service handlers
 type Services struct {
-	SeedService      SeedService
-	PingService      PingService
-	LoggerService    LoggerService
-	CreateOrgService CreateOrgService
-	UpdateOrgService UpdateOrgService
-	FindOrgService   FindOrgService
-	CreateAppService CreateAppService
-	FindAppService   FindAppService
-	FindUserService  FindUserService
-	AuthorizeService AuthorizeService
+	CreateMovieService    CreateMovieService
+	UpdateMovieService    UpdateMovieService
+	DeleteMovieService    DeleteMovieService
+	FindMovieService      FindMovieService
+	SeedService           SeedService
+	PingService           PingService
+	LoggerService         LoggerService
+	CreateOrgService      CreateOrgService
+	UpdateOrgService      UpdateOrgService
+	DeleteOrgService      DeleteOrgService
+	MoveOrgService        MoveOrgService
+	OrgSettingsService    OrgSettingsService
+	FindOrgService        FindOrgService
+	CreateAppService      CreateAppService
+	FindOrgAppService     FindOrgAppService
+	UpdateAppService      UpdateAppService
+	DeleteAppService      DeleteAppService
+	FindAppService        FindAppService
+	APIKeyService         APIKeyService
+	FindAppUsageService   FindAppUsageService
+	FindUserService       FindUserService
+	RegisterUserService   RegisterUserService
+	FindOrgUserService    FindOrgUserService
+	UpdateUserService     UpdateUserService
+	DeactivateUserService DeactivateUserService
+	AuthorizeService      AuthorizeService
+	PolicyService         PolicyService
+	RateLimitService      RateLimitService
+	UsageMeter            UsageMeter
+	QuotaService          QuotaService
 }
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"database/sql"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,64 +24,168 @@ import (
 type CreateAppRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// Scopes limit the requests the App can make, e.g. orgs:read.
+	// If not sent, the App is given the scopes of the calling App.
+	Scopes []string `json:"scopes"`
+	// ServiceAccount allows the App to authenticate without a User
+	// on routes which allow it
+	ServiceAccount bool `json:"service_account"`
+	// RateLimit is the number of requests per minute allowed for the
+	// App. If zero or not sent, the server default applies.
+	RateLimit int `json:"rate_limit"`
+	// MonthlyQuota is the number of requests per calendar month (UTC)
+	// allowed for the App. If zero or not sent, the server default
+	// applies.
+	MonthlyQuota int64 `json:"monthly_quota"`
 }
 
-// AppResponse is the response struct for an App
+// AppResponse is the response struct for an App. API keys are only
+// sent when the App is created.
 type AppResponse struct {
-	ExternalID  string           `json:"external_id"`
-	Name        string           `json:"name"`
-	Description string           `json:"description"`
-	APIKeys     []APIKeyResponse `json:"api_keys"`
+	ExternalID     string           `json:"external_id"`
+	Name           string           `json:"name"`
+	Description    string           `json:"description"`
+	Active         bool             `json:"active"`
+	Scopes         []string         `json:"scopes"`
+	ServiceAccount bool             `json:"service_account"`
+	RateLimit      int              `json:"rate_limit"`
+	MonthlyQuota   int64            `json:"monthly_quota"`
+	CreateAudit    auditResponse    `json:"create_audit"`
+	UpdateAudit    auditResponse    `json:"update_audit"`
+	APIKeys        []APIKeyResponse `json:"api_keys,omitempty"`
 }
 
 // APIKeyResponse is the response fields for an API key
 type APIKeyResponse struct {
-	Key              string `json:"key"`
-	DeactivationDate string `json:"deactivation_date"`
+	ExternalID       string        `json:"external_id"`
+	Key              string        `json:"key"`
+	DeactivationDate string        `json:"deactivation_date"`
+	CreateAudit      auditResponse `json:"create_audit"`
+	UpdateAudit      auditResponse `json:"update_audit"`
 }
 
 // newAPIKeyResponse initializes an APIKeyResponse. The app.APIKey is
-// decrypted and set to the Key field as part of initialization.
-func newAPIKeyResponse(key app.APIKey) APIKeyResponse {
-	return APIKeyResponse{Key: key.Key(), DeactivationDate: key.DeactivationDate().String()}
+// masked as part of initialization, the full key should only be sent
+// when it is created.
+func newAPIKeyResponse(key app.APIKey, ca, ua audit.Audit) APIKeyResponse {
+	return APIKeyResponse{
+		ExternalID:       key.ExternalID().String(),
+		Key:              key.Masked(),
+		DeactivationDate: key.DeactivationDate().Format(time.RFC3339),
+		CreateAudit:      newAuditResponse(ca),
+		UpdateAudit:      newAuditResponse(ua),
+	}
 }
 
-// newAppResponse initializes an AppResponse given an app.App
-func newAppResponse(a app.App) AppResponse {
+// newAppResponse initializes an AppResponse given a newly created
+// app.App. The API keys were just created, so they are sent in full.
+func newAppResponse(a app.App, adt audit.Audit) AppResponse {
 	var keys []APIKeyResponse
 	for _, key := range a.APIKeys {
-		akr := newAPIKeyResponse(key)
+		akr := newAPIKeyResponse(key, adt, adt)
+		akr.Key = key.Key()
 		keys = append(keys, akr)
 	}
 	return AppResponse{
-		ExternalID:  a.ExternalID.String(),
-		Name:        a.Name,
-		Description: a.Description,
-		APIKeys:     keys,
+		ExternalID:     a.ExternalID.String(),
+		Name:           a.Name,
+		Description:    a.Description,
+		Active:         a.Active,
+		Scopes:         a.Scopes.Strings(),
+		ServiceAccount: a.ServiceAccount,
+		RateLimit:      a.RateLimit,
+		MonthlyQuota:   a.MonthlyQuota,
+		CreateAudit:    newAuditResponse(adt),
+		UpdateAudit:    newAuditResponse(adt),
+		APIKeys:        keys,
+	}
+}
+
+// newAppResponseFromDB initializes an AppResponse given an app.App
+// retrieved from the datastore. app.App does not embed create/update
+// App and User, so these are retrieved from the datastore as well,
+// through the auditLoader. API keys are not sent.
+func newAppResponseFromDB(ctx context.Context, l *auditLoader, a app.App) (AppResponse, error) {
+	// queue the update audit so both audits are found together
+	l.add(a.UpdateAppID, a.UpdateUserID)
+	ca, err := l.audit(ctx, a.CreateAppID, a.CreateUserID, a.CreateTime)
+	if err != nil {
+		return AppResponse{}, err
 	}
+	ua, err := l.audit(ctx, a.UpdateAppID, a.UpdateUserID, a.UpdateTime)
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	return AppResponse{
+		ExternalID:     a.ExternalID.String(),
+		Name:           a.Name,
+		Description:    a.Description,
+		Active:         a.Active,
+		Scopes:         a.Scopes.Strings(),
+		ServiceAccount: a.ServiceAccount,
+		RateLimit:      a.RateLimit,
+		MonthlyQuota:   a.MonthlyQuota,
+		CreateAudit:    newAuditResponse(ca),
+		UpdateAudit:    newAuditResponse(ua),
+	}, nil
 }
 
 // CreateAppService is a service for creating an App
 type CreateAppService struct {
 	Datastorer            Datastorer
 	CryptoRandomGenerator CryptoRandomGenerator
-	EncryptionKey         *[32]byte
+	// APIKeySecret is the server secret API keys are hashed with
+	APIKeySecret *[32]byte
+	// KMS wraps the data key each new API key is hashed with, if nil
+	// new API keys are hashed with APIKeySecret
+	KMS secure.KMS
+	// SettingsCache is optional, if nil the Org settings are read
+	// from the datastore on every call
+	SettingsCache SettingsCache
 }
 
 // Create is used to create an App
 func (cas CreateAppService) Create(ctx context.Context, r *CreateAppRequest, adt audit.Audit) (AppResponse, error) {
+	err := validLimits(r.RateLimit, r.MonthlyQuota)
+	if err != nil {
+		return AppResponse{}, err
+	}
+	scopes := adt.App.Scopes
+	if r.Scopes != nil {
+		scopes, err = grantScopes(r.Scopes, adt)
+		if err != nil {
+			return AppResponse{}, err
+		}
+	}
+
 	var a app.App
 	a.ID = uuid.New()
 	a.ExternalID = secure.NewID()
 	a.Org = adt.App.Org
 	a.Name = r.Name
 	a.Description = r.Description
+	a.Active = true
+	a.Scopes = scopes
+	a.ServiceAccount = r.ServiceAccount
+	a.RateLimit = r.RateLimit
+	a.MonthlyQuota = r.MonthlyQuota
 
-	aak, err := app.NewAPIKey(cas.CryptoRandomGenerator, cas.EncryptionKey)
+	aak, err := app.NewAPIKey(ctx, cas.CryptoRandomGenerator, cas.APIKeySecret, cas.KMS)
+	if err != nil {
+		return AppResponse{}, err
+	}
+	// the initial key is usable for the Org's default API key
+	// lifetime, if it has one
+	settings, err := findOrgSettings(ctx, cas.Datastorer.Pool(), cas.SettingsCache, a.Org.ID)
 	if err != nil {
 		return AppResponse{}, err
 	}
-	aak.SetDeactivationDate(time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC))
+	deactv := time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+	if lifetime := settings.APIKeyLifetime(0); lifetime > 0 {
+		deactv = adt.Moment.Add(lifetime)
+	}
+	aak.SetDeactivationDate(deactv)
 
 	var keys []app.APIKey
 	keys = append(keys, aak)
@@ -109,7 +215,7 @@ func (cas CreateAppService) Create(ctx context.Context, r *CreateAppRequest, adt
 		return AppResponse{}, err
 	}
 
-	return newAppResponse(a), nil
+	return newAppResponse(a, adt), nil
 }
 
 // NewCreateAppParams maps an App to appstore.CreateAppParams
@@ -127,13 +233,55 @@ func NewCreateAppParams(a app.App, adt audit.Audit) appstore.CreateAppParams {
 		UpdateAppID:     adt.App.ID,
 		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
 		UpdateTimestamp: adt.Moment,
+		Scopes:          a.Scopes.Strings(),
+		ServiceAccount:  a.ServiceAccount,
+		RateLimit:       datastore.NewNullInt32(int32(a.RateLimit)),
+		MonthlyQuota:    datastore.NewNullInt64(a.MonthlyQuota),
+	}
+}
+
+// validLimits validates the rate limit and monthly quota requested
+// for an App
+func validLimits(rateLimit int, monthlyQuota int64) error {
+	if rateLimit < 0 || rateLimit > math.MaxInt32 {
+		return errs.E(errs.Validation, errs.Parameter("rate_limit"), "rate_limit must be zero (the server default) or a positive number of requests per minute")
+	}
+	if monthlyQuota < 0 {
+		return errs.E(errs.Validation, errs.Parameter("monthly_quota"), "monthly_quota must be zero (the server default) or a positive number of requests per month")
 	}
+	return nil
+}
+
+// grantScopes parses the scopes requested for an App. An App can
+// only give out scopes it holds itself.
+func grantScopes(ss []string, adt audit.Audit) (app.Scopes, error) {
+	scopes, err := app.ParseScopes(ss)
+	if err != nil {
+		return nil, err
+	}
+	if !adt.App.Scopes.Includes(scopes) {
+		return nil, errs.E(errs.Validation, errs.Parameter("scopes"), "an app cannot grant scopes it does not have")
+	}
+	return scopes, nil
+}
+
+// newScopesFromDB initializes app.Scopes given the scopes stored for
+// an App. Malformed scopes never allow a request.
+func newScopesFromDB(ss []string) app.Scopes {
+	scopes := make(app.Scopes, 0, len(ss))
+	for _, s := range ss {
+		scopes = append(scopes, app.Scope(s))
+	}
+	return scopes
 }
 
 // NewCreateAppAPIKeyParams maps an AppAPIKey to appstore.CreateAppAPIKeyParams
 func NewCreateAppAPIKeyParams(a app.App, k app.APIKey, adt audit.Audit) appstore.CreateAppAPIKeyParams {
 	return appstore.CreateAppAPIKeyParams{
-		ApiKey:          k.Ciphertext(),
+		ApiKeyExtlID:    k.ExternalID().String(),
+		ApiKeyPrefix:    datastore.NewNullString(k.Prefix()),
+		ApiKeyHash:      datastore.NewNullString(k.Hash()),
+		ApiKeyDataKey:   datastore.NewNullString(k.DataKey()),
 		AppID:           a.ID,
 		DeactvDate:      k.DeactivationDate(),
 		CreateAppID:     adt.App.ID,
@@ -145,23 +293,298 @@ func NewCreateAppAPIKeyParams(a app.App, k app.APIKey, adt audit.Audit) appstore
 	}
 }
 
+// newUpdateAppParams maps an App to appstore.UpdateAppParams
+func newUpdateAppParams(a app.App, adt audit.Audit) appstore.UpdateAppParams {
+	return appstore.UpdateAppParams{
+		AppName:         a.Name,
+		AppDescription:  a.Description,
+		Active:          sql.NullBool{Bool: a.Active, Valid: true},
+		Scopes:          a.Scopes.Strings(),
+		ServiceAccount:  a.ServiceAccount,
+		RateLimit:       datastore.NewNullInt32(int32(a.RateLimit)),
+		MonthlyQuota:    datastore.NewNullInt64(a.MonthlyQuota),
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+		AppID:           a.ID,
+	}
+}
+
+// UpdateAppRequest is the request struct for Updating an App
+type UpdateAppRequest struct {
+	ExternalID  string
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Scopes replace the scopes of the App, if sent
+	Scopes         []string `json:"scopes"`
+	ServiceAccount bool     `json:"service_account"`
+	RateLimit      int      `json:"rate_limit"`
+	MonthlyQuota   int64    `json:"monthly_quota"`
+}
+
+// UpdateAppService is a service for updating an App of the caller's
+// Org
+type UpdateAppService struct {
+	Datastorer Datastorer
+}
+
+// Update is used to update the name and description of an App
+func (uas UpdateAppService) Update(ctx context.Context, r *UpdateAppRequest, adt audit.Audit) (AppResponse, error) {
+	if strings.TrimSpace(r.Name) == "" {
+		return AppResponse{}, errs.E(errs.Validation, errs.Parameter("name"), "name is required")
+	}
+	err := validLimits(r.RateLimit, r.MonthlyQuota)
+	if err != nil {
+		return AppResponse{}, err
+	}
+	var scopes app.Scopes
+	if r.Scopes != nil {
+		scopes, err = grantScopes(r.Scopes, adt)
+		if err != nil {
+			return AppResponse{}, err
+		}
+	}
+
+	// start db txn using pgxpool
+	tx, err := uas.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	// retrieve existing App
+	a, err := findOrgAppByExternalID(ctx, tx, r.ExternalID, adt)
+	if err != nil {
+		return AppResponse{}, uas.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	if !a.Active {
+		return AppResponse{}, uas.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "app has been deleted"))
+	}
+
+	// override fields with data from request
+	a.Name = r.Name
+	a.Description = r.Description
+	a.ServiceAccount = r.ServiceAccount
+	a.RateLimit = r.RateLimit
+	a.MonthlyQuota = r.MonthlyQuota
+	if r.Scopes != nil {
+		a.Scopes = scopes
+	}
+
+	err = appstore.New(tx).UpdateApp(ctx, newUpdateAppParams(a, adt))
+	if err != nil {
+		return AppResponse{}, errs.E(errs.Database, uas.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	return commitAppChange(ctx, uas.Datastorer, tx, a.ID)
+}
+
+// DeleteAppService is a service for deleting an App of the caller's
+// Org
+type DeleteAppService struct {
+	Datastorer Datastorer
+}
+
+// Delete marks an App as inactive and deactivates all of its API
+// keys immediately. The App is not removed from the datastore, as
+// it is referenced by the audit columns of the data it created.
+func (das DeleteAppService) Delete(ctx context.Context, extlID string, adt audit.Audit) (AppResponse, error) {
+	// start db txn using pgxpool
+	tx, err := das.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	// retrieve existing App
+	a, err := findOrgAppByExternalID(ctx, tx, extlID, adt)
+	if err != nil {
+		return AppResponse{}, das.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	if a.ID == adt.App.ID {
+		return AppResponse{}, das.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "apps cannot delete themselves"))
+	}
+
+	a.Active = false
+	err = appstore.New(tx).UpdateApp(ctx, newUpdateAppParams(a, adt))
+	if err != nil {
+		return AppResponse{}, errs.E(errs.Database, das.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	err = appstore.New(tx).DeactivateAppAPIKeys(ctx, appstore.DeactivateAppAPIKeysParams{
+		DeactvDate:      adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+		AppID:           a.ID,
+	})
+	if err != nil {
+		return AppResponse{}, errs.E(errs.Database, das.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	return commitAppChange(ctx, das.Datastorer, tx, a.ID)
+}
+
+// commitAppChange reads back the changed App and commits the
+// transaction
+func commitAppChange(ctx context.Context, ds Datastorer, tx pgx.Tx, id uuid.UUID) (AppResponse, error) {
+	a, err := findAppByID(ctx, tx, id)
+	if err != nil {
+		return AppResponse{}, ds.RollbackTx(ctx, tx, err)
+	}
+
+	ar, err := newAppResponseFromDB(ctx, newAuditLoader(tx), a)
+	if err != nil {
+		return AppResponse{}, ds.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = ds.CommitTx(ctx, tx)
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	return ar, nil
+}
+
+// FindOrgAppService is a service for reading the Apps of the
+// caller's Org
+type FindOrgAppService struct {
+	Datastorer Datastorer
+}
+
+// AppListResponse is the response struct for a page of Apps
+type AppListResponse struct {
+	Data []AppResponse `json:"data"`
+	PageCursors
+}
+
+// FindAll is used to page through the Apps of the caller's Org,
+// sorted by name (the default) or created
+func (fas FindOrgAppService) FindAll(ctx context.Context, r ListRequest, adt audit.Audit) (AppListResponse, error) {
+	p, err := newListPage(r, nameSort, createdSort)
+	if err != nil {
+		return AppListResponse{}, err
+	}
+
+	dbtx := fas.Datastorer.Pool()
+	q := appstore.New(dbtx)
+
+	var rows []appstore.App
+	switch p.field {
+	case createdSort:
+		params := appstore.FindAppsPageByCreateTimestampParams{
+			OrgID:                 adt.App.Org.ID,
+			NamePattern:           p.namePattern,
+			CreatedAfter:          p.createdAfter,
+			CursorExtlID:          p.cursorExtlID(),
+			CursorCreateTimestamp: p.cursorTime,
+			RowLimit:              p.rowLimit(),
+		}
+		if p.descending() {
+			rows, err = q.FindAppsPageByCreateTimestampDesc(ctx, appstore.FindAppsPageByCreateTimestampDescParams(params))
+		} else {
+			rows, err = q.FindAppsPageByCreateTimestamp(ctx, params)
+		}
+	default:
+		params := appstore.FindAppsPageByNameParams{
+			OrgID:        adt.App.Org.ID,
+			NamePattern:  p.namePattern,
+			CreatedAfter: p.createdAfter,
+			CursorExtlID: p.cursorExtlID(),
+			CursorName:   p.cursorKey(),
+			RowLimit:     p.rowLimit(),
+		}
+		if p.descending() {
+			rows, err = q.FindAppsPageByNameDesc(ctx, appstore.FindAppsPageByNameDescParams(params))
+		} else {
+			rows, err = q.FindAppsPageByName(ctx, params)
+		}
+	}
+	if err != nil {
+		return AppListResponse{}, errs.E(errs.Database, err)
+	}
+
+	n, more := p.trim(rows)
+	rows = rows[:n]
+
+	response := AppListResponse{
+		PageCursors: p.cursors(n, more, func(i int) (string, string) {
+			return p.sortKey(rows[i].AppName, rows[i].CreateTimestamp), rows[i].AppExtlID
+		}),
+	}
+	response.Data, err = newAppResponses(ctx, dbtx, rows, adt.App.Org)
+	if err != nil {
+		return AppListResponse{}, err
+	}
+
+	return response, nil
+}
+
+// newAppResponses initializes an AppResponse for each appstore.App
+// of the Org. The audits of all the Apps are found together.
+func newAppResponses(ctx context.Context, dbtx DBTX, rows []appstore.App, o org.Org) ([]AppResponse, error) {
+	l := newAuditLoader(dbtx)
+	apps := make([]app.App, 0, len(rows))
+	for _, row := range rows {
+		a, err := newAppFromDB(row, o)
+		if err != nil {
+			return nil, err
+		}
+		l.add(a.CreateAppID, a.CreateUserID)
+		l.add(a.UpdateAppID, a.UpdateUserID)
+		apps = append(apps, a)
+	}
+
+	response := make([]AppResponse, 0, len(apps))
+	for _, a := range apps {
+		ar, err := newAppResponseFromDB(ctx, l, a)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, ar)
+	}
+
+	return response, nil
+}
+
+// FindByExternalID is used to find an App of the caller's Org by
+// its External ID
+func (fas FindOrgAppService) FindByExternalID(ctx context.Context, extlID string, adt audit.Audit) (AppResponse, error) {
+	dbtx := fas.Datastorer.Pool()
+
+	a, err := findOrgAppByExternalID(ctx, dbtx, extlID, adt)
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	return newAppResponseFromDB(ctx, newAuditLoader(dbtx), a)
+}
+
 // FindAppService is a service for retrieving an App from the datastore
 type FindAppService struct {
-	Datastorer    Datastorer
-	EncryptionKey *[32]byte
+	Datastorer Datastorer
+	// APIKeySecret is the server secret API keys are hashed with
+	APIKeySecret *[32]byte
+	// KMS unwraps the data keys API keys are hashed with
+	KMS secure.KMS
 }
 
 // FindAppByAPIKey finds an app given its External ID and determines
 // if the given API key is a valid key for it. It is used as part of
-// app authentication
+// app authentication. Only the keys sharing the prefix of the given
+// key are retrieved, their hashes are compared in constant time. The
+// ancestors of the App's Org are found along with the App.
 func (fas FindAppService) FindAppByAPIKey(ctx context.Context, realm, appExtlID, apiKey string) (app.App, error) {
 
 	var (
-		kr  []appstore.FindAppAPIKeysByAppExtlIDRow
+		kr  []appstore.FindAppAPIKeysByPrefixRow
 		err error
 	)
 
-	kr, err = appstore.New(fas.Datastorer.Pool()).FindAppAPIKeysByAppExtlID(ctx, appExtlID)
+	params := appstore.FindAppAPIKeysByPrefixParams{
+		AppExtlID:    appExtlID,
+		ApiKeyPrefix: datastore.NewNullString(app.APIKeyPrefix(apiKey)),
+	}
+	kr, err = appstore.New(fas.Datastorer.Pool()).FindAppAPIKeysByPrefix(ctx, params)
 	if err != nil {
 		return app.App{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
 	}
@@ -172,37 +595,54 @@ func (fas FindAppService) FindAppByAPIKey(ctx context.Context, realm, appExtlID,
 	)
 	for i, row := range kr {
 		if i == 0 { // only need to fill the app struct on first iteration
-			var extl secure.Identifier
-			extl, err = secure.ParseIdentifier(row.OrgExtlID)
+			var appExtl, orgExtl secure.Identifier
+			appExtl, err = secure.ParseIdentifier(row.AppExtlID)
+			if err != nil {
+				return app.App{}, err
+			}
+			orgExtl, err = secure.ParseIdentifier(row.OrgExtlID)
 			if err != nil {
 				return app.App{}, err
 			}
 			a.ID = row.AppID
-			a.ExternalID = extl
+			a.ExternalID = appExtl
 			a.Org = org.Org{
 				ID:          row.OrgID,
-				ExternalID:  extl,
+				ExternalID:  orgExtl,
 				Name:        row.OrgName,
 				Description: row.OrgDescription,
+				ParentID:    row.ParentOrgID.UUID,
 			}
 			a.Name = row.AppName
 			a.Description = row.AppDescription
+			// only active apps have keys returned
+			a.Active = true
+			a.Scopes = newScopesFromDB(row.Scopes)
+			a.ServiceAccount = row.ServiceAccount
+			a.RateLimit = int(row.RateLimit.Int32)
+			a.MonthlyQuota = row.MonthlyQuota.Int64
 		}
-		var key app.APIKey
-		key, err = app.NewAPIKeyFromCipher(row.ApiKey, fas.EncryptionKey)
-		if err != nil {
-			return app.App{}, err
-		}
+		key := app.NewAPIKeyFromHash(params.ApiKeyPrefix.String, row.ApiKeyHash.String)
+		key.SetDataKey(row.ApiKeyDataKey.String)
 		key.SetDeactivationDate(row.DeactvDate)
 		keys = append(keys, key)
 	}
 	a.APIKeys = keys
 
-	err = a.ValidKey(realm, apiKey)
+	err = a.ValidKey(ctx, realm, apiKey, fas.APIKeySecret, fas.KMS)
 	if err != nil {
 		return app.App{}, err
 	}
 
+	// roles granted in an ancestor of the App's Org apply within it,
+	// the ancestors are only found for sub-organizations
+	if a.Org.ParentID != uuid.Nil {
+		a.Org.Ancestors, err = findOrgAncestors(ctx, fas.Datastorer.Pool(), a.Org.ID)
+		if err != nil {
+			return app.App{}, err
+		}
+	}
+
 	return a, nil
 }
 
@@ -224,25 +664,36 @@ func findAppByID(ctx context.Context, dbtx DBTX, id uuid.UUID) (app.App, error)
 	if err != nil {
 		return app.App{}, err
 	}
-	var extl secure.Identifier
-	extl, err = secure.ParseIdentifier(dba.AppExtlID)
+
+	return newAppFromDB(dba, o)
+}
+
+// newAppFromDB initializes an app.App of the Org given an
+// appstore.App. API keys are not set.
+func newAppFromDB(dba appstore.App, o org.Org) (app.App, error) {
+	extl, err := secure.ParseIdentifier(dba.AppExtlID)
 	if err != nil {
 		return app.App{}, err
 	}
 
 	a := app.App{
-		ID:           dba.AppID,
-		ExternalID:   extl,
-		Org:          o,
-		Name:         dba.AppName,
-		Description:  dba.AppDescription,
-		CreateAppID:  dba.CreateAppID,
-		CreateUserID: dba.CreateUserID.UUID,
-		CreateTime:   dba.CreateTimestamp,
-		UpdateAppID:  dba.UpdateAppID,
-		UpdateUserID: dba.UpdateUserID.UUID,
-		UpdateTime:   dba.UpdateTimestamp,
-		APIKeys:      nil,
+		ID:             dba.AppID,
+		ExternalID:     extl,
+		Org:            o,
+		Name:           dba.AppName,
+		Description:    dba.AppDescription,
+		Active:         dba.Active.Bool,
+		Scopes:         newScopesFromDB(dba.Scopes),
+		ServiceAccount: dba.ServiceAccount,
+		RateLimit:      int(dba.RateLimit.Int32),
+		MonthlyQuota:   dba.MonthlyQuota.Int64,
+		CreateAppID:    dba.CreateAppID,
+		CreateUserID:   dba.CreateUserID.UUID,
+		CreateTime:     dba.CreateTimestamp,
+		UpdateAppID:    dba.UpdateAppID,
+		UpdateUserID:   dba.UpdateUserID.UUID,
+		UpdateTime:     dba.UpdateTimestamp,
+		APIKeys:        nil,
 	}
 
 	return a, nil
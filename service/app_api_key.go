@@ -0,0 +1,565 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore"
+	"github.com/gilcrest/go-api-basic/datastore/appstore"
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+// DefaultAPIKeyOverlap is the time a rolled API key remains usable
+// after its replacement is created, if no overlap is requested
+const DefaultAPIKeyOverlap = 24 * time.Hour
+
+// CreateAPIKeyRequest is the request struct for minting a new API
+// key for an App
+type CreateAPIKeyRequest struct {
+	// AppExternalID is the external ID of the App, taken from the path
+	AppExternalID string `json:"-"`
+	// DeactivationDate is the time (RFC3339) the key is no longer
+	// usable, optional if the Org has a default API key lifetime
+	DeactivationDate string `json:"deactivation_date"`
+}
+
+// RollAPIKeyRequest is the request struct for replacing an App's
+// API key with a new one
+type RollAPIKeyRequest struct {
+	// AppExternalID is the external ID of the App, taken from the path
+	AppExternalID string `json:"-"`
+	// KeyExternalID is the external ID of the key being replaced,
+	// taken from the path
+	KeyExternalID string `json:"-"`
+	// DeactivationDate is the time (RFC3339) the new key is no
+	// longer usable, optional if the Org has a default API key
+	// lifetime
+	DeactivationDate string `json:"deactivation_date"`
+	// Overlap is how long (e.g. 1h30m) the replaced key remains
+	// usable, DefaultAPIKeyOverlap if not sent
+	Overlap string `json:"overlap"`
+}
+
+// RollAPIKeyResponse is the response struct for a rolled API key
+type RollAPIKeyResponse struct {
+	NewKey APIKeyResponse `json:"new_key"`
+	OldKey APIKeyResponse `json:"old_key"`
+}
+
+// parseDeactivationDate parses the requested deactivation date of a
+// new API key, which must be in the future. If no date is requested,
+// the key is usable for the lifetime, a date is required if the
+// lifetime is zero.
+func parseDeactivationDate(s string, now time.Time, lifetime time.Duration) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		if lifetime > 0 {
+			return now.Add(lifetime), nil
+		}
+		return time.Time{}, errs.E(errs.Validation, errs.Parameter("deactivation_date"), "deactivation_date is required")
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errs.E(errs.Validation, errs.Parameter("deactivation_date"), "deactivation_date must be in RFC3339 format")
+	}
+	if !t.After(now) {
+		return time.Time{}, errs.E(errs.Validation, errs.Parameter("deactivation_date"), "deactivation_date must be in the future")
+	}
+	return t, nil
+}
+
+// parseOverlap parses the requested overlap window of a rolled key
+func parseOverlap(s string) (time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultAPIKeyOverlap, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errs.E(errs.Validation, errs.Parameter("overlap"), "overlap must be a duration, e.g. 24h")
+	}
+	if d < 0 {
+		return 0, errs.E(errs.Validation, errs.Parameter("overlap"), "overlap cannot be negative")
+	}
+	return d, nil
+}
+
+// APIKeyService is a service for managing the API keys of the Apps
+// within the caller's Org
+type APIKeyService struct {
+	Datastorer            Datastorer
+	CryptoRandomGenerator CryptoRandomGenerator
+	// APIKeySecret is the server secret API keys are hashed with
+	APIKeySecret *[32]byte
+	// KMS wraps the data key each new API key is hashed with, if nil
+	// new API keys are hashed with APIKeySecret
+	KMS secure.KMS
+	// SettingsCache is optional, if nil the Org settings are read
+	// from the datastore on every call
+	SettingsCache SettingsCache
+}
+
+// FindAll lists the API keys of an App. Keys are masked.
+func (s APIKeyService) FindAll(ctx context.Context, appExtlID string, adt audit.Audit) ([]APIKeyResponse, error) {
+	dbtx := s.Datastorer.Pool()
+
+	a, err := findOrgAppByExternalID(ctx, dbtx, appExtlID, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := appstore.New(dbtx).FindAPIKeysByAppID(ctx, a.ID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	// the audits of all the keys are found together
+	l := newAuditLoader(dbtx)
+	for _, row := range rows {
+		l.add(row.CreateAppID, row.CreateUserID.UUID)
+		l.add(row.UpdateAppID, row.UpdateUserID.UUID)
+	}
+
+	response := make([]APIKeyResponse, 0, len(rows))
+	for _, row := range rows {
+		var akr APIKeyResponse
+		akr, err = newAPIKeyResponseFromDB(ctx, l, row)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, akr)
+	}
+
+	return response, nil
+}
+
+// Create mints a new API key for an App. The response is the only
+// time the key is sent unmasked.
+func (s APIKeyService) Create(ctx context.Context, r *CreateAPIKeyRequest, adt audit.Audit) (APIKeyResponse, error) {
+	settings, err := findOrgSettings(ctx, s.Datastorer.Pool(), s.SettingsCache, adt.App.Org.ID)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+	deactv, err := parseDeactivationDate(r.DeactivationDate, adt.Moment, settings.APIKeyLifetime(0))
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	// start db txn using pgxpool
+	tx, err := s.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	a, err := findOrgAppByExternalID(ctx, tx, r.AppExternalID, adt)
+	if err != nil {
+		return APIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	if !a.Active {
+		return APIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "app has been deleted"))
+	}
+
+	k, err := s.createAPIKey(ctx, tx, a, deactv, adt)
+	if err != nil {
+		return APIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	akr := newAPIKeyResponse(k, adt, adt)
+	akr.Key = k.Key()
+
+	return akr, nil
+}
+
+// Revoke makes an API key of an App unusable immediately
+func (s APIKeyService) Revoke(ctx context.Context, appExtlID, keyExtlID string, adt audit.Audit) (APIKeyResponse, error) {
+	// start db txn using pgxpool
+	tx, err := s.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	a, err := findOrgAppByExternalID(ctx, tx, appExtlID, adt)
+	if err != nil {
+		return APIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	akr, err := s.deactivateAPIKey(ctx, tx, a, keyExtlID, adt.Moment, adt)
+	if err != nil {
+		return APIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	return akr, nil
+}
+
+// Roll mints a new API key for an App to replace an existing one.
+// The replaced key remains usable for the overlap window, giving
+// clients time to switch keys.
+func (s APIKeyService) Roll(ctx context.Context, r *RollAPIKeyRequest, adt audit.Audit) (RollAPIKeyResponse, error) {
+	settings, err := findOrgSettings(ctx, s.Datastorer.Pool(), s.SettingsCache, adt.App.Org.ID)
+	if err != nil {
+		return RollAPIKeyResponse{}, err
+	}
+	deactv, err := parseDeactivationDate(r.DeactivationDate, adt.Moment, settings.APIKeyLifetime(0))
+	if err != nil {
+		return RollAPIKeyResponse{}, err
+	}
+	overlap, err := parseOverlap(r.Overlap)
+	if err != nil {
+		return RollAPIKeyResponse{}, err
+	}
+
+	// start db txn using pgxpool
+	tx, err := s.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return RollAPIKeyResponse{}, err
+	}
+
+	a, err := findOrgAppByExternalID(ctx, tx, r.AppExternalID, adt)
+	if err != nil {
+		return RollAPIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	if !a.Active {
+		return RollAPIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "app has been deleted"))
+	}
+
+	old, err := s.deactivateAPIKey(ctx, tx, a, r.KeyExternalID, adt.Moment.Add(overlap), adt)
+	if err != nil {
+		return RollAPIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	k, err := s.createAPIKey(ctx, tx, a, deactv, adt)
+	if err != nil {
+		return RollAPIKeyResponse{}, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return RollAPIKeyResponse{}, err
+	}
+
+	nkr := newAPIKeyResponse(k, adt, adt)
+	nkr.Key = k.Key()
+
+	return RollAPIKeyResponse{NewKey: nkr, OldKey: old}, nil
+}
+
+// createAPIKey creates a new API key for the App in the datastore
+func (s APIKeyService) createAPIKey(ctx context.Context, tx pgx.Tx, a app.App, deactv time.Time, adt audit.Audit) (app.APIKey, error) {
+	k, err := app.NewAPIKey(ctx, s.CryptoRandomGenerator, s.APIKeySecret, s.KMS)
+	if err != nil {
+		return app.APIKey{}, err
+	}
+	k.SetDeactivationDate(deactv)
+
+	_, err = appstore.New(tx).CreateAppAPIKey(ctx, NewCreateAppAPIKeyParams(a, k, adt))
+	if err != nil {
+		return app.APIKey{}, errs.E(errs.Database, err)
+	}
+
+	return k, nil
+}
+
+// deactivateAPIKey moves the deactivation date of an App's API key
+// to t, if earlier than its current deactivation date
+func (s APIKeyService) deactivateAPIKey(ctx context.Context, tx pgx.Tx, a app.App, keyExtlID string, t time.Time, adt audit.Audit) (APIKeyResponse, error) {
+	row, err := appstore.New(tx).FindAPIKeyByExtlID(ctx, appstore.FindAPIKeyByExtlIDParams{AppID: a.ID, ApiKeyExtlID: keyExtlID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return APIKeyResponse{}, errs.E(errs.NotExist, "No API key exists for the given external ID")
+		}
+		return APIKeyResponse{}, errs.E(errs.Database, err)
+	}
+
+	k, err := newAPIKeyFromDB(row)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+	k.DeactivateBy(t)
+
+	err = appstore.New(tx).UpdateAppAPIKeyDeactvDate(ctx, appstore.UpdateAppAPIKeyDeactvDateParams{
+		DeactvDate:      k.DeactivationDate(),
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+		ApiKeyExtlID:    keyExtlID,
+	})
+	if err != nil {
+		return APIKeyResponse{}, errs.E(errs.Database, err)
+	}
+
+	ca, err := newAuditLoader(tx).audit(ctx, row.CreateAppID, row.CreateUserID.UUID, row.CreateTimestamp)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	return newAPIKeyResponse(k, ca, adt), nil
+}
+
+// newAPIKeyFromDB initializes an app.APIKey given an appstore.AppApiKey
+func newAPIKeyFromDB(row appstore.AppApiKey) (app.APIKey, error) {
+	k := app.NewAPIKeyFromHash(row.ApiKeyPrefix.String, row.ApiKeyHash.String)
+	extl, err := secure.ParseIdentifier(row.ApiKeyExtlID)
+	if err != nil {
+		return app.APIKey{}, err
+	}
+	k.SetExternalID(extl)
+	k.SetDeactivationDate(row.DeactvDate)
+
+	return k, nil
+}
+
+// newAPIKeyResponseFromDB initializes a masked APIKeyResponse given
+// an appstore.AppApiKey. The create/update App and User are
+// retrieved from the datastore as well, through the auditLoader.
+func newAPIKeyResponseFromDB(ctx context.Context, l *auditLoader, row appstore.AppApiKey) (APIKeyResponse, error) {
+	k, err := newAPIKeyFromDB(row)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+	// queue the update audit so both audits are found together
+	l.add(row.UpdateAppID, row.UpdateUserID.UUID)
+	ca, err := l.audit(ctx, row.CreateAppID, row.CreateUserID.UUID, row.CreateTimestamp)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+	ua, err := l.audit(ctx, row.UpdateAppID, row.UpdateUserID.UUID, row.UpdateTimestamp)
+	if err != nil {
+		return APIKeyResponse{}, err
+	}
+
+	return newAPIKeyResponse(k, ca, ua), nil
+}
+
+// MigrateAPIKeyService migrates API keys stored encrypted, the way
+// keys were stored before they were hashed, to a prefix and hash
+type MigrateAPIKeyService struct {
+	Datastorer Datastorer
+	// Keyring holds the keys the legacy API keys were encrypted with
+	Keyring *secure.Keyring
+	// APIKeySecret is the server secret API keys are hashed with
+	APIKeySecret *[32]byte
+	// KMS decrypts legacy API keys sealed in an envelope and wraps
+	// the data key each migrated key is hashed with, if not nil
+	KMS secure.KMS
+}
+
+// Migrate hashes every encrypted API key and removes its ciphertext.
+// The number of keys migrated is returned. Keys are migrated in a
+// single transaction, it is safe to run Migrate on every startup.
+func (s MigrateAPIKeyService) Migrate(ctx context.Context) (int, error) {
+	// start db txn using pgxpool
+	tx, err := s.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated, err := s.migrate(ctx, tx)
+	if err != nil {
+		return 0, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return migrated, nil
+}
+
+// migrate hashes every encrypted API key and removes its ciphertext
+func (s MigrateAPIKeyService) migrate(ctx context.Context, dbtx DBTX) (int, error) {
+	q := appstore.New(dbtx)
+
+	rows, err := q.FindLegacyAPIKeys(ctx)
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+
+	for _, row := range rows {
+		var k app.APIKey
+		k, err = app.NewAPIKeyFromCipher(ctx, row.ApiKey.String, s.Keyring, s.APIKeySecret, s.KMS)
+		if err != nil {
+			return 0, err
+		}
+
+		err = q.UpdateAppAPIKeyHash(ctx, appstore.UpdateAppAPIKeyHashParams{
+			ApiKeyPrefix:  datastore.NewNullString(k.Prefix()),
+			ApiKeyHash:    datastore.NewNullString(k.Hash()),
+			ApiKeyDataKey: datastore.NewNullString(k.DataKey()),
+			ApiKeyExtlID:  row.ApiKeyExtlID,
+		})
+		if err != nil {
+			return 0, errs.E(errs.Database, err)
+		}
+	}
+
+	return len(rows), nil
+}
+
+// RotateEncryptionKeyService re-encrypts stored ciphertexts with the
+// primary key of the keyring, so that older keys can be removed from
+// it. If a KMS is set, ciphertexts are instead sealed in an envelope
+// with a data key of their own, after which the keyring is no longer
+// needed to decrypt them. The only ciphertexts stored are legacy API
+// keys not yet migrated to a hash. If a KMS is set, the data keys
+// API keys are hashed with are also wrapped again with the primary
+// key-encryption key of the KMS, so that older key-encryption keys
+// can be removed from it.
+type RotateEncryptionKeyService struct {
+	Datastorer Datastorer
+	Keyring    *secure.Keyring
+	KMS        secure.KMS
+}
+
+// Rotate re-encrypts every ciphertext not encrypted with the primary
+// key (or not sealed in an envelope, if a KMS is set), rewraps every
+// data key not wrapped with the primary key-encryption key of the KMS
+// and returns the number of ciphertexts and data keys rotated. They
+// are rotated in a single transaction, so either all or none are.
+func (s RotateEncryptionKeyService) Rotate(ctx context.Context) (int, error) {
+	// start db txn using pgxpool
+	tx, err := s.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated, err := s.rotate(ctx, tx)
+	if err != nil {
+		return 0, s.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return rotated, nil
+}
+
+// rotate re-encrypts the legacy API key ciphertexts and rewraps the
+// API key data keys, see Rotate
+func (s RotateEncryptionKeyService) rotate(ctx context.Context, dbtx DBTX) (int, error) {
+	q := appstore.New(dbtx)
+
+	rows, err := q.FindLegacyAPIKeys(ctx)
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+
+	var rotated int
+	for _, row := range rows {
+		ciphertext, ok, err := s.reencrypt(ctx, row.ApiKey.String)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = q.UpdateAppAPIKeyCiphertext(ctx, appstore.UpdateAppAPIKeyCiphertextParams{
+			ApiKey:       datastore.NewNullString(ciphertext),
+			ApiKeyExtlID: row.ApiKeyExtlID,
+		})
+		if err != nil {
+			return 0, errs.E(errs.Database, err)
+		}
+		rotated++
+	}
+
+	if s.KMS == nil {
+		return rotated, nil
+	}
+
+	dataKeys, err := q.FindAPIKeyDataKeys(ctx)
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+
+	for _, row := range dataKeys {
+		wrapped, ok, err := secure.RewrapDataKey(ctx, s.KMS, row.ApiKeyDataKey.String)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = q.UpdateAppAPIKeyDataKey(ctx, appstore.UpdateAppAPIKeyDataKeyParams{
+			ApiKeyDataKey: datastore.NewNullString(wrapped),
+			ApiKeyExtlID:  row.ApiKeyExtlID,
+		})
+		if err != nil {
+			return 0, errs.E(errs.Database, err)
+		}
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+// reencrypt re-encrypts a ciphertext with the primary key of the
+// keyring, or seals it in an envelope if a KMS is set. ok is false if
+// the ciphertext is already encrypted that way.
+func (s RotateEncryptionKeyService) reencrypt(ctx context.Context, ciphertext string) (string, bool, error) {
+	if secure.IsEnvelope(ciphertext) {
+		return "", false, nil
+	}
+	id, versioned, err := secure.CiphertextKeyID(ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	if s.KMS == nil && versioned && id == s.Keyring.PrimaryID() {
+		return "", false, nil
+	}
+
+	plaintext, err := s.Keyring.Decrypt(ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	if s.KMS != nil {
+		ciphertext, err = secure.EnvelopeEncrypt(ctx, s.KMS, plaintext)
+	} else {
+		ciphertext, err = s.Keyring.Encrypt(plaintext)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return ciphertext, true, nil
+}
+
+// findOrgAppByExternalID finds an App given its external ID. Only
+// Apps within the Org of the calling App can be found.
+func findOrgAppByExternalID(ctx context.Context, dbtx DBTX, extlID string, adt audit.Audit) (app.App, error) {
+	row, err := appstore.New(dbtx).FindAppByExternalID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return app.App{}, errs.E(errs.NotExist, "No app exists for the given external ID")
+		}
+		return app.App{}, errs.E(errs.Database, err)
+	}
+	if row.OrgID != adt.App.Org.ID {
+		return app.App{}, errs.E(errs.NotExist, "No app exists for the given external ID")
+	}
+
+	return newAppFromDB(row, adt.App.Org)
+}
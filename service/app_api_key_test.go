@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore/appstore"
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+func Test_parseDeactivationDate(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := parseDeactivationDate("2021-07-01T00:00:00Z", now, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Equal(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)), qt.IsTrue)
+
+	for _, s := range []string{"", "2021-07-01", "2021-05-01T00:00:00Z", "2021-06-01T00:00:00Z"} {
+		_, err = parseDeactivationDate(s, now, 0)
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue, qt.Commentf("%q", s))
+	}
+
+	// the Org's default lifetime applies when no date is requested
+	got, err = parseDeactivationDate("", now, 720*time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Equal(time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)), qt.IsTrue)
+	got, err = parseDeactivationDate("2021-06-02T00:00:00Z", now, 720*time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Equal(time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC)), qt.IsTrue)
+}
+
+func Test_parseOverlap(t *testing.T) {
+	c := qt.New(t)
+
+	got, err := parseOverlap("")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, DefaultAPIKeyOverlap)
+
+	got, err = parseOverlap("1h30m")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, 90*time.Minute)
+
+	_, err = parseOverlap("-1h")
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+
+	_, err = parseOverlap("a day")
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
+
+// apiKeyDBTX is a DBTX holding API keys in memory, answering the
+// queries used to migrate and rotate them
+type apiKeyDBTX struct {
+	keys map[string]appstore.AppApiKey
+}
+
+func (db *apiKeyDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	extlID := args[len(args)-1].(string)
+	k, ok := db.keys[extlID]
+	if !ok {
+		return nil, fmt.Errorf("no API key %s", extlID)
+	}
+	switch {
+	case strings.HasPrefix(query, "-- name: UpdateAppAPIKeyHash "):
+		k.ApiKey = sql.NullString{}
+		k.ApiKeyPrefix, k.ApiKeyHash, k.ApiKeyDataKey = args[0].(sql.NullString), args[1].(sql.NullString), args[2].(sql.NullString)
+	case strings.HasPrefix(query, "-- name: UpdateAppAPIKeyCiphertext "):
+		k.ApiKey = args[0].(sql.NullString)
+	case strings.HasPrefix(query, "-- name: UpdateAppAPIKeyDataKey "):
+		k.ApiKeyDataKey = args[0].(sql.NullString)
+	default:
+		return nil, fmt.Errorf("unexpected Exec: %s", query)
+	}
+	db.keys[extlID] = k
+	return pgconn.CommandTag("UPDATE 1"), nil
+}
+
+func (db *apiKeyDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return &fakeRows{err: fmt.Errorf("unexpected QueryRow: %s", query)}
+}
+
+func (db *apiKeyDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	rows := &fakeRows{}
+	for _, k := range db.keys {
+		switch {
+		case strings.HasPrefix(query, "-- name: FindLegacyAPIKeys "):
+			if k.ApiKey.Valid {
+				rows.values = append(rows.values, fieldValues(k))
+			}
+		case strings.HasPrefix(query, "-- name: FindAPIKeyDataKeys "):
+			if k.ApiKeyDataKey.Valid {
+				rows.values = append(rows.values, fieldValues(appstore.FindAPIKeyDataKeysRow{ApiKeyExtlID: k.ApiKeyExtlID, ApiKeyDataKey: k.ApiKeyDataKey}))
+			}
+		default:
+			return nil, fmt.Errorf("unexpected Query: %s", query)
+		}
+	}
+	return rows, nil
+}
+
+func TestRotateEncryptionKeyService_afterMigrate(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// keys holds a hex encoded key for each keyring key ID
+	keys := make(map[string]string)
+	for _, id := range []string{"k1", "kek1", "kek2"} {
+		ek, err := secure.NewEncryptionKey()
+		c.Assert(err, qt.IsNil)
+		keys[id] = hex.EncodeToString(ek[:])
+	}
+	newKMS := func(ids ...string) secure.KMS {
+		var s []string
+		for _, id := range ids {
+			s = append(s, id+":"+keys[id])
+		}
+		kr, err := secure.ParseKeyring(strings.Join(s, ","))
+		c.Assert(err, qt.IsNil)
+		return secure.NewLocalKMS(kr)
+	}
+	keyring, err := secure.ParseKeyring("k1:" + keys["k1"])
+	c.Assert(err, qt.IsNil)
+	secret, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+
+	// a legacy API key, stored encrypted
+	const legacyKey = "zaIbK9DWCbh6Qq2mRFeNQ2Ac"
+	ct, err := keyring.Encrypt([]byte(legacyKey))
+	c.Assert(err, qt.IsNil)
+	db := &apiKeyDBTX{keys: map[string]appstore.AppApiKey{
+		"key1": {ApiKey: sql.NullString{String: ct, Valid: true}, ApiKeyExtlID: "key1"},
+	}}
+
+	// migrated on startup, hashed with a data key wrapped with kek1
+	migrated, err := MigrateAPIKeyService{Keyring: keyring, APIKeySecret: secret, KMS: newKMS("kek1")}.migrate(ctx, db)
+	c.Assert(err, qt.IsNil)
+	c.Assert(migrated, qt.Equals, 1)
+
+	// kek2 becomes the primary key-encryption key, the data key is
+	// rewrapped with it once
+	rs := RotateEncryptionKeyService{Keyring: keyring, KMS: newKMS("kek2", "kek1")}
+	rotated, err := rs.rotate(ctx, db)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rotated, qt.Equals, 1)
+	rotated, err = rs.rotate(ctx, db)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rotated, qt.Equals, 0)
+
+	// kek1 is no longer needed to check the key
+	row := db.keys["key1"]
+	k := app.NewAPIKeyFromHash(row.ApiKeyPrefix.String, row.ApiKeyHash.String)
+	k.SetDataKey(row.ApiKeyDataKey.String)
+	k.SetDeactivationDate(time.Now().Add(time.Hour))
+	a := app.App{APIKeys: []app.APIKey{k}}
+	c.Assert(a.ValidKey(ctx, "test", legacyKey, secret, newKMS("kek2")), qt.IsNil)
+}
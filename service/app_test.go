@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+func TestUpdateAppService_Update(t *testing.T) {
+	c := qt.New(t)
+
+	// the name is validated before the datastore is used
+	uas := UpdateAppService{}
+	_, err := uas.Update(context.Background(), &UpdateAppRequest{ExternalID: "abc", Name: "  "}, audit.Audit{})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/datastore/appstore"
+	"github.com/gilcrest/go-api-basic/datastore/userstore"
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/org"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+	"github.com/gilcrest/go-api-basic/domain/user"
+)
+
+// auditLoader finds the Apps and Users of audit columns in batches.
+// The App and User IDs of every record in a response are added first
+// and are found together, with one query for the Apps and one for
+// the Users, rather than queries per record. A loader lives for a
+// single request and caches what it finds.
+type auditLoader struct {
+	dbtx DBTX
+
+	apps  map[uuid.UUID]app.App
+	users map[uuid.UUID]user.User
+	// pendingApps and pendingUsers are the IDs added since the
+	// last load
+	pendingApps  map[uuid.UUID]bool
+	pendingUsers map[uuid.UUID]bool
+}
+
+// newAuditLoader initializes an auditLoader which reads from dbtx
+func newAuditLoader(dbtx DBTX) *auditLoader {
+	return &auditLoader{
+		dbtx:         dbtx,
+		apps:         make(map[uuid.UUID]app.App),
+		users:        make(map[uuid.UUID]user.User),
+		pendingApps:  make(map[uuid.UUID]bool),
+		pendingUsers: make(map[uuid.UUID]bool),
+	}
+}
+
+// add queues the App and User of an audit to be found on the next
+// load. Data changed by a service account App without a User has no
+// User ID.
+func (l *auditLoader) add(appID, userID uuid.UUID) {
+	if _, ok := l.apps[appID]; !ok {
+		l.pendingApps[appID] = true
+	}
+	if _, ok := l.users[userID]; !ok && userID != uuid.Nil {
+		l.pendingUsers[userID] = true
+	}
+}
+
+// load finds the queued Apps and Users
+func (l *auditLoader) load(ctx context.Context) error {
+	if len(l.pendingApps) > 0 {
+		rows, err := appstore.New(l.dbtx).FindAppsByIDs(ctx, keys(l.pendingApps))
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		for _, row := range rows {
+			a, err := newAuditAppFromDB(row)
+			if err != nil {
+				return err
+			}
+			l.apps[a.ID] = a
+		}
+		l.pendingApps = make(map[uuid.UUID]bool)
+	}
+
+	if len(l.pendingUsers) > 0 {
+		rows, err := userstore.New(l.dbtx).FindUsersByIDs(ctx, keys(l.pendingUsers))
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		for _, row := range rows {
+			l.users[row.UserID] = hydrateUserFromDB(userstore.FindUserByUsernameRow(row))
+		}
+		l.pendingUsers = make(map[uuid.UUID]bool)
+	}
+
+	return nil
+}
+
+// audit returns the audit.Audit of an App and User at a moment,
+// loading them along with anything else queued if need be. The User
+// of an audit without a User ID is the service principal of the App.
+func (l *auditLoader) audit(ctx context.Context, appID, userID uuid.UUID, moment time.Time) (audit.Audit, error) {
+	l.add(appID, userID)
+	err := l.load(ctx)
+	if err != nil {
+		return audit.Audit{}, err
+	}
+
+	a, ok := l.apps[appID]
+	if !ok {
+		return audit.Audit{}, errs.E(errs.Database, fmt.Sprintf("no app found for audit app ID %s", appID))
+	}
+	if userID == uuid.Nil {
+		return audit.Audit{App: a, User: user.NewServicePrincipal(a.Org, a.ExternalID), Moment: moment}, nil
+	}
+	u, ok := l.users[userID]
+	if !ok {
+		return audit.Audit{}, errs.E(errs.Database, fmt.Sprintf("no user found for audit user ID %s", userID))
+	}
+
+	return audit.Audit{App: a, User: u, Moment: moment}, nil
+}
+
+// newAuditAppFromDB initializes the app.App of an audit given an
+// appstore.FindAppsByIDsRow. Only the App and Org identity are set.
+func newAuditAppFromDB(row appstore.FindAppsByIDsRow) (app.App, error) {
+	appExtl, err := secure.ParseIdentifier(row.AppExtlID)
+	if err != nil {
+		return app.App{}, err
+	}
+	orgExtl, err := secure.ParseIdentifier(row.OrgExtlID)
+	if err != nil {
+		return app.App{}, err
+	}
+
+	return app.App{
+		ID:         row.AppID,
+		ExternalID: appExtl,
+		Org: org.Org{
+			ID:          row.OrgID,
+			ExternalID:  orgExtl,
+			Name:        row.OrgName,
+			Description: row.OrgDescription,
+		},
+		Name:        row.AppName,
+		Description: row.AppDescription,
+	}, nil
+}
+
+// keys returns the keys of a set of IDs
+func keys(m map[uuid.UUID]bool) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore"
+	"github.com/gilcrest/go-api-basic/datastore/appstore"
+	"github.com/gilcrest/go-api-basic/datastore/orgstore"
+	"github.com/gilcrest/go-api-basic/datastore/userstore"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+// countingDBTX is a DBTX which counts the queries it is sent and
+// answers the auditLoader queries from in memory Apps and Users
+type countingDBTX struct {
+	queries int
+	apps    map[uuid.UUID]appstore.FindAppsByIDsRow
+	users   map[uuid.UUID]userstore.FindUsersByIDsRow
+}
+
+func (db *countingDBTX) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.queries++
+	return nil, fmt.Errorf("unexpected Exec: %s", sql)
+}
+
+func (db *countingDBTX) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.queries++
+	return &fakeRows{err: fmt.Errorf("unexpected QueryRow: %s", sql)}
+}
+
+func (db *countingDBTX) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.queries++
+
+	rows := &fakeRows{}
+	for _, id := range args[0].([]uuid.UUID) {
+		switch {
+		case strings.HasPrefix(sql, "-- name: FindAppsByIDs "):
+			if a, ok := db.apps[id]; ok {
+				rows.values = append(rows.values, fieldValues(a))
+			}
+		case strings.HasPrefix(sql, "-- name: FindUsersByIDs "):
+			if u, ok := db.users[id]; ok {
+				rows.values = append(rows.values, fieldValues(u))
+			}
+		default:
+			return nil, fmt.Errorf("unexpected Query: %s", sql)
+		}
+	}
+	return rows, nil
+}
+
+// fakeRows are pgx.Rows holding the values of each row in memory
+type fakeRows struct {
+	pgx.Rows
+	values [][]interface{}
+	i      int
+	err    error
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.err == nil && r.i <= len(r.values)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.values[r.i-1][i]))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return r.err }
+
+func (r *fakeRows) Close() {}
+
+// fieldValues returns the field values of a sqlc row struct, which
+// are in the order the row is scanned
+func fieldValues(row interface{}) []interface{} {
+	v := reflect.ValueOf(row)
+	values := make([]interface{}, v.NumField())
+	for i := range values {
+		values[i] = v.Field(i).Interface()
+	}
+	return values
+}
+
+func Test_newOrgResponses_queryCount(t *testing.T) {
+	c := qt.New(t)
+
+	orgID := uuid.New()
+	db := &countingDBTX{
+		apps:  make(map[uuid.UUID]appstore.FindAppsByIDsRow),
+		users: make(map[uuid.UUID]userstore.FindUsersByIDsRow),
+	}
+	var appIDs, userIDs []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		db.apps[id] = appstore.FindAppsByIDsRow{AppID: id, AppExtlID: secure.NewID().String(), AppName: fmt.Sprintf("app%d", i), OrgID: orgID, OrgExtlID: secure.NewID().String()}
+		appIDs = append(appIDs, id)
+	}
+	for i := 0; i < 10; i++ {
+		id := uuid.New()
+		db.users[id] = userstore.FindUsersByIDsRow{UserID: id, UserExtlID: secure.NewID().String(), Username: fmt.Sprintf("user%d", i), OrgID: orgID, OrgExtlID: secure.NewID().String()}
+		userIDs = append(userIDs, id)
+	}
+
+	// 100 orgs created and updated by a mix of the apps and users,
+	// some by a service account app without a user
+	now := time.Now()
+	dbos := make([]orgstore.Org, 100)
+	for i := range dbos {
+		createUserID := userIDs[i%len(userIDs)]
+		if i%7 == 0 {
+			createUserID = uuid.Nil
+		}
+		dbos[i] = orgstore.Org{
+			OrgID:           uuid.New(),
+			OrgExtlID:       secure.NewID().String(),
+			OrgName:         fmt.Sprintf("org%d", i),
+			CreateAppID:     appIDs[i%len(appIDs)],
+			CreateUserID:    datastore.NewNullUUID(createUserID),
+			CreateTimestamp: now,
+			UpdateAppID:     appIDs[(i+1)%len(appIDs)],
+			UpdateUserID:    datastore.NewNullUUID(userIDs[(i+1)%len(userIDs)]),
+			UpdateTimestamp: now,
+			Settings:        json.RawMessage(`{"version": 1}`),
+		}
+	}
+
+	response, err := newOrgResponses(context.Background(), db, dbos)
+	c.Assert(err, qt.IsNil)
+	c.Assert(response, qt.HasLen, 100)
+
+	// one query for the apps and one for the users, however many orgs
+	c.Assert(db.queries, qt.Equals, 2)
+
+	c.Assert(response[1].CreateAudit.AppName, qt.Equals, "app1")
+	c.Assert(response[1].CreateAudit.Username, qt.Equals, "user1")
+	c.Assert(response[1].UpdateAudit.AppName, qt.Equals, "app2")
+	c.Assert(response[1].UpdateAudit.Username, qt.Equals, "user2")
+	c.Assert(response[7].CreateAudit.Username, qt.Equals, "app:"+db.apps[appIDs[1]].AppExtlID)
+
+	// a single response finds both of its audits together as well
+	db.queries = 0
+	o, err := newOrgFromDB(dbos[3])
+	c.Assert(err, qt.IsNil)
+	_, err = newOrgResponse(context.Background(), newAuditLoader(db), o, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(db.queries, qt.Equals, 2)
+}
+
+func TestAuditLoader_audit(t *testing.T) {
+	c := qt.New(t)
+
+	db := &countingDBTX{}
+	l := newAuditLoader(db)
+
+	// Apps and Users which do not exist are an error
+	_, err := l.audit(context.Background(), uuid.New(), uuid.New(), time.Now())
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(db.queries, qt.Equals, 2)
+}
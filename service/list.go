@@ -0,0 +1,254 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+const (
+	// DefaultListLimit is the number of items returned when no
+	// limit is requested
+	DefaultListLimit = 20
+	// MaxListLimit is the maximum number of items returned at once
+	MaxListLimit = 100
+)
+
+// Sort fields of lists, not every list has every field
+const (
+	nameSort     = "name"
+	usernameSort = "username"
+	createdSort  = "created"
+)
+
+// ListRequest is the request struct for paging through a list.
+// Lists are paged with a cursor rather than an offset (keyset
+// pagination), so reading a page costs the same however deep into
+// the list it is.
+type ListRequest struct {
+	// Limit is the maximum number of items returned, it defaults
+	// to DefaultListLimit and may not exceed MaxListLimit
+	Limit int
+	// Cursor is the next or previous cursor of a page, empty for
+	// the first page. Cursors are opaque.
+	Cursor string
+	// Sort is the field the list is ordered by, prefixed with - for
+	// descending order, e.g. -created. It defaults to the first
+	// sort field of the list.
+	Sort string
+	// NamePrefix limits the list to items whose name starts with it
+	NamePrefix string
+	// CreatedAfter limits the list to items created after it
+	CreatedAfter time.Time
+}
+
+// PageCursors are the cursors of the pages either side of a page of
+// a list. They are empty if there is no such page.
+type PageCursors struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// cursor is the position of a page boundary within a list: the sort
+// key and external ID of the item at the boundary, the sort the
+// list was read with and whether the page is before the item
+// (previous) rather than after it (next)
+type cursor struct {
+	Sort   string `json:"s"`
+	Key    string `json:"k"`
+	ExtlID string `json:"id"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// encode returns the cursor as an opaque string
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes a cursor returned by encode
+func decodeCursor(s string) (cursor, error) {
+	invalid := errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, invalid
+	}
+	var c cursor
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.ExtlID == "" {
+		return cursor{}, invalid
+	}
+	return c, nil
+}
+
+// listPage is a validated ListRequest, holding the parameters the
+// keyset queries of a list are given
+type listPage struct {
+	limit int
+	// sort is the sort as requested, e.g. -created
+	sort  string
+	field string
+	desc  bool
+	// cursor is nil for the first page
+	cursor     *cursor
+	cursorTime time.Time
+	// namePattern is a LIKE pattern matching NamePrefix
+	namePattern  string
+	createdAfter sql.NullTime
+}
+
+// newListPage validates the request given the sort fields of the
+// list, the first being the default
+func newListPage(r ListRequest, fields ...string) (listPage, error) {
+	p := listPage{
+		limit:       r.Limit,
+		sort:        r.Sort,
+		namePattern: likePrefix(r.NamePrefix),
+	}
+	if p.limit == 0 {
+		p.limit = DefaultListLimit
+	}
+	if p.limit < 0 || p.limit > MaxListLimit {
+		return listPage{}, errs.E(errs.Validation, errs.Parameter("limit"), fmt.Sprintf("limit must be between 1 and %d", MaxListLimit))
+	}
+
+	if p.sort == "" {
+		p.sort = fields[0]
+	}
+	p.field = strings.TrimPrefix(p.sort, "-")
+	p.desc = p.field != p.sort
+	if !contains(fields, p.field) {
+		return listPage{}, errs.E(errs.Validation, errs.Parameter("sort"), fmt.Sprintf("sort must be one of %s, prefixed with - for descending order", strings.Join(fields, ", ")))
+	}
+
+	if !r.CreatedAfter.IsZero() {
+		p.createdAfter = sql.NullTime{Time: r.CreatedAfter, Valid: true}
+	}
+
+	if r.Cursor != "" {
+		c, err := decodeCursor(r.Cursor)
+		if err != nil {
+			return listPage{}, err
+		}
+		if c.Sort != p.sort {
+			return listPage{}, errs.E(errs.Validation, errs.Parameter("cursor"), "cursor was returned for a different sort")
+		}
+		if p.field == createdSort {
+			p.cursorTime, err = time.Parse(time.RFC3339Nano, c.Key)
+			if err != nil {
+				return listPage{}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+			}
+		}
+		p.cursor = &c
+	}
+
+	return p, nil
+}
+
+// backward reports whether the page is before its cursor
+func (p listPage) backward() bool {
+	return p.cursor != nil && p.cursor.Before
+}
+
+// descending reports whether the rows are read in descending order,
+// which is the sort order reversed when reading backward
+func (p listPage) descending() bool {
+	return p.desc != p.backward()
+}
+
+// rowLimit is the number of rows to read, one more than the page so
+// it is known whether the list goes on
+func (p listPage) rowLimit() int32 {
+	return int32(p.limit + 1)
+}
+
+// cursorExtlID is the external ID of the cursor item, null for the
+// first page
+func (p listPage) cursorExtlID() sql.NullString {
+	if p.cursor == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: p.cursor.ExtlID, Valid: true}
+}
+
+// cursorKey is the sort key of the cursor item for text sort fields
+func (p listPage) cursorKey() string {
+	if p.cursor == nil {
+		return ""
+	}
+	return p.cursor.Key
+}
+
+// sortKey returns the cursor key of an item given its name and
+// create time
+func (p listPage) sortKey(name string, created time.Time) string {
+	if p.field == createdSort {
+		return created.Format(time.RFC3339Nano)
+	}
+	return name
+}
+
+// trim trims rows, a slice read with rowLimit, to the page and puts
+// the page in sort order. It returns the length of the page and
+// whether more rows were read beyond it.
+func (p listPage) trim(rows interface{}) (n int, more bool) {
+	n = reflect.ValueOf(rows).Len()
+	if n > p.limit {
+		n, more = p.limit, true
+	}
+	if p.backward() {
+		swap := reflect.Swapper(rows)
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	return n, more
+}
+
+// cursors returns the cursors either side of a page of n items in
+// sort order. key returns the sort key and external ID of an item.
+func (p listPage) cursors(n int, more bool, key func(i int) (string, string)) PageCursors {
+	var pc PageCursors
+	if n == 0 {
+		return pc
+	}
+	// going backward, there is always a next page (the one the
+	// cursor came from), going forward there is always a previous
+	// page unless this is the first page
+	hasNext, hasPrev := more, p.cursor != nil
+	if p.backward() {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		k, id := key(n - 1)
+		pc.NextCursor = cursor{Sort: p.sort, Key: k, ExtlID: id}.encode()
+	}
+	if hasPrev {
+		k, id := key(0)
+		pc.PrevCursor = cursor{Sort: p.sort, Key: k, ExtlID: id, Before: true}.encode()
+	}
+	return pc
+}
+
+// likePrefix returns a LIKE pattern matching strings which start
+// with prefix
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
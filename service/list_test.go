@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/go-api-basic/domain/errs"
+)
+
+func Test_newListPage(t *testing.T) {
+	c := qt.New(t)
+
+	p, err := newListPage(ListRequest{NamePrefix: "50%_off"}, nameSort, createdSort)
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.limit, qt.Equals, DefaultListLimit)
+	c.Assert(p.field, qt.Equals, nameSort)
+	c.Assert(p.desc, qt.IsFalse)
+	c.Assert(p.namePattern, qt.Equals, `50\%\_off%`)
+	c.Assert(p.cursorExtlID().Valid, qt.IsFalse)
+
+	p, err = newListPage(ListRequest{Sort: "-created"}, nameSort, createdSort)
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.field, qt.Equals, createdSort)
+	c.Assert(p.descending(), qt.IsTrue)
+
+	// a cursor is only valid for the sort it was returned for
+	next := cursor{Sort: "name", Key: "a", ExtlID: "abc"}.encode()
+	_, err = newListPage(ListRequest{Sort: "-name", Cursor: next}, nameSort, createdSort)
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+
+	// a previous page is read in reverse order
+	prev := cursor{Sort: "name", Key: "a", ExtlID: "abc", Before: true}.encode()
+	p, err = newListPage(ListRequest{Cursor: prev}, nameSort, createdSort)
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.backward(), qt.IsTrue)
+	c.Assert(p.descending(), qt.IsTrue)
+	c.Assert(p.cursorKey(), qt.Equals, "a")
+	c.Assert(p.cursorExtlID().String, qt.Equals, "abc")
+}
+
+func TestListPage_cursors(t *testing.T) {
+	c := qt.New(t)
+
+	items := []string{"a", "b", "c", "d", "e"}
+	key := func(rows []string) func(i int) (string, string) {
+		return func(i int) (string, string) { return rows[i], "id-" + rows[i] }
+	}
+
+	// first page of 2, read ascending with one extra row
+	p, err := newListPage(ListRequest{Limit: 2}, nameSort)
+	c.Assert(err, qt.IsNil)
+	rows := append([]string(nil), items[:3]...)
+	n, more := p.trim(rows)
+	c.Assert(n, qt.Equals, 2)
+	c.Assert(more, qt.IsTrue)
+	pc := p.cursors(n, more, key(rows[:n]))
+	c.Assert(pc.PrevCursor, qt.Equals, "")
+	next, err := decodeCursor(pc.NextCursor)
+	c.Assert(err, qt.IsNil)
+	c.Assert(next, qt.Equals, cursor{Sort: "name", Key: "b", ExtlID: "id-b"})
+
+	// the page before e is read descending, d, c and b, then put
+	// back in ascending order
+	prevCursor := cursor{Sort: "name", Key: "e", ExtlID: "id-e", Before: true}.encode()
+	p, err = newListPage(ListRequest{Limit: 2, Cursor: prevCursor}, nameSort)
+	c.Assert(err, qt.IsNil)
+	rows = []string{"d", "c", "b"}
+	n, more = p.trim(rows)
+	rows = rows[:n]
+	c.Assert(rows, qt.DeepEquals, []string{"c", "d"})
+	pc = p.cursors(n, more, key(rows))
+	prev, err := decodeCursor(pc.PrevCursor)
+	c.Assert(err, qt.IsNil)
+	c.Assert(prev, qt.Equals, cursor{Sort: "name", Key: "c", ExtlID: "id-c", Before: true})
+	next, err = decodeCursor(pc.NextCursor)
+	c.Assert(err, qt.IsNil)
+	c.Assert(next, qt.Equals, cursor{Sort: "name", Key: "d", ExtlID: "id-d"})
+
+	// created keys round trip through the cursor
+	now := time.Date(2021, 6, 15, 10, 30, 0, 123456000, time.UTC)
+	p, err = newListPage(ListRequest{Sort: "created"}, nameSort, createdSort)
+	c.Assert(err, qt.IsNil)
+	k := p.sortKey("ignored", now)
+	p, err = newListPage(ListRequest{Sort: "created", Cursor: cursor{Sort: "created", Key: k, ExtlID: "x"}.encode()}, nameSort, createdSort)
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.cursorTime.Equal(now), qt.IsTrue)
+}
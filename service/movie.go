@@ -1,258 +1,332 @@
 package service
 
-//// RandomStringGenerator is the interface that generates random strings
-//type RandomStringGenerator interface {
-//	CryptoString(n int) (string, error)
-//}
-//
-//// MovieTransactor is the interface that wraps the DML actions for
-//// a Movie in the DB
-//type MovieTransactor interface {
-//	Create(ctx context.Context, m *movie.Movie) error
-//	Update(ctx context.Context, m *movie.Movie) error
-//	Delete(ctx context.Context, m *movie.Movie) error
-//}
-//
-//// MovieSelector reads Movie records from the db
-//type MovieSelector interface {
-//	FindByID(context.Context, string) (*movie.Movie, error)
-//	FindAll(context.Context) ([]*movie.Movie, error)
-//}
-//
-//// CreateMovieRequest is the request struct for Creating a Movie
-//type CreateMovieRequest struct {
-//	Title    string `json:"title"`
-//	Rated    string `json:"rated"`
-//	Released string `json:"release_date"`
-//	RunTime  int    `json:"run_time"`
-//	Director string `json:"director"`
-//	Writer   string `json:"writer"`
-//}
-//
-//// UpdateMovieRequest is the request struct for updating a Movie
-//type UpdateMovieRequest struct {
-//	ExternalID string
-//	Title      string `json:"title"`
-//	Rated      string `json:"rated"`
-//	Released   string `json:"release_date"`
-//	RunTime    int    `json:"run_time"`
-//	Director   string `json:"director"`
-//	Writer     string `json:"writer"`
-//}
-//
-//// MovieResponse is the response struct for a Movie
-//type MovieResponse struct {
-//	ExternalID      string `json:"external_id"`
-//	Title           string `json:"title"`
-//	Rated           string `json:"rated"`
-//	Released        string `json:"release_date"`
-//	RunTime         int    `json:"run_time"`
-//	Director        string `json:"director"`
-//	Writer          string `json:"writer"`
-//	CreateUsername  string `json:"create_username"`
-//	CreateTimestamp string `json:"create_timestamp"`
-//	UpdateUsername  string `json:"update_username"`
-//	UpdateTimestamp string `json:"update_timestamp"`
-//}
-//
-//// DeleteMovieResponse is the response struct for deleted Movies
-//type DeleteMovieResponse struct {
-//	ExternalID string `json:"extl_id"`
-//	Deleted    bool   `json:"deleted"`
-//}
-//
-//func newMovieResponse(m *movie.Movie) MovieResponse {
-//	return MovieResponse{
-//		ExternalID:      m.ExternalID,
-//		Title:           m.Title,
-//		Rated:           m.Rated,
-//		Released:        m.Released.Format(time.RFC3339),
-//		RunTime:         m.RunTime,
-//		Director:        m.Director,
-//		Writer:          m.Writer,
-//		CreateUsername:  m.CreateUser.Email,
-//		CreateTimestamp: m.CreateTime.Format(time.RFC3339),
-//		UpdateUsername:  m.UpdateUser.Email,
-//		UpdateTimestamp: m.UpdateTime.Format(time.RFC3339),
-//	}
-//}
-//
-//// CreateMovieService is a service for creating a Movie
-//type CreateMovieService struct {
-//	RandomStringGenerator RandomStringGenerator
-//	MovieTransactor       MovieTransactor
-//}
-//
-//// NewCreateMovieService is an initializer for CreateMovieService
-//func NewCreateMovieService(rsg RandomStringGenerator, mt MovieTransactor) *CreateMovieService {
-//	return &CreateMovieService{RandomStringGenerator: rsg, MovieTransactor: mt}
-//}
-//
-//// Create is used to create a Movie
-//func (cms CreateMovieService) Create(ctx context.Context, r *CreateMovieRequest, u user.User) (MovieResponse, error) {
-//
-//	mr := MovieResponse{}
-//
-//	extlID, err := cms.RandomStringGenerator.CryptoString(15)
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	// Call the NewMovie method for struct initialization
-//	m, err := movie.NewMovie(uuid.New(), extlID, u)
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	m, err = m.SetReleased(r.Released)
-//	if err != nil {
-//		return mr, err
-//	}
-//	m.SetTitle(r.Title).
-//		SetRated(r.Rated).
-//		SetRunTime(r.RunTime).
-//		SetDirector(r.Director).
-//		SetWriter(r.Writer)
-//
-//	err = m.IsValid()
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	// Call the Create method of the Transactor to insert data to
-//	// the database. If an error occurs, rollback the transaction
-//	err = cms.MovieTransactor.Create(ctx, m)
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	return newMovieResponse(m), nil
-//}
-//
-//// UpdateMovieService is a service for updating a Movie
-//type UpdateMovieService struct {
-//	MovieTransactor MovieTransactor
-//}
-//
-//// NewUpdateMovieService is an initializer for UpdateMovieService
-//func NewUpdateMovieService(mt MovieTransactor) *UpdateMovieService {
-//	return &UpdateMovieService{MovieTransactor: mt}
-//}
-//
-//// Update is used to update a movie
-//func (ums UpdateMovieService) Update(ctx context.Context, r *UpdateMovieRequest, u user.User) (MovieResponse, error) {
-//
-//	mr := MovieResponse{}
-//
-//	// Convert request into a Movie struct
-//	m := new(movie.Movie)
-//	m.SetExternalID(r.ExternalID)
-//	m.SetTitle(r.Title)
-//	m.SetRated(r.Rated)
-//	m, err := m.SetReleased(r.Released)
-//	if err != nil {
-//		return mr, err
-//	}
-//	m.SetRunTime(r.RunTime)
-//	m.SetDirector(r.Director)
-//	m.SetWriter(r.Writer)
-//	m.SetUpdateUser(u)
-//	m.SetUpdateTime()
-//
-//	err = m.IsValid()
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	// Call the Update method of the Transactor to update the record
-//	// in the database.
-//	err = ums.MovieTransactor.Update(ctx, m)
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	return newMovieResponse(m), nil
-//}
-//
-//// DeleteMovieService is a service for deleting a Movie
-//type DeleteMovieService struct {
-//	MovieSelector   MovieSelector
-//	MovieTransactor MovieTransactor
-//}
-//
-//// NewDeleteMovieService is an initializer for DeleteMovieService
-//func NewDeleteMovieService(ms MovieSelector, mt MovieTransactor) *DeleteMovieService {
-//	return &DeleteMovieService{MovieSelector: ms, MovieTransactor: mt}
-//}
-//
-//// Delete is used to delete a movie
-//func (dms DeleteMovieService) Delete(ctx context.Context, extlID string) (DeleteMovieResponse, error) {
-//
-//	dmr := DeleteMovieResponse{}
-//
-//	// Find the Movie by ID using the selector.FindMovieByID method
-//	// It's arguable I don't need to do this and can just send
-//	// the external ID to the database Transactor directly instead,
-//	// (I'd have to rework it slightly) but this way works as an
-//	// example
-//	m, err := dms.MovieSelector.FindByID(ctx, extlID)
-//	if err != nil {
-//		return dmr, err
-//	}
-//
-//	// Delete method of Transactor physically deletes the record
-//	// from the DB, unless mocked
-//	err = dms.MovieTransactor.Delete(ctx, m)
-//	if err != nil {
-//		return dmr, err
-//	}
-//
-//	response := DeleteMovieResponse{
-//		ExternalID: m.ExternalID,
-//		Deleted:    true,
-//	}
-//
-//	return response, nil
-//}
-//
-//// FindMovieService is a service for reading a Movie from the DB
-//type FindMovieService struct {
-//	MovieSelector MovieSelector
-//}
-//
-//// NewFindMovieService is an initializer for FindMovieService
-//func NewFindMovieService(ms MovieSelector) *FindMovieService {
-//	return &FindMovieService{MovieSelector: ms}
-//}
-//
-//// FindMovieByID is used to find an individual movie
-//func (fms FindMovieService) FindMovieByID(ctx context.Context, extlID string) (MovieResponse, error) {
-//
-//	mr := MovieResponse{}
-//
-//	m, err := fms.MovieSelector.FindByID(ctx, extlID)
-//	if err != nil {
-//		return mr, err
-//	}
-//
-//	return newMovieResponse(m), nil
-//}
-//
-//// FindAllMovies is used to list all movies in the db
-//func (fms FindMovieService) FindAllMovies(ctx context.Context) ([]MovieResponse, error) {
-//
-//	var response []MovieResponse
-//
-//	// Find the list of all Movies using the selector.FindAll method
-//	movies, err := fms.MovieSelector.FindAll(ctx)
-//	if err != nil {
-//		return response, err
-//	}
-//
-//	for _, m := range movies {
-//		mr := newMovieResponse(m)
-//		response = append(response, mr)
-//	}
-//
-//	return response, nil
-//}
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore"
+	"github.com/gilcrest/go-api-basic/datastore/moviestore"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/movie"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+// CreateMovieRequest is the request struct for Creating a Movie
+type CreateMovieRequest struct {
+	Title    string `json:"title"`
+	Rated    string `json:"rated"`
+	Released string `json:"release_date"`
+	RunTime  int    `json:"run_time"`
+	Director string `json:"director"`
+	Writer   string `json:"writer"`
+}
+
+// UpdateMovieRequest is the request struct for updating a Movie
+type UpdateMovieRequest struct {
+	ExternalID string
+	Title      string `json:"title"`
+	Rated      string `json:"rated"`
+	Released   string `json:"release_date"`
+	RunTime    int    `json:"run_time"`
+	Director   string `json:"director"`
+	Writer     string `json:"writer"`
+}
+
+// MovieResponse is the response struct for a Movie
+type MovieResponse struct {
+	ExternalID  string        `json:"external_id"`
+	Title       string        `json:"title"`
+	Rated       string        `json:"rated"`
+	Released    string        `json:"release_date"`
+	RunTime     int           `json:"run_time"`
+	Director    string        `json:"director"`
+	Writer      string        `json:"writer"`
+	CreateAudit auditResponse `json:"create_audit"`
+	UpdateAudit auditResponse `json:"update_audit"`
+}
+
+// DeleteMovieResponse is the response struct for deleted Movies
+type DeleteMovieResponse struct {
+	ExternalID string `json:"extl_id"`
+	Deleted    bool   `json:"deleted"`
+}
+
+// newMovieResponse initializes MovieResponse given a movie.Movie
+func newMovieResponse(m *movie.Movie) MovieResponse {
+	return MovieResponse{
+		ExternalID:  m.ExternalID,
+		Title:       m.Title,
+		Rated:       m.Rated,
+		Released:    m.Released.Format(time.RFC3339),
+		RunTime:     m.RunTime,
+		Director:    m.Director,
+		Writer:      m.Writer,
+		CreateAudit: newAuditResponse(m.CreateAudit),
+		UpdateAudit: newAuditResponse(m.UpdateAudit),
+	}
+}
+
+// CreateMovieService is a service for creating a Movie
+type CreateMovieService struct {
+	Datastorer Datastorer
+}
+
+// Create is used to create a Movie
+func (cms CreateMovieService) Create(ctx context.Context, r *CreateMovieRequest, adt audit.Audit) (MovieResponse, error) {
+	// Call the NewMovie method for struct initialization
+	m, err := movie.NewMovie(uuid.New(), secure.NewID().String(), adt)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	m, err = m.SetReleased(r.Released)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+	m.SetTitle(r.Title).
+		SetRated(r.Rated).
+		SetRunTime(r.RunTime).
+		SetDirector(r.Director).
+		SetWriter(r.Writer)
+
+	err = m.IsValid()
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	// start db txn using pgxpool
+	tx, err := cms.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	// create database record using moviestore
+	_, err = moviestore.New(tx).CreateMovie(ctx, newCreateMovieParams(m))
+	if err != nil {
+		return MovieResponse{}, errs.E(errs.Database, cms.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// commit db txn using pgxpool
+	err = cms.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	return newMovieResponse(m), nil
+}
+
+// newCreateMovieParams maps a Movie to moviestore.CreateMovieParams
+func newCreateMovieParams(m *movie.Movie) moviestore.CreateMovieParams {
+	return moviestore.CreateMovieParams{
+		MovieID:         m.ID,
+		MovieExtlID:     m.ExternalID,
+		Title:           m.Title,
+		Rated:           m.Rated,
+		Released:        m.Released,
+		RunTime:         int32(m.RunTime),
+		Director:        m.Director,
+		Writer:          m.Writer,
+		CreateAppID:     m.CreateAudit.App.ID,
+		CreateUserID:    datastore.NewNullUUID(m.CreateAudit.User.ID),
+		CreateTimestamp: m.CreateAudit.Moment,
+		UpdateAppID:     m.UpdateAudit.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(m.UpdateAudit.User.ID),
+		UpdateTimestamp: m.UpdateAudit.Moment,
+	}
+}
+
+// newUpdateMovieParams maps a Movie to moviestore.UpdateMovieParams
+func newUpdateMovieParams(m *movie.Movie) moviestore.UpdateMovieParams {
+	return moviestore.UpdateMovieParams{
+		Title:           m.Title,
+		Rated:           m.Rated,
+		Released:        m.Released,
+		RunTime:         int32(m.RunTime),
+		Director:        m.Director,
+		Writer:          m.Writer,
+		UpdateAppID:     m.UpdateAudit.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(m.UpdateAudit.User.ID),
+		UpdateTimestamp: m.UpdateAudit.Moment,
+		MovieID:         m.ID,
+	}
+}
+
+// UpdateMovieService is a service for updating a Movie
+type UpdateMovieService struct {
+	Datastorer Datastorer
+}
+
+// Update is used to update a movie
+func (ums UpdateMovieService) Update(ctx context.Context, r *UpdateMovieRequest, adt audit.Audit) (MovieResponse, error) {
+	// start db txn using pgxpool
+	tx, err := ums.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	// retrieve existing Movie
+	m, err := findMovieByExternalID(ctx, tx, r.ExternalID)
+	if err != nil {
+		return MovieResponse{}, ums.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// override fields with data from request
+	m, err = m.SetReleased(r.Released)
+	if err != nil {
+		return MovieResponse{}, ums.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	m.SetTitle(r.Title).
+		SetRated(r.Rated).
+		SetRunTime(r.RunTime).
+		SetDirector(r.Director).
+		SetWriter(r.Writer).
+		SetUpdateAudit(adt)
+
+	err = m.IsValid()
+	if err != nil {
+		return MovieResponse{}, ums.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// update database record using moviestore
+	err = moviestore.New(tx).UpdateMovie(ctx, newUpdateMovieParams(m))
+	if err != nil {
+		return MovieResponse{}, errs.E(errs.Database, ums.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// commit db txn using pgxpool
+	err = ums.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	return newMovieResponse(m), nil
+}
+
+// DeleteMovieService is a service for deleting a Movie
+type DeleteMovieService struct {
+	Datastorer Datastorer
+}
+
+// Delete is used to delete a movie
+func (dms DeleteMovieService) Delete(ctx context.Context, extlID string) (DeleteMovieResponse, error) {
+	// start db txn using pgxpool
+	tx, err := dms.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return DeleteMovieResponse{}, err
+	}
+
+	// retrieve existing Movie
+	m, err := findMovieByExternalID(ctx, tx, extlID)
+	if err != nil {
+		return DeleteMovieResponse{}, dms.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// delete database record using moviestore
+	err = moviestore.New(tx).DeleteMovie(ctx, m.ID)
+	if err != nil {
+		return DeleteMovieResponse{}, errs.E(errs.Database, dms.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// commit db txn using pgxpool
+	err = dms.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return DeleteMovieResponse{}, err
+	}
+
+	response := DeleteMovieResponse{
+		ExternalID: m.ExternalID,
+		Deleted:    true,
+	}
+
+	return response, nil
+}
+
+// FindMovieService is a service for reading Movies from the datastore
+type FindMovieService struct {
+	Datastorer Datastorer
+}
+
+// FindMovieByID is used to find an individual movie
+func (fms FindMovieService) FindMovieByID(ctx context.Context, extlID string) (MovieResponse, error) {
+	m, err := findMovieByExternalID(ctx, fms.Datastorer.Pool(), extlID)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	return newMovieResponse(m), nil
+}
+
+// FindAllMovies is used to list all movies in the datastore
+func (fms FindMovieService) FindAllMovies(ctx context.Context) ([]MovieResponse, error) {
+	dbtx := fms.Datastorer.Pool()
+
+	dbms, err := moviestore.New(dbtx).FindMovies(ctx)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	// the audits of all the movies are found together
+	l := newAuditLoader(dbtx)
+	for _, dbm := range dbms {
+		l.add(dbm.CreateAppID, dbm.CreateUserID.UUID)
+		l.add(dbm.UpdateAppID, dbm.UpdateUserID.UUID)
+	}
+
+	var response []MovieResponse
+	for _, dbm := range dbms {
+		var m *movie.Movie
+		m, err = hydrateMovieFromDB(ctx, l, dbm)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, newMovieResponse(m))
+	}
+
+	return response, nil
+}
+
+// findMovieByExternalID retrieves a Movie from the datastore given a unique external ID
+func findMovieByExternalID(ctx context.Context, dbtx DBTX, extlID string) (*movie.Movie, error) {
+	dbm, err := moviestore.New(dbtx).FindMovieByExternalID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(errs.NotExist, "No movie exists for the given external ID")
+		}
+		return nil, errs.E(errs.Database, err)
+	}
+
+	return hydrateMovieFromDB(ctx, newAuditLoader(dbtx), dbm)
+}
+
+// hydrateMovieFromDB initializes a movie.Movie given a moviestore.Movie.
+// The create/update App and User are retrieved from the datastore,
+// through the auditLoader, to populate the Movie audit fields.
+func hydrateMovieFromDB(ctx context.Context, l *auditLoader, dbm moviestore.Movie) (*movie.Movie, error) {
+	// queue the update audit so both audits are found together
+	l.add(dbm.UpdateAppID, dbm.UpdateUserID.UUID)
+	ca, err := l.audit(ctx, dbm.CreateAppID, dbm.CreateUserID.UUID, dbm.CreateTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	ua, err := l.audit(ctx, dbm.UpdateAppID, dbm.UpdateUserID.UUID, dbm.UpdateTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &movie.Movie{
+		ID:         dbm.MovieID,
+		ExternalID: dbm.MovieExtlID,
+		Title:      dbm.Title,
+		Rated:      dbm.Rated,
+		Released:   dbm.Released,
+		RunTime:    int(dbm.RunTime),
+		Director:   dbm.Director,
+		Writer:     dbm.Writer,
+		CreateAudit: ca,
+		UpdateAudit: ua,
+	}, nil
+}
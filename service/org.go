@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
@@ -10,6 +13,7 @@ import (
 	"github.com/gilcrest/go-api-basic/datastore"
 	"github.com/gilcrest/go-api-basic/datastore/orgstore"
 	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/auth"
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/org"
 	"github.com/gilcrest/go-api-basic/domain/secure"
@@ -29,66 +33,122 @@ type Datastorer interface {
 
 // CreateOrgRequest is the request struct for Creating a Movie
 type CreateOrgRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name                    string `json:"name"`
+	Description             string `json:"description"`
+	SelfRegistrationAllowed bool   `json:"self_registration_allowed"`
+	// ParentExternalID is optional, the Org is created as a
+	// sub-organization of the parent if given
+	ParentExternalID string `json:"parent_external_id"`
 }
 
 // OrgResponse is the response struct for a Movie
 type OrgResponse struct {
-	ExternalID  string        `json:"external_id"`
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	CreateAudit auditResponse `json:"create_audit"`
-	UpdateAudit auditResponse `json:"update_audit"`
+	ExternalID              string        `json:"external_id"`
+	Name                    string        `json:"name"`
+	Description             string        `json:"description"`
+	SelfRegistrationAllowed bool          `json:"self_registration_allowed"`
+	Archived                bool          `json:"archived"`
+	ParentExternalID        string        `json:"parent_external_id,omitempty"`
+	CreateAudit             auditResponse `json:"create_audit"`
+	UpdateAudit             auditResponse `json:"update_audit"`
 }
 
-// newOrgResponse initializes OrgResponse given an org.Org.
+// newOrgResponse initializes OrgResponse given an org.Org and the
+// external ID of its parent (empty for a top level Org).
 // org.Org does not embed create/update App and User (intentionally),
-// so these structs are retrieved from the datastore as well.
-func newOrgResponse(ctx context.Context, dbtx DBTX, o org.Org) (OrgResponse, error) {
-	createApp, err := findAppByID(ctx, dbtx, o.CreateAppID)
+// so these structs are retrieved from the datastore as well, through
+// the auditLoader.
+func newOrgResponse(ctx context.Context, l *auditLoader, o org.Org, parentExtlID string) (OrgResponse, error) {
+	// queue the update audit so both audits are found together
+	l.add(o.UpdateAppID, o.UpdateUserID)
+	ca, err := l.audit(ctx, o.CreateAppID, o.CreateUserID, o.CreateTime)
 	if err != nil {
 		return OrgResponse{}, err
 	}
-	createUser, err := findUserByID(ctx, dbtx, o.CreateUserID)
+	ua, err := l.audit(ctx, o.UpdateAppID, o.UpdateUserID, o.UpdateTime)
 	if err != nil {
 		return OrgResponse{}, err
 	}
-	updateApp, err := findAppByID(ctx, dbtx, o.UpdateAppID)
-	if err != nil {
-		return OrgResponse{}, err
+
+	return OrgResponse{
+		ExternalID:              o.ExternalID.String(),
+		Name:                    o.Name,
+		Description:             o.Description,
+		SelfRegistrationAllowed: o.Settings.SelfRegistration(false),
+		Archived:                o.Archived(),
+		ParentExternalID:        parentExtlID,
+		CreateAudit:             newAuditResponse(ca),
+		UpdateAudit:             newAuditResponse(ua),
+	}, nil
+}
+
+// newOrgResponses initializes an OrgResponse for each orgstore.Org.
+// The audits and parents of all the Orgs are found together.
+func newOrgResponses(ctx context.Context, dbtx DBTX, dbos []orgstore.Org) ([]OrgResponse, error) {
+	l := newAuditLoader(dbtx)
+	orgs := make([]org.Org, 0, len(dbos))
+	for _, dbo := range dbos {
+		o, err := newOrgFromDB(dbo)
+		if err != nil {
+			return nil, err
+		}
+		l.add(o.CreateAppID, o.CreateUserID)
+		l.add(o.UpdateAppID, o.UpdateUserID)
+		orgs = append(orgs, o)
 	}
-	updateUser, err := findUserByID(ctx, dbtx, o.UpdateUserID)
+
+	parents, err := findParentExternalIDs(ctx, dbtx, orgs...)
 	if err != nil {
-		return OrgResponse{}, err
+		return nil, err
 	}
-	ca := audit.Audit{
-		App:    createApp,
-		User:   createUser,
-		Moment: o.CreateTime,
+
+	response := make([]OrgResponse, 0, len(orgs))
+	for _, o := range orgs {
+		or, err := newOrgResponse(ctx, l, o, parents[o.ParentID])
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, or)
 	}
 
-	ua := audit.Audit{
-		App:    updateApp,
-		User:   updateUser,
-		Moment: o.UpdateTime,
+	return response, nil
+}
+
+// findParentExternalIDs returns the external IDs of the parents of
+// the Orgs, keyed by parent ID. Top level Orgs have no parent, the
+// external ID of uuid.Nil is empty.
+func findParentExternalIDs(ctx context.Context, dbtx DBTX, orgs ...org.Org) (map[uuid.UUID]string, error) {
+	parents := make(map[uuid.UUID]bool)
+	for _, o := range orgs {
+		if o.ParentID != uuid.Nil {
+			parents[o.ParentID] = true
+		}
 	}
 
-	return OrgResponse{
-		ExternalID:  o.ExternalID.String(),
-		Name:        o.Name,
-		Description: o.Description,
-		CreateAudit: newAuditResponse(ca),
-		UpdateAudit: newAuditResponse(ua),
-	}, nil
+	extlIDs := make(map[uuid.UUID]string, len(parents))
+	if len(parents) == 0 {
+		return extlIDs, nil
+	}
+	rows, err := orgstore.New(dbtx).FindOrgExtlIDsByIDs(ctx, keys(parents))
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	for _, row := range rows {
+		extlIDs[row.OrgID] = row.OrgExtlID
+	}
+
+	return extlIDs, nil
 }
 
 // CreateOrgService is a service for creating an Org
 type CreateOrgService struct {
-	Datastorer Datastorer
+	Datastorer    Datastorer
+	PolicyManager PolicyManager
 }
 
-// Create is used to create an Org
+// Create is used to create an Org. The parent must be the App's Org
+// or within its scope (see checkOrgInScope), and only the genesis Org
+// can create a top level Org.
 func (cos CreateOrgService) Create(ctx context.Context, r *CreateOrgRequest, adt audit.Audit) (OrgResponse, error) {
 
 	// initialize Org and inject dependent fields
@@ -103,7 +163,9 @@ func (cos CreateOrgService) Create(ctx context.Context, r *CreateOrgRequest, adt
 		UpdateAppID:  adt.App.ID,
 		UpdateUserID: adt.User.ID,
 		UpdateTime:   adt.Moment,
+		Settings:     org.NewSettings(),
 	}
+	o.Settings.SelfRegistrationAllowed = &r.SelfRegistrationAllowed
 
 	// start db txn using pgxpool
 	tx, err := cos.Datastorer.BeginTx(ctx)
@@ -111,8 +173,35 @@ func (cos CreateOrgService) Create(ctx context.Context, r *CreateOrgRequest, adt
 		return OrgResponse{}, err
 	}
 
+	var parentExtlID string
+	if r.ParentExternalID == "" {
+		// a top level Org is out of the scope of all but the
+		// genesis Org
+		var genesis bool
+		genesis, err = orgstore.New(tx).IsGenesisOrg(ctx, adt.App.Org.ID)
+		if err != nil {
+			return OrgResponse{}, errs.E(errs.Database, cos.Datastorer.RollbackTx(ctx, tx, err))
+		}
+		if !genesis {
+			return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, errs.Parameter("parent_external_id"), "only the genesis org can create a top level org"))
+		}
+	} else {
+		var parent org.Org
+		parent, err = findScopedParentOrg(ctx, tx, r.ParentExternalID, adt)
+		if err != nil {
+			return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, err)
+		}
+		o.ParentID = parent.ID
+		parentExtlID = parent.ExternalID.String()
+	}
+
+	params, err := NewCreateOrgParams(o)
+	if err != nil {
+		return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
 	// create database record using orgstore
-	_, err = orgstore.New(tx).CreateOrg(ctx, NewCreateOrgParams(o))
+	_, err = orgstore.New(tx).CreateOrg(ctx, params)
 	if err != nil {
 		return OrgResponse{}, errs.E(errs.Database, cos.Datastorer.RollbackTx(ctx, tx, err))
 	}
@@ -123,31 +212,48 @@ func (cos CreateOrgService) Create(ctx context.Context, r *CreateOrgRequest, adt
 		return OrgResponse{}, err
 	}
 
+	// the user creating the Org is granted the admin role in it
+	if adt.User.Username != "" {
+		err = grantOrgRole(cos.PolicyManager, adt.User.Username, auth.AdminRole, o)
+		if err != nil {
+			return OrgResponse{}, err
+		}
+	}
+
 	or := OrgResponse{
-		ExternalID:  o.ExternalID.String(),
-		Name:        o.Name,
-		Description: o.Description,
-		CreateAudit: newAuditResponse(adt),
-		UpdateAudit: newAuditResponse(adt),
+		ExternalID:              o.ExternalID.String(),
+		Name:                    o.Name,
+		Description:             o.Description,
+		SelfRegistrationAllowed: o.Settings.SelfRegistration(false),
+		ParentExternalID:        parentExtlID,
+		CreateAudit:             newAuditResponse(adt),
+		UpdateAudit:             newAuditResponse(adt),
 	}
 
 	return or, nil
 }
 
 // NewCreateOrgParams maps an Org to orgstore.CreateOrgParams
-func NewCreateOrgParams(o org.Org) orgstore.CreateOrgParams {
+func NewCreateOrgParams(o org.Org) (orgstore.CreateOrgParams, error) {
+	settings, err := json.Marshal(o.Settings)
+	if err != nil {
+		return orgstore.CreateOrgParams{}, errs.E(errs.Internal, err)
+	}
+
 	return orgstore.CreateOrgParams{
 		OrgID:           uuid.New(),
 		OrgExtlID:       o.ExternalID.String(),
 		OrgName:         o.Name,
 		OrgDescription:  o.Description,
+		Settings:        settings,
 		CreateAppID:     o.CreateAppID,
 		CreateUserID:    datastore.NewNullUUID(o.CreateUserID),
 		CreateTimestamp: o.CreateTime,
 		UpdateAppID:     o.UpdateAppID,
 		UpdateUserID:    datastore.NewNullUUID(o.UpdateUserID),
 		UpdateTimestamp: o.UpdateTime,
-	}
+		ParentOrgID:     datastore.NewNullUUID(o.ParentID),
+	}, nil
 }
 
 // newUpdateOrgParams maps an Org to orgstore.UpdateOrgParams
@@ -162,7 +268,9 @@ func newUpdateOrgParams(o org.Org) orgstore.UpdateOrgParams {
 	}
 }
 
-// UpdateOrgRequest is the request struct for Updating an Org
+// UpdateOrgRequest is the request struct for Updating an Org. The
+// Org's settings, e.g. self registration, are changed through the
+// org settings endpoint.
 type UpdateOrgRequest struct {
 	ExternalID  string
 	Name        string `json:"name"`
@@ -172,9 +280,13 @@ type UpdateOrgRequest struct {
 // UpdateOrgService is a service for updating an Org
 type UpdateOrgService struct {
 	Datastorer Datastorer
+	// SettingsCache is optional, the Org's cached Settings are
+	// deleted when the Org is updated
+	SettingsCache SettingsCache
 }
 
-// Update is used to update an Org
+// Update is used to update an Org. Only the App's Org and Orgs within
+// its scope (see checkOrgInScope) can be updated.
 func (cos UpdateOrgService) Update(ctx context.Context, r *UpdateOrgRequest, adt audit.Audit) (OrgResponse, error) {
 	// start db txn using pgxpool
 	tx, err := cos.Datastorer.BeginTx(ctx)
@@ -183,12 +295,20 @@ func (cos UpdateOrgService) Update(ctx context.Context, r *UpdateOrgRequest, adt
 	}
 
 	// retrieve existing Org
-	o, err := findOrgByExternalID(ctx, tx, r.ExternalID)
+	dbo, err := orgstore.New(tx).FindOrgByExtlID(ctx, r.ExternalID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return OrgResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+			return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.NotExist, "No org exists for the given external ID"))
 		}
-		return OrgResponse{}, errs.E(errs.Database, err)
+		return OrgResponse{}, errs.E(errs.Database, cos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+	err = checkOrgInScope(ctx, tx, dbo.OrgID, true, adt)
+	if err != nil {
+		return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	o, err := newOrgFromDB(dbo)
+	if err != nil {
+		return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, err)
 	}
 
 	// override fields with data from request
@@ -204,8 +324,13 @@ func (cos UpdateOrgService) Update(ctx context.Context, r *UpdateOrgRequest, adt
 		return OrgResponse{}, errs.E(errs.Database, cos.Datastorer.RollbackTx(ctx, tx, err))
 	}
 
+	parents, err := findParentExternalIDs(ctx, tx, o)
+	if err != nil {
+		return OrgResponse{}, cos.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
 	var or OrgResponse
-	or, err = newOrgResponse(ctx, tx, o)
+	or, err = newOrgResponse(ctx, newAuditLoader(tx), o, parents[o.ParentID])
 	if err != nil {
 		return OrgResponse{}, err
 	}
@@ -216,72 +341,578 @@ func (cos UpdateOrgService) Update(ctx context.Context, r *UpdateOrgRequest, adt
 		return OrgResponse{}, err
 	}
 
+	if cos.SettingsCache != nil {
+		cos.SettingsCache.Delete(o.ID)
+	}
+
 	return or, nil
 }
 
-// FindOrgService interface reads Orgs form the datastore
-type FindOrgService struct {
+// MoveOrgRequest is the request struct for moving an Org under
+// another parent
+type MoveOrgRequest struct {
+	ExternalID string
+	// ParentExternalID is the external ID of the new parent, empty
+	// to make the Org a top level Org
+	ParentExternalID string `json:"parent_external_id"`
+}
+
+// MoveOrgService is a service for moving an Org within the Org
+// hierarchy
+type MoveOrgService struct {
 	Datastorer Datastorer
 }
 
-// FindAll is used to list all orgs in the datastore
-func (fos FindOrgService) FindAll(ctx context.Context) ([]OrgResponse, error) {
+// Move moves an Org, along with its descendants, under a new parent
+// or to the top level. The hierarchy must remain a tree, an Org
+// cannot be moved under itself or one of its descendants. Moves are
+// made one at a time, so two concurrent moves cannot form a cycle
+// between them either.
+func (mos MoveOrgService) Move(ctx context.Context, r *MoveOrgRequest, adt audit.Audit) (OrgResponse, error) {
+	// start db txn using pgxpool
+	tx, err := mos.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return OrgResponse{}, err
+	}
+	q := orgstore.New(tx)
 
-	var (
-		dbos     []orgstore.Org
-		response []OrgResponse
-		err      error
-	)
+	err = q.LockOrgHierarchy(ctx)
+	if err != nil {
+		return OrgResponse{}, errs.E(errs.Database, mos.Datastorer.RollbackTx(ctx, tx, err))
+	}
 
-	dbtx := fos.Datastorer.Pool()
-	dbos, err = orgstore.New(dbtx).FindOrgs(ctx)
+	dbo, err := q.FindOrgByExtlID(ctx, r.ExternalID)
 	if err != nil {
-		return nil, errs.E(errs.Database, err)
+		if err == pgx.ErrNoRows {
+			return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.NotExist, "No org exists for the given external ID"))
+		}
+		return OrgResponse{}, errs.E(errs.Database, mos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+	o, err := newOrgFromDB(dbo)
+	if err != nil {
+		return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	err = checkOrgInScope(ctx, tx, o.ID, true, adt)
+	if err != nil {
+		return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, err)
 	}
 
-	for _, dbo := range dbos {
-		var (
-			or   OrgResponse
-			extl secure.Identifier
-		)
-		extl, err = secure.ParseIdentifier(dbo.OrgExtlID)
+	var parent org.Org
+	if r.ParentExternalID == "" {
+		// a top level Org is out of the scope of all but the
+		// genesis Org
+		var genesis bool
+		genesis, err = q.IsGenesisOrg(ctx, adt.App.Org.ID)
 		if err != nil {
-			return nil, err
+			return OrgResponse{}, errs.E(errs.Database, mos.Datastorer.RollbackTx(ctx, tx, err))
+		}
+		if !genesis {
+			return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, errs.Parameter("parent_external_id"), "only the genesis org can move an org to the top level"))
+		}
+	} else {
+		var genesis bool
+		genesis, err = q.IsGenesisOrg(ctx, o.ID)
+		if err != nil {
+			return OrgResponse{}, errs.E(errs.Database, mos.Datastorer.RollbackTx(ctx, tx, err))
+		}
+		if genesis {
+			return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "the genesis org cannot be moved under another org"))
 		}
 
-		// add data from db
-		o := org.Org{
-			ID:           dbo.OrgID,
-			ExternalID:   extl,
-			Name:         dbo.OrgName,
-			Description:  dbo.OrgDescription,
-			CreateAppID:  dbo.CreateAppID,
-			CreateUserID: dbo.CreateUserID.UUID,
-			CreateTime:   dbo.CreateTimestamp,
-			UpdateAppID:  dbo.UpdateAppID,
-			UpdateUserID: dbo.UpdateUserID.UUID,
+		parent, err = findScopedParentOrg(ctx, tx, r.ParentExternalID, adt)
+		if err != nil {
+			return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, err)
 		}
-		or, err = newOrgResponse(ctx, dbtx, o)
+
+		var ancestors []orgstore.FindOrgAncestorsRow
+		ancestors, err = q.FindOrgAncestors(ctx, parent.ID)
 		if err != nil {
-			return nil, err
+			return OrgResponse{}, errs.E(errs.Database, mos.Datastorer.RollbackTx(ctx, tx, err))
 		}
-		response = append(response, or)
+		ancestorIDs := make([]uuid.UUID, 0, len(ancestors))
+		for _, a := range ancestors {
+			ancestorIDs = append(ancestorIDs, a.OrgID)
+		}
+
+		err = checkOrgMove(o.ID, parent.ID, ancestorIDs)
+		if err != nil {
+			return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, err)
+		}
+	}
+
+	o.ParentID = parent.ID
+	o.UpdateAppID = adt.App.ID
+	o.UpdateUserID = adt.User.ID
+	o.UpdateTime = adt.Moment
+
+	err = q.UpdateOrgParent(ctx, orgstore.UpdateOrgParentParams{
+		ParentOrgID:     datastore.NewNullUUID(o.ParentID),
+		UpdateAppID:     o.UpdateAppID,
+		UpdateUserID:    datastore.NewNullUUID(o.UpdateUserID),
+		UpdateTimestamp: o.UpdateTime,
+		OrgID:           o.ID,
+	})
+	if err != nil {
+		return OrgResponse{}, errs.E(errs.Database, mos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	var parentExtlID string
+	if r.ParentExternalID != "" {
+		parentExtlID = parent.ExternalID.String()
+	}
+
+	or, err := newOrgResponse(ctx, newAuditLoader(tx), o, parentExtlID)
+	if err != nil {
+		return OrgResponse{}, mos.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = mos.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return OrgResponse{}, err
+	}
+
+	return or, nil
+}
+
+// checkOrgMove ensures moving the Org under the parent keeps the Org
+// hierarchy a tree, given the IDs of the parent's ancestors. An Org
+// moved under itself or one of its descendants would form a cycle.
+func checkOrgMove(orgID, parentID uuid.UUID, parentAncestorIDs []uuid.UUID) error {
+	if parentID == orgID {
+		return errs.E(errs.Validation, errs.Parameter("parent_external_id"), "an org cannot be its own parent")
+	}
+	for _, id := range parentAncestorIDs {
+		if id == orgID {
+			return errs.E(errs.Validation, errs.Parameter("parent_external_id"), "an org cannot be moved under one of its descendants")
+		}
+	}
+	return nil
+}
+
+// findParentOrg finds the Org given as the parent of another. An
+// archived Org cannot be a parent.
+func findParentOrg(ctx context.Context, dbtx DBTX, extlID string) (org.Org, error) {
+	dbo, err := orgstore.New(dbtx).FindOrgByExtlID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return org.Org{}, errs.E(errs.Validation, errs.Parameter("parent_external_id"), "No org exists for the given parent external ID")
+		}
+		return org.Org{}, errs.E(errs.Database, err)
+	}
+	if dbo.ArchiveTimestamp.Valid {
+		return org.Org{}, errs.E(errs.Validation, errs.Parameter("parent_external_id"), "an archived org cannot be a parent")
+	}
+
+	return newOrgFromDB(dbo)
+}
+
+// findScopedParentOrg finds the Org given as the parent of another,
+// which must be the App's Org or within its scope (see
+// checkOrgInScope)
+func findScopedParentOrg(ctx context.Context, dbtx DBTX, extlID string, adt audit.Audit) (org.Org, error) {
+	parent, err := findParentOrg(ctx, dbtx, extlID)
+	if err != nil {
+		return org.Org{}, err
+	}
+
+	err = checkOrgInScope(ctx, dbtx, parent.ID, true, adt)
+	if err != nil {
+		if errs.KindIs(errs.NotExist, err) {
+			return org.Org{}, errs.E(errs.Validation, errs.Parameter("parent_external_id"), "No org exists for the given parent external ID")
+		}
+		return org.Org{}, err
+	}
+
+	return parent, nil
+}
+
+// Org deletion modes
+const (
+	// ArchiveOrgMode soft deletes an Org: the Org is hidden from
+	// listings and its apps, API keys and users are deactivated, so
+	// none of them can authenticate. Nothing is removed.
+	ArchiveOrgMode = "archive"
+	// HardDeleteOrgMode removes an Org and everything belonging to it
+	HardDeleteOrgMode = "delete"
+)
+
+// DeleteOrgRequest is the request struct for deleting an Org
+type DeleteOrgRequest struct {
+	ExternalID string
+	// Mode is either ArchiveOrgMode (the default) or HardDeleteOrgMode
+	Mode string
+	// DryRun reports what would be affected without changing anything
+	DryRun bool
+}
+
+// OrgDependentsResponse is the number of records belonging to an Org
+type OrgDependentsResponse struct {
+	Apps           int64 `json:"apps"`
+	APIKeys        int64 `json:"api_keys"`
+	AppUsage       int64 `json:"app_usage"`
+	Users          int64 `json:"users"`
+	Persons        int64 `json:"persons"`
+	PersonProfiles int64 `json:"person_profiles"`
+	PolicyRules    int64 `json:"policy_rules"`
+}
+
+// OrgReferenceResponse is the number of records in a table outside
+// the Org whose audit columns reference the Org's apps or users
+type OrgReferenceResponse struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// DeleteOrgResponse is the response struct for deleting an Org
+type DeleteOrgResponse struct {
+	ExternalID string `json:"external_id"`
+	Mode       string `json:"mode"`
+	DryRun     bool   `json:"dry_run"`
+	// Affected is the records which are (or would be) deactivated
+	// when archiving or removed when deleting
+	Affected OrgDependentsResponse `json:"affected"`
+	// ExternalReferences is only reported when deleting. An Org with
+	// external references cannot be deleted, only archived.
+	ExternalReferences []OrgReferenceResponse `json:"external_references,omitempty"`
+	// ChildOrgs is the number of sub-organizations of the Org. An Org
+	// with sub-organizations cannot be deleted or archived until they
+	// are moved or deleted themselves.
+	ChildOrgs int64 `json:"child_orgs,omitempty"`
+}
+
+// DeleteOrgService is a service for deleting or archiving an Org
+type DeleteOrgService struct {
+	Datastorer    Datastorer
+	PolicyManager PolicyManager
+	// IdentityCache is optional, if set the users of the Org are
+	// removed from it
+	IdentityCache IdentityCache
+	// SettingsCache is optional, if set the Settings of the Org are
+	// removed from it
+	SettingsCache SettingsCache
+}
+
+// Delete archives or deletes an Org, depending on the request mode.
+// Deleting removes the Org's dependents in foreign key order within
+// a single transaction, followed by the Org's casbin policy rules.
+func (dos DeleteOrgService) Delete(ctx context.Context, r *DeleteOrgRequest, adt audit.Audit) (DeleteOrgResponse, error) {
+	if r.Mode == "" {
+		r.Mode = ArchiveOrgMode
+	}
+	if r.Mode != ArchiveOrgMode && r.Mode != HardDeleteOrgMode {
+		return DeleteOrgResponse{}, errs.E(errs.Validation, errs.Parameter("mode"), fmt.Sprintf("mode must be %s or %s", ArchiveOrgMode, HardDeleteOrgMode))
+	}
+
+	// start db txn using pgxpool
+	tx, err := dos.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return DeleteOrgResponse{}, err
+	}
+
+	dbo, err := orgstore.New(tx).FindOrgByExtlID(ctx, r.ExternalID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return DeleteOrgResponse{}, dos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.NotExist, "No org exists for the given external ID"))
+		}
+		return DeleteOrgResponse{}, errs.E(errs.Database, dos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+	if dbo.OrgID == adt.App.Org.ID {
+		return DeleteOrgResponse{}, dos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "an org cannot be deleted by one of its own apps"))
+	}
+	err = checkOrgInScope(ctx, tx, dbo.OrgID, false, adt)
+	if err != nil {
+		return DeleteOrgResponse{}, dos.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	deps, err := orgstore.New(tx).CountOrgDependents(ctx, dbo.OrgID)
+	if err != nil {
+		return DeleteOrgResponse{}, errs.E(errs.Database, dos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+	if deps.GenesisOrg {
+		return DeleteOrgResponse{}, dos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "the genesis org cannot be deleted"))
+	}
+
+	dom := dbo.OrgExtlID
+	response := DeleteOrgResponse{
+		ExternalID: dbo.OrgExtlID,
+		Mode:       r.Mode,
+		DryRun:     r.DryRun,
+		Affected: OrgDependentsResponse{
+			Apps:           deps.AppCount,
+			APIKeys:        deps.ApiKeyCount,
+			AppUsage:       deps.UsageCount,
+			Users:          deps.UserCount,
+			Persons:        deps.PersonCount,
+			PersonProfiles: deps.PersonProfileCount,
+			PolicyRules:    int64(len(dos.PolicyManager.GetFilteredPolicy(1, dom)) + len(dos.PolicyManager.GetFilteredGroupingPolicy(2, dom))),
+		},
+		ChildOrgs: deps.ChildOrgCount,
+	}
+
+	if r.Mode == HardDeleteOrgMode {
+		var refs []orgstore.FindOrgExternalReferencesRow
+		refs, err = orgstore.New(tx).FindOrgExternalReferences(ctx, dbo.OrgID)
+		if err != nil {
+			return DeleteOrgResponse{}, errs.E(errs.Database, dos.Datastorer.RollbackTx(ctx, tx, err))
+		}
+		for _, ref := range refs {
+			if ref.ReferenceCount > 0 {
+				response.ExternalReferences = append(response.ExternalReferences, OrgReferenceResponse{Table: ref.TableName, Rows: ref.ReferenceCount})
+			}
+		}
+	}
+
+	if r.DryRun {
+		err = tx.Rollback(ctx)
+		if err != nil {
+			return DeleteOrgResponse{}, errs.E(errs.Database, err)
+		}
+		return response, nil
+	}
+
+	if response.ChildOrgs > 0 {
+		return DeleteOrgResponse{}, dos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "org has child orgs, they must be moved or deleted first"))
+	}
+
+	userIDs, err := orgstore.New(tx).FindOrgUserIDs(ctx, dbo.OrgID)
+	if err != nil {
+		return DeleteOrgResponse{}, errs.E(errs.Database, dos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	switch r.Mode {
+	case ArchiveOrgMode:
+		err = archiveOrg(ctx, tx, dbo, adt)
+	case HardDeleteOrgMode:
+		if len(response.ExternalReferences) > 0 {
+			return DeleteOrgResponse{}, dos.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, fmt.Sprintf("org apps or users are referenced by records outside the org and cannot be deleted, use mode %s instead", ArchiveOrgMode)))
+		}
+		err = hardDeleteOrg(ctx, tx, dbo.OrgID)
+	}
+	if err != nil {
+		return DeleteOrgResponse{}, errs.E(errs.Database, dos.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// commit db txn using pgxpool
+	err = dos.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return DeleteOrgResponse{}, err
+	}
+
+	if r.Mode == HardDeleteOrgMode {
+		// casbin rules are not in the transaction, they are removed
+		// once the Org is gone so they never outlive it
+		_, err = dos.PolicyManager.RemoveFilteredPolicy(1, dom)
+		if err != nil {
+			return DeleteOrgResponse{}, errs.E(errs.Database, err)
+		}
+		_, err = dos.PolicyManager.RemoveFilteredGroupingPolicy(2, dom)
+		if err != nil {
+			return DeleteOrgResponse{}, errs.E(errs.Database, err)
+		}
+	}
+
+	if dos.IdentityCache != nil {
+		for _, id := range userIDs {
+			dos.IdentityCache.DeleteUser(id)
+		}
+	}
+	if dos.SettingsCache != nil {
+		dos.SettingsCache.Delete(dbo.OrgID)
+	}
+
+	return response, nil
+}
+
+// archiveOrg marks the Org as archived and deactivates its apps, API
+// keys and users. An Org which is already archived keeps its
+// original archive timestamp.
+func archiveOrg(ctx context.Context, tx pgx.Tx, dbo orgstore.Org, adt audit.Audit) error {
+	q := orgstore.New(tx)
+	updateUserID := datastore.NewNullUUID(adt.User.ID)
+
+	if !dbo.ArchiveTimestamp.Valid {
+		err := q.ArchiveOrg(ctx, orgstore.ArchiveOrgParams{
+			ArchiveTimestamp: sql.NullTime{Time: adt.Moment, Valid: true},
+			UpdateAppID:      adt.App.ID,
+			UpdateUserID:     updateUserID,
+			OrgID:            dbo.OrgID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := q.DeactivateOrgApps(ctx, orgstore.DeactivateOrgAppsParams{
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    updateUserID,
+		UpdateTimestamp: adt.Moment,
+		OrgID:           dbo.OrgID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = q.DeactivateOrgAPIKeys(ctx, orgstore.DeactivateOrgAPIKeysParams{
+		DeactvDate:   adt.Moment,
+		UpdateAppID:  adt.App.ID,
+		UpdateUserID: updateUserID,
+		OrgID:        dbo.OrgID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.DeactivateOrgUsers(ctx, orgstore.DeactivateOrgUsersParams{
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    updateUserID,
+		UpdateTimestamp: adt.Moment,
+		OrgID:           dbo.OrgID,
+	})
+}
+
+// hardDeleteOrg removes the Org and its dependents, children before
+// parents
+func hardDeleteOrg(ctx context.Context, tx pgx.Tx, orgID uuid.UUID) error {
+	q := orgstore.New(tx)
+	for _, del := range []func(context.Context, uuid.UUID) error{
+		q.DeleteOrgAppUsage,
+		q.DeleteOrgAPIKeys,
+		q.DeleteOrgUsers,
+		q.DeleteOrgPersonProfiles,
+		q.DeleteOrgPersons,
+		q.DeleteOrgApps,
+		q.DeleteOrg,
+	} {
+		if err := del(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindOrgService interface reads Orgs form the datastore
+type FindOrgService struct {
+	Datastorer Datastorer
+}
+
+// OrgListResponse is the response struct for a page of Orgs
+type OrgListResponse struct {
+	Data []OrgResponse `json:"data"`
+	PageCursors
+}
+
+// FindAll is used to page through the Orgs in the datastore, sorted
+// by name (the default) or created. Archived Orgs are not listed.
+// Only the App's Org and its descendants are listed, unless the App
+// belongs to the genesis Org.
+func (fos FindOrgService) FindAll(ctx context.Context, r ListRequest, adt audit.Audit) (OrgListResponse, error) {
+	p, err := newListPage(r, nameSort, createdSort)
+	if err != nil {
+		return OrgListResponse{}, err
+	}
+
+	dbtx := fos.Datastorer.Pool()
+	q := orgstore.New(dbtx)
+
+	rootOrgID, err := orgListRoot(ctx, dbtx, adt)
+	if err != nil {
+		return OrgListResponse{}, err
+	}
+
+	var dbos []orgstore.Org
+	switch p.field {
+	case createdSort:
+		params := orgstore.FindOrgsPageByCreateTimestampParams{
+			NamePattern:           p.namePattern,
+			CreatedAfter:          p.createdAfter,
+			CursorExtlID:          p.cursorExtlID(),
+			CursorCreateTimestamp: p.cursorTime,
+			RootOrgID:             rootOrgID,
+			RowLimit:              p.rowLimit(),
+		}
+		if p.descending() {
+			dbos, err = q.FindOrgsPageByCreateTimestampDesc(ctx, orgstore.FindOrgsPageByCreateTimestampDescParams(params))
+		} else {
+			dbos, err = q.FindOrgsPageByCreateTimestamp(ctx, params)
+		}
+	default:
+		params := orgstore.FindOrgsPageByNameParams{
+			NamePattern:  p.namePattern,
+			CreatedAfter: p.createdAfter,
+			CursorExtlID: p.cursorExtlID(),
+			CursorName:   p.cursorKey(),
+			RootOrgID:    rootOrgID,
+			RowLimit:     p.rowLimit(),
+		}
+		if p.descending() {
+			dbos, err = q.FindOrgsPageByNameDesc(ctx, orgstore.FindOrgsPageByNameDescParams(params))
+		} else {
+			dbos, err = q.FindOrgsPageByName(ctx, params)
+		}
+	}
+	if err != nil {
+		return OrgListResponse{}, errs.E(errs.Database, err)
+	}
+
+	n, more := p.trim(dbos)
+	dbos = dbos[:n]
+
+	response := OrgListResponse{
+		PageCursors: p.cursors(n, more, func(i int) (string, string) {
+			return p.sortKey(dbos[i].OrgName, dbos[i].CreateTimestamp), dbos[i].OrgExtlID
+		}),
+	}
+	response.Data, err = newOrgResponses(ctx, dbtx, dbos)
+	if err != nil {
+		return OrgListResponse{}, err
 	}
 
 	return response, nil
 }
 
-// FindByExternalID is used to find an Org by its External ID
-func (fos FindOrgService) FindByExternalID(ctx context.Context, extlID string) (OrgResponse, error) {
+// orgListRoot returns the Org whose subtree the App may list, none
+// (all Orgs) for the genesis Org
+func orgListRoot(ctx context.Context, dbtx DBTX, adt audit.Audit) (uuid.NullUUID, error) {
+	genesis, err := orgstore.New(dbtx).IsGenesisOrg(ctx, adt.App.Org.ID)
+	if err != nil {
+		return uuid.NullUUID{}, errs.E(errs.Database, err)
+	}
+	if genesis {
+		return uuid.NullUUID{}, nil
+	}
+	return uuid.NullUUID{UUID: adt.App.Org.ID, Valid: true}, nil
+}
+
+// FindByExternalID is used to find an Org by its External ID. Only
+// the App's Org and Orgs within its scope (see checkOrgInScope) can
+// be found.
+func (fos FindOrgService) FindByExternalID(ctx context.Context, extlID string, adt audit.Audit) (OrgResponse, error) {
 
 	dbtx := fos.Datastorer.Pool()
 
-	o, err := findOrgByExternalID(ctx, dbtx, extlID)
+	dbo, err := orgstore.New(dbtx).FindOrgByExtlID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return OrgResponse{}, errs.E(errs.NotExist, "No org exists for the given external ID")
+		}
+		return OrgResponse{}, errs.E(errs.Database, err)
+	}
+	err = checkOrgInScope(ctx, dbtx, dbo.OrgID, true, adt)
+	if err != nil {
+		return OrgResponse{}, err
+	}
+	o, err := newOrgFromDB(dbo)
+	if err != nil {
+		return OrgResponse{}, err
+	}
+
+	parents, err := findParentExternalIDs(ctx, dbtx, o)
 	if err != nil {
 		return OrgResponse{}, err
 	}
 
-	or, err := newOrgResponse(ctx, dbtx, o)
+	or, err := newOrgResponse(ctx, newAuditLoader(dbtx), o, parents[o.ParentID])
 	if err != nil {
 		return OrgResponse{}, err
 	}
@@ -289,59 +920,158 @@ func (fos FindOrgService) FindByExternalID(ctx context.Context, extlID string) (
 	return or, nil
 }
 
-// findOrgByID retrieves an Org from the datastore given a unique ID
-func findOrgByID(ctx context.Context, dbtx DBTX, id uuid.UUID) (org.Org, error) {
-	var (
-		dbo  orgstore.Org
-		extl secure.Identifier
-		err  error
-	)
+// OrgTreeResponse is the response struct for an Org within a subtree
+// of the Org hierarchy
+type OrgTreeResponse struct {
+	OrgResponse
+	// Depth is the number of levels the Org is below the root of
+	// the subtree, the root itself is at depth 0
+	Depth int `json:"depth"`
+}
 
-	dbo, err = orgstore.New(dbtx).FindOrgByID(ctx, id)
+// FindSubtree is used to find an Org and all of its descendants.
+// The Orgs are listed depth first, each followed by its children in
+// name order. Archived Orgs are included, they remain in the
+// hierarchy until deleted. Only the App's Org and Orgs within its
+// scope (see checkOrgInScope) can be found.
+func (fos FindOrgService) FindSubtree(ctx context.Context, extlID string, adt audit.Audit) ([]OrgTreeResponse, error) {
+	dbtx := fos.Datastorer.Pool()
+	q := orgstore.New(dbtx)
+
+	dbo, err := q.FindOrgByExtlID(ctx, extlID)
 	if err != nil {
-		return org.Org{}, errs.E(errs.Database, err)
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(errs.NotExist, "No org exists for the given external ID")
+		}
+		return nil, errs.E(errs.Database, err)
+	}
+	err = checkOrgInScope(ctx, dbtx, dbo.OrgID, true, adt)
+	if err != nil {
+		return nil, err
 	}
 
-	extl, err = secure.ParseIdentifier(dbo.OrgExtlID)
+	rows, err := q.FindOrgSubtree(ctx, dbo.OrgID)
 	if err != nil {
-		return org.Org{}, err
+		return nil, errs.E(errs.Database, err)
 	}
 
-	o := org.Org{
-		ID:           dbo.OrgID,
-		ExternalID:   extl,
-		Name:         dbo.OrgName,
-		Description:  dbo.OrgDescription,
-		CreateAppID:  dbo.CreateAppID,
-		CreateUserID: dbo.CreateUserID.UUID,
-		CreateTime:   dbo.CreateTimestamp,
-		UpdateAppID:  dbo.UpdateAppID,
-		UpdateUserID: dbo.UpdateUserID.UUID,
-		UpdateTime:   dbo.UpdateTimestamp,
+	dbos := make([]orgstore.Org, 0, len(rows))
+	for _, row := range rows {
+		dbos = append(dbos, orgstore.Org{
+			OrgID:            row.OrgID,
+			OrgExtlID:        row.OrgExtlID,
+			OrgName:          row.OrgName,
+			OrgDescription:   row.OrgDescription,
+			CreateAppID:      row.CreateAppID,
+			CreateUserID:     row.CreateUserID,
+			CreateTimestamp:  row.CreateTimestamp,
+			UpdateAppID:      row.UpdateAppID,
+			UpdateUserID:     row.UpdateUserID,
+			UpdateTimestamp:  row.UpdateTimestamp,
+			ArchiveTimestamp: row.ArchiveTimestamp,
+			ParentOrgID:      row.ParentOrgID,
+			Settings:         row.Settings,
+		})
+	}
+
+	ors, err := newOrgResponses(ctx, dbtx, dbos)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]OrgTreeResponse, 0, len(ors))
+	for i, or := range ors {
+		response = append(response, OrgTreeResponse{OrgResponse: or, Depth: int(rows[i].Depth)})
+	}
+
+	return response, nil
+}
+
+// checkOrgInScope ensures the App's Org can manage the Org: the
+// App's Org is the genesis Org or one of the Org's ancestors, or the
+// Org itself when self is true
+func checkOrgInScope(ctx context.Context, dbtx DBTX, orgID uuid.UUID, self bool, adt audit.Audit) error {
+	q := orgstore.New(dbtx)
+
+	genesis, err := q.IsGenesisOrg(ctx, adt.App.Org.ID)
+	if err != nil {
+		return errs.E(errs.Database, err)
 	}
 
-	return o, nil
+	var ancestorIDs []uuid.UUID
+	if !genesis {
+		var ancestors []orgstore.FindOrgAncestorsRow
+		ancestors, err = q.FindOrgAncestors(ctx, orgID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		ancestorIDs = make([]uuid.UUID, 0, len(ancestors))
+		for _, a := range ancestors {
+			ancestorIDs = append(ancestorIDs, a.OrgID)
+		}
+	}
+
+	return checkOrgScope(orgID, ancestorIDs, adt.App.Org.ID, genesis, self)
 }
 
-// findOrgByExternalID retrieves an Org from the datastore given a unique external ID
-func findOrgByExternalID(ctx context.Context, dbtx DBTX, extlID string) (org.Org, error) {
-	var (
-		dbo  orgstore.Org
-		extl secure.Identifier
-		err  error
-	)
+// checkOrgScope ensures the App's Org can manage the Org, given the
+// IDs of the Org's ancestors. An Org out of scope is reported as not
+// existing, as with Apps of another Org.
+func checkOrgScope(orgID uuid.UUID, ancestorIDs []uuid.UUID, appOrgID uuid.UUID, genesis, self bool) error {
+	if genesis || (self && orgID == appOrgID) {
+		return nil
+	}
+	for _, id := range ancestorIDs {
+		if id == appOrgID {
+			return nil
+		}
+	}
+	return errs.E(errs.NotExist, "No org exists for the given external ID")
+}
 
-	dbo, err = orgstore.New(dbtx).FindOrgByExtlID(ctx, extlID)
+// findOrgAncestors finds the external IDs of the ancestors of an
+// Org, its parent first
+func findOrgAncestors(ctx context.Context, dbtx DBTX, id uuid.UUID) ([]secure.Identifier, error) {
+	rows, err := orgstore.New(dbtx).FindOrgAncestors(ctx, id)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	ancestors := make([]secure.Identifier, 0, len(rows))
+	for _, row := range rows {
+		extl, err := secure.ParseIdentifier(row.OrgExtlID)
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, extl)
+	}
+
+	return ancestors, nil
+}
+
+// findOrgByID retrieves an Org from the datastore given a unique ID
+func findOrgByID(ctx context.Context, dbtx DBTX, id uuid.UUID) (org.Org, error) {
+	dbo, err := orgstore.New(dbtx).FindOrgByID(ctx, id)
 	if err != nil {
 		return org.Org{}, errs.E(errs.Database, err)
 	}
 
-	extl, err = secure.ParseIdentifier(dbo.OrgExtlID)
+	return newOrgFromDB(dbo)
+}
+
+// newOrgFromDB initializes an org.Org given an orgstore.Org
+func newOrgFromDB(dbo orgstore.Org) (org.Org, error) {
+	extl, err := secure.ParseIdentifier(dbo.OrgExtlID)
 	if err != nil {
 		return org.Org{}, err
 	}
 
-	o := org.Org{
+	settings, err := org.ParseSettings(dbo.Settings)
+	if err != nil {
+		return org.Org{}, err
+	}
+
+	return org.Org{
 		ID:           dbo.OrgID,
 		ExternalID:   extl,
 		Name:         dbo.OrgName,
@@ -352,7 +1082,8 @@ func findOrgByExternalID(ctx context.Context, dbtx DBTX, extlID string) (org.Org
 		UpdateAppID:  dbo.UpdateAppID,
 		UpdateUserID: dbo.UpdateUserID.UUID,
 		UpdateTime:   dbo.UpdateTimestamp,
-	}
-
-	return o, nil
+		ArchiveTime:  dbo.ArchiveTimestamp.Time,
+		ParentID:     dbo.ParentOrgID.UUID,
+		Settings:     settings,
+	}, nil
 }
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore"
+	"github.com/gilcrest/go-api-basic/datastore/orgstore"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/org"
+)
+
+// SettingsCache caches the Settings of each Org (see
+// settingscache.Cache) so that services can read a setting without
+// going to the datastore on every request
+type SettingsCache interface {
+	Get(orgID uuid.UUID) (org.Settings, bool)
+	Set(orgID uuid.UUID, s org.Settings)
+	// Delete invalidates the Settings of a changed or deleted Org
+	Delete(orgID uuid.UUID)
+}
+
+// findOrgSettings finds the Settings of an Org, from the cache if
+// present. The cache is optional.
+func findOrgSettings(ctx context.Context, dbtx DBTX, cache SettingsCache, orgID uuid.UUID) (org.Settings, error) {
+	if cache != nil {
+		if s, ok := cache.Get(orgID); ok {
+			return s, nil
+		}
+	}
+
+	b, err := orgstore.New(dbtx).FindOrgSettings(ctx, orgID)
+	if err != nil {
+		return org.Settings{}, errs.E(errs.Database, err)
+	}
+	s, err := org.ParseSettings(b)
+	if err != nil {
+		return org.Settings{}, err
+	}
+
+	if cache != nil {
+		cache.Set(orgID, s)
+	}
+
+	return s, nil
+}
+
+// OrgSettingsResponse is the response struct for the Settings of an
+// Org
+type OrgSettingsResponse struct {
+	ExternalID string       `json:"external_id"`
+	Settings   org.Settings `json:"settings"`
+}
+
+// PatchOrgSettingsRequest is the request struct for patching the
+// Settings of an Org. Patch is a JSON merge patch (RFC 7396) of the
+// Settings document.
+type PatchOrgSettingsRequest struct {
+	ExternalID string
+	Patch      json.RawMessage
+}
+
+// OrgSettingsService is a service for reading and changing the
+// Settings of an Org
+type OrgSettingsService struct {
+	Datastorer Datastorer
+	// SettingsCache is optional, the Org's cached Settings are
+	// deleted when they are patched
+	SettingsCache SettingsCache
+}
+
+// Find is used to find the Settings of an Org. Only the Settings of
+// the App's Org and Orgs within its scope (see checkOrgInScope) can
+// be found.
+func (oss OrgSettingsService) Find(ctx context.Context, extlID string, adt audit.Audit) (OrgSettingsResponse, error) {
+	dbtx := oss.Datastorer.Pool()
+
+	dbo, err := orgstore.New(dbtx).FindOrgByExtlID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return OrgSettingsResponse{}, errs.E(errs.NotExist, "No org exists for the given external ID")
+		}
+		return OrgSettingsResponse{}, errs.E(errs.Database, err)
+	}
+	err = checkOrgInScope(ctx, dbtx, dbo.OrgID, true, adt)
+	if err != nil {
+		return OrgSettingsResponse{}, err
+	}
+
+	s, err := org.ParseSettings(dbo.Settings)
+	if err != nil {
+		return OrgSettingsResponse{}, err
+	}
+
+	return OrgSettingsResponse{ExternalID: dbo.OrgExtlID, Settings: s}, nil
+}
+
+// Patch applies a JSON merge patch to the Settings of an Org. The
+// Org is locked while the Settings are patched, so concurrent
+// patches are applied one after the other. Only the Settings of the
+// App's Org and Orgs within its scope can be patched.
+func (oss OrgSettingsService) Patch(ctx context.Context, r *PatchOrgSettingsRequest, adt audit.Audit) (OrgSettingsResponse, error) {
+	// start db txn using pgxpool
+	tx, err := oss.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return OrgSettingsResponse{}, err
+	}
+	q := orgstore.New(tx)
+
+	row, err := q.FindOrgSettingsForUpdate(ctx, r.ExternalID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return OrgSettingsResponse{}, oss.Datastorer.RollbackTx(ctx, tx, errs.E(errs.NotExist, "No org exists for the given external ID"))
+		}
+		return OrgSettingsResponse{}, errs.E(errs.Database, oss.Datastorer.RollbackTx(ctx, tx, err))
+	}
+	err = checkOrgInScope(ctx, tx, row.OrgID, true, adt)
+	if err != nil {
+		return OrgSettingsResponse{}, oss.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	s, err := org.ParseSettings(row.Settings)
+	if err != nil {
+		return OrgSettingsResponse{}, oss.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	s, err = s.Patch(r.Patch)
+	if err != nil {
+		return OrgSettingsResponse{}, oss.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return OrgSettingsResponse{}, oss.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Internal, err))
+	}
+
+	err = q.UpdateOrgSettings(ctx, orgstore.UpdateOrgSettingsParams{
+		Settings:        b,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+		OrgID:           row.OrgID,
+	})
+	if err != nil {
+		return OrgSettingsResponse{}, errs.E(errs.Database, oss.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// commit db txn using pgxpool
+	err = oss.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return OrgSettingsResponse{}, err
+	}
+
+	if oss.SettingsCache != nil {
+		oss.SettingsCache.Delete(row.OrgID)
+	}
+
+	return OrgSettingsResponse{ExternalID: r.ExternalID, Settings: s}, nil
+}
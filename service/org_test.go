@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/go-api-basic/datastore/orgstore"
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/org"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+)
+
+func TestDeleteOrgService_Delete(t *testing.T) {
+	c := qt.New(t)
+
+	// the mode is validated before the datastore is used
+	dos := DeleteOrgService{}
+	_, err := dos.Delete(context.Background(), &DeleteOrgRequest{ExternalID: "abc", Mode: "purge"}, audit.Audit{})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
+
+func Test_checkOrgMove(t *testing.T) {
+	c := qt.New(t)
+
+	// a <- b <- c, each the parent of the next
+	a, b, cc := uuid.New(), uuid.New(), uuid.New()
+
+	// moving c under a, whose ancestors are none
+	c.Assert(checkOrgMove(cc, a, nil), qt.IsNil)
+	// moving a under itself
+	err := checkOrgMove(a, a, nil)
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+	// moving a under c, whose ancestors are b and a, is a cycle
+	err = checkOrgMove(a, cc, []uuid.UUID{b, a})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+	// moving b under a sibling, whose ancestor is a, is not
+	c.Assert(checkOrgMove(b, uuid.New(), []uuid.UUID{a}), qt.IsNil)
+}
+
+func Test_checkOrgScope(t *testing.T) {
+	c := qt.New(t)
+
+	// a <- b <- c, each the parent of the next, and another tenant d
+	a, b, cc, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	// the genesis org reaches every org
+	c.Assert(checkOrgScope(cc, nil, d, true, false), qt.IsNil)
+	// an org reaches its descendants
+	c.Assert(checkOrgScope(cc, []uuid.UUID{b, a}, a, false, false), qt.IsNil)
+	c.Assert(checkOrgScope(cc, []uuid.UUID{b, a}, b, false, false), qt.IsNil)
+	// and itself only when allowed
+	c.Assert(checkOrgScope(b, []uuid.UUID{a}, b, false, true), qt.IsNil)
+	err := checkOrgScope(b, []uuid.UUID{a}, b, false, false)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	// but not its ancestors
+	err = checkOrgScope(a, nil, b, false, true)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	// nor another tenant
+	err = checkOrgScope(cc, []uuid.UUID{b, a}, d, false, true)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+}
+
+// orgTreeDBTX is a DBTX which answers the Org hierarchy queries from
+// in memory Orgs
+type orgTreeDBTX struct {
+	orgs    map[uuid.UUID]orgstore.Org
+	genesis uuid.UUID
+}
+
+// add adds an Org under the parent, uuid.Nil for a top level Org
+func (db *orgTreeDBTX) add(parentID uuid.UUID) orgstore.Org {
+	o := orgstore.Org{
+		OrgID:       uuid.New(),
+		OrgExtlID:   secure.NewID().String(),
+		ParentOrgID: uuid.NullUUID{UUID: parentID, Valid: parentID != uuid.Nil},
+		Settings:    json.RawMessage(`{"version": 1}`),
+	}
+	db.orgs[o.OrgID] = o
+	return o
+}
+
+func (db *orgTreeDBTX) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return nil, fmt.Errorf("unexpected Exec: %s", sql)
+}
+
+func (db *orgTreeDBTX) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	switch {
+	case strings.HasPrefix(sql, "-- name: IsGenesisOrg "):
+		return &fakeRows{values: [][]interface{}{{args[0].(uuid.UUID) == db.genesis}}, i: 1}
+	case strings.HasPrefix(sql, "-- name: FindOrgByExtlID "):
+		for _, o := range db.orgs {
+			if o.OrgExtlID == args[0].(string) {
+				return &fakeRows{values: [][]interface{}{fieldValues(o)}, i: 1}
+			}
+		}
+		return &fakeRows{err: pgx.ErrNoRows}
+	}
+	return &fakeRows{err: fmt.Errorf("unexpected QueryRow: %s", sql)}
+}
+
+func (db *orgTreeDBTX) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if !strings.HasPrefix(sql, "-- name: FindOrgAncestors ") {
+		return nil, fmt.Errorf("unexpected Query: %s", sql)
+	}
+	rows := &fakeRows{}
+	o := db.orgs[args[0].(uuid.UUID)]
+	for depth := int32(1); o.ParentOrgID.Valid; depth++ {
+		o = db.orgs[o.ParentOrgID.UUID]
+		rows.values = append(rows.values, fieldValues(orgstore.FindOrgAncestorsRow{OrgID: o.OrgID, OrgExtlID: o.OrgExtlID, Depth: depth}))
+	}
+	return rows, nil
+}
+
+func Test_checkOrgInScope(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// the genesis org, tenant a with sub-organization b and tenant d
+	db := &orgTreeDBTX{orgs: make(map[uuid.UUID]orgstore.Org)}
+	db.genesis = db.add(uuid.Nil).OrgID
+	a := db.add(uuid.Nil)
+	b := db.add(a.OrgID)
+	d := db.add(uuid.Nil)
+
+	adtOf := func(o orgstore.Org) audit.Audit {
+		return audit.Audit{App: app.App{Org: org.Org{ID: o.OrgID}}}
+	}
+
+	c.Assert(checkOrgInScope(ctx, db, b.OrgID, false, adtOf(a)), qt.IsNil)
+	c.Assert(checkOrgInScope(ctx, db, a.OrgID, true, adtOf(a)), qt.IsNil)
+	c.Assert(checkOrgInScope(ctx, db, d.OrgID, false, adtOf(db.orgs[db.genesis])), qt.IsNil)
+
+	// another tenant is out of scope
+	err := checkOrgInScope(ctx, db, d.OrgID, true, adtOf(a))
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	err = checkOrgInScope(ctx, db, b.OrgID, true, adtOf(d))
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	// as is an ancestor
+	err = checkOrgInScope(ctx, db, a.OrgID, true, adtOf(b))
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+}
+
+func Test_orgListRoot(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	db := &orgTreeDBTX{orgs: make(map[uuid.UUID]orgstore.Org)}
+	db.genesis = db.add(uuid.Nil).OrgID
+	a := db.add(uuid.Nil)
+
+	// the genesis org lists every org
+	root, err := orgListRoot(ctx, db, audit.Audit{App: app.App{Org: org.Org{ID: db.genesis}}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(root.Valid, qt.IsFalse)
+
+	// other orgs only list their own subtree
+	root, err = orgListRoot(ctx, db, audit.Audit{App: app.App{Org: org.Org{ID: a.OrgID}}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(root, qt.Equals, uuid.NullUUID{UUID: a.OrgID, Valid: true})
+}
+
+func Test_findScopedParentOrg(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	db := &orgTreeDBTX{orgs: make(map[uuid.UUID]orgstore.Org)}
+	db.genesis = db.add(uuid.Nil).OrgID
+	a := db.add(uuid.Nil)
+	b := db.add(a.OrgID)
+	d := db.add(uuid.Nil)
+	adt := audit.Audit{App: app.App{Org: org.Org{ID: a.OrgID}}}
+
+	parent, err := findScopedParentOrg(ctx, db, b.OrgExtlID, adt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(parent.ID, qt.Equals, b.OrgID)
+	parent, err = findScopedParentOrg(ctx, db, a.OrgExtlID, adt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(parent.ID, qt.Equals, a.OrgID)
+
+	// an org cannot be created or moved under another tenant, which
+	// would give the other tenant's roles over it
+	_, err = findScopedParentOrg(ctx, db, d.OrgExtlID, adt)
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
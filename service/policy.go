@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gilcrest/go-api-basic/datastore/orgstore"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/auth"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/org"
+)
+
+// Casbin policy types
+const (
+	// policyType is the casbin ptype for a policy rule
+	// (role, domain, object, action)
+	policyType = "p"
+	// groupingPolicyType is the casbin ptype for a role assignment
+	// (user, role, domain)
+	groupingPolicyType = "g"
+)
+
+// PolicyManager reads and changes casbin policy rules. Changes are
+// persisted by the enforcer's adapter and announced to other
+// instances through its watcher.
+type PolicyManager interface {
+	GetFilteredPolicy(fieldIndex int, fieldValues ...string) [][]string
+	GetFilteredGroupingPolicy(fieldIndex int, fieldValues ...string) [][]string
+	AddPolicy(params ...interface{}) (bool, error)
+	RemovePolicy(params ...interface{}) (bool, error)
+	AddGroupingPolicy(params ...interface{}) (bool, error)
+	RemoveGroupingPolicy(params ...interface{}) (bool, error)
+	RemoveFilteredPolicy(fieldIndex int, fieldValues ...string) (bool, error)
+	RemoveFilteredGroupingPolicy(fieldIndex int, fieldValues ...string) (bool, error)
+	GetImplicitRolesForUser(name string, domain ...string) ([]string, error)
+}
+
+// PolicyRequest is the request struct for adding or removing a
+// casbin policy rule. Rules are always added to or removed from the
+// domain of the caller's Org.
+type PolicyRequest struct {
+	// PType is either p (a policy rule) or g (a role assignment)
+	PType string `json:"ptype"`
+	// Rule is the rule values, (role, object, action) for a
+	// policy rule or (user, role) for a role assignment
+	Rule []string `json:"rule"`
+}
+
+// PolicyResponse is the response struct for a casbin policy rule
+type PolicyResponse struct {
+	PType string `json:"ptype"`
+	// Domain is the Org external ID the rule applies to, or *
+	// for rules which apply to every Org
+	Domain string   `json:"domain"`
+	Rule   []string `json:"rule"`
+}
+
+// PermissionsResponse is the response struct for the permissions of
+// the current User within the App's Org
+type PermissionsResponse struct {
+	Roles       []string             `json:"roles"`
+	Permissions []PermissionResponse `json:"permissions"`
+}
+
+// PermissionResponse is an action the User may take on a resource.
+// Resource is the path template of the route, e.g. /api/v1/users/{extlID}
+type PermissionResponse struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// validate ensures the request has a known ptype and the number of
+// rule values that ptype requires
+func (r *PolicyRequest) validate() error {
+	var n int
+	switch r.PType {
+	case policyType:
+		n = 3
+	case groupingPolicyType:
+		n = 2
+	default:
+		return errs.E(errs.Validation, errs.Parameter("ptype"), fmt.Sprintf("ptype must be %s or %s", policyType, groupingPolicyType))
+	}
+	if len(r.Rule) != n {
+		return errs.E(errs.Validation, errs.Parameter("rule"), fmt.Sprintf("rule for ptype %s must have %d values", r.PType, n))
+	}
+	for _, v := range r.Rule {
+		if strings.TrimSpace(v) == "" {
+			return errs.E(errs.Validation, errs.Parameter("rule"), "rule values cannot be empty")
+		}
+	}
+	return nil
+}
+
+// casbinRule returns the request rule with the domain placed where
+// the model expects it for the ptype
+func (r *PolicyRequest) casbinRule(dom string) []string {
+	if r.PType == policyType {
+		return []string{r.Rule[0], dom, r.Rule[1], r.Rule[2]}
+	}
+	return []string{r.Rule[0], r.Rule[1], dom}
+}
+
+// newPolicyResponse initializes PolicyResponse given a casbin rule
+// as stored by the enforcer
+func newPolicyResponse(ptype string, rule []string) PolicyResponse {
+	if ptype == policyType {
+		return PolicyResponse{PType: ptype, Domain: rule[1], Rule: []string{rule[0], rule[2], rule[3]}}
+	}
+	return PolicyResponse{PType: ptype, Domain: rule[2], Rule: []string{rule[0], rule[1]}}
+}
+
+// PolicyService is a service for managing casbin policy rules at runtime
+type PolicyService struct {
+	Datastorer    Datastorer
+	PolicyManager PolicyManager
+}
+
+// FindAll returns the policy rules which apply to the Org (including
+// those which apply to every Org) and the Org's role assignments
+func (ps PolicyService) FindAll(o org.Org) []PolicyResponse {
+	dom := o.ExternalID.String()
+
+	var response []PolicyResponse
+	for _, d := range []string{auth.AnyDomain, dom} {
+		for _, rule := range ps.PolicyManager.GetFilteredPolicy(1, d) {
+			response = append(response, newPolicyResponse(policyType, rule))
+		}
+	}
+	for _, rule := range ps.PolicyManager.GetFilteredGroupingPolicy(2, dom) {
+		response = append(response, newPolicyResponse(groupingPolicyType, rule))
+	}
+	return response
+}
+
+// FindPermissions returns the roles of the current User within the
+// App's Org (including those granted in its ancestors) and the
+// actions those roles allow which the App's scopes allow as well.
+// The policy rules themselves are not returned.
+func (ps PolicyService) FindPermissions(adt audit.Audit) (PermissionsResponse, error) {
+	dom := adt.App.Org.ExternalID.String()
+
+	roles, err := orgRoles(ps.PolicyManager, adt.User.Username, adt.App.Org)
+	if err != nil {
+		return PermissionsResponse{}, errs.E(errs.Internal, err)
+	}
+	sort.Strings(roles)
+
+	seen := make(map[PermissionResponse]bool)
+	permissions := make([]PermissionResponse, 0)
+	for _, sub := range append([]string{adt.User.Username}, roles...) {
+		for _, d := range []string{auth.AnyDomain, dom} {
+			for _, rule := range ps.PolicyManager.GetFilteredPolicy(0, sub, d) {
+				p := PermissionResponse{Resource: rule[2], Action: rule[3]}
+				if seen[p] || !adt.App.Scopes.Allow(auth.ScopeResource(p.Resource), p.Action) {
+					continue
+				}
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].Resource != permissions[j].Resource {
+			return permissions[i].Resource < permissions[j].Resource
+		}
+		return permissions[i].Action < permissions[j].Action
+	})
+
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	return PermissionsResponse{Roles: roles, Permissions: permissions}, nil
+}
+
+// Add adds a policy rule or role assignment to the Org. Policy rules
+// for objects without a rule in every Org are for the genesis Org only
+// (see checkPolicyObject).
+func (ps PolicyService) Add(ctx context.Context, o org.Org, r *PolicyRequest) (PolicyResponse, error) {
+	err := r.validate()
+	if err != nil {
+		return PolicyResponse{}, err
+	}
+	err = ps.checkPolicyObject(ctx, o, r)
+	if err != nil {
+		return PolicyResponse{}, err
+	}
+	rule := r.casbinRule(o.ExternalID.String())
+
+	var added bool
+	switch r.PType {
+	case policyType:
+		added, err = ps.PolicyManager.AddPolicy(rule)
+	case groupingPolicyType:
+		added, err = ps.PolicyManager.AddGroupingPolicy(rule)
+	}
+	if err != nil {
+		return PolicyResponse{}, errs.E(errs.Database, err)
+	}
+	if !added {
+		return PolicyResponse{}, errs.E(errs.Exist, "policy rule already exists")
+	}
+
+	return newPolicyResponse(r.PType, rule), nil
+}
+
+// Remove removes a policy rule or role assignment from the Org, with
+// the same limits as Add
+func (ps PolicyService) Remove(ctx context.Context, o org.Org, r *PolicyRequest) (PolicyResponse, error) {
+	err := r.validate()
+	if err != nil {
+		return PolicyResponse{}, err
+	}
+	err = ps.checkPolicyObject(ctx, o, r)
+	if err != nil {
+		return PolicyResponse{}, err
+	}
+	rule := r.casbinRule(o.ExternalID.String())
+
+	var removed bool
+	switch r.PType {
+	case policyType:
+		removed, err = ps.PolicyManager.RemovePolicy(rule)
+	case groupingPolicyType:
+		removed, err = ps.PolicyManager.RemoveGroupingPolicy(rule)
+	}
+	if err != nil {
+		return PolicyResponse{}, errs.E(errs.Database, err)
+	}
+	if !removed {
+		return PolicyResponse{}, errs.E(errs.NotExist, "policy rule does not exist")
+	}
+
+	return newPolicyResponse(r.PType, rule), nil
+}
+
+// checkPolicyObject ensures the Org may manage a policy rule for the
+// request object. Any Org may manage rules for the objects which have
+// a rule in every Org (domain *), as the services behind them are
+// scoped to the Org. Rules for other objects, e.g. the routes across
+// all Orgs, can only be managed by the genesis Org. Role assignments
+// are not limited.
+func (ps PolicyService) checkPolicyObject(ctx context.Context, o org.Org, r *PolicyRequest) error {
+	if r.PType != policyType || len(ps.PolicyManager.GetFilteredPolicy(1, auth.AnyDomain, r.Rule[1])) > 0 {
+		return nil
+	}
+	return checkGenesisPolicyObject(ctx, ps.Datastorer.Pool(), o, r.Rule[1])
+}
+
+// checkGenesisPolicyObject ensures the Org is the genesis Org, which
+// alone may manage policy rules for the object
+func checkGenesisPolicyObject(ctx context.Context, dbtx DBTX, o org.Org, obj string) error {
+	genesis, err := orgstore.New(dbtx).IsGenesisOrg(ctx, o.ID)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	if !genesis {
+		return errs.E(errs.Validation, errs.Parameter("rule"), fmt.Sprintf("policy rules for %s can only be managed by the genesis org", obj))
+	}
+	return nil
+}
+
+// orgRoles returns the roles of the user within the Org, including
+// the roles granted in any of its ancestors
+func orgRoles(pm PolicyManager, username string, o org.Org) ([]string, error) {
+	doms := []string{o.ExternalID.String()}
+	for _, a := range o.Ancestors {
+		doms = append(doms, a.String())
+	}
+
+	seen := make(map[string]bool)
+	var roles []string
+	for _, d := range doms {
+		rs, err := pm.GetImplicitRolesForUser(username, d)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			if !seen[r] {
+				seen[r] = true
+				roles = append(roles, r)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// grantOrgRole grants the user the role in the Org
+func grantOrgRole(pm PolicyManager, username, role string, o org.Org) error {
+	_, err := pm.AddGroupingPolicy(username, role, o.ExternalID.String())
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	return nil
+}
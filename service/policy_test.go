@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/datastore/orgstore"
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/auth"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/org"
+	"github.com/gilcrest/go-api-basic/domain/secure"
+	"github.com/gilcrest/go-api-basic/domain/user"
+)
+
+func TestPolicyService(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// enforcer without an adapter, policy is kept in memory only
+	e, err := casbin.NewSyncedEnforcer("../config/rbac_model.conf")
+	c.Assert(err, qt.IsNil)
+	ps := PolicyService{PolicyManager: e}
+
+	o1 := org.Org{ExternalID: secure.NewID()}
+	o2 := org.Org{ExternalID: secure.NewID()}
+	dom1 := o1.ExternalID.String()
+	dom2 := o2.ExternalID.String()
+
+	// a rule which applies to every Org
+	_, err = e.AddPolicy("admin", auth.AnyDomain, "/api/v1/users", "read")
+	c.Assert(err, qt.IsNil)
+
+	p := &PolicyRequest{PType: "p", Rule: []string{"user", "/api/v1/users", "read"}}
+	g := &PolicyRequest{PType: "g", Rule: []string{"otto.maddox711@gmail.com", "user"}}
+
+	_, err = ps.Add(ctx, o1, p)
+	c.Assert(err, qt.IsNil)
+	gr, err := ps.Add(ctx, o1, g)
+	c.Assert(err, qt.IsNil)
+	c.Assert(gr, qt.DeepEquals, PolicyResponse{PType: "g", Domain: dom1, Rule: g.Rule})
+
+	_, err = ps.Add(ctx, o1, p)
+	c.Assert(errs.KindIs(errs.Exist, err), qt.IsTrue)
+
+	// user in o1 only
+	for _, tt := range []struct {
+		dom  string
+		want bool
+	}{
+		{dom1, true},
+		{dom2, false},
+	} {
+		ok, err := e.Enforce("otto.maddox711@gmail.com", tt.dom, "/api/v1/users", "read")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ok, qt.Equals, tt.want, qt.Commentf("%s", tt.dom))
+	}
+
+	c.Assert(ps.FindAll(o1), qt.DeepEquals, []PolicyResponse{
+		{PType: "p", Domain: auth.AnyDomain, Rule: []string{"admin", "/api/v1/users", "read"}},
+		{PType: "p", Domain: dom1, Rule: p.Rule},
+		{PType: "g", Domain: dom1, Rule: g.Rule},
+	})
+	c.Assert(ps.FindAll(o2), qt.DeepEquals, []PolicyResponse{
+		{PType: "p", Domain: auth.AnyDomain, Rule: []string{"admin", "/api/v1/users", "read"}},
+	})
+
+	// o2 cannot remove the grant made in o1
+	_, err = ps.Remove(ctx, o2, g)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	_, err = ps.Remove(ctx, o1, g)
+	c.Assert(err, qt.IsNil)
+
+	ok, err := e.Enforce("otto.maddox711@gmail.com", dom1, "/api/v1/users", "read")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestPolicyService_FindPermissions(t *testing.T) {
+	c := qt.New(t)
+
+	e, err := casbin.NewSyncedEnforcer("../config/rbac_model.conf")
+	c.Assert(err, qt.IsNil)
+	ps := PolicyService{PolicyManager: e}
+
+	o1 := org.Org{ExternalID: secure.NewID()}
+	o2 := org.Org{ExternalID: secure.NewID()}
+	dom1 := o1.ExternalID.String()
+
+	for _, rule := range [][]string{
+		{"admin", auth.AnyDomain, "/api/v1/users", "read"},
+		{"admin", auth.AnyDomain, "/api/v1/users", "write"},
+		{"user", auth.AnyDomain, "/api/v1/movies", "read"},
+		{"admin", dom1, "/api/v1/orgs", "read"},
+		// granted to the role twice, listed once
+		{"admin", dom1, "/api/v1/users", "read"},
+	} {
+		_, err = e.AddPolicy(rule)
+		c.Assert(err, qt.IsNil)
+	}
+	_, err = e.AddGroupingPolicy("otto.maddox711@gmail.com", "admin", dom1)
+	c.Assert(err, qt.IsNil)
+
+	u := user.User{Username: "otto.maddox711@gmail.com"}
+	full := app.Scopes{app.FullAccess}
+
+	got, err := ps.FindPermissions(audit.Audit{App: app.App{Org: o1, Scopes: full}, User: u})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, PermissionsResponse{
+		Roles: []string{"admin"},
+		Permissions: []PermissionResponse{
+			{Resource: "/api/v1/orgs", Action: "read"},
+			{Resource: "/api/v1/users", Action: "read"},
+			{Resource: "/api/v1/users", Action: "write"},
+		},
+	})
+
+	// only the permissions the App's scopes allow as well
+	got, err = ps.FindPermissions(audit.Audit{App: app.App{Org: o1, Scopes: app.Scopes{"users:read"}}, User: u})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, PermissionsResponse{
+		Roles:       []string{"admin"},
+		Permissions: []PermissionResponse{{Resource: "/api/v1/users", Action: "read"}},
+	})
+
+	// no role in o2
+	got, err = ps.FindPermissions(audit.Audit{App: app.App{Org: o2, Scopes: full}, User: u})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, PermissionsResponse{Roles: []string{}, Permissions: []PermissionResponse{}})
+
+	// the admin role of o1 is inherited by its sub-organization,
+	// with the permissions the role has in the sub-organization
+	sub := org.Org{ExternalID: secure.NewID(), Ancestors: []secure.Identifier{o1.ExternalID}}
+	got, err = ps.FindPermissions(audit.Audit{App: app.App{Org: sub, Scopes: full}, User: u})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, PermissionsResponse{
+		Roles: []string{"admin"},
+		Permissions: []PermissionResponse{
+			{Resource: "/api/v1/users", Action: "read"},
+			{Resource: "/api/v1/users", Action: "write"},
+		},
+	})
+}
+
+func Test_checkGenesisPolicyObject(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	db := &orgTreeDBTX{orgs: make(map[uuid.UUID]orgstore.Org)}
+	db.genesis = db.add(uuid.Nil).OrgID
+	a := db.add(uuid.Nil)
+
+	// only the genesis org manages rules for routes across all orgs
+	c.Assert(checkGenesisPolicyObject(ctx, db, org.Org{ID: db.genesis}, "/api/v1/orgs"), qt.IsNil)
+	err := checkGenesisPolicyObject(ctx, db, org.Org{ID: a.OrgID}, "/api/v1/orgs")
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
+
+func Test_grantOrgRole(t *testing.T) {
+	c := qt.New(t)
+
+	e, err := casbin.NewSyncedEnforcer("../config/rbac_model.conf")
+	c.Assert(err, qt.IsNil)
+
+	o := org.Org{ExternalID: secure.NewID()}
+	dom := o.ExternalID.String()
+
+	// roles are only those granted, the first user is not made admin
+	c.Assert(grantOrgRole(e, "otto", auth.UserRole, o), qt.IsNil)
+	c.Assert(grantOrgRole(e, "leila", auth.AdminRole, o), qt.IsNil)
+
+	c.Assert(e.GetFilteredGroupingPolicy(2, dom), qt.DeepEquals, [][]string{
+		{"otto", auth.UserRole, dom},
+		{"leila", auth.AdminRole, dom},
+	})
+}
+
+func TestPolicyRequest_validate(t *testing.T) {
+	tests := []struct {
+		name string
+		r    PolicyRequest
+		ok   bool
+	}{
+		{"policy", PolicyRequest{PType: "p", Rule: []string{"admin", "/api/v1/users", "read"}}, true},
+		{"grouping", PolicyRequest{PType: "g", Rule: []string{"otto", "admin"}}, true},
+		{"unknown ptype", PolicyRequest{PType: "x", Rule: []string{"otto", "admin"}}, false},
+		{"too few values", PolicyRequest{PType: "p", Rule: []string{"admin", "/api/v1/users"}}, false},
+		{"empty value", PolicyRequest{PType: "g", Rule: []string{"otto", " "}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			err := tt.r.validate()
+			if tt.ok {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/ratelimit"
+	"github.com/gilcrest/go-api-basic/domain/user"
+)
+
+// RateLimitStore keeps the token buckets used for rate limiting.
+// ratelimit.MemoryStore keeps them in memory for a single instance,
+// ratelimitstore.Store keeps them in the database to share them
+// across instances.
+type RateLimitStore interface {
+	// Take takes a token from the bucket for the key, the bucket
+	// holds up to limit tokens and is refilled at limit tokens per
+	// minute
+	Take(ctx context.Context, key string, limit int, now time.Time) (ratelimit.Result, error)
+}
+
+// RateLimitService limits the rate of requests of an App, and
+// optionally of each User of the App
+type RateLimitService struct {
+	Store RateLimitStore
+	// DefaultLimit is the number of requests per minute allowed for
+	// an App without its own limit, zero or less for no limit
+	DefaultLimit int
+	// PerUser limits each User of an App separately, rather than all
+	// requests of the App together
+	PerUser bool
+}
+
+// Allow takes a token from the bucket of the App (and User, if
+// limited per User). If the limit has been reached, an error of Kind
+// errs.TooManyRequests is returned along with the Result.
+func (rls RateLimitService) Allow(ctx context.Context, a app.App, u user.User) (ratelimit.Result, error) {
+	limit := a.RateLimit
+	if limit == 0 {
+		limit = rls.DefaultLimit
+	}
+	if limit <= 0 {
+		return ratelimit.Result{Allowed: true}, nil
+	}
+
+	key := "app:" + a.ExternalID.String()
+	if rls.PerUser && u.Username != "" {
+		key += " user:" + u.Username
+	}
+
+	r, err := rls.Store.Take(ctx, key, limit, time.Now())
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	if !r.Allowed {
+		return r, errs.E(errs.TooManyRequests, errs.RetryAfter(r.RetryAfter), "rate limit exceeded")
+	}
+
+	return r, nil
+}
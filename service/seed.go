@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/gilcrest/go-api-basic/datastore/userstore"
 	"github.com/gilcrest/go-api-basic/domain/app"
 	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/auth"
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/org"
 	"github.com/gilcrest/go-api-basic/domain/person"
@@ -43,7 +45,14 @@ type SeedResponse struct {
 type SeedService struct {
 	Datastorer            Datastorer
 	CryptoRandomGenerator CryptoRandomGenerator
-	EncryptionKey         *[32]byte
+	// APIKeySecret is the server secret API keys are hashed with
+	APIKeySecret *[32]byte
+	// KMS wraps the data key each new API key is hashed with, if nil
+	// new API keys are hashed with APIKeySecret
+	KMS secure.KMS
+	// PolicyManager is used to grant the seed user the admin role
+	// in the seed Org
+	PolicyManager PolicyManager
 }
 
 // Seed method seeds the database
@@ -69,6 +78,7 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 		ExternalID:  secure.NewID(),
 		Name:        r.OrgName,
 		Description: r.OrgDescription,
+		Settings:    org.NewSettings(),
 	}
 
 	// initialize App and inject dependent fields
@@ -78,11 +88,13 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 		Org:         o,
 		Name:        r.AppName,
 		Description: r.AppDescription,
+		Active:      true,
+		Scopes:      app.Scopes{app.FullAccess},
 		APIKeys:     nil,
 	}
 
 	// generate App API key
-	aak, err := app.NewAPIKey(sr.CryptoRandomGenerator, sr.EncryptionKey)
+	aak, err := app.NewAPIKey(ctx, sr.CryptoRandomGenerator, sr.APIKeySecret, sr.KMS)
 	if err != nil {
 		return SeedResponse{}, errs.E(errs.Internal, sr.Datastorer.RollbackTx(ctx, tx, err))
 	}
@@ -102,10 +114,12 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 
 	// create User
 	u := user.User{
-		ID:       uuid.New(),
-		Username: strings.TrimSpace(r.SeedUsername),
-		Org:      o,
-		Profile:  pfl,
+		ID:         uuid.New(),
+		ExternalID: secure.NewID(),
+		Username:   strings.TrimSpace(r.SeedUsername),
+		Org:        o,
+		Profile:    pfl,
+		Active:     true,
 	}
 
 	//create Audit
@@ -123,11 +137,17 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 	o.UpdateUserID = adt.User.ID
 	o.UpdateTime = adt.Moment
 
+	settings, err := json.Marshal(o.Settings)
+	if err != nil {
+		return SeedResponse{}, sr.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Internal, err))
+	}
+
 	cop := orgstore.CreateOrgParams{
 		OrgID:           o.ID,
 		OrgExtlID:       o.ExternalID.String(),
 		OrgName:         o.Name,
 		OrgDescription:  o.Description,
+		Settings:        settings,
 		CreateAppID:     a.ID,
 		CreateUserID:    datastore.NewNullUUID(u.ID),
 		CreateTimestamp: adt.Moment,
@@ -203,9 +223,11 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 
 	cup := userstore.CreateUserParams{
 		UserID:          u.ID,
+		UserExtlID:      u.ExternalID.String(),
 		Username:        u.Username,
 		OrgID:           u.Org.ID,
 		PersonProfileID: u.Profile.ID,
+		Active:          u.Active,
 		CreateAppID:     a.ID,
 		CreateUserID:    datastore.NewNullUUID(u.ID),
 		CreateTimestamp: adt.Moment,
@@ -219,7 +241,7 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 		return SeedResponse{}, errs.E(errs.Database, sr.Datastorer.RollbackTx(ctx, tx, err))
 	}
 
-	orgResponse, err := newOrgResponse(ctx, tx, o)
+	orgResponse, err := newOrgResponse(ctx, newAuditLoader(tx), o, "")
 	if err != nil {
 		return SeedResponse{}, errs.E(errs.Database, sr.Datastorer.RollbackTx(ctx, tx, err))
 	}
@@ -230,9 +252,16 @@ func (sr SeedService) Seed(ctx context.Context, r *SeedRequest) (SeedResponse, e
 		return SeedResponse{}, err
 	}
 
+	// the seed user is the first user of the seed Org and is
+	// granted the admin role
+	err = grantOrgRole(sr.PolicyManager, u.Username, auth.AdminRole, o)
+	if err != nil {
+		return SeedResponse{}, err
+	}
+
 	response := SeedResponse{
 		OrgResponse: orgResponse,
-		AppResponse: newAppResponse(a),
+		AppResponse: newAppResponse(a, adt),
 	}
 
 	return response, nil
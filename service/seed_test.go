@@ -7,6 +7,7 @@ import (
 
 	"github.com/gilcrest/go-api-basic/datastore/datastoretest"
 	"github.com/gilcrest/go-api-basic/domain/secure"
+	"github.com/gilcrest/go-api-basic/domain/secure/random"
 	"github.com/gilcrest/go-api-basic/service"
 
 	qt "github.com/frankban/quicktest"
@@ -20,7 +21,7 @@ func TestSeedService_Seed(t *testing.T) {
 		ds, cleanup := datastoretest.NewDatastore(t)
 		c.Cleanup(cleanup)
 
-		const keyEnv string = "ENCRYPT_KEY"
+		const keyEnv string = "API_KEY_SECRET"
 		ekey, ok := os.LookupEnv(keyEnv)
 		if !ok {
 			c.Fatalf("%s not set\n", keyEnv)
@@ -32,8 +33,8 @@ func TestSeedService_Seed(t *testing.T) {
 
 		sr := service.SeedService{
 			Datastorer:            ds,
-			CryptoRandomGenerator: secure.CryptoRandomGenerator{},
-			EncryptionKey:         ek,
+			CryptoRandomGenerator: random.CryptoGenerator{},
+			APIKeySecret:          ek,
 		}
 		r := service.SeedRequest{
 			OrgName:           "WOPR",
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/go-api-basic/datastore/usagestore"
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/usage"
+)
+
+// Usage granularities
+const (
+	// HourlyUsage reports usage by hour
+	HourlyUsage string = "hour"
+	// DailyUsage reports usage by day (UTC)
+	DailyUsage string = "day"
+)
+
+// maxUsageRange is the longest period usage may be requested for,
+// by granularity
+var maxUsageRange = map[string]time.Duration{
+	HourlyUsage: 31 * 24 * time.Hour,
+	DailyUsage:  366 * 24 * time.Hour,
+}
+
+// UsageMeter records the requests of Apps, usage.Meter aggregates
+// them in memory and flushes them to the datastore in batches
+type UsageMeter interface {
+	Record(r usage.Record)
+	// Pending returns the requests of the App recorded since the
+	// time given which have not been flushed to the datastore yet
+	Pending(appID uuid.UUID, since time.Time) int64
+}
+
+// FindAppUsageRequest is the request struct for the usage of an App
+type FindAppUsageRequest struct {
+	AppExtlID string
+	// Granularity is hour or day, day if empty
+	Granularity string
+	// From is the start of the period, truncated to the granularity.
+	// If zero, it defaults to a day (hourly) or 30 days (daily)
+	// before To.
+	From time.Time
+	// To is the end of the period (exclusive), now if zero
+	To time.Time
+}
+
+// AppUsageResponse is the response struct for the usage of an App
+type AppUsageResponse struct {
+	AppExternalID string `json:"app_external_id"`
+	Granularity   string `json:"granularity"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	// MonthlyQuota is the quota of the App for the current month,
+	// zero if unlimited
+	MonthlyQuota int64 `json:"monthly_quota"`
+	// MonthRequests is the number of requests counted against the
+	// quota in the current month
+	MonthRequests int64           `json:"month_requests"`
+	TotalRequests int64           `json:"total_requests"`
+	Usage         []UsageResponse `json:"usage"`
+}
+
+// UsageResponse is the usage of an App for an hour or a day, by user,
+// route and status
+type UsageResponse struct {
+	Start      string `json:"start"`
+	Username   string `json:"username"`
+	Method     string `json:"method"`
+	Route      string `json:"route"`
+	Status     int    `json:"status"`
+	Requests   int64  `json:"requests"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// FindAppUsageService is a service for reporting the usage of an App
+// of the caller's Org. Usage is flushed to the datastore in batches,
+// so the most recent requests may not be reported yet.
+type FindAppUsageService struct {
+	Datastorer Datastorer
+	// DefaultQuota is the monthly quota of Apps without their own
+	DefaultQuota int64
+}
+
+// Find returns the usage of an App for a period
+func (faus FindAppUsageService) Find(ctx context.Context, r FindAppUsageRequest, adt audit.Audit) (AppUsageResponse, error) {
+	from, to, err := usagePeriod(r, adt.Moment)
+	if err != nil {
+		return AppUsageResponse{}, err
+	}
+	if r.Granularity == "" {
+		r.Granularity = DailyUsage
+	}
+
+	dbpool := faus.Datastorer.Pool()
+
+	a, err := findOrgAppByExternalID(ctx, dbpool, r.AppExtlID, adt)
+	if err != nil {
+		return AppUsageResponse{}, err
+	}
+
+	q := usagestore.New(dbpool)
+
+	var uu []UsageResponse
+	switch r.Granularity {
+	case HourlyUsage:
+		rows, err := q.FindAppUsageByHour(ctx, usagestore.FindAppUsageByHourParams{AppID: a.ID, UsageHour: from, UsageHour_2: to})
+		if err != nil {
+			return AppUsageResponse{}, errs.E(errs.Database, err)
+		}
+		for _, row := range rows {
+			uu = append(uu, newUsageResponse(usagestore.FindAppUsageByDayRow(row)))
+		}
+	default:
+		rows, err := q.FindAppUsageByDay(ctx, usagestore.FindAppUsageByDayParams{AppID: a.ID, UsageHour: from, UsageHour_2: to})
+		if err != nil {
+			return AppUsageResponse{}, errs.E(errs.Database, err)
+		}
+		for _, row := range rows {
+			uu = append(uu, newUsageResponse(row))
+		}
+	}
+
+	monthRequests, err := q.CountAppRequestsSince(ctx, usagestore.CountAppRequestsSinceParams{AppID: a.ID, UsageHour: usage.MonthStart(adt.Moment)})
+	if err != nil {
+		return AppUsageResponse{}, errs.E(errs.Database, err)
+	}
+
+	response := AppUsageResponse{
+		AppExternalID: a.ExternalID.String(),
+		Granularity:   r.Granularity,
+		From:          from.Format(time.RFC3339),
+		To:            to.Format(time.RFC3339),
+		MonthlyQuota:  monthlyQuota(a, faus.DefaultQuota),
+		MonthRequests: monthRequests,
+		Usage:         uu,
+	}
+	for _, u := range uu {
+		response.TotalRequests += u.Requests
+	}
+
+	return response, nil
+}
+
+// usagePeriod validates the granularity and period of a
+// FindAppUsageRequest and returns the period, from truncated to the
+// start of its hour or day
+func usagePeriod(r FindAppUsageRequest, now time.Time) (from, to time.Time, err error) {
+	granularity := r.Granularity
+	if granularity == "" {
+		granularity = DailyUsage
+	}
+	maxRange, ok := maxUsageRange[granularity]
+	if !ok {
+		return time.Time{}, time.Time{}, errs.E(errs.Validation, errs.Parameter("granularity"), "granularity must be hour or day")
+	}
+
+	to = r.To.UTC()
+	if to.IsZero() {
+		to = now.UTC()
+	}
+	from = r.From.UTC()
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+		if granularity == DailyUsage {
+			from = to.AddDate(0, 0, -30)
+		}
+	}
+	from = usage.Hour(from)
+	if granularity == DailyUsage {
+		from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errs.E(errs.Validation, errs.Parameter("from"), "from must be before to")
+	}
+	if to.Sub(from) > maxRange {
+		return time.Time{}, time.Time{}, errs.E(errs.Validation, errs.Parameter("from"), "period is too long for the granularity")
+	}
+
+	return from, to, nil
+}
+
+// newUsageResponse initializes a UsageResponse given a row of usage
+func newUsageResponse(row usagestore.FindAppUsageByDayRow) UsageResponse {
+	return UsageResponse{
+		Start:      row.UsageStart.UTC().Format(time.RFC3339),
+		Username:   row.Username,
+		Method:     row.HttpMethod,
+		Route:      row.Route,
+		Status:     int(row.StatusCode),
+		Requests:   row.RequestCount,
+		DurationMS: row.DurationMs,
+	}
+}
+
+// monthlyQuota returns the monthly quota of the App, zero or less if
+// unlimited
+func monthlyQuota(a app.App, defaultQuota int64) int64 {
+	if a.MonthlyQuota != 0 {
+		return a.MonthlyQuota
+	}
+	if defaultQuota < 0 {
+		return 0
+	}
+	return defaultQuota
+}
+
+// quotaCountTTL is how long the requests of an App counted in the
+// datastore are cached for quota checks
+const quotaCountTTL = time.Minute
+
+// monthCount is the cached count of the requests of an App
+type monthCount struct {
+	month    time.Time
+	requests int64
+	loaded   time.Time
+}
+
+// QuotaService rejects the requests of Apps which have used up their
+// monthly quota. The requests of an App are counted from the
+// datastore (cached for a minute) plus those pending in the
+// UsageMeter. Quotas are approximate: requests flushed by other
+// instances show up when the count is reloaded.
+type QuotaService struct {
+	Datastorer Datastorer
+	Meter      UsageMeter
+	// DefaultQuota is the monthly quota of Apps without their own,
+	// zero for no quota
+	DefaultQuota int64
+
+	mu     sync.Mutex
+	counts map[uuid.UUID]monthCount
+}
+
+// NewQuotaService is an initializer for QuotaService
+func NewQuotaService(ds Datastorer, m UsageMeter, defaultQuota int64) *QuotaService {
+	return &QuotaService{
+		Datastorer:   ds,
+		Meter:        m,
+		DefaultQuota: defaultQuota,
+		counts:       make(map[uuid.UUID]monthCount),
+	}
+}
+
+// Check returns an error of Kind errs.TooManyRequests if the App has
+// used up its quota for the month
+func (qs *QuotaService) Check(ctx context.Context, a app.App) error {
+	quota := monthlyQuota(a, qs.DefaultQuota)
+	if quota <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	month := usage.MonthStart(now)
+
+	requests, err := qs.monthRequests(ctx, a.ID, month, now)
+	if err != nil {
+		return err
+	}
+	requests += qs.Meter.Pending(a.ID, month)
+
+	if requests >= quota {
+		nextMonth := month.AddDate(0, 1, 0)
+		return errs.E(errs.TooManyRequests, errs.RetryAfter(nextMonth.Sub(now)), "monthly quota exceeded")
+	}
+
+	return nil
+}
+
+// monthRequests returns the requests of the App in the month counted
+// in the datastore, cached for quotaCountTTL
+func (qs *QuotaService) monthRequests(ctx context.Context, appID uuid.UUID, month, now time.Time) (int64, error) {
+	qs.mu.Lock()
+	mc, ok := qs.counts[appID]
+	qs.mu.Unlock()
+	if ok && mc.month.Equal(month) && now.Sub(mc.loaded) < quotaCountTTL {
+		return mc.requests, nil
+	}
+
+	requests, err := usagestore.New(qs.Datastorer.Pool()).CountAppRequestsSince(ctx, usagestore.CountAppRequestsSinceParams{AppID: appID, UsageHour: month})
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+
+	qs.mu.Lock()
+	qs.counts[appID] = monthCount{month: month, requests: requests, loaded: now}
+	qs.mu.Unlock()
+
+	return requests, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/usage"
+)
+
+func Test_usagePeriod(t *testing.T) {
+	now := time.Date(2021, 6, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		r        FindAppUsageRequest
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{"daily default", FindAppUsageRequest{}, time.Date(2021, 5, 16, 0, 0, 0, 0, time.UTC), now, false},
+		{"hourly default", FindAppUsageRequest{Granularity: HourlyUsage}, time.Date(2021, 6, 14, 10, 0, 0, 0, time.UTC), now, false},
+		{"invalid granularity", FindAppUsageRequest{Granularity: "minute"}, time.Time{}, time.Time{}, true},
+		{"from after to", FindAppUsageRequest{From: now.AddDate(0, 0, 1)}, time.Time{}, time.Time{}, true},
+		{"too long", FindAppUsageRequest{Granularity: HourlyUsage, From: now.AddDate(0, -2, 0)}, time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			from, to, err := usagePeriod(tt.r, now)
+			if tt.wantErr {
+				c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(from, qt.Equals, tt.wantFrom)
+			c.Assert(to, qt.Equals, tt.wantTo)
+		})
+	}
+}
+
+func TestQuotaService_Check(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	a := app.App{ID: uuid.New(), MonthlyQuota: 3}
+	m := usage.NewMeter(nil, time.Minute, zerolog.Nop())
+
+	qs := NewQuotaService(nil, m, 0)
+	// the datastore count is cached, so the datastore is not used
+	now := time.Now()
+	qs.counts[a.ID] = monthCount{month: usage.MonthStart(now), requests: 1, loaded: now}
+
+	c.Assert(qs.Check(ctx, a), qt.IsNil)
+
+	m.Record(usage.Record{AppID: a.ID, Time: now})
+	c.Assert(qs.Check(ctx, a), qt.IsNil)
+
+	m.Record(usage.Record{AppID: a.ID, Time: now})
+	err := qs.Check(ctx, a)
+	c.Assert(errs.KindIs(errs.TooManyRequests, err), qt.IsTrue)
+
+	// Apps without a quota are not counted
+	c.Assert(qs.Check(ctx, app.App{ID: uuid.New()}), qt.IsNil)
+}
@@ -2,20 +2,26 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 	"golang.org/x/oauth2"
 
+	"github.com/gilcrest/go-api-basic/datastore"
+	"github.com/gilcrest/go-api-basic/datastore/personstore"
 	"github.com/gilcrest/go-api-basic/datastore/userstore"
 	"github.com/gilcrest/go-api-basic/domain/app"
+	"github.com/gilcrest/go-api-basic/domain/audit"
 	"github.com/gilcrest/go-api-basic/domain/auth"
 	"github.com/gilcrest/go-api-basic/domain/errs"
 	"github.com/gilcrest/go-api-basic/domain/org"
 	"github.com/gilcrest/go-api-basic/domain/person"
 	"github.com/gilcrest/go-api-basic/domain/secure"
 	"github.com/gilcrest/go-api-basic/domain/user"
+	"github.com/gilcrest/go-api-basic/domain/user/usercache"
 	"github.com/gilcrest/go-api-basic/gateway/authgateway"
 )
 
@@ -24,41 +30,89 @@ type GoogleOauth2TokenConverter interface {
 	Convert(ctx context.Context, realm string, token oauth2.Token) (authgateway.Userinfo, error)
 }
 
+// AppleTokenConverter verifies an Apple identity token and converts
+// it to an authgateway.Userinfo struct
+type AppleTokenConverter interface {
+	Convert(ctx context.Context, realm string, token oauth2.Token) (authgateway.Userinfo, error)
+}
+
+// OIDCTokenConverter verifies a token issued by a configured
+// OpenID Connect provider and converts it to an authgateway.Userinfo struct
+type OIDCTokenConverter interface {
+	Supports(provider string) bool
+	Convert(ctx context.Context, realm string, provider string, token oauth2.Token) (authgateway.Userinfo, error)
+}
+
+// IdentityCache caches the User authenticated by a set of
+// credentials (see usercache.Key) so that the provider and
+// datastore are not called on every request
+type IdentityCache interface {
+	Get(key string) (user.User, bool)
+	// Generation is read before a User is looked up and passed to
+	// Set, it changes whenever a User is deleted from the cache
+	Generation() uint64
+	// Set caches the User until expiry (zero meaning the cache
+	// default) or the cache's own limit, whichever is sooner. The
+	// User is not cached if any User was deleted since generation
+	// gen, so a lookup racing a change cannot cache a stale User.
+	Set(key string, u user.User, expiry time.Time, gen uint64)
+	// DeleteUser invalidates all entries for a changed or deleted User
+	DeleteUser(id uuid.UUID)
+}
+
 // FindUserParams is the parameters for the FindUser function
 type FindUserParams struct {
 	Realm    string
 	App      app.App
 	Provider auth.Provider
-	Token    oauth2.Token
+	// ProviderName is the raw X-AUTH-PROVIDER value, used to
+	// resolve configured OpenID Connect providers
+	ProviderName string
+	Token        oauth2.Token
 }
 
 // FindUserService retrieves a User from the Database
 type FindUserService struct {
 	GoogleOauth2TokenConverter GoogleOauth2TokenConverter
-	Datastorer                 Datastorer
+	AppleTokenConverter        AppleTokenConverter
+	OIDCTokenConverter         OIDCTokenConverter
+	// IdentityCache is optional, if nil every call goes to the
+	// provider and datastore
+	IdentityCache IdentityCache
+	// SettingsCache is optional, if nil the Org settings are read
+	// from the datastore on every call
+	SettingsCache SettingsCache
+	Datastorer    Datastorer
 }
 
 // FindUserByOauth2Token retrieves a users' identity from a Provider
-// and then retrieves the associated registered user from the datastore
+// and then retrieves the associated registered user from the datastore.
+// A configured OpenID Connect provider takes precedence over the
+// built-in Google and Apple providers of the same name.
 func (fus FindUserService) FindUserByOauth2Token(ctx context.Context, params FindUserParams) (user.User, error) {
-	var (
-		uInfo authgateway.Userinfo
-		err   error
-	)
-
-	if params.Provider == auth.Invalid {
-		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "provider not recognized")
+	err := fus.checkAuthProvider(ctx, params)
+	if err != nil {
+		return user.User{}, err
 	}
 
-	if params.Provider == auth.Apple {
-		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "apple authentication not yet implemented")
+	var (
+		cacheKey string
+		cacheGen uint64
+	)
+	if fus.IdentityCache != nil {
+		cacheKey = usercache.Key(strings.ToLower(params.ProviderName), params.Token.AccessToken, params.App.Org.ID)
+		cacheGen = fus.IdentityCache.Generation()
+		if u, ok := fus.IdentityCache.Get(cacheKey); ok {
+			if !u.Active {
+				return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "user is not active")
+			}
+			return u, nil
+		}
 	}
 
-	if params.Provider == auth.Google {
-		uInfo, err = fus.GoogleOauth2TokenConverter.Convert(ctx, params.Realm, params.Token)
-		if err != nil {
-			return user.User{}, err
-		}
+	uInfo, err := fus.findUserinfo(ctx, params)
+	if err != nil {
+		return user.User{}, err
 	}
 
 	findUserByUsernameParams := userstore.FindUserByUsernameParams{
@@ -74,13 +128,324 @@ func (fus FindUserService) FindUserByOauth2Token(ctx context.Context, params Fin
 		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), err)
 	}
 
-	return hydrateUserFromDB(findUserByUsernameRow), nil
+	u := hydrateUserFromDB(findUserByUsernameRow)
+	if !u.Active {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "user is not active")
+	}
+
+	if fus.IdentityCache != nil {
+		// never cache a user beyond the expiry of the token
+		expiry := params.Token.Expiry
+		if exp, ok := authgateway.TokenExpiry(params.Token.AccessToken); ok {
+			expiry = exp
+		}
+		fus.IdentityCache.Set(cacheKey, u, expiry, cacheGen)
+	}
+
+	return u, nil
+}
+
+// FindUserinfo retrieves the users' identity from the Provider
+// without requiring the user be registered in the datastore
+func (fus FindUserService) FindUserinfo(ctx context.Context, params FindUserParams) (authgateway.Userinfo, error) {
+	err := fus.checkAuthProvider(ctx, params)
+	if err != nil {
+		return authgateway.Userinfo{}, err
+	}
+
+	return fus.findUserinfo(ctx, params)
+}
+
+// checkAuthProvider ensures the App's Org allows its users to
+// authenticate with the provider
+func (fus FindUserService) checkAuthProvider(ctx context.Context, params FindUserParams) error {
+	s, err := findOrgSettings(ctx, fus.Datastorer.Pool(), fus.SettingsCache, params.App.Org.ID)
+	if err != nil {
+		return err
+	}
+	if !s.AuthProviderAllowed(params.ProviderName) {
+		return errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "provider not allowed for this org")
+	}
+	return nil
+}
+
+// findUserinfo retrieves the users' identity from the Provider. A
+// provider username which could pass for a service principal is
+// rejected.
+func (fus FindUserService) findUserinfo(ctx context.Context, params FindUserParams) (authgateway.Userinfo, error) {
+	var (
+		uInfo authgateway.Userinfo
+		err   error
+	)
+	switch {
+	case fus.OIDCTokenConverter != nil && fus.OIDCTokenConverter.Supports(params.ProviderName):
+		uInfo, err = fus.OIDCTokenConverter.Convert(ctx, params.Realm, params.ProviderName, params.Token)
+	case params.Provider == auth.Google:
+		uInfo, err = fus.GoogleOauth2TokenConverter.Convert(ctx, params.Realm, params.Token)
+	case params.Provider == auth.Apple:
+		uInfo, err = fus.AppleTokenConverter.Convert(ctx, params.Realm, params.Token)
+	default:
+		return authgateway.Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "provider not recognized")
+	}
+	if err != nil {
+		return authgateway.Userinfo{}, err
+	}
+
+	if user.IsServicePrincipalUsername(uInfo.Username) {
+		return authgateway.Userinfo{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "provider username is reserved")
+	}
+
+	return uInfo, nil
+}
+
+// RegisterUserRequest is the request struct for a provider
+// authenticated identity registering itself as a User. FirstName
+// and LastName override the names sent by the provider (Apple, for
+// instance, does not send names).
+type RegisterUserRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// UserResponse is the response struct for a User
+type UserResponse struct {
+	ExternalID        string        `json:"external_id"`
+	Username          string        `json:"username"`
+	OrgExternalID     string        `json:"org_external_id"`
+	Active            bool          `json:"active"`
+	NamePrefix        string        `json:"name_prefix,omitempty"`
+	FirstName         string        `json:"first_name"`
+	MiddleName        string        `json:"middle_name,omitempty"`
+	LastName          string        `json:"last_name"`
+	NameSuffix        string        `json:"name_suffix,omitempty"`
+	Nickname          string        `json:"nickname,omitempty"`
+	CompanyName       string        `json:"company_name,omitempty"`
+	CompanyDepartment string        `json:"company_department,omitempty"`
+	JobTitle          string        `json:"job_title,omitempty"`
+	CreateAudit       auditResponse `json:"create_audit"`
+	UpdateAudit       auditResponse `json:"update_audit"`
+}
+
+// newUserResponse initializes UserResponse given a user.User and
+// its create/update audits
+func newUserResponse(u user.User, ca, ua audit.Audit) UserResponse {
+	return UserResponse{
+		ExternalID:        u.ExternalID.String(),
+		Username:          u.Username,
+		OrgExternalID:     u.Org.ExternalID.String(),
+		Active:            u.Active,
+		NamePrefix:        u.Profile.NamePrefix,
+		FirstName:         u.Profile.FirstName,
+		MiddleName:        u.Profile.MiddleName,
+		LastName:          u.Profile.LastName,
+		NameSuffix:        u.Profile.NameSuffix,
+		Nickname:          u.Profile.Nickname,
+		CompanyName:       u.Profile.CompanyName,
+		CompanyDepartment: u.Profile.CompanyDepartment,
+		JobTitle:          u.Profile.JobTitle,
+		CreateAudit:       newAuditResponse(ca),
+		UpdateAudit:       newAuditResponse(ua),
+	}
+}
+
+// newUserResponseFromDB initializes UserResponse given a userstore
+// row. The create/update App and User are retrieved from the
+// datastore through the auditLoader, as they are for OrgResponse.
+func newUserResponseFromDB(ctx context.Context, l *auditLoader, row userstore.FindUserByUsernameRow) (UserResponse, error) {
+	// queue the update audit so both audits are found together
+	l.add(row.UpdateAppID, row.UpdateUserID.UUID)
+	ca, err := l.audit(ctx, row.CreateAppID, row.CreateUserID.UUID, row.CreateTimestamp)
+	if err != nil {
+		return UserResponse{}, err
+	}
+	ua, err := l.audit(ctx, row.UpdateAppID, row.UpdateUserID.UUID, row.UpdateTimestamp)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(hydrateUserFromDB(row), ca, ua), nil
+}
+
+// newUserResponses initializes a UserResponse for each userstore
+// row. The audits of all the Users are found together.
+func newUserResponses(ctx context.Context, dbtx DBTX, rows []userstore.FindUserByUsernameRow) ([]UserResponse, error) {
+	l := newAuditLoader(dbtx)
+	for _, row := range rows {
+		l.add(row.CreateAppID, row.CreateUserID.UUID)
+		l.add(row.UpdateAppID, row.UpdateUserID.UUID)
+	}
+
+	response := make([]UserResponse, 0, len(rows))
+	for _, row := range rows {
+		ur, err := newUserResponseFromDB(ctx, l, row)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, ur)
+	}
+
+	return response, nil
+}
+
+// RegisterUserService is a service for provider authenticated
+// identities to register themselves as a User
+type RegisterUserService struct {
+	Datastorer Datastorer
+	// PolicyManager is used to grant the registered User a role
+	// in the Org
+	PolicyManager PolicyManager
+}
+
+// Register creates the Person, Person Profile and User for the
+// provider identity in the App's Org, provided the Org allows
+// self registration. Every registered User is granted the user role,
+// the admin role is only granted when the Org is created or seeded.
+func (rus RegisterUserService) Register(ctx context.Context, r *RegisterUserRequest, a app.App, uInfo authgateway.Userinfo) (UserResponse, error) {
+	// start db txn using pgxpool
+	tx, err := rus.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	o, err := findOrgByID(ctx, tx, a.Org.ID)
+	if err != nil {
+		return UserResponse{}, rus.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	if !o.Settings.SelfRegistration(false) {
+		return UserResponse{}, rus.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Unauthorized, "self registration is not allowed for this org"))
+	}
+
+	u := newUserFromUserinfo(uInfo, r, o)
+	if user.IsServicePrincipalUsername(u.Username) {
+		return UserResponse{}, rus.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, fmt.Sprintf("usernames starting with %s are reserved", user.ServicePrincipalPrefix)))
+	}
+	if !u.IsValid() {
+		return UserResponse{}, rus.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "username, first name and last name are required"))
+	}
+
+	_, err = userstore.New(tx).FindUserByUsername(ctx, userstore.FindUserByUsernameParams{Username: u.Username, OrgID: o.ID})
+	if err == nil {
+		return UserResponse{}, rus.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Exist, "user is already registered"))
+	}
+	if err != pgx.ErrNoRows {
+		return UserResponse{}, errs.E(errs.Database, rus.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// the User creates itself
+	adt := audit.Audit{
+		App:    a,
+		User:   u,
+		Moment: time.Now(),
+	}
+
+	err = createUser(ctx, tx, u, adt)
+	if err != nil {
+		return UserResponse{}, errs.E(errs.Database, rus.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	// commit db txn using pgxpool
+	err = rus.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	err = grantOrgRole(rus.PolicyManager, u.Username, auth.UserRole, o)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(u, adt, adt), nil
+}
+
+// newUserFromUserinfo initializes a User (along with its Person and
+// Profile) for the Org given the provider Userinfo
+func newUserFromUserinfo(uInfo authgateway.Userinfo, r *RegisterUserRequest, o org.Org) user.User {
+	firstName, lastName := uInfo.GivenName, uInfo.FamilyName
+	if r.FirstName != "" {
+		firstName = r.FirstName
+	}
+	if r.LastName != "" {
+		lastName = r.LastName
+	}
+
+	p := person.Person{ID: uuid.New(), Org: o}
+	pfl := person.Profile{
+		ID:        uuid.New(),
+		Person:    p,
+		FirstName: strings.TrimSpace(firstName),
+		LastName:  strings.TrimSpace(lastName),
+	}
+
+	return user.User{
+		ID:         uuid.New(),
+		ExternalID: secure.NewID(),
+		Username:   strings.TrimSpace(uInfo.Username),
+		Org:        o,
+		Profile:    pfl,
+		Active:     true,
+	}
+}
+
+// createUser creates the Person, Person Profile and User database
+// records for the User
+func createUser(ctx context.Context, tx pgx.Tx, u user.User, adt audit.Audit) error {
+	_, err := personstore.New(tx).CreatePerson(ctx, personstore.CreatePersonParams{
+		PersonID:        u.Profile.Person.ID,
+		OrgID:           u.Org.ID,
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    datastore.NewNullUUID(adt.User.ID),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = personstore.New(tx).CreatePersonProfile(ctx, personstore.CreatePersonProfileParams{
+		PersonProfileID: u.Profile.ID,
+		PersonID:        u.Profile.Person.ID,
+		FirstName:       u.Profile.FirstName,
+		LastName:        u.Profile.LastName,
+		CreateAppID:     datastore.NewNullUUID(adt.App.ID),
+		CreateUserID:    datastore.NewNullUUID(adt.User.ID),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     datastore.NewNullUUID(adt.App.ID),
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = userstore.New(tx).CreateUser(ctx, userstore.CreateUserParams{
+		UserID:          u.ID,
+		UserExtlID:      u.ExternalID.String(),
+		Username:        u.Username,
+		OrgID:           u.Org.ID,
+		PersonProfileID: u.Profile.ID,
+		Active:          u.Active,
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    datastore.NewNullUUID(adt.User.ID),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+	})
+
+	return err
 }
 
+// hydrateUserFromDB initializes a User given a userstore row. Rows
+// from the other userstore find queries have the same shape and can
+// be converted to userstore.FindUserByUsernameRow.
 func hydrateUserFromDB(row userstore.FindUserByUsernameRow) user.User {
 	u := user.User{}
 	u.ID = row.UserID
+	u.ExternalID = secure.MustParseIdentifier(row.UserExtlID)
 	u.Username = row.Username
+	u.Active = row.Active
 	o := org.Org{
 		ID:          row.OrgID,
 		ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
@@ -116,46 +481,261 @@ func hydrateUserFromDB(row userstore.FindUserByUsernameRow) user.User {
 	return u
 }
 
-// findUserByID finds a user given its ID
-func findUserByID(ctx context.Context, dbtx DBTX, id uuid.UUID) (user.User, error) {
-	row, err := userstore.New(dbtx).FindUserByID(ctx, id)
+// findUserRowByExternalID finds the userstore row for a User in the
+// Org given its external ID
+func findUserRowByExternalID(ctx context.Context, dbtx DBTX, orgID uuid.UUID, extlID string) (userstore.FindUserByUsernameRow, error) {
+	row, err := userstore.New(dbtx).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: extlID, OrgID: orgID})
 	if err != nil {
-		return user.User{}, errs.E(errs.Database, err)
+		if err == pgx.ErrNoRows {
+			return userstore.FindUserByUsernameRow{}, errs.E(errs.NotExist, "No user exists for the given external ID")
+		}
+		return userstore.FindUserByUsernameRow{}, errs.E(errs.Database, err)
 	}
-	u := user.User{}
-	u.ID = row.UserID
-	u.Username = row.Username
-	o := org.Org{
-		ID:          row.OrgID,
-		ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
-		Name:        row.OrgName,
-		Description: row.OrgDescription,
+
+	return userstore.FindUserByUsernameRow(row), nil
+}
+
+// FindOrgUserService retrieves the Users of an Org
+type FindOrgUserService struct {
+	Datastorer Datastorer
+}
+
+// UserListResponse is the response struct for a page of Users
+type UserListResponse struct {
+	Data []UserResponse `json:"data"`
+	PageCursors
+}
+
+// FindAll returns a page of the Users of the Org, sorted by username
+// (the default) or created. The NamePrefix of the request is
+// matched against the username.
+func (fous FindOrgUserService) FindAll(ctx context.Context, o org.Org, r ListRequest) (UserListResponse, error) {
+	p, err := newListPage(r, usernameSort, createdSort)
+	if err != nil {
+		return UserListResponse{}, err
 	}
-	p := person.Person{
-		ID:  row.PersonID,
-		Org: o,
+
+	dbtx := fous.Datastorer.Pool()
+	q := userstore.New(dbtx)
+
+	var rows []userstore.FindUserByUsernameRow
+	switch p.field {
+	case createdSort:
+		params := userstore.FindUsersPageByCreateTimestampParams{
+			OrgID:                 o.ID,
+			NamePattern:           p.namePattern,
+			CreatedAfter:          p.createdAfter,
+			CursorExtlID:          p.cursorExtlID(),
+			CursorCreateTimestamp: p.cursorTime,
+			RowLimit:              p.rowLimit(),
+		}
+		if p.descending() {
+			var page []userstore.FindUsersPageByCreateTimestampDescRow
+			page, err = q.FindUsersPageByCreateTimestampDesc(ctx, userstore.FindUsersPageByCreateTimestampDescParams(params))
+			for _, row := range page {
+				rows = append(rows, userstore.FindUserByUsernameRow(row))
+			}
+		} else {
+			var page []userstore.FindUsersPageByCreateTimestampRow
+			page, err = q.FindUsersPageByCreateTimestamp(ctx, params)
+			for _, row := range page {
+				rows = append(rows, userstore.FindUserByUsernameRow(row))
+			}
+		}
+	default:
+		params := userstore.FindUsersPageByUsernameParams{
+			OrgID:          o.ID,
+			NamePattern:    p.namePattern,
+			CreatedAfter:   p.createdAfter,
+			CursorExtlID:   p.cursorExtlID(),
+			CursorUsername: p.cursorKey(),
+			RowLimit:       p.rowLimit(),
+		}
+		if p.descending() {
+			var page []userstore.FindUsersPageByUsernameDescRow
+			page, err = q.FindUsersPageByUsernameDesc(ctx, userstore.FindUsersPageByUsernameDescParams(params))
+			for _, row := range page {
+				rows = append(rows, userstore.FindUserByUsernameRow(row))
+			}
+		} else {
+			var page []userstore.FindUsersPageByUsernameRow
+			page, err = q.FindUsersPageByUsername(ctx, params)
+			for _, row := range page {
+				rows = append(rows, userstore.FindUserByUsernameRow(row))
+			}
+		}
 	}
-	pp := person.Profile{
-		ID:                row.PersonProfileID,
-		Person:            p,
-		NamePrefix:        row.NamePrefix.String,
-		FirstName:         row.FirstName,
-		MiddleName:        row.MiddleName.String,
-		LastName:          row.LastName,
-		NameSuffix:        row.NameSuffix.String,
-		Nickname:          row.Nickname.String,
-		CompanyName:       row.CompanyName.String,
-		CompanyDepartment: row.CompanyDept.String,
-		JobTitle:          row.JobTitle.String,
-		BirthDate:         time.Time{},
-		LanguageID:        row.LanguageID.UUID,
-		HostedDomain:      "",
-		PictureURL:        "",
-		ProfileLink:       "",
-		ProfileSource:     "",
+	if err != nil {
+		return UserListResponse{}, errs.E(errs.Database, err)
 	}
-	u.Org = o
-	u.Profile = pp
 
-	return u, nil
+	n, more := p.trim(rows)
+	rows = rows[:n]
+
+	response := UserListResponse{
+		PageCursors: p.cursors(n, more, func(i int) (string, string) {
+			return p.sortKey(rows[i].Username, rows[i].CreateTimestamp), rows[i].UserExtlID
+		}),
+	}
+	response.Data, err = newUserResponses(ctx, dbtx, rows)
+	if err != nil {
+		return UserListResponse{}, err
+	}
+
+	return response, nil
+}
+
+// FindByExternalID is used to find a User of the Org by its External ID
+func (fous FindOrgUserService) FindByExternalID(ctx context.Context, o org.Org, extlID string) (UserResponse, error) {
+	dbtx := fous.Datastorer.Pool()
+
+	row, err := findUserRowByExternalID(ctx, dbtx, o.ID, extlID)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponseFromDB(ctx, newAuditLoader(dbtx), row)
+}
+
+// UpdateUserRequest is the request struct for updating the profile
+// of a User
+type UpdateUserRequest struct {
+	ExternalID        string
+	NamePrefix        string `json:"name_prefix"`
+	FirstName         string `json:"first_name"`
+	MiddleName        string `json:"middle_name"`
+	LastName          string `json:"last_name"`
+	NameSuffix        string `json:"name_suffix"`
+	Nickname          string `json:"nickname"`
+	CompanyName       string `json:"company_name"`
+	CompanyDepartment string `json:"company_department"`
+	JobTitle          string `json:"job_title"`
+}
+
+// UpdateUserService is a service for updating a User of the
+// caller's Org
+type UpdateUserService struct {
+	Datastorer Datastorer
+	// IdentityCache is optional, if set the updated User is removed
+	// from it
+	IdentityCache IdentityCache
+}
+
+// Update is used to update the profile of a User
+func (uus UpdateUserService) Update(ctx context.Context, r *UpdateUserRequest, adt audit.Audit) (UserResponse, error) {
+	if strings.TrimSpace(r.FirstName) == "" || strings.TrimSpace(r.LastName) == "" {
+		return UserResponse{}, errs.E(errs.Validation, "first name and last name are required")
+	}
+
+	// start db txn using pgxpool
+	tx, err := uus.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	// retrieve existing User
+	row, err := findUserRowByExternalID(ctx, tx, adt.User.Org.ID, r.ExternalID)
+	if err != nil {
+		return UserResponse{}, uus.Datastorer.RollbackTx(ctx, tx, err)
+	}
+
+	// update database records using personstore and userstore
+	err = personstore.New(tx).UpdatePersonProfile(ctx, personstore.UpdatePersonProfileParams{
+		NamePrefix:      datastore.NewNullString(strings.TrimSpace(r.NamePrefix)),
+		FirstName:       strings.TrimSpace(r.FirstName),
+		MiddleName:      datastore.NewNullString(strings.TrimSpace(r.MiddleName)),
+		LastName:        strings.TrimSpace(r.LastName),
+		NameSuffix:      datastore.NewNullString(strings.TrimSpace(r.NameSuffix)),
+		Nickname:        datastore.NewNullString(strings.TrimSpace(r.Nickname)),
+		CompanyName:     datastore.NewNullString(strings.TrimSpace(r.CompanyName)),
+		CompanyDept:     datastore.NewNullString(strings.TrimSpace(r.CompanyDepartment)),
+		JobTitle:        datastore.NewNullString(strings.TrimSpace(r.JobTitle)),
+		UpdateAppID:     datastore.NewNullUUID(adt.App.ID),
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+		PersonProfileID: row.PersonProfileID,
+	})
+	if err != nil {
+		return UserResponse{}, errs.E(errs.Database, uus.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	err = userstore.New(tx).UpdateUser(ctx, newUpdateUserParams(row.UserID, row.Active, adt))
+	if err != nil {
+		return UserResponse{}, errs.E(errs.Database, uus.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	return commitUserChange(ctx, uus.Datastorer, uus.IdentityCache, tx, adt.User.Org.ID, r.ExternalID)
+}
+
+// DeactivateUserService is a service for deactivating a User of the
+// caller's Org
+type DeactivateUserService struct {
+	Datastorer Datastorer
+	// IdentityCache is optional, if set the deactivated User is
+	// removed from it
+	IdentityCache IdentityCache
+}
+
+// Deactivate marks a User as inactive. The User is not deleted, but
+// will no longer be able to authenticate.
+func (dus DeactivateUserService) Deactivate(ctx context.Context, extlID string, adt audit.Audit) (UserResponse, error) {
+	// start db txn using pgxpool
+	tx, err := dus.Datastorer.BeginTx(ctx)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	// retrieve existing User
+	row, err := findUserRowByExternalID(ctx, tx, adt.User.Org.ID, extlID)
+	if err != nil {
+		return UserResponse{}, dus.Datastorer.RollbackTx(ctx, tx, err)
+	}
+	if row.UserID == adt.User.ID {
+		return UserResponse{}, dus.Datastorer.RollbackTx(ctx, tx, errs.E(errs.Validation, "users cannot deactivate themselves"))
+	}
+
+	err = userstore.New(tx).UpdateUser(ctx, newUpdateUserParams(row.UserID, false, adt))
+	if err != nil {
+		return UserResponse{}, errs.E(errs.Database, dus.Datastorer.RollbackTx(ctx, tx, err))
+	}
+
+	return commitUserChange(ctx, dus.Datastorer, dus.IdentityCache, tx, adt.User.Org.ID, extlID)
+}
+
+// newUpdateUserParams initializes userstore.UpdateUserParams given
+// the User ID, active flag and update audit
+func newUpdateUserParams(id uuid.UUID, active bool, adt audit.Audit) userstore.UpdateUserParams {
+	return userstore.UpdateUserParams{
+		Active:          active,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    datastore.NewNullUUID(adt.User.ID),
+		UpdateTimestamp: adt.Moment,
+		UserID:          id,
+	}
+}
+
+// commitUserChange reads back the changed User, commits the
+// transaction and removes the User from the identity cache, if any
+func commitUserChange(ctx context.Context, ds Datastorer, cache IdentityCache, tx pgx.Tx, orgID uuid.UUID, extlID string) (UserResponse, error) {
+	row, err := findUserRowByExternalID(ctx, tx, orgID, extlID)
+	if err != nil {
+		return UserResponse{}, ds.RollbackTx(ctx, tx, err)
+	}
+
+	ur, err := newUserResponseFromDB(ctx, newAuditLoader(tx), row)
+	if err != nil {
+		return UserResponse{}, ds.RollbackTx(ctx, tx, err)
+	}
+
+	// commit db txn using pgxpool
+	err = ds.CommitTx(ctx, tx)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	if cache != nil {
+		cache.DeleteUser(row.UserID)
+	}
+
+	return ur, nil
 }
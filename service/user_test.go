@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/go-api-basic/domain/audit"
+	"github.com/gilcrest/go-api-basic/domain/auth"
+	"github.com/gilcrest/go-api-basic/domain/errs"
+	"github.com/gilcrest/go-api-basic/domain/org"
+	"github.com/gilcrest/go-api-basic/gateway/authgateway"
+)
+
+func Test_newUserFromUserinfo(t *testing.T) {
+	o := org.Org{ID: uuid.New(), Name: "Movie Makers Inc."}
+
+	t.Run("names from provider", func(t *testing.T) {
+		c := qt.New(t)
+
+		uInfo := authgateway.Userinfo{Username: "otto.maddox@example.com", GivenName: "Otto", FamilyName: "Maddox"}
+		u := newUserFromUserinfo(uInfo, &RegisterUserRequest{}, o)
+
+		c.Assert(u.IsValid(), qt.IsTrue)
+		c.Assert(u.Username, qt.Equals, "otto.maddox@example.com")
+		c.Assert(u.Org.ID, qt.Equals, o.ID)
+		c.Assert(u.Profile.Person.Org.ID, qt.Equals, o.ID)
+		c.Assert(u.Profile.FirstName, qt.Equals, "Otto")
+		c.Assert(u.Profile.LastName, qt.Equals, "Maddox")
+	})
+
+	t.Run("names from request", func(t *testing.T) {
+		c := qt.New(t)
+
+		// Apple does not send names
+		uInfo := authgateway.Userinfo{Username: "otto.maddox@example.com"}
+		u := newUserFromUserinfo(uInfo, &RegisterUserRequest{FirstName: " Otto ", LastName: "Maddox"}, o)
+
+		c.Assert(u.IsValid(), qt.IsTrue)
+		c.Assert(u.Profile.FirstName, qt.Equals, "Otto")
+		c.Assert(u.Profile.LastName, qt.Equals, "Maddox")
+	})
+
+	t.Run("no names", func(t *testing.T) {
+		c := qt.New(t)
+
+		uInfo := authgateway.Userinfo{Username: "otto.maddox@example.com"}
+		u := newUserFromUserinfo(uInfo, &RegisterUserRequest{}, o)
+
+		c.Assert(u.IsValid(), qt.IsFalse)
+	})
+}
+
+// userinfoConverter converts every token to its Userinfo
+type userinfoConverter struct {
+	uInfo authgateway.Userinfo
+}
+
+func (c userinfoConverter) Convert(ctx context.Context, realm string, token oauth2.Token) (authgateway.Userinfo, error) {
+	return c.uInfo, nil
+}
+
+func TestFindUserService_findUserinfo(t *testing.T) {
+	c := qt.New(t)
+
+	params := FindUserParams{Realm: "go-api-basic", Provider: auth.Google}
+
+	fus := FindUserService{GoogleOauth2TokenConverter: userinfoConverter{authgateway.Userinfo{Username: "otto.maddox@example.com"}}}
+	uInfo, err := fus.findUserinfo(context.Background(), params)
+	c.Assert(err, qt.IsNil)
+	c.Assert(uInfo.Username, qt.Equals, "otto.maddox@example.com")
+
+	// a provider username cannot pass for a service principal
+	fus = FindUserService{GoogleOauth2TokenConverter: userinfoConverter{authgateway.Userinfo{Username: "app:d3r4ZbyNnMbcVqeo6tqh"}}}
+	_, err = fus.findUserinfo(context.Background(), params)
+	c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+}
+
+func TestFindOrgUserService_FindAll_validation(t *testing.T) {
+	c := qt.New(t)
+
+	// validation happens before the datastore is used
+	fous := FindOrgUserService{}
+
+	_, err := fous.FindAll(context.Background(), org.Org{}, ListRequest{Limit: MaxListLimit + 1})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+
+	_, err = fous.FindAll(context.Background(), org.Org{}, ListRequest{Limit: -1})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+
+	_, err = fous.FindAll(context.Background(), org.Org{}, ListRequest{Sort: "name"})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+
+	_, err = fous.FindAll(context.Background(), org.Org{}, ListRequest{Cursor: "not a cursor"})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}
+
+func TestUpdateUserService_Update_validation(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := UpdateUserService{}.Update(context.Background(), &UpdateUserRequest{FirstName: "Otto", LastName: " "}, audit.Audit{})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+}